@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/reconcile_users"
+	"vpn/internal/hysteria/domain"
+)
+
+const exitDrift = 3
+
+type desiredStateFile struct {
+	Users []struct {
+		Username string   `yaml:"username"`
+		Password string   `yaml:"password"`
+		Tags     []string `yaml:"tags"`
+	} `yaml:"users"`
+}
+
+func runApply(ctx context.Context, args []string, useCase *reconcile_users.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	file := fs.String("f", "", "path to desired users file (YAML)")
+	prune := fs.Bool("prune", false, "remove users that are not listed in the file")
+	yes := fs.Bool("yes", false, "skip confirmation")
+	output := fs.String("output", "text", "output format: text|json")
+
+	fs.Usage = func() {
+		printApplyHelp(errOut, "apply")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if *file == "" {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+
+	desired, err := loadDesiredUsers(*file)
+	if err != nil {
+		return err
+	}
+
+	plan, err := useCase.Plan(ctx, desired, *prune)
+	if err != nil {
+		return fmt.Errorf("plan users: %w", err)
+	}
+	if !plan.HasChanges() {
+		if *output == "json" {
+			return encodePlan(out, "ok", plan, nil)
+		}
+		printPlan(out, plan)
+		return nil
+	}
+
+	if !*yes {
+		if *output == "text" {
+			printPlan(out, plan)
+		}
+		if !confirm(bufio.NewReader(in), out, fmt.Sprintf("Apply %d changes to %s? [y/N]: ", len(plan.Items), cfg.HysteriaConfigPath)) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	result, err := useCase.Apply(ctx, desired, *prune)
+	if !applied(err) {
+		return fmt.Errorf("apply users: %w", err)
+	}
+
+	if *output == "json" {
+		if encErr := encodePlan(out, "ok", result.Plan, result.Passwords); encErr != nil {
+			return encErr
+		}
+		return wrapErr("apply users", err)
+	}
+	if *yes {
+		printPlan(out, result.Plan)
+	}
+	for _, item := range result.Plan.Items {
+		if password, ok := result.Passwords[item.Username]; ok {
+			fmt.Fprintf(out, "%s password: %s\n", item.Username, password)
+		}
+	}
+	fmt.Fprintf(out, "Applied %d changes to %s\n", len(result.Plan.Items), cfg.HysteriaConfigPath)
+	return wrapErr("apply users", err)
+}
+
+func runPlan(ctx context.Context, args []string, useCase *reconcile_users.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	file := fs.String("f", "", "path to desired users file (YAML)")
+	prune := fs.Bool("prune", false, "plan removal of users that are not listed in the file")
+	output := fs.String("output", "text", "output format: text|json")
+
+	fs.Usage = func() {
+		printApplyHelp(errOut, "plan")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if *file == "" {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+
+	desired, err := loadDesiredUsers(*file)
+	if err != nil {
+		return err
+	}
+	plan, err := useCase.Plan(ctx, desired, *prune)
+	if err != nil {
+		return fmt.Errorf("plan users: %w", err)
+	}
+
+	if *output == "json" {
+		status := "ok"
+		if plan.HasChanges() {
+			status = "drift"
+		}
+		if err := encodePlan(out, status, plan, nil); err != nil {
+			return err
+		}
+	} else {
+		printPlan(out, plan)
+	}
+	if plan.HasChanges() {
+		return exitWithCode(exitDrift)
+	}
+	return nil
+}
+
+func loadDesiredUsers(path string) ([]domain.DesiredUser, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read desired state: %w", err)
+	}
+	var file desiredStateFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parse desired state: %w", err)
+	}
+	desired := make([]domain.DesiredUser, 0, len(file.Users))
+	for _, u := range file.Users {
+		desired = append(desired, domain.DesiredUser{
+			Username: strings.TrimSpace(u.Username),
+			Password: u.Password,
+			Tags:     u.Tags,
+		})
+	}
+	return desired, nil
+}
+
+func printPlan(out io.Writer, plan domain.ReconcilePlan) {
+	for _, item := range plan.Items {
+		marker := "~"
+		switch item.Action {
+		case domain.PlanCreate:
+			marker = "+"
+		case domain.PlanDelete:
+			marker = "-"
+		}
+		fmt.Fprintf(out, "%s %-7s %-24s %s\n", marker, item.Action, item.Username, item.Reason)
+	}
+	for _, username := range plan.Unmanaged {
+		fmt.Fprintf(out, "? %-7s %-24s %s\n", "keep", username, "not listed in desired state (use --prune to remove)")
+	}
+	fmt.Fprintf(out, "Plan: %d to create, %d to rotate, %d to retag, %d to delete\n",
+		plan.Count(domain.PlanCreate), plan.Count(domain.PlanRotate), plan.Count(domain.PlanRetag), plan.Count(domain.PlanDelete))
+}
+
+func encodePlan(out io.Writer, status string, plan domain.ReconcilePlan, passwords map[string]string) error {
+	items := make([]map[string]any, 0, len(plan.Items))
+	for _, item := range plan.Items {
+		entry := map[string]any{
+			"action":   item.Action,
+			"username": item.Username,
+			"reason":   item.Reason,
+		}
+		if password, ok := passwords[item.Username]; ok {
+			entry["password"] = password
+		}
+		items = append(items, entry)
+	}
+	unmanaged := plan.Unmanaged
+	if unmanaged == nil {
+		unmanaged = []string{}
+	}
+	return json.NewEncoder(out).Encode(map[string]any{
+		"status":    status,
+		"changes":   items,
+		"unmanaged": unmanaged,
+	})
+}
+
+func printApplyHelp(w io.Writer, command string) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s %s -f users.yaml [flags]\n\n", os.Args[0], command)
+	fmt.Fprintf(w, "File format:\n")
+	fmt.Fprintf(w, "  users:\n")
+	fmt.Fprintf(w, "    - username: alice            # password generated on create\n")
+	fmt.Fprintf(w, "    - username: bob\n")
+	fmt.Fprintf(w, "      password: generate         # same as omitted\n")
+	fmt.Fprintf(w, "    - username: carol\n")
+	fmt.Fprintf(w, "      password: fixed-secret     # rotated when live password differs\n")
+	fmt.Fprintf(w, "      tags: [ops]                # listed tags replace live ones; [] clears them\n\n")
+	fmt.Fprintf(w, "Examples:\n")
+	fmt.Fprintf(w, "  %s plan -f users.yaml --prune   # exit code 3 on drift\n", os.Args[0])
+	fmt.Fprintf(w, "  %s apply -f users.yaml --prune --yes\n\n", os.Args[0])
+	fmt.Fprintf(w, "Flags:\n")
+}
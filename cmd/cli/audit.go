@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"vpn/internal/hysteria/app/list_audit_entries"
+	"vpn/internal/hysteria/app/verify_audit_log"
+	"vpn/internal/hysteria/domain"
+)
+
+func runAudit(ctx context.Context, args []string, listUseCase *list_audit_entries.UseCase, verifyUseCase *verify_audit_log.UseCase, out, errOut io.Writer) error {
+	sub := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	switch sub {
+	case "list":
+		return runAuditList(ctx, args, listUseCase, out, errOut)
+	case "export":
+		return runAuditExport(ctx, args, listUseCase, out, errOut)
+	case "verify":
+		return runAuditVerify(ctx, args, verifyUseCase, out, errOut)
+	default:
+		printAuditHelp(errOut)
+		return fmt.Errorf("unknown audit command %q", sub)
+	}
+}
+
+func runAuditList(ctx context.Context, args []string, useCase *list_audit_entries.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("audit list", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	filterFlags := registerAuditFilterFlags(fs)
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		printAuditHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	filter, err := filterFlags.filter(time.Now())
+	if err != nil {
+		return err
+	}
+
+	entries, err := useCase.Execute(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+	if *output == "json" {
+		return json.NewEncoder(out).Encode(map[string]any{
+			"status":  "ok",
+			"entries": entries,
+		})
+	}
+	for _, e := range entries {
+		line := fmt.Sprintf("%s  %-16s %-16s %-20s %s", e.Time.Local().Format(time.RFC3339), e.Action, e.Username, e.Actor, e.Result)
+		if e.Error != "" {
+			line += ": " + e.Error
+		}
+		fmt.Fprintln(out, line)
+	}
+	return nil
+}
+
+func runAuditExport(ctx context.Context, args []string, useCase *list_audit_entries.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("audit export", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	filterFlags := registerAuditFilterFlags(fs)
+	format := fs.String("format", "jsonl", "export format: jsonl|csv")
+	file := fs.String("file", "", "write export to file instead of stdout")
+	fs.Usage = func() {
+		printAuditHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "jsonl" && *format != "csv" {
+		return fmt.Errorf("invalid --format %q (allowed: jsonl|csv)", *format)
+	}
+	filter, err := filterFlags.filter(time.Now())
+	if err != nil {
+		return err
+	}
+
+	entries, err := useCase.Execute(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+
+	w := out
+	if *file != "" {
+		f, err := os.OpenFile(*file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return fmt.Errorf("create export file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "csv" {
+		return writeAuditCSV(w, entries)
+	}
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runAuditVerify(ctx context.Context, args []string, useCase *verify_audit_log.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	output := fs.String("output", "text", "output format: text|json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	result, err := useCase.Execute(ctx)
+	if err != nil {
+		return fmt.Errorf("verify audit log: %w", err)
+	}
+	if *output == "json" {
+		status := "ok"
+		if !result.Valid {
+			status = "tampered"
+		}
+		if err := json.NewEncoder(out).Encode(map[string]any{
+			"status":    status,
+			"entries":   result.Entries,
+			"broken_at": result.BrokenAt,
+			"reason":    result.Reason,
+		}); err != nil {
+			return err
+		}
+	} else if result.Valid {
+		fmt.Fprintf(out, "Audit log OK: %d entries, hash chain intact\n", result.Entries)
+	} else {
+		fmt.Fprintf(out, "Audit log TAMPERED at line %d: %s\n", result.BrokenAt, result.Reason)
+	}
+	if !result.Valid {
+		return exitWithCode(exitError)
+	}
+	return nil
+}
+
+type auditFilterFlags struct {
+	username *string
+	action   *string
+	actor    *string
+	result   *string
+	since    *string
+	until    *string
+}
+
+func registerAuditFilterFlags(fs *flag.FlagSet) auditFilterFlags {
+	return auditFilterFlags{
+		username: fs.String("username", "", "only entries for this username"),
+		action:   fs.String("action", "", "only entries with this action (add-user, rotate-password, remove-user, rotate-shared-password, migrate-auth, switch-backend, migrate-storage, tls-self-signed, update-settings, update-acl)"),
+		actor:    fs.String("actor", "", "only entries by this actor (e.g. os:root, or just os|token|tui)"),
+		result:   fs.String("result", "", "only entries with this result: ok|error"),
+		since:    fs.String("since", "", "only entries at or after this time (RFC3339 or duration like 24h)"),
+		until:    fs.String("until", "", "only entries at or before this time (RFC3339 or duration like 1h)"),
+	}
+}
+
+func (f auditFilterFlags) filter(now time.Time) (domain.AuditFilter, error) {
+	if *f.result != "" && *f.result != domain.AuditResultOK && *f.result != domain.AuditResultError {
+		return domain.AuditFilter{}, fmt.Errorf("invalid --result %q (allowed: ok|error)", *f.result)
+	}
+	since, err := parseTimeFlag("since", *f.since, now)
+	if err != nil {
+		return domain.AuditFilter{}, err
+	}
+	until, err := parseTimeFlag("until", *f.until, now)
+	if err != nil {
+		return domain.AuditFilter{}, err
+	}
+	return domain.AuditFilter{
+		Username: *f.username,
+		Action:   *f.action,
+		Actor:    *f.actor,
+		Result:   *f.result,
+		Since:    since,
+		Until:    until,
+	}, nil
+}
+
+func parseTimeFlag(name, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q (use RFC3339 or duration like 24h)", name, value)
+	}
+	return t, nil
+}
+
+func writeAuditCSV(w io.Writer, entries []domain.AuditEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "actor", "action", "username", "result", "error", "config_hash_before", "config_hash_after", "prev_hash", "hash"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			e.Time.UTC().Format(time.RFC3339Nano),
+			e.Actor,
+			e.Action,
+			e.Username,
+			e.Result,
+			e.Error,
+			e.ConfigHashBefore,
+			e.ConfigHashAfter,
+			e.PrevHash,
+			e.Hash,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func printAuditHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s audit [list] [flags]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s audit export --format jsonl|csv [--file path] [flags]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s audit verify\n\n", os.Args[0])
+	fmt.Fprintf(w, "Examples:\n")
+	fmt.Fprintf(w, "  %s audit --username alice --since 168h\n", os.Args[0])
+	fmt.Fprintf(w, "  %s audit export --format csv --file audit.csv --action remove-user\n", os.Args[0])
+	fmt.Fprintf(w, "  %s audit verify\n\n", os.Args[0])
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/migrate_auth"
+	"vpn/internal/hysteria/app/rotate_shared_password"
+	"vpn/internal/hysteria/domain"
+)
+
+func runRotateSharedPassword(ctx context.Context, args []string, useCase *rotate_shared_password.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("rotate-shared-password", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	yes := fs.Bool("yes", false, "skip confirmation")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s rotate-shared-password [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Rotates auth.password when the server uses auth.type: password.\n")
+		fmt.Fprintf(errOut, "All clients share this password and must be updated.\n\n")
+		fmt.Fprintf(errOut, "Examples:\n")
+		fmt.Fprintf(errOut, "  %s rotate-shared-password --yes --output json\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if !*yes {
+		if !confirm(bufio.NewReader(in), out, fmt.Sprintf("Rotate shared password in %s? All clients will be disconnected. [y/N]: ", cfg.HysteriaConfigPath)) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	password, err := useCase.Execute(ctx)
+	if !applied(err) {
+		return fmt.Errorf("rotate shared password: %w", err)
+	}
+	if *output == "json" {
+		if encErr := json.NewEncoder(out).Encode(map[string]any{
+			"status":       "ok",
+			"password":     password,
+			"entropy_bits": passwordEntropy(cfg),
+			"config":       cfg.HysteriaConfigPath,
+		}); encErr != nil {
+			return encErr
+		}
+		return wrapErr("rotate shared password", err)
+	}
+	fmt.Fprintf(out, "Shared password rotated in %s\n", cfg.HysteriaConfigPath)
+	fmt.Fprintf(out, "Password: %s\n", password)
+	printPasswordEntropy(out, cfg)
+	return wrapErr("rotate shared password", err)
+}
+
+func runMigrateAuth(ctx context.Context, args []string, useCase *migrate_auth.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("migrate-auth", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	to := fs.String("to", domain.AuthModeUserpass, "target auth mode (only userpass is supported)")
+	username := fs.String("username", "default", "user that receives the current shared password")
+	yes := fs.Bool("yes", false, "skip confirmation")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s migrate-auth --to userpass [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Converts auth.type: password into auth.type: userpass. The shared password is kept\n")
+		fmt.Fprintf(errOut, "as the password of --username, other users can be added with add-user afterwards.\n\n")
+		fmt.Fprintf(errOut, "Examples:\n")
+		fmt.Fprintf(errOut, "  %s migrate-auth --to userpass --username legacy --yes\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if !*yes {
+		if !confirm(bufio.NewReader(in), out, fmt.Sprintf("Migrate %s to auth.type %s with user %q? [y/N]: ", cfg.HysteriaConfigPath, *to, *username)) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	user, err := useCase.Execute(ctx, *to, *username)
+	if !applied(err) {
+		return fmt.Errorf("migrate auth: %w", err)
+	}
+	if *output == "json" {
+		if encErr := json.NewEncoder(out).Encode(map[string]any{
+			"status":    "ok",
+			"auth_mode": *to,
+			"username":  user.Username,
+			"config":    cfg.HysteriaConfigPath,
+		}); encErr != nil {
+			return encErr
+		}
+		return wrapErr("migrate auth", err)
+	}
+	fmt.Fprintf(out, "Migrated %s to auth.type %s\n", cfg.HysteriaConfigPath, *to)
+	fmt.Fprintf(out, "Shared password now belongs to user %q; update client URLs to include the username\n", user.Username)
+	return wrapErr("migrate auth", err)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/serve_auth"
+	"vpn/internal/hysteria/app/switch_backend"
+)
+
+func runAuthServer(ctx context.Context, args []string, useCase *serve_auth.UseCase, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("auth-server", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	listen := fs.String("listen", cfg.AuthServerListen, "address to serve the Hysteria HTTP auth callback on")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s auth-server [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Serves Hysteria auth.type: http from the user store (%s).\n", cfg.UserStorePath)
+		fmt.Fprintf(errOut, "Run it as a service next to Hysteria after \"switch-backend --to http\".\n\n")
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(out, "Auth server listening on http://%s/auth (store: %s)\n", *listen, cfg.UserStorePath)
+	return useCase.Execute(ctx, *listen)
+}
+
+func runSwitchBackend(ctx context.Context, args []string, useCase *switch_backend.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("switch-backend", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	to := fs.String("to", "", "target user backend: http|yaml")
+	authURL := fs.String("url", cfg.AuthServerURL(), "auth server URL written to auth.http.url (http only)")
+	yes := fs.Bool("yes", false, "skip confirmation")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s switch-backend --to http|yaml [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "http: makes the user store match auth.userpass exactly, points Hysteria at the auth server\n")
+		fmt.Fprintf(errOut, "      and stores user_backend: http in the CLI config. User changes then apply without restarts.\n")
+		fmt.Fprintf(errOut, "yaml: writes users from the store back into auth.userpass.\n\n")
+		fmt.Fprintf(errOut, "Examples:\n")
+		fmt.Fprintf(errOut, "  %s switch-backend --to http --yes\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s switch-backend --to yaml\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if *to == "" {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+	if !*yes {
+		if !confirm(bufio.NewReader(in), out, fmt.Sprintf("Switch user backend to %s and rewrite auth in %s? [y/N]: ", *to, cfg.HysteriaConfigPath)) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	result, err := useCase.Execute(ctx, *to, *authURL)
+	if !applied(err) {
+		return fmt.Errorf("switch backend: %w", err)
+	}
+	if *output == "json" {
+		if encErr := json.NewEncoder(out).Encode(map[string]any{
+			"status":  "ok",
+			"backend": result.Backend,
+			"users":   result.Users,
+			"config":  cfg.HysteriaConfigPath,
+		}); encErr != nil {
+			return encErr
+		}
+		return wrapErr("switch backend", err)
+	}
+	fmt.Fprintf(out, "User backend switched to %s (%d users)\n", result.Backend, result.Users)
+	if result.Backend == switch_backend.BackendHTTP {
+		fmt.Fprintf(out, "Hysteria now authenticates via %s; keep \"%s auth-server\" running\n", *authURL, os.Args[0])
+	}
+	return wrapErr("switch backend", err)
+}
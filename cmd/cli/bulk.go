@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/export_users"
+	"vpn/internal/hysteria/app/import_users"
+	"vpn/internal/hysteria/domain"
+)
+
+func runImport(ctx context.Context, args []string, useCase *import_users.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	file := fs.String("file", "", "path to users file (.csv or .json)")
+	format := fs.String("format", "", "input format: csv|json (default: by file extension)")
+	dryRun := fs.Bool("dry-run", false, "validate and print the plan without applying it")
+	yes := fs.Bool("yes", false, "skip confirmation")
+	output := fs.String("output", "text", "output format: text|json")
+
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s import --file users.csv [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "CSV columns: action,username[,password] (action: add|rotate|remove, empty means add).\n")
+		fmt.Fprintf(errOut, "JSON: [{\"action\": \"add\", \"username\": \"alice\", \"password\": \"...\"}, ...]\n")
+		fmt.Fprintf(errOut, "An empty password is generated; a supplied one is checked against the password\n")
+		fmt.Fprintf(errOut, "policy and never printed. Files written by export can be imported as is.\n\n")
+		fmt.Fprintf(errOut, "Examples:\n")
+		fmt.Fprintf(errOut, "  %s import --file users.csv --dry-run\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s import --file users.json --yes --output json\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if *file == "" {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("invalid --format %q (allowed: csv|json)", *format)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("open import file: %w", err)
+	}
+	defer f.Close()
+
+	var rows []import_users.Row
+	if *format == "csv" {
+		rows, err = parseImportCSV(f)
+	} else {
+		rows, err = parseImportJSON(f)
+	}
+	if err != nil {
+		return fmt.Errorf("parse import file: %w", err)
+	}
+
+	if !*dryRun && !*yes {
+		if !confirm(bufio.NewReader(in), out, fmt.Sprintf("Apply %d changes to %s? [y/N]: ", len(rows), cfg.HysteriaConfigPath)) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	report, err := useCase.Execute(ctx, rows, *dryRun)
+	if *output == "json" {
+		status := "ok"
+		if err != nil {
+			status = "error"
+		}
+		results := make([]map[string]any, 0, len(report.Results))
+		for _, r := range report.Results {
+			item := map[string]any{
+				"line":     r.Row.Line,
+				"action":   r.Row.Action,
+				"username": r.Row.Username,
+				"status":   r.Status,
+			}
+			if r.Error != "" {
+				item["error"] = r.Error
+			}
+			if r.Password != "" {
+				item["password"] = r.Password
+			}
+			if r.Supplied {
+				item["password_supplied"] = true
+			}
+			results = append(results, item)
+		}
+		if encErr := json.NewEncoder(out).Encode(map[string]any{
+			"status":  status,
+			"dry_run": report.DryRun,
+			"applied": report.Applied,
+			"results": results,
+			"config":  cfg.HysteriaConfigPath,
+		}); encErr != nil {
+			return encErr
+		}
+	} else {
+		for _, r := range report.Results {
+			line := fmt.Sprintf("line %-4d %-7s %-24s %s", r.Row.Line, r.Row.Action, r.Row.Username, r.Status)
+			if r.Error != "" {
+				line += ": " + r.Error
+			}
+			if r.Password != "" {
+				line += "  password: " + r.Password
+			}
+			if r.Supplied {
+				line += "  password: (supplied)"
+			}
+			fmt.Fprintln(out, line)
+		}
+		switch {
+		case report.Applied:
+			fmt.Fprintf(out, "Applied %d changes to %s\n", len(report.Results), cfg.HysteriaConfigPath)
+		case report.DryRun && err == nil:
+			fmt.Fprintf(out, "Dry run: %d changes validated, nothing applied\n", len(report.Results))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("import users: %w", err)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, args []string, useCase *export_users.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	format := fs.String("format", "csv", "export format: csv|json")
+	file := fs.String("file", "", "write export to file instead of stdout")
+
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s export [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Examples:\n")
+		fmt.Fprintf(errOut, "  %s export --format csv > users.csv\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s export --format json --file users.json\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("invalid --format %q (allowed: csv|json)", *format)
+	}
+
+	users, err := useCase.Execute(ctx)
+	if err != nil {
+		return fmt.Errorf("export users: %w", err)
+	}
+
+	w := out
+	if *file != "" {
+		f, err := os.OpenFile(*file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return fmt.Errorf("create export file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "json" {
+		type exportedUser struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		items := make([]exportedUser, 0, len(users))
+		for _, u := range users {
+			items = append(items, exportedUser{Username: u.Username, Password: u.Password})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"username", "password"}); err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := cw.Write([]string{u.Username, u.Password}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func parseImportCSV(r io.Reader) ([]import_users.Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	columns := map[string]int{"action": 0, "username": 1}
+	var rows []import_users.Row
+	first := true
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			first = false
+			if isImportHeader(record) {
+				columns = map[string]int{}
+				for i, name := range record {
+					columns[strings.ToLower(strings.TrimSpace(name))] = i
+				}
+				continue
+			}
+		}
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, import_users.Row{
+			Line:     line,
+			Action:   normalizeImportAction(csvField(record, columns, "action")),
+			Username: csvField(record, columns, "username"),
+			Password: csvPassword(record, columns),
+		})
+	}
+	return rows, nil
+}
+
+func parseImportJSON(r io.Reader) ([]import_users.Row, error) {
+	var items []struct {
+		Action   string `json:"action"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+	rows := make([]import_users.Row, 0, len(items))
+	for i, item := range items {
+		rows = append(rows, import_users.Row{
+			Line:     i + 1,
+			Action:   normalizeImportAction(item.Action),
+			Username: strings.TrimSpace(item.Username),
+			Password: item.Password,
+		})
+	}
+	return rows, nil
+}
+
+func isImportHeader(record []string) bool {
+	for _, field := range record {
+		if strings.EqualFold(strings.TrimSpace(field), "username") {
+			return true
+		}
+	}
+	return false
+}
+
+func csvField(record []string, columns map[string]int, name string) string {
+	idx, ok := columns[name]
+	if !ok || idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
+}
+
+// csvPassword is read without trimming so stray whitespace is rejected by
+// the policy instead of silently changing the password.
+func csvPassword(record []string, columns map[string]int) string {
+	idx, ok := columns["password"]
+	if !ok || idx >= len(record) {
+		return ""
+	}
+	return record[idx]
+}
+
+func normalizeImportAction(action string) string {
+	action = strings.ToLower(strings.TrimSpace(action))
+	if action == "" {
+		return domain.UserChangeAdd
+	}
+	return action
+}
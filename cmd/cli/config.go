@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/generate_config"
+	"vpn/internal/hysteria/app/validate_config"
+)
+
+func runConfig(ctx context.Context, args []string, uc *useCases, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printConfigHelp(errOut)
+		return exitWithCode(exitUsage)
+	}
+
+	switch args[0] {
+	case "generate":
+		return runConfigGenerate(ctx, args[1:], uc.generateConfig, cfg, in, out, errOut)
+	case "validate":
+		return runConfigValidate(ctx, args[1:], uc.validateConfig, cfg, out, errOut)
+	default:
+		printConfigHelp(errOut)
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+}
+
+func runConfigGenerate(ctx context.Context, args []string, useCase *generate_config.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("config generate", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	opts := generate_config.DefaultOptions()
+	values := map[string]*string{}
+	for _, field := range generate_config.Fields {
+		values[field.Key] = fs.String(field.Key, opts.Get(field.Key), strings.ToLower(field.Label[:1])+field.Label[1:])
+	}
+	obfsPassword := fs.String("obfs-password", "", "salamander password (default: generated)")
+	statsSecret := fs.String("stats-secret", "", "trafficStats secret (default: generated)")
+	file := fs.String("file", "", "write the config to this file instead of stdout")
+	force := fs.Bool("force", false, "overwrite --file if it exists")
+	noSaveStats := fs.Bool("no-save-stats", false, "do not store the trafficStats URL and secret in the CLI config")
+	nonInteractive := fs.Bool("non-interactive", false, "take every answer from flags and defaults")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		printConfigHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	interactive := !*nonInteractive && *output == "text" && isInteractiveInput()
+	reader := bufio.NewReader(in)
+	// The wizard output goes to stderr so stdout carries only the config.
+	promptOut := errOut
+	for _, field := range generate_config.Fields {
+		if !field.Applies(opts) {
+			continue
+		}
+		if !interactive || explicit[field.Key] {
+			if err := opts.Set(field.Key, *values[field.Key]); err != nil {
+				return err
+			}
+			continue
+		}
+		for {
+			answer, err := promptDefault(reader, promptOut, field.Label, opts.Get(field.Key))
+			if err != nil {
+				return err
+			}
+			if err := opts.Set(field.Key, answer); err != nil {
+				fmt.Fprintln(promptOut, err)
+				continue
+			}
+			break
+		}
+	}
+	opts.ObfsPassword = *obfsPassword
+	opts.StatsSecret = *statsSecret
+
+	if *file != "" && !*force {
+		if _, err := os.Stat(*file); err == nil {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", *file)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	result, err := useCase.Execute(ctx, opts, !*noSaveStats)
+	if err != nil {
+		return fmt.Errorf("generate config: %w", err)
+	}
+	if *file != "" {
+		if err := os.WriteFile(*file, result.Config, 0o600); err != nil {
+			return fmt.Errorf("write config: %w", err)
+		}
+	}
+
+	statsSaved := result.StatsURL != "" && !*noSaveStats
+	if *output == "json" {
+		payload := map[string]any{
+			"status":        "ok",
+			"obfs_password": result.ObfsPassword,
+			"stats_url":     result.StatsURL,
+			"stats_saved":   statsSaved,
+		}
+		if *file != "" {
+			payload["file"] = *file
+		} else {
+			payload["config"] = string(result.Config)
+		}
+		return json.NewEncoder(out).Encode(payload)
+	}
+
+	if *file == "" {
+		out.Write(result.Config)
+	} else {
+		fmt.Fprintf(out, "Config written to %s\n", *file)
+	}
+	if result.ObfsPassword != "" {
+		fmt.Fprintf(errOut, "Obfs password: %s\n", result.ObfsPassword)
+	}
+	if opts.TLSMode == generate_config.TLSModeSelfSigned {
+		fmt.Fprintf(errOut, "Create the certificate after upload: %s tls self-signed --cn <server ip> --ip <server ip>\n", os.Args[0])
+	}
+	if statsSaved {
+		fmt.Fprintf(errOut, "trafficStats %s saved to %s\n", result.StatsURL, cfg.Path)
+	}
+	return nil
+}
+
+func runConfigValidate(ctx context.Context, args []string, useCase *validate_config.UseCase, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	strict := fs.Bool("strict", false, "fail on warnings too")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s config validate [file] [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Checks a Hysteria server config and prints problems as line:column. Without\n")
+		fmt.Fprintf(errOut, "a file the config of the current context is checked. Exits 1 on errors.\n\n")
+		fmt.Fprintf(errOut, "Examples:\n")
+		fmt.Fprintf(errOut, "  %s config validate config.yaml\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s --context fi1 config validate --output json\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	// The file may come before or after the flags.
+	var file string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		file, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		if file != "" || fs.NArg() > 1 {
+			fs.Usage()
+			return exitWithCode(exitUsage)
+		}
+		file = fs.Arg(0)
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	target := cfg.HysteriaConfigPath
+	if file != "" {
+		cfg.HysteriaConfigPath = file
+		target = file
+		var err error
+		if useCase, err = validate_config.BuildUseCase(cfg); err != nil {
+			return fmt.Errorf("build config validate usecase: %w", err)
+		}
+	}
+
+	result, err := useCase.Execute(ctx)
+	if err != nil {
+		return fmt.Errorf("validate config: %w", err)
+	}
+	valid := result.Valid(*strict)
+
+	if *output == "json" {
+		issues := make([]map[string]any, 0, len(result.Issues))
+		for _, issue := range result.Issues {
+			issues = append(issues, map[string]any{
+				"line":     issue.Line,
+				"column":   issue.Column,
+				"path":     issue.Path,
+				"severity": issue.Severity,
+				"message":  issue.Message,
+			})
+		}
+		status := "ok"
+		if !valid {
+			status = "invalid"
+		}
+		if err := json.NewEncoder(out).Encode(map[string]any{
+			"status": status,
+			"file":   target,
+			"issues": issues,
+		}); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range result.Issues {
+			location := target
+			if issue.Line > 0 {
+				location = fmt.Sprintf("%s:%d:%d", target, issue.Line, issue.Column)
+			}
+			message := issue.Message
+			if issue.Path != "" {
+				message = issue.Path + ": " + message
+			}
+			fmt.Fprintf(out, "%s: %s: %s\n", location, issue.Severity, message)
+		}
+		if valid {
+			fmt.Fprintf(out, "%s: OK\n", target)
+		}
+	}
+	if !valid {
+		return exitWithCode(exitError)
+	}
+	return nil
+}
+
+func promptDefault(reader *bufio.Reader, out io.Writer, label, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(out, "%s: ", label)
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if value := strings.TrimSpace(line); value != "" {
+		return value, nil
+	}
+	if errors.Is(err, io.EOF) && line == "" {
+		return "", errors.New("input ended")
+	}
+	return def, nil
+}
+
+func printConfigHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s config generate [flags]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s config validate [file] [flags]\n\n", os.Args[0])
+	fmt.Fprintf(w, "generate creates a Hysteria server config. In a terminal every question is asked\n")
+	fmt.Fprintf(w, "with its default; flags answer questions up front. validate checks an existing one.\n\n")
+	fmt.Fprintf(w, "Examples:\n")
+	fmt.Fprintf(w, "  %s config generate --file config.yaml\n", os.Args[0])
+	fmt.Fprintf(w, "  %s config generate --non-interactive --acme-domains vpn.example.com --acme-email ops@example.com > config.yaml\n", os.Args[0])
+	fmt.Fprintf(w, "  %s config generate --non-interactive --tls self-signed --obfs none --stats-listen \"\"\n", os.Args[0])
+	fmt.Fprintf(w, "  %s config validate config.yaml\n\n", os.Args[0])
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/get_user_stats"
+)
+
+type globalFlags struct {
+	context     string
+	allContexts bool
+}
+
+// parseGlobalFlags pulls --context and --all-contexts out of args wherever
+// they appear, so both "vpn --context nl1 list-users" and
+// "vpn list-users --context nl1" work.
+func parseGlobalFlags(args []string) (globalFlags, []string, error) {
+	var g globalFlags
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return g, append(rest, args[i:]...), nil
+		case arg == "--all-contexts":
+			g.allContexts = true
+		case arg == "--context":
+			if i+1 >= len(args) {
+				return g, nil, errors.New("--context requires a name")
+			}
+			i++
+			g.context = args[i]
+		case strings.HasPrefix(arg, "--context="):
+			g.context = strings.TrimPrefix(arg, "--context=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if g.allContexts && g.context != "" {
+		return g, nil, errors.New("--context and --all-contexts are mutually exclusive")
+	}
+	return g, rest, nil
+}
+
+type fleetNode struct {
+	name string
+	cfg  appconfig.Config
+	uc   *useCases
+	err  error
+}
+
+// buildFleet prepares use cases for every context. A node that cannot be
+// configured keeps its error so the others still run.
+func buildFleet(ctx context.Context, cfg appconfig.Config) ([]fleetNode, error) {
+	if len(cfg.Contexts) == 0 {
+		return nil, fmt.Errorf("--all-contexts: no contexts configured in %s", cfg.Path)
+	}
+	nodes := make([]fleetNode, 0, len(cfg.Contexts))
+	for _, name := range cfg.ContextNames() {
+		node := fleetNode{name: name}
+		node.cfg, node.err = cfg.ForContext(name)
+		if node.err == nil {
+			node.cfg, node.err = resolveContainerConfig(ctx, node.cfg)
+		}
+		if node.err == nil {
+			node.uc, node.err = buildUseCases(node.cfg, nil)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func runFleet(ctx context.Context, args []string, nodes []fleetNode, in io.Reader, out, errOut io.Writer) error {
+	if len(args) == 0 {
+		printRootHelp(errOut)
+		return exitWithCode(exitUsage)
+	}
+	switch args[0] {
+	case "list-users":
+		return runListUsersFleet(ctx, args[1:], nodes, out, errOut)
+	case "add-user":
+		return runAddUserFleet(ctx, args[1:], nodes, in, out, errOut)
+	default:
+		return fmt.Errorf("%s does not support --all-contexts", args[0])
+	}
+}
+
+func runListUsersFleet(ctx context.Context, args []string, nodes []fleetNode, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("list-users", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	output := fs.String("output", "text", "output format: text|json")
+	withStats := fs.Bool("stats", false, "include online status and traffic summed over all nodes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	userNodes := map[string][]string{}
+	totals := map[string]get_user_stats.UserStats{}
+	perNode := make([]map[string]any, 0, len(nodes))
+	failed := 0
+	for _, node := range nodes {
+		entry := map[string]any{"name": node.name}
+		perNode = append(perNode, entry)
+		if node.err != nil {
+			entry["error"] = node.err.Error()
+			failed++
+			continue
+		}
+		view, err := node.uc.listUsers.View(ctx)
+		if err != nil {
+			entry["error"] = err.Error()
+			failed++
+			continue
+		}
+		users := view.Users
+		if users == nil {
+			users = []string{}
+		}
+		entry["auth_mode"] = view.Auth.Mode
+		entry["read_only"] = view.ReadOnly
+		entry["users"] = users
+		if view.Warning != "" {
+			entry["warning"] = view.Warning
+		}
+		for _, u := range users {
+			userNodes[u] = append(userNodes[u], node.name)
+		}
+		if !*withStats {
+			continue
+		}
+		stats, _ := node.uc.userStats.Execute(ctx, users)
+		for u, stat := range stats {
+			total := totals[u]
+			total.Online = total.Online || stat.Online
+			total.RxBytes += stat.RxBytes
+			total.TxBytes += stat.TxBytes
+			total.TotalBytes += stat.TotalBytes
+			totals[u] = total
+		}
+	}
+
+	usernames := make([]string, 0, len(userNodes))
+	for u := range userNodes {
+		usernames = append(usernames, u)
+	}
+	sort.Strings(usernames)
+
+	if *output == "json" {
+		users := make([]map[string]any, 0, len(usernames))
+		for _, u := range usernames {
+			users = append(users, map[string]any{"username": u, "contexts": userNodes[u]})
+		}
+		payload := map[string]any{
+			"status":   "ok",
+			"contexts": perNode,
+			"users":    users,
+		}
+		if failed > 0 {
+			payload["status"] = "partial"
+		}
+		if *withStats {
+			payload["stats"] = encodeUserStats(totals)
+		}
+		if err := json.NewEncoder(out).Encode(payload); err != nil {
+			return err
+		}
+	} else {
+		for _, entry := range perNode {
+			if msg, ok := entry["error"]; ok {
+				fmt.Fprintf(errOut, "context %s: %s\n", entry["name"], msg)
+			}
+			if msg, ok := entry["warning"]; ok {
+				fmt.Fprintf(errOut, "context %s: warning: %s\n", entry["name"], msg)
+			}
+		}
+		for _, u := range usernames {
+			line := u
+			if *withStats {
+				line = formatUserStats(u, totals[u])
+			}
+			fmt.Fprintf(out, "%-24s %s\n", line, strings.Join(userNodes[u], ","))
+		}
+	}
+	if failed > 0 {
+		return exitWithCode(exitError)
+	}
+	return nil
+}
+
+func runContext(args []string, cfg appconfig.Config, out, errOut io.Writer) error {
+	sub := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	switch sub {
+	case "list":
+		return runContextList(args, cfg, out, errOut)
+	case "use":
+		return runContextUse(args, cfg, out, errOut)
+	default:
+		printContextHelp(errOut)
+		return fmt.Errorf("unknown context command %q", sub)
+	}
+}
+
+func runContextList(args []string, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("context list", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		printContextHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	if *output == "json" {
+		contexts := make([]map[string]any, 0, len(cfg.Contexts))
+		for _, sc := range cfg.Contexts {
+			contexts = append(contexts, map[string]any{
+				"name":    sc.Name,
+				"target":  contextTarget(sc),
+				"current": sc.Name == cfg.CurrentContext,
+			})
+		}
+		return json.NewEncoder(out).Encode(map[string]any{
+			"status":   "ok",
+			"current":  cfg.CurrentContext,
+			"contexts": contexts,
+		})
+	}
+	if len(cfg.Contexts) == 0 {
+		fmt.Fprintf(errOut, "No contexts configured in %s; using %s\n", cfg.Path, cfg.HysteriaConfigPath)
+		return nil
+	}
+	for _, sc := range cfg.Contexts {
+		marker := " "
+		if sc.Name == cfg.CurrentContext {
+			marker = "*"
+		}
+		fmt.Fprintf(out, "%s %-16s %s\n", marker, sc.Name, contextTarget(sc))
+	}
+	return nil
+}
+
+func runContextUse(args []string, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("context use", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() { printContextHelp(errOut) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+	name := fs.Arg(0)
+	if err := appconfig.SetCurrentContext(cfg.Path, name); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Switched to context %q\n", name)
+	return nil
+}
+
+func contextTarget(sc appconfig.ServerContext) string {
+	if sc.SSH != "" {
+		return sc.SSH + sc.HysteriaConfigPath
+	}
+	return sc.HysteriaConfigPath
+}
+
+func printContextHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s context [list] [--output text|json]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s context use <name>\n\n", os.Args[0])
+	fmt.Fprintf(w, "Contexts are defined under \"contexts:\" in the CLI config. Any command accepts\n")
+	fmt.Fprintf(w, "--context <name> to target one node; list-users and add-user also accept --all-contexts.\n\n")
+	fmt.Fprintf(w, "Examples:\n")
+	fmt.Fprintf(w, "  %s context use nl1\n", os.Args[0])
+	fmt.Fprintf(w, "  %s list-users --all-contexts --stats\n\n", os.Args[0])
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"vpn/internal/hysteria/app/doctor"
+)
+
+func runDoctor(ctx context.Context, args []string, useCase *doctor.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s doctor [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Checks the server for problems and prints pass, warn or fail for every check\n")
+		fmt.Fprintf(errOut, "with a hint how to fix it; skip marks checks that do not apply. Exits 1 when a\n")
+		fmt.Fprintf(errOut, "check fails.\n\n")
+		fmt.Fprintf(errOut, "Checks: cli-config, hysteria-config, auth, service, listen-port, traffic-stats,\n")
+		fmt.Fprintf(errOut, "certificates, tools, usernames.\n\n")
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	report := useCase.Execute(ctx)
+	if *output == "json" {
+		checks := make([]map[string]any, 0, len(report.Checks))
+		for _, c := range report.Checks {
+			item := map[string]any{
+				"name":    c.Name,
+				"status":  c.Status,
+				"message": c.Message,
+			}
+			if len(c.Details) > 0 {
+				item["details"] = c.Details
+			}
+			if c.Hint != "" {
+				item["hint"] = c.Hint
+			}
+			checks = append(checks, item)
+		}
+		status := "ok"
+		if report.Failed() {
+			status = "failed"
+		}
+		if err := json.NewEncoder(out).Encode(map[string]any{
+			"status": status,
+			"checks": checks,
+		}); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range report.Checks {
+			fmt.Fprintf(out, "[%s] %s: %s\n", c.Status, c.Name, c.Message)
+			for _, detail := range c.Details {
+				fmt.Fprintf(out, "       %s\n", detail)
+			}
+			if c.Hint != "" {
+				fmt.Fprintf(out, "       hint: %s\n", c.Hint)
+			}
+		}
+	}
+	if report.Failed() {
+		return exitWithCode(exitError)
+	}
+	return nil
+}
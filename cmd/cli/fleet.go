@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/diff_fleet"
+	"vpn/internal/hysteria/app/sync_user"
+)
+
+func runAddUserFleet(ctx context.Context, args []string, nodes []fleetNode, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("add-user", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	username := fs.String("username", "", "username to add on every context")
+	tags := fs.String("tags", "", "comma-separated tags, e.g. team-a,ops")
+	yes := fs.Bool("yes", false, "skip confirmation")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s add-user --all-contexts --username <name> [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Creates the same credentials on every node. All nodes are checked first;\n")
+		fmt.Fprintf(errOut, "if adding or restarting fails on any node, the user is removed again everywhere.\n\n")
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if *username == "" {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+
+	cfgs, err := fleetConfigs(nodes)
+	if err != nil {
+		return err
+	}
+	useCase, err := sync_user.BuildUseCase(cfgs)
+	if err != nil {
+		return fmt.Errorf("build fleet add-user usecase: %w", err)
+	}
+	if !*yes {
+		prompt := fmt.Sprintf("Add user %q on %d nodes (%s)? [y/N]: ", *username, len(cfgs), strings.Join(fleetNames(nodes), ", "))
+		if !confirm(bufio.NewReader(in), out, prompt) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	result, syncErr := useCase.Execute(ctx, *username, splitList(*tags)...)
+	if *output == "json" {
+		payload := map[string]any{
+			"status":   "ok",
+			"username": result.Username,
+			"nodes":    encodeNodeResults(result.Nodes),
+		}
+		if syncErr != nil {
+			payload["status"] = "error"
+			payload["error"] = syncErr.Error()
+		} else {
+			payload["password"] = result.Password
+		}
+		if err := json.NewEncoder(out).Encode(payload); err != nil {
+			return err
+		}
+	} else {
+		for _, node := range result.Nodes {
+			line := fmt.Sprintf("%-16s %s", node.Name, node.Status)
+			if node.Err != nil {
+				line += "  " + node.Err.Error()
+			}
+			fmt.Fprintln(out, line)
+		}
+		if syncErr == nil {
+			fmt.Fprintf(out, "User %q added on %d nodes\nPassword: %s\n", result.Username, len(result.Nodes), result.Password)
+		}
+	}
+	if syncErr != nil {
+		if *output == "json" {
+			return exitWithCode(exitError)
+		}
+		return fmt.Errorf("add user: %w", syncErr)
+	}
+	return nil
+}
+
+func runFleetCommand(ctx context.Context, args []string, nodes []fleetNode, out, errOut io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printFleetHelp(errOut)
+		return exitWithCode(exitUsage)
+	}
+	switch args[0] {
+	case "diff":
+		return runFleetDiff(ctx, args[1:], nodes, out, errOut)
+	default:
+		printFleetHelp(errOut)
+		return fmt.Errorf("unknown fleet command %q", args[0])
+	}
+}
+
+func runFleetDiff(ctx context.Context, args []string, nodes []fleetNode, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("fleet diff", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		printFleetHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	var cfgs []appconfig.Config
+	var nodeErrors []diff_fleet.NodeError
+	for _, node := range nodes {
+		if node.err != nil {
+			nodeErrors = append(nodeErrors, diff_fleet.NodeError{Name: node.name, Err: node.err})
+			continue
+		}
+		cfgs = append(cfgs, node.cfg)
+	}
+	useCase, err := diff_fleet.BuildUseCase(cfgs)
+	if err != nil {
+		return fmt.Errorf("build fleet diff usecase: %w", err)
+	}
+	report, err := useCase.Execute(ctx)
+	if err != nil {
+		return fmt.Errorf("fleet diff: %w", err)
+	}
+	report.Errors = append(nodeErrors, report.Errors...)
+
+	if *output == "json" {
+		drift := make([]map[string]any, 0, len(report.Drift))
+		for _, d := range report.Drift {
+			drift = append(drift, map[string]any{"username": d.Username, "present": d.Present, "missing": d.Missing})
+		}
+		errs := make([]map[string]any, 0, len(report.Errors))
+		for _, e := range report.Errors {
+			errs = append(errs, map[string]any{"name": e.Name, "error": e.Err.Error()})
+		}
+		status := "ok"
+		if report.HasDrift() {
+			status = "drift"
+		}
+		if err := json.NewEncoder(out).Encode(map[string]any{
+			"status": status,
+			"nodes":  report.Nodes,
+			"drift":  drift,
+			"errors": errs,
+		}); err != nil {
+			return err
+		}
+	} else {
+		for _, e := range report.Errors {
+			fmt.Fprintf(errOut, "context %s: %v\n", e.Name, e.Err)
+		}
+		if !report.HasDrift() {
+			fmt.Fprintf(out, "All %d nodes have the same users\n", len(report.Nodes))
+		}
+		for _, d := range report.Drift {
+			fmt.Fprintf(out, "%-24s present: %-20s missing: %s\n", d.Username, strings.Join(d.Present, ","), strings.Join(d.Missing, ","))
+		}
+	}
+
+	switch {
+	case len(report.Errors) > 0:
+		return exitWithCode(exitError)
+	case report.HasDrift():
+		return exitWithCode(exitDrift)
+	}
+	return nil
+}
+
+// fleetConfigs refuses to run a fleet-wide change when any node cannot even
+// be configured, since the change could not reach every node.
+func fleetConfigs(nodes []fleetNode) ([]appconfig.Config, error) {
+	cfgs := make([]appconfig.Config, 0, len(nodes))
+	for _, node := range nodes {
+		if node.err != nil {
+			return nil, fmt.Errorf("context %s: %w", node.name, node.err)
+		}
+		cfgs = append(cfgs, node.cfg)
+	}
+	return cfgs, nil
+}
+
+func fleetNames(nodes []fleetNode) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.name)
+	}
+	return names
+}
+
+func encodeNodeResults(results []sync_user.NodeResult) []map[string]any {
+	encoded := make([]map[string]any, 0, len(results))
+	for _, r := range results {
+		entry := map[string]any{"name": r.Name, "status": r.Status}
+		if r.Err != nil {
+			entry["error"] = r.Err.Error()
+		}
+		encoded = append(encoded, entry)
+	}
+	return encoded
+}
+
+func printFleetHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s fleet diff [--output text|json]\n\n", os.Args[0])
+	fmt.Fprintf(w, "Compares users across all contexts; exits 3 when some users are missing on some nodes.\n\n")
+	fmt.Fprintf(w, "Examples:\n")
+	fmt.Fprintf(w, "  %s fleet diff\n", os.Args[0])
+	fmt.Fprintf(w, "  %s add-user --all-contexts --username alice --yes\n\n", os.Args[0])
+}
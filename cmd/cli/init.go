@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/provision_server"
+	"vpn/internal/hysteria/infra/remote"
+	"vpn/resources"
+)
+
+func runInit(ctx context.Context, args []string, cfg appconfig.Config, out, errOut io.Writer) error {
+	if len(args) > 0 && args[0] == "export-assets" {
+		return runInitExportAssets(args[1:], out, errOut)
+	}
+
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	host := fs.String("host", "", "target server host or IP (default: ssh host of the current context)")
+	sshUser := fs.String("user", "root", "ssh username")
+	sshKey := fs.String("ssh-key", "", "path to private ssh key (default: ~/.ssh/id_* or ssh-agent)")
+	knownHosts := fs.String("known-hosts", "", "known_hosts file (default: ~/.ssh/known_hosts)")
+	sshPort := fs.Int("port", 22, "ssh port")
+	configPath := fs.String("config", "", "path to local hysteria config.yaml (required; see config generate)")
+	remoteConfig := fs.String("remote-config", "", "config path on the server (default: "+provision_server.DefaultConfigPath+")")
+	version := fs.String("hysteria-version", provision_server.DefaultVersion, "hysteria release to install")
+	checksum := fs.String("sha256", "", "expected sha256 of the hysteria binary (default: from the release hashes.txt)")
+	forceConfig := fs.Bool("force-config", false, "replace an existing config on the server that differs (--check lists the differing keys)")
+	check := fs.Bool("check", false, "only show what would change; exits 3 when the server differs")
+	output := fs.String("output", "text", "output format: text|json")
+
+	useAnsible := fs.Bool("ansible", false, "run the legacy ansible playbook instead")
+	inventory := fs.String("inventory", "", "ansible inventory path or host list (with --ansible)")
+	playbookPath := fs.String("playbook", "", "path to ansible playbook (with --ansible, default: embedded)")
+	varsPath := fs.String("vars", "", "path to ansible vars yaml (with --ansible, default: embedded)")
+	become := fs.Bool("become", true, "run ansible tasks with privilege escalation (with --ansible)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s init [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Installs packages and the hysteria binary, uploads the config and enables the\n")
+		fmt.Fprintf(errOut, "systemd unit. Steps that are already in place are reported as ok and skipped.\n")
+		fmt.Fprintf(errOut, "An existing config that differs is kept unless --force-config is passed;\n")
+		fmt.Fprintf(errOut, "--check lists the keys it differs in (values are not shown).\n\n")
+		fmt.Fprintf(errOut, "Examples:\n")
+		fmt.Fprintf(errOut, "  %s init --host 1.2.3.4 --user root --ssh-key ~/.ssh/id_rsa --config config.yaml\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s init --host 1.2.3.4 --config my-config.yaml --check\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s --context fi1 init --config config.yaml\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s init --ansible --inventory inventories/prod.ini --config config.yaml\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s init export-assets --dir ./vpn-assets\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if *configPath == "" {
+		fs.Usage()
+		return fmt.Errorf("--config is required; create one with: %s config generate --file config.yaml", os.Args[0])
+	}
+
+	if *useAnsible {
+		return runInitAnsible(ansibleInit{
+			host:      *host,
+			inventory: *inventory,
+			user:      *sshUser,
+			key:       *sshKey,
+			port:      *sshPort,
+			playbook:  *playbookPath,
+			vars:      *varsPath,
+			config:    *configPath,
+			become:    *become,
+			check:     *check,
+		}, cfg, out, errOut)
+	}
+
+	target, path, err := initTarget(cfg, *host, *sshUser, *sshKey, *knownHosts, *sshPort)
+	if err != nil {
+		fs.Usage()
+		return err
+	}
+	if *remoteConfig != "" {
+		path = *remoteConfig
+	}
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		return fmt.Errorf("read hysteria config: %w", err)
+	}
+
+	useCase, err := provision_server.BuildUseCase(ctx, target)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	report, err := useCase.Execute(ctx, provision_server.Request{
+		Config:      data,
+		ConfigPath:  path,
+		ServiceName: cfg.HysteriaServiceName,
+		Version:     *version,
+		SHA256:      *checksum,
+		Check:       *check,
+		ForceConfig: *forceConfig,
+	})
+
+	if *output == "json" {
+		status := "ok"
+		switch {
+		case err != nil:
+			status = "error"
+		case *check && report.Changed():
+			status = "drift"
+		}
+		payload := map[string]any{
+			"status": status,
+			"host":   target.String(),
+			"check":  *check,
+			"steps":  encodeSteps(report.Steps),
+		}
+		if err != nil {
+			payload["error"] = err.Error()
+		}
+		if encErr := json.NewEncoder(out).Encode(payload); encErr != nil {
+			return encErr
+		}
+		if err != nil {
+			return exitWithCode(exitError)
+		}
+	} else {
+		fmt.Fprintf(out, "%s\n", target)
+		for _, step := range report.Steps {
+			fmt.Fprintf(out, "  %-9s %-13s %s\n", step.Name, step.Status, step.Detail)
+			for _, line := range strings.Split(strings.TrimSuffix(step.Diff, "\n"), "\n") {
+				if line != "" {
+					fmt.Fprintf(out, "      %s\n", line)
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("init: %w", err)
+		}
+	}
+	if *check && report.Changed() {
+		return exitWithCode(exitDrift)
+	}
+	return nil
+}
+
+// initTarget takes the host from the flags or, without --host, from the ssh
+// target of the active context together with its config path.
+func initTarget(cfg appconfig.Config, host, user, key, knownHosts string, port int) (remote.Target, string, error) {
+	if host == "" {
+		if cfg.SSH == "" {
+			return remote.Target{}, "", errors.New("--host is required unless the current context has an ssh target")
+		}
+		target, _, err := remote.Parse(cfg.SSH)
+		if err != nil {
+			return remote.Target{}, "", err
+		}
+		_, path, err := remote.Parse(cfg.HysteriaConfigPath)
+		if err != nil || path == "" {
+			path = provision_server.DefaultConfigPath
+		}
+		return target, path, nil
+	}
+	return remote.Target{
+		User:       user,
+		Host:       host,
+		Port:       port,
+		Identity:   key,
+		KnownHosts: knownHosts,
+	}, provision_server.DefaultConfigPath, nil
+}
+
+func encodeSteps(steps []provision_server.Step) []map[string]any {
+	encoded := make([]map[string]any, 0, len(steps))
+	for _, s := range steps {
+		item := map[string]any{"name": s.Name, "status": s.Status, "detail": s.Detail}
+		if s.Diff != "" {
+			item["diff"] = s.Diff
+		}
+		encoded = append(encoded, item)
+	}
+	return encoded
+}
+
+type ansibleInit struct {
+	host      string
+	inventory string
+	user      string
+	key       string
+	port      int
+	playbook  string
+	vars      string
+	config    string
+	become    bool
+	check     bool
+}
+
+func runInitAnsible(opts ansibleInit, cfg appconfig.Config, out, errOut io.Writer) error {
+	if opts.inventory == "" && opts.host == "" {
+		return fmt.Errorf("either --host or --inventory is required")
+	}
+
+	ansiblePath, err := exec.LookPath("ansible-playbook")
+	if err != nil {
+		return errors.New("ansible-playbook not found in PATH")
+	}
+
+	// Embedded assets fill in whatever was not overridden by flags.
+	workspace, err := os.MkdirTemp("", "vpn-init-")
+	if err != nil {
+		return fmt.Errorf("create init workspace: %w", err)
+	}
+	defer os.RemoveAll(workspace)
+	if _, err := resources.Extract(workspace, true); err != nil {
+		return fmt.Errorf("extract init assets: %w", err)
+	}
+	if opts.playbook == "" {
+		opts.playbook = filepath.Join(workspace, resources.Playbook)
+	}
+	if opts.vars == "" {
+		opts.vars = filepath.Join(workspace, resources.Vars)
+	}
+
+	absPlaybook, err := filepath.Abs(opts.playbook)
+	if err != nil {
+		return fmt.Errorf("resolve playbook path: %w", err)
+	}
+	absConfig, err := filepath.Abs(opts.config)
+	if err != nil {
+		return fmt.Errorf("resolve config path: %w", err)
+	}
+	absVars, err := filepath.Abs(opts.vars)
+	if err != nil {
+		return fmt.Errorf("resolve vars path: %w", err)
+	}
+
+	inv := opts.inventory
+	if inv == "" {
+		inv = opts.host + ","
+	}
+
+	cmdArgs := []string{
+		"-i", inv,
+		absPlaybook,
+		"-u", opts.user,
+		"-e", "@" + absVars,
+		"-e", fmt.Sprintf("ansible_port=%d", opts.port),
+		"-e", fmt.Sprintf("hysteria_service_name=%s", cfg.HysteriaServiceName),
+		"-e", fmt.Sprintf("hysteria_config_src=%s", absConfig),
+	}
+
+	if opts.key != "" {
+		cmdArgs = append(cmdArgs, "--private-key", opts.key)
+	}
+	if opts.become {
+		cmdArgs = append(cmdArgs, "--become")
+	}
+	if opts.check {
+		cmdArgs = append(cmdArgs, "--check")
+	}
+
+	fmt.Fprintf(out, "Running: %s %s\n", ansiblePath, strings.Join(cmdArgs, " "))
+	cmd := exec.Command(ansiblePath, cmdArgs...)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	return cmd.Run()
+}
+
+func runInitExportAssets(args []string, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("init export-assets", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	dir := fs.String("dir", "vpn-assets", "directory to write the playbook, vars and config to")
+	force := fs.Bool("force", false, "overwrite existing files")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s init export-assets [--dir <path>] [--force]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Writes the embedded init assets for customization. Pass them back with\n")
+		fmt.Fprintf(errOut, "--config, or with --ansible --playbook/--vars.\n\n")
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	written, err := resources.Extract(*dir, *force)
+	if err != nil {
+		return fmt.Errorf("export assets: %w", err)
+	}
+	for _, name := range written {
+		fmt.Fprintln(out, name)
+	}
+	return nil
+}
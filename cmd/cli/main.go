@@ -3,6 +3,8 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -10,15 +12,43 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"os/user"
 	"strings"
 
+	"golang.org/x/term"
+
 	appconfig "vpn/internal/config"
 	"vpn/internal/hysteria/app/add_user"
+	"vpn/internal/hysteria/app/control_service"
+	"vpn/internal/hysteria/app/doctor"
+	"vpn/internal/hysteria/app/export_users"
+	"vpn/internal/hysteria/app/generate_config"
+	"vpn/internal/hysteria/app/get_acl"
 	"vpn/internal/hysteria/app/get_connection_url"
+	"vpn/internal/hysteria/app/get_settings"
+	"vpn/internal/hysteria/app/get_tls_info"
+	"vpn/internal/hysteria/app/get_user_stats"
+	"vpn/internal/hysteria/app/import_users"
+	"vpn/internal/hysteria/app/issue_self_signed_cert"
+	"vpn/internal/hysteria/app/list_audit_entries"
+	"vpn/internal/hysteria/app/list_connection_urls"
 	"vpn/internal/hysteria/app/list_users"
+	"vpn/internal/hysteria/app/migrate_auth"
+	"vpn/internal/hysteria/app/migrate_storage"
+	"vpn/internal/hysteria/app/reconcile_users"
 	"vpn/internal/hysteria/app/remove_user"
+	"vpn/internal/hysteria/app/resolve_config_path"
 	"vpn/internal/hysteria/app/rotate_password"
+	"vpn/internal/hysteria/app/rotate_passwords"
+	"vpn/internal/hysteria/app/rotate_shared_password"
+	"vpn/internal/hysteria/app/serve_auth"
+	"vpn/internal/hysteria/app/switch_backend"
+	"vpn/internal/hysteria/app/test_acl"
+	"vpn/internal/hysteria/app/update_acl"
+	"vpn/internal/hysteria/app/update_settings"
+	"vpn/internal/hysteria/app/validate_config"
+	"vpn/internal/hysteria/app/verify_audit_log"
+	"vpn/internal/hysteria/domain"
 )
 
 const (
@@ -36,50 +66,315 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Config created: %s\n", loadResult.Path)
 	}
 
+	global, args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	ctx := domain.ContextWithActor(context.Background(), currentActor())
+	if err := dispatch(ctx, global, args, cfg); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		var codeErr exitCodeError
+		if errors.As(err, &codeErr) {
+			os.Exit(codeErr.code)
+		}
+		fatalf("%v", err)
+	}
+}
+
+type useCases struct {
+	addUser         *add_user.UseCase
+	rotatePassword  *rotate_password.UseCase
+	rotatePasswords *rotate_passwords.UseCase
+	removeUser      *remove_user.UseCase
+	listUsers       *list_users.UseCase
+	userStats       *get_user_stats.UseCase
+	connection      *get_connection_url.UseCase
+	listAudit       *list_audit_entries.UseCase
+	verifyAudit     *verify_audit_log.UseCase
+	importUsers     *import_users.UseCase
+	exportUsers     *export_users.UseCase
+	reconcileUsers  *reconcile_users.UseCase
+	rotateShared    *rotate_shared_password.UseCase
+	migrateAuth     *migrate_auth.UseCase
+	serveAuth       *serve_auth.UseCase
+	switchBackend   *switch_backend.UseCase
+	migrateStorage  *migrate_storage.UseCase
+	generateConfig  *generate_config.UseCase
+	issueSelfSigned *issue_self_signed_cert.UseCase
+	tlsInfo         *get_tls_info.UseCase
+	validateConfig  *validate_config.UseCase
+	getSettings     *get_settings.UseCase
+	updateSettings  *update_settings.UseCase
+	clientURLs      *list_connection_urls.UseCase
+	getACL          *get_acl.UseCase
+	updateACL       *update_acl.UseCase
+	testACL         *test_acl.UseCase
+	doctor          *doctor.UseCase
+	service         *control_service.UseCase
+}
+
+// dispatch picks the node(s) a command runs against. Context and secrets
+// management only touch the CLI config and never build use cases.
+func dispatch(ctx context.Context, global globalFlags, args []string, cfg appconfig.Config) error {
+	if len(args) > 0 && args[0] == "context" {
+		return runContext(args[1:], cfg, os.Stdout, os.Stderr)
+	}
+	if len(args) > 0 && args[0] == "secrets" {
+		return runSecrets(args[1:], cfg, os.Stdin, os.Stdout, os.Stderr)
+	}
+	if len(args) > 0 && args[0] == "fleet" {
+		nodes, err := buildFleet(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		return runFleetCommand(ctx, args[1:], nodes, os.Stdout, os.Stderr)
+	}
+	if global.allContexts {
+		nodes, err := buildFleet(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		return runFleet(ctx, args, nodes, os.Stdin, os.Stdout, os.Stderr)
+	}
+
+	cfg, err := cfg.ForContext(global.context)
+	if err != nil {
+		return err
+	}
+	var pathErr error
+	if readsHysteriaConfig(args) {
+		cfg, pathErr = resolveContainerConfig(ctx, cfg)
+		// doctor reports the failed mapping as a check instead.
+		if pathErr != nil && args[0] != "doctor" {
+			return pathErr
+		}
+	}
+	useCases, err := buildUseCases(cfg, pathErr)
+	if err != nil {
+		return err
+	}
+	return run(ctx, args, useCases, cfg, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// readsHysteriaConfig reports whether the command reads the Hysteria config,
+// so only those commands inspect the container to find it.
+func readsHysteriaConfig(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "help", "-h", "--help", "init", "service", "audit":
+		return false
+	case "config":
+		return len(args) > 1 && args[1] == "validate"
+	}
+	return true
+}
+
+// resolveContainerConfig points hysteria_config_path, the path Hysteria
+// reads inside its container, at the host file mounted there. On error cfg
+// is returned unchanged.
+func resolveContainerConfig(ctx context.Context, cfg appconfig.Config) (appconfig.Config, error) {
+	useCase, err := resolve_config_path.BuildUseCase(cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("build config path usecase: %w", err)
+	}
+	path, err := useCase.Execute(ctx)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.HysteriaConfigPath = path
+	return cfg, nil
+}
+
+// buildUseCases wires every use case for cfg; pathErr is why the config
+// path could not be resolved, reported by doctor.
+func buildUseCases(cfg appconfig.Config, pathErr error) (*useCases, error) {
 	addUserUseCase, err := add_user.BuildUseCase(cfg)
 	if err != nil {
-		fatalf("build add-user usecase: %v", err)
+		return nil, fmt.Errorf("build add-user usecase: %w", err)
 	}
 
 	rotatePasswordUseCase, err := rotate_password.BuildUseCase(cfg)
 	if err != nil {
-		fatalf("build rotate-password usecase: %v", err)
+		return nil, fmt.Errorf("build rotate-password usecase: %w", err)
+	}
+
+	rotatePasswordsUseCase, err := rotate_passwords.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build rotate-passwords usecase: %w", err)
 	}
 
 	removeUserUseCase, err := remove_user.BuildUseCase(cfg)
 	if err != nil {
-		fatalf("build remove-user usecase: %v", err)
+		return nil, fmt.Errorf("build remove-user usecase: %w", err)
 	}
 
 	listUsersUseCase, err := list_users.BuildUseCase(cfg)
 	if err != nil {
-		fatalf("build list-users usecase: %v", err)
+		return nil, fmt.Errorf("build list-users usecase: %w", err)
+	}
+
+	userStatsUseCase, err := get_user_stats.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build user-stats usecase: %w", err)
 	}
 
 	connectionURLUseCase, err := get_connection_url.BuildUseCase(cfg)
 	if err != nil {
-		fatalf("build connection usecase: %v", err)
+		return nil, fmt.Errorf("build connection usecase: %w", err)
 	}
 
-	if err := run(os.Args[1:], addUserUseCase, rotatePasswordUseCase, removeUserUseCase, listUsersUseCase, connectionURLUseCase, cfg, os.Stdin, os.Stdout, os.Stderr); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			os.Exit(0)
-		}
-		var codeErr exitCodeError
-		if errors.As(err, &codeErr) {
-			os.Exit(codeErr.code)
-		}
-		fatalf("%v", err)
+	listAuditUseCase, err := list_audit_entries.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build list-audit usecase: %w", err)
+	}
+
+	verifyAuditUseCase, err := verify_audit_log.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build verify-audit usecase: %w", err)
+	}
+
+	importUsersUseCase, err := import_users.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build import usecase: %w", err)
+	}
+
+	exportUsersUseCase, err := export_users.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build export usecase: %w", err)
+	}
+
+	reconcileUsersUseCase, err := reconcile_users.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build apply usecase: %w", err)
+	}
+
+	rotateSharedUseCase, err := rotate_shared_password.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build rotate-shared-password usecase: %w", err)
+	}
+
+	migrateAuthUseCase, err := migrate_auth.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build migrate-auth usecase: %w", err)
+	}
+
+	serveAuthUseCase, err := serve_auth.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build auth-server usecase: %w", err)
+	}
+
+	switchBackendUseCase, err := switch_backend.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build switch-backend usecase: %w", err)
+	}
+
+	migrateStorageUseCase, err := migrate_storage.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build storage usecase: %w", err)
+	}
+
+	generateConfigUseCase, err := generate_config.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build config generate usecase: %w", err)
+	}
+
+	issueSelfSignedUseCase, err := issue_self_signed_cert.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build tls self-signed usecase: %w", err)
+	}
+
+	tlsInfoUseCase, err := get_tls_info.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build tls info usecase: %w", err)
+	}
+
+	validateConfigUseCase, err := validate_config.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build config validate usecase: %w", err)
+	}
+
+	getSettingsUseCase, err := get_settings.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build settings get usecase: %w", err)
+	}
+
+	updateSettingsUseCase, err := update_settings.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build settings set usecase: %w", err)
+	}
+
+	clientURLsUseCase, err := list_connection_urls.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build client urls usecase: %w", err)
+	}
+
+	getACLUseCase, err := get_acl.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build acl list usecase: %w", err)
+	}
+
+	updateACLUseCase, err := update_acl.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build acl usecase: %w", err)
+	}
+
+	testACLUseCase, err := test_acl.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build acl test usecase: %w", err)
+	}
+
+	doctorUseCase, err := doctor.BuildUseCase(cfg, doctor.ConfigPathError{Err: pathErr})
+	if err != nil {
+		return nil, fmt.Errorf("build doctor usecase: %w", err)
 	}
+
+	serviceUseCase, err := control_service.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build service usecase: %w", err)
+	}
+
+	return &useCases{
+		addUser:         addUserUseCase,
+		rotatePassword:  rotatePasswordUseCase,
+		rotatePasswords: rotatePasswordsUseCase,
+		removeUser:      removeUserUseCase,
+		listUsers:       listUsersUseCase,
+		userStats:       userStatsUseCase,
+		connection:      connectionURLUseCase,
+		listAudit:       listAuditUseCase,
+		verifyAudit:     verifyAuditUseCase,
+		importUsers:     importUsersUseCase,
+		exportUsers:     exportUsersUseCase,
+		reconcileUsers:  reconcileUsersUseCase,
+		rotateShared:    rotateSharedUseCase,
+		migrateAuth:     migrateAuthUseCase,
+		serveAuth:       serveAuthUseCase,
+		switchBackend:   switchBackendUseCase,
+		migrateStorage:  migrateStorageUseCase,
+		generateConfig:  generateConfigUseCase,
+		issueSelfSigned: issueSelfSignedUseCase,
+		tlsInfo:         tlsInfoUseCase,
+		validateConfig:  validateConfigUseCase,
+		getSettings:     getSettingsUseCase,
+		updateSettings:  updateSettingsUseCase,
+		clientURLs:      clientURLsUseCase,
+		getACL:          getACLUseCase,
+		updateACL:       updateACLUseCase,
+		testACL:         testACLUseCase,
+		doctor:          doctorUseCase,
+		service:         serviceUseCase,
+	}, nil
 }
 
 func run(
+	ctx context.Context,
 	args []string,
-	addUserUseCase *add_user.UseCase,
-	rotatePasswordUseCase *rotate_password.UseCase,
-	removeUserUseCase *remove_user.UseCase,
-	listUsersUseCase *list_users.UseCase,
-	connectionUseCase *get_connection_url.UseCase,
+	uc *useCases,
 	cfg appconfig.Config,
 	in io.Reader,
 	out, errOut io.Writer,
@@ -94,28 +389,63 @@ func run(
 		printRootHelp(out)
 		return nil
 	case "init":
-		return runInit(args[1:], cfg, out, errOut)
+		return runInit(ctx, args[1:], cfg, out, errOut)
 	case "add-user":
-		return runAddUser(args[1:], addUserUseCase, cfg, in, out, errOut)
+		return runAddUser(ctx, args[1:], uc.addUser, cfg, in, out, errOut)
 	case "rotate-password":
-		return runRotatePassword(args[1:], rotatePasswordUseCase, cfg, in, out, errOut)
+		return runRotatePassword(ctx, args[1:], uc.rotatePassword, uc.rotatePasswords, cfg, in, out, errOut)
 	case "remove-user":
-		return runRemoveUser(args[1:], removeUserUseCase, cfg, in, out, errOut)
+		return runRemoveUser(ctx, args[1:], uc.removeUser, cfg, in, out, errOut)
 	case "list-users":
-		return runListUsers(args[1:], listUsersUseCase, out, errOut)
+		return runListUsers(ctx, args[1:], uc, out, errOut)
 	case "connection":
-		return runConnection(args[1:], connectionUseCase, in, out, errOut)
+		return runConnection(ctx, args[1:], uc.connection, in, out, errOut)
+	case "import":
+		return runImport(ctx, args[1:], uc.importUsers, cfg, in, out, errOut)
+	case "export":
+		return runExport(ctx, args[1:], uc.exportUsers, out, errOut)
+	case "apply":
+		return runApply(ctx, args[1:], uc.reconcileUsers, cfg, in, out, errOut)
+	case "plan":
+		return runPlan(ctx, args[1:], uc.reconcileUsers, out, errOut)
+	case "rotate-shared-password":
+		return runRotateSharedPassword(ctx, args[1:], uc.rotateShared, cfg, in, out, errOut)
+	case "migrate-auth":
+		return runMigrateAuth(ctx, args[1:], uc.migrateAuth, cfg, in, out, errOut)
+	case "auth-server":
+		return runAuthServer(ctx, args[1:], uc.serveAuth, cfg, out, errOut)
+	case "switch-backend":
+		return runSwitchBackend(ctx, args[1:], uc.switchBackend, cfg, in, out, errOut)
+	case "storage":
+		return runStorage(ctx, args[1:], uc.migrateStorage, cfg, in, out, errOut)
+	case "config":
+		return runConfig(ctx, args[1:], uc, cfg, in, out, errOut)
+	case "tls":
+		return runTLS(ctx, args[1:], uc, cfg, in, out, errOut)
+	case "settings":
+		return runSettings(ctx, args[1:], uc, cfg, in, out, errOut)
+	case "acl":
+		return runACL(ctx, args[1:], uc, cfg, in, out, errOut)
+	case "audit":
+		return runAudit(ctx, args[1:], uc.listAudit, uc.verifyAudit, out, errOut)
+	case "doctor":
+		return runDoctor(ctx, args[1:], uc.doctor, out, errOut)
+	case "service":
+		return runService(ctx, args[1:], uc.service, cfg, in, out, errOut)
 	default:
 		printRootHelp(errOut)
 		return fmt.Errorf("unknown command %q", args[0])
 	}
 }
 
-func runAddUser(args []string, useCase *add_user.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+func runAddUser(ctx context.Context, args []string, useCase *add_user.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
 	fs := flag.NewFlagSet("add-user", flag.ContinueOnError)
 	fs.SetOutput(errOut)
 
 	username := fs.String("username", "", "username to add")
+	tags := fs.String("tags", "", "comma-separated tags, e.g. team-a,ops")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin instead of generating one")
+	passwordFile := fs.String("password-file", "", "read the password from a file instead of generating one")
 	yes := fs.Bool("yes", false, "skip confirmation")
 	output := fs.String("output", "text", "output format: text|json")
 
@@ -124,7 +454,9 @@ func runAddUser(args []string, useCase *add_user.UseCase, cfg appconfig.Config,
 		fmt.Fprintf(errOut, "  %s add-user [flags]\n\n", os.Args[0])
 		fmt.Fprintf(errOut, "Examples:\n")
 		fmt.Fprintf(errOut, "  %s add-user --username alice\n", os.Args[0])
-		fmt.Fprintf(errOut, "  %s add-user --username alice --output json --yes\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s add-user --username alice --output json --yes\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s add-user --username alice --tags team-a,ops\n", os.Args[0])
+		fmt.Fprintf(errOut, "  printf '%%s\\n' \"$OLD_PASSWORD\" | %s add-user --username alice --password-stdin --yes\n\n", os.Args[0])
 		fmt.Fprintf(errOut, "Flags:\n")
 		fs.PrintDefaults()
 	}
@@ -137,10 +469,18 @@ func runAddUser(args []string, useCase *add_user.UseCase, cfg appconfig.Config,
 		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
 	}
 
+	if *passwordStdin && !*yes {
+		return errors.New("--password-stdin requires --yes: stdin carries the password, not the confirmation")
+	}
+
 	reader := bufio.NewReader(in)
+	supplied, err := readSuppliedPassword(reader, errOut, *passwordStdin, *passwordFile)
+	if err != nil {
+		return err
+	}
 	interactive := isInteractiveInput()
 
-	if *username == "" && interactive {
+	if *username == "" && interactive && !*passwordStdin {
 		value, err := promptRequired(reader, out, "Username")
 		if err != nil {
 			return err
@@ -159,25 +499,30 @@ func runAddUser(args []string, useCase *add_user.UseCase, cfg appconfig.Config,
 		}
 	}
 
-	password, err := useCase.Execute(context.Background(), *username)
-	if err != nil {
+	user, err := useCase.ExecuteWithPassword(ctx, *username, supplied, splitList(*tags)...)
+	if !applied(err) {
 		return fmt.Errorf("add user: %w", err)
 	}
 
 	if *output == "json" {
-		return json.NewEncoder(out).Encode(map[string]any{
+		payload := map[string]any{
 			"status":   "ok",
-			"username": *username,
-			"password": password,
+			"username": user.Username,
 			"config":   cfg.HysteriaConfigPath,
-		})
+		}
+		addPasswordPayload(payload, cfg, user.Password, supplied != "")
+		if encErr := json.NewEncoder(out).Encode(payload); encErr != nil {
+			return encErr
+		}
+		return wrapErr("add user", err)
 	}
 
-	fmt.Fprintf(out, "User %q added to %s\nPassword: %s\n", *username, cfg.HysteriaConfigPath, password)
-	return nil
+	fmt.Fprintf(out, "User %q added to %s\n", user.Username, cfg.HysteriaConfigPath)
+	printPassword(out, cfg, "Password", user.Password, supplied != "")
+	return wrapErr("add user", err)
 }
 
-func runConnection(args []string, useCase *get_connection_url.UseCase, in io.Reader, out, errOut io.Writer) error {
+func runConnection(ctx context.Context, args []string, useCase *get_connection_url.UseCase, in io.Reader, out, errOut io.Writer) error {
 	fs := flag.NewFlagSet("connection", flag.ContinueOnError)
 	fs.SetOutput(errOut)
 
@@ -215,7 +560,7 @@ func runConnection(args []string, useCase *get_connection_url.UseCase, in io.Rea
 		return exitWithCode(exitUsage)
 	}
 
-	connectionURL, err := useCase.Execute(context.Background(), *username)
+	connectionURL, err := useCase.Execute(ctx, *username)
 	if err != nil {
 		return fmt.Errorf("build connection url: %w", err)
 	}
@@ -238,11 +583,15 @@ func runConnection(args []string, useCase *get_connection_url.UseCase, in io.Rea
 	return nil
 }
 
-func runRotatePassword(args []string, useCase *rotate_password.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+func runRotatePassword(ctx context.Context, args []string, useCase *rotate_password.UseCase, batchUseCase *rotate_passwords.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
 	fs := flag.NewFlagSet("rotate-password", flag.ContinueOnError)
 	fs.SetOutput(errOut)
 
 	username := fs.String("username", "", "existing username")
+	all := fs.Bool("all", false, "rotate passwords for all users with a single restart")
+	tag := fs.String("tag", "", "rotate passwords for users with this tag with a single restart")
+	passwordStdin := fs.Bool("password-stdin", false, "read the new password from the first line of stdin instead of generating one")
+	passwordFile := fs.String("password-file", "", "read the new password from a file instead of generating one")
 	yes := fs.Bool("yes", false, "skip confirmation")
 	output := fs.String("output", "text", "output format: text|json")
 
@@ -251,7 +600,10 @@ func runRotatePassword(args []string, useCase *rotate_password.UseCase, cfg appc
 		fmt.Fprintf(errOut, "  %s rotate-password [flags]\n\n", os.Args[0])
 		fmt.Fprintf(errOut, "Examples:\n")
 		fmt.Fprintf(errOut, "  %s rotate-password --username alice\n", os.Args[0])
-		fmt.Fprintf(errOut, "  %s rotate-password --username alice --output json --yes\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s rotate-password --username alice --output json --yes\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s rotate-password --username alice --password-file ./alice.pass\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s rotate-password --all --yes\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s rotate-password --tag team-a\n\n", os.Args[0])
 		fmt.Fprintf(errOut, "Flags:\n")
 		fs.PrintDefaults()
 	}
@@ -263,10 +615,60 @@ func runRotatePassword(args []string, useCase *rotate_password.UseCase, cfg appc
 		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
 	}
 
+	if *passwordStdin && !*yes {
+		return errors.New("--password-stdin requires --yes: stdin carries the password, not the confirmation")
+	}
+
 	reader := bufio.NewReader(in)
+	if *all || *tag != "" {
+		if *username != "" {
+			return errors.New("--username cannot be combined with --all or --tag")
+		}
+		if *passwordStdin || *passwordFile != "" {
+			return errors.New("--password-stdin and --password-file set one user's password; use import for several")
+		}
+		target := "all users"
+		if !*all {
+			target = fmt.Sprintf("users tagged %q", *tag)
+		}
+		if !*yes {
+			if !confirm(reader, out, fmt.Sprintf("Rotate passwords for %s in %s? [y/N]: ", target, cfg.HysteriaConfigPath)) {
+				return errors.New("operation canceled")
+			}
+		}
+		rotated, err := batchUseCase.Execute(ctx, rotate_passwords.Selector{All: *all, Tag: *tag})
+		if !applied(err) {
+			return fmt.Errorf("rotate passwords: %w", err)
+		}
+		if *output == "json" {
+			users := make([]map[string]string, 0, len(rotated))
+			for _, u := range rotated {
+				users = append(users, map[string]string{"username": u.Username, "password": u.Password})
+			}
+			if encErr := json.NewEncoder(out).Encode(map[string]any{
+				"status":       "ok",
+				"users":        users,
+				"entropy_bits": passwordEntropy(cfg),
+				"config":       cfg.HysteriaConfigPath,
+			}); encErr != nil {
+				return encErr
+			}
+			return wrapErr("rotate passwords", err)
+		}
+		fmt.Fprintf(out, "Passwords rotated for %d users in %s\n", len(rotated), cfg.HysteriaConfigPath)
+		for _, u := range rotated {
+			fmt.Fprintf(out, "%s: %s\n", u.Username, u.Password)
+		}
+		printPasswordEntropy(out, cfg)
+		return wrapErr("rotate passwords", err)
+	}
+	supplied, err := readSuppliedPassword(reader, errOut, *passwordStdin, *passwordFile)
+	if err != nil {
+		return err
+	}
 	interactive := isInteractiveInput()
 
-	if *username == "" && interactive {
+	if *username == "" && interactive && !*passwordStdin {
 		value, err := promptRequired(reader, out, "Username")
 		if err != nil {
 			return err
@@ -285,25 +687,99 @@ func runRotatePassword(args []string, useCase *rotate_password.UseCase, cfg appc
 		}
 	}
 
-	password, err := useCase.Execute(context.Background(), *username)
-	if err != nil {
+	password, err := useCase.ExecuteWithPassword(ctx, *username, supplied)
+	if !applied(err) {
 		return fmt.Errorf("rotate password: %w", err)
 	}
 
 	if *output == "json" {
-		return json.NewEncoder(out).Encode(map[string]any{
+		payload := map[string]any{
 			"status":   "ok",
 			"username": *username,
-			"password": password,
 			"config":   cfg.HysteriaConfigPath,
-		})
+		}
+		addPasswordPayload(payload, cfg, password, supplied != "")
+		if encErr := json.NewEncoder(out).Encode(payload); encErr != nil {
+			return encErr
+		}
+		return wrapErr("rotate password", err)
 	}
 
-	fmt.Fprintf(out, "Password rotated for %q in %s\nNew password: %s\n", *username, cfg.HysteriaConfigPath, password)
-	return nil
+	fmt.Fprintf(out, "Password rotated for %q in %s\n", *username, cfg.HysteriaConfigPath)
+	printPassword(out, cfg, "New password", password, supplied != "")
+	return wrapErr("rotate password", err)
+}
+
+// passwordEntropy estimates generated passwords in whole bits.
+func passwordEntropy(cfg appconfig.Config) int {
+	return int(cfg.PasswordPolicy.GeneratorPolicy().Entropy())
+}
+
+func printPasswordEntropy(out io.Writer, cfg appconfig.Config) {
+	fmt.Fprintf(out, "Entropy: ~%d bits (%s)\n", passwordEntropy(cfg), cfg.PasswordPolicy.GeneratorPolicy())
+}
+
+// printPassword shows a generated password with its entropy; a supplied
+// one is never echoed back.
+func printPassword(out io.Writer, cfg appconfig.Config, label, password string, supplied bool) {
+	if supplied {
+		fmt.Fprintf(out, "%s: (supplied, not shown)\n", label)
+		return
+	}
+	fmt.Fprintf(out, "%s: %s\n", label, password)
+	printPasswordEntropy(out, cfg)
+}
+
+func addPasswordPayload(payload map[string]any, cfg appconfig.Config, password string, supplied bool) {
+	if supplied {
+		payload["password_supplied"] = true
+		return
+	}
+	payload["password"] = password
+	payload["entropy_bits"] = passwordEntropy(cfg)
+}
+
+// readSuppliedPassword returns the password given with --password-stdin
+// (the first line of stdin) or --password-file, or "" to generate one.
+// Only the trailing line break is trimmed; everything else is checked
+// against the password policy as is. On a terminal the password is
+// prompted for on errOut and read without echo.
+func readSuppliedPassword(reader *bufio.Reader, errOut io.Writer, fromStdin bool, file string) (string, error) {
+	var raw string
+	switch {
+	case fromStdin && file != "":
+		return "", errors.New("--password-stdin and --password-file are mutually exclusive")
+	case fromStdin && isInteractiveInput():
+		fmt.Fprint(errOut, "Password: ")
+		data, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(errOut)
+		if err != nil {
+			return "", fmt.Errorf("read password from terminal: %w", err)
+		}
+		raw = string(data)
+	case fromStdin:
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("read password from stdin: %w", err)
+		}
+		raw = line
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("read password file: %w", err)
+		}
+		raw = string(data)
+	default:
+		return "", nil
+	}
+	password := strings.TrimSuffix(strings.TrimSuffix(raw, "\n"), "\r")
+	if password == "" {
+		return "", errors.New("supplied password is empty")
+	}
+	return password, nil
 }
 
-func runRemoveUser(args []string, useCase *remove_user.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+func runRemoveUser(ctx context.Context, args []string, useCase *remove_user.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
 	fs := flag.NewFlagSet("remove-user", flag.ContinueOnError)
 	fs.SetOutput(errOut)
 
@@ -343,7 +819,7 @@ func runRemoveUser(args []string, useCase *remove_user.UseCase, cfg appconfig.Co
 			return errors.New("operation canceled")
 		}
 	}
-	if err := useCase.Execute(context.Background(), *username); err != nil {
+	if err := useCase.Execute(ctx, *username); err != nil {
 		return fmt.Errorf("remove user: %w", err)
 	}
 	if *output == "json" {
@@ -357,13 +833,15 @@ func runRemoveUser(args []string, useCase *remove_user.UseCase, cfg appconfig.Co
 	return nil
 }
 
-func runListUsers(args []string, useCase *list_users.UseCase, out, errOut io.Writer) error {
+func runListUsers(ctx context.Context, args []string, uc *useCases, out, errOut io.Writer) error {
 	fs := flag.NewFlagSet("list-users", flag.ContinueOnError)
 	fs.SetOutput(errOut)
 	output := fs.String("output", "text", "output format: text|json")
+	withStats := fs.Bool("stats", false, "include online status and traffic from the traffic stats API")
 	fs.Usage = func() {
 		fmt.Fprintf(errOut, "Usage:\n")
-		fmt.Fprintf(errOut, "  %s list-users [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s list-users [--stats] [flags]\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s list-users --all-contexts [--stats] [flags]\n\n", os.Args[0])
 		fmt.Fprintf(errOut, "Flags:\n")
 		fs.PrintDefaults()
 	}
@@ -373,104 +851,85 @@ func runListUsers(args []string, useCase *list_users.UseCase, out, errOut io.Wri
 	if *output != "text" && *output != "json" {
 		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
 	}
-	users, err := useCase.Execute(context.Background())
+	view, err := uc.listUsers.View(ctx)
 	if err != nil {
 		return fmt.Errorf("list users: %w", err)
 	}
+	users := view.Users
+	if users == nil {
+		users = []string{}
+	}
+	var stats map[string]get_user_stats.UserStats
+	if *withStats {
+		stats, _ = uc.userStats.Execute(ctx, users)
+	}
 	if *output == "json" {
-		return json.NewEncoder(out).Encode(map[string]any{
-			"status": "ok",
-			"users":  users,
-		})
+		payload := map[string]any{
+			"status":    "ok",
+			"auth_mode": view.Auth.Mode,
+			"read_only": view.ReadOnly,
+			"users":     users,
+		}
+		if view.Warning != "" {
+			payload["warning"] = view.Warning
+		}
+		if *withStats {
+			payload["stats"] = encodeUserStats(stats)
+		}
+		return json.NewEncoder(out).Encode(payload)
+	}
+	if view.ReadOnly {
+		fmt.Fprintf(errOut, "auth.type is %s: users are read-only, showing identities reported by traffic stats\n", view.Auth.Mode)
+	}
+	if view.Warning != "" {
+		fmt.Fprintf(errOut, "Warning: %s\n", view.Warning)
 	}
 	for _, u := range users {
+		if *withStats {
+			fmt.Fprintln(out, formatUserStats(u, stats[u]))
+			continue
+		}
 		fmt.Fprintln(out, u)
 	}
 	return nil
 }
 
-func runInit(args []string, cfg appconfig.Config, out, errOut io.Writer) error {
-	fs := flag.NewFlagSet("init", flag.ContinueOnError)
-	fs.SetOutput(errOut)
-
-	host := fs.String("host", "", "target server host or IP")
-	inventory := fs.String("inventory", "", "ansible inventory path or host list")
-	sshUser := fs.String("user", "root", "ssh username")
-	sshKey := fs.String("ssh-key", "", "path to private ssh key")
-	sshPort := fs.Int("port", 22, "ssh port")
-	playbookPath := fs.String("playbook", "resources/ansible/hysteria_init.yml", "path to ansible playbook")
-	varsPath := fs.String("vars", "resources/ansible/vars.yaml", "path to ansible vars yaml")
-	configPath := fs.String("config", "resources/config.yaml", "path to local hysteria config.yaml")
-	become := fs.Bool("become", true, "run tasks with privilege escalation")
-	check := fs.Bool("check", false, "ansible check mode")
-
-	fs.Usage = func() {
-		fmt.Fprintf(errOut, "Usage:\n")
-		fmt.Fprintf(errOut, "  %s init [flags]\n\n", os.Args[0])
-		fmt.Fprintf(errOut, "Examples:\n")
-		fmt.Fprintf(errOut, "  %s init --host 1.2.3.4 --user root --ssh-key ~/.ssh/id_rsa\n", os.Args[0])
-		fmt.Fprintf(errOut, "  %s init --inventory inventories/prod.ini --config resources/config.yaml\n\n", os.Args[0])
-		fmt.Fprintf(errOut, "Flags:\n")
-		fs.PrintDefaults()
-	}
-
-	if err := fs.Parse(args); err != nil {
-		return err
-	}
-
-	if *inventory == "" && *host == "" {
-		fs.Usage()
-		return fmt.Errorf("either --host or --inventory is required")
-	}
-
-	ansiblePath, err := exec.LookPath("ansible-playbook")
-	if err != nil {
-		return errors.New("ansible-playbook not found in PATH")
-	}
-
-	absPlaybook, err := filepath.Abs(*playbookPath)
-	if err != nil {
-		return fmt.Errorf("resolve playbook path: %w", err)
-	}
-	absConfig, err := filepath.Abs(*configPath)
-	if err != nil {
-		return fmt.Errorf("resolve config path: %w", err)
-	}
-	absVars, err := filepath.Abs(*varsPath)
-	if err != nil {
-		return fmt.Errorf("resolve vars path: %w", err)
-	}
-
-	inv := *inventory
-	if inv == "" {
-		inv = *host + ","
+func formatUserStats(username string, stat get_user_stats.UserStats) string {
+	online := "offline"
+	if stat.Online {
+		online = "online"
 	}
+	return fmt.Sprintf("%-24s %-7s rx=%-8s tx=%-8s", username, online, formatBytes(stat.RxBytes), formatBytes(stat.TxBytes))
+}
 
-	cmdArgs := []string{
-		"-i", inv,
-		absPlaybook,
-		"-u", *sshUser,
-		"-e", "@" + absVars,
-		"-e", fmt.Sprintf("ansible_port=%d", *sshPort),
-		"-e", fmt.Sprintf("hysteria_service_name=%s", cfg.HysteriaServiceName),
-		"-e", fmt.Sprintf("hysteria_config_src=%s", absConfig),
+func encodeUserStats(stats map[string]get_user_stats.UserStats) map[string]any {
+	encoded := make(map[string]any, len(stats))
+	for username, stat := range stats {
+		encoded[username] = map[string]any{
+			"online":   stat.Online,
+			"rx_bytes": stat.RxBytes,
+			"tx_bytes": stat.TxBytes,
+		}
 	}
+	return encoded
+}
 
-	if *sshKey != "" {
-		cmdArgs = append(cmdArgs, "--private-key", *sshKey)
-	}
-	if *become {
-		cmdArgs = append(cmdArgs, "--become")
-	}
-	if *check {
-		cmdArgs = append(cmdArgs, "--check")
+func formatBytes(v uint64) string {
+	const (
+		kb = 1024
+		mb = 1024 * kb
+		gb = 1024 * mb
+	)
+	switch {
+	case v >= gb:
+		return fmt.Sprintf("%.1fG", float64(v)/float64(gb))
+	case v >= mb:
+		return fmt.Sprintf("%.1fM", float64(v)/float64(mb))
+	case v >= kb:
+		return fmt.Sprintf("%.1fK", float64(v)/float64(kb))
+	default:
+		return fmt.Sprintf("%dB", v)
 	}
-
-	fmt.Fprintf(out, "Running: %s %s\n", ansiblePath, strings.Join(cmdArgs, " "))
-	cmd := exec.Command(ansiblePath, cmdArgs...)
-	cmd.Stdout = out
-	cmd.Stderr = errOut
-	return cmd.Run()
 }
 
 func printQRCode(out io.Writer, content string) {
@@ -492,16 +951,48 @@ func printRootHelp(w io.Writer) {
 	fmt.Fprintf(w, "Usage:\n")
 	fmt.Fprintf(w, "  %s <command> [flags]\n\n", os.Args[0])
 	fmt.Fprintf(w, "Commands:\n")
-	fmt.Fprintf(w, "  init         Run ansible playbook to bootstrap Hysteria on server\n")
+	fmt.Fprintf(w, "  init         Install and start Hysteria on a server over SSH\n")
 	fmt.Fprintf(w, "  add-user     Add user to hysteria auth.userpass\n")
 	fmt.Fprintf(w, "  remove-user  Remove existing user from hysteria auth.userpass\n")
 	fmt.Fprintf(w, "  list-users   List users from hysteria auth.userpass\n")
-	fmt.Fprintf(w, "  rotate-password Rotate password for existing user\n")
+	fmt.Fprintf(w, "  rotate-password Rotate password for existing user, all users or a tag\n")
+	fmt.Fprintf(w, "  rotate-shared-password Rotate the shared password (auth.type: password)\n")
+	fmt.Fprintf(w, "  migrate-auth Convert a shared-password server to per-user userpass auth\n")
+	fmt.Fprintf(w, "  switch-backend Move users between auth.userpass and the built-in HTTP auth backend\n")
+	fmt.Fprintf(w, "  config       Generate or validate a Hysteria server config\n")
+	fmt.Fprintf(w, "  tls          Issue a self-signed certificate or show certificate expiry\n")
+	fmt.Fprintf(w, "  settings     Show or change listen, obfs, masquerade, bandwidth and other settings\n")
+	fmt.Fprintf(w, "  acl          Edit ACL rules and outbounds, test where a destination is routed\n")
+	fmt.Fprintf(w, "  storage      Migrate users between auth.userpass and the embedded database\n")
+	fmt.Fprintf(w, "  auth-server  Serve Hysteria auth.type: http from the user store\n")
 	fmt.Fprintf(w, "  connection   Print hy2 URL and QR code for a user\n")
+	fmt.Fprintf(w, "  import       Add, rotate and remove users from a CSV/JSON file in one write\n")
+	fmt.Fprintf(w, "  export       Export users with passwords as CSV or JSON\n")
+	fmt.Fprintf(w, "  apply        Reconcile users with a desired-state YAML file in one write\n")
+	fmt.Fprintf(w, "  plan         Show changes apply would make; exits 3 on drift\n")
+	fmt.Fprintf(w, "  context      List server contexts or switch the current one\n")
+	fmt.Fprintf(w, "  secrets      Keep CLI config secrets in an encrypted vault, rotate its key\n")
+	fmt.Fprintf(w, "  fleet        Compare users across all contexts (fleet diff)\n")
+	fmt.Fprintf(w, "  audit        Show, export or verify the audit log of user changes\n")
+	fmt.Fprintf(w, "  doctor       Check the server for problems and suggest fixes\n")
+	fmt.Fprintf(w, "  service      Show status, start, stop, restart or reload Hysteria, show its logs\n")
 	fmt.Fprintf(w, "  help         Show this help\n\n")
+	fmt.Fprintf(w, "Global flags:\n")
+	fmt.Fprintf(w, "  --context <name>  Run against a named server context instead of current_context\n")
+	fmt.Fprintf(w, "  --all-contexts    Run against every context (list-users, add-user)\n\n")
 	fmt.Fprintf(w, "Use \"%s <command> --help\" for command flags.\n", os.Args[0])
 }
 
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func promptRequired(reader *bufio.Reader, out io.Writer, label string) (string, error) {
 	for {
 		fmt.Fprintf(out, "%s: ", label)
@@ -537,6 +1028,36 @@ func isInteractiveInput() bool {
 	return (info.Mode() & os.ModeCharDevice) != 0
 }
 
+func currentActor() domain.Actor {
+	if token := os.Getenv("VPN_API_TOKEN"); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return domain.Actor{Kind: domain.ActorToken, Name: hex.EncodeToString(sum[:6])}
+	}
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != name {
+		name = sudoUser + "(sudo:" + name + ")"
+	}
+	return domain.Actor{Kind: domain.ActorOS, Name: name}
+}
+
+// applied reports whether the change behind err went through: err is nil
+// or only says its audit entry was not recorded. The result, which may hold
+// new credentials, is then printed before err is returned.
+func applied(err error) bool {
+	return err == nil || errors.Is(err, domain.ErrAuditRecord)
+}
+
+// wrapErr prefixes err with op unless it is nil.
+func wrapErr(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
 func fatalf(format string, args ...any) {
 	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
 	os.Exit(exitError)
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/utils/passwordgen"
+	"vpn/internal/utils/secretvault"
+)
+
+// runSecrets manages the encrypted vault next to the CLI config. Like
+// context it only touches local files and never builds use cases.
+func runSecrets(args []string, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	sub := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+
+	switch sub {
+	case "list":
+		return runSecretsList(args, cfg, out, errOut)
+	case "set":
+		return runSecretsSet(args, cfg, in, out, errOut)
+	case "rm":
+		return runSecretsRemove(args, cfg, out, errOut)
+	case "seal":
+		return runSecretsSeal(args, cfg, out, errOut)
+	case "rotate":
+		return runSecretsRotate(args, cfg, out, errOut)
+	default:
+		printSecretsHelp(errOut)
+		return fmt.Errorf("unknown secrets command %q", sub)
+	}
+}
+
+func runSecretsList(args []string, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("secrets list", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		printSecretsHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	// cfg comes straight from the CLI config, so references are not
+	// resolved yet.
+	vault := cfg.Vault()
+	names := []string{}
+	if vault.Exists() {
+		var err error
+		if names, err = vault.Names(); err != nil {
+			return fmt.Errorf("open vault: %w", err)
+		}
+	}
+	refs := cfg.SecretRefs()
+
+	if *output == "json" {
+		items := make([]map[string]any, 0, len(refs))
+		for _, ref := range refs {
+			item := map[string]any{"field": ref.Field, "source": ref.Source}
+			if ref.Context != "" {
+				item["context"] = ref.Context
+			}
+			if ref.Source != secretvault.SourceLiteral {
+				item["target"] = ref.Target
+			}
+			items = append(items, item)
+		}
+		return json.NewEncoder(out).Encode(map[string]any{
+			"status":   "ok",
+			"vault":    vault.Path(),
+			"key_file": vault.KeyFile(),
+			"entries":  names,
+			"config":   items,
+		})
+	}
+
+	if vault.Exists() {
+		fmt.Fprintf(out, "Vault %s (key %s): %d entries\n", vault.Path(), vault.KeyFile(), len(names))
+		for _, name := range names {
+			fmt.Fprintf(out, "  %s\n", name)
+		}
+	} else {
+		fmt.Fprintf(out, "No vault yet; \"secrets set\" or \"secrets seal\" creates %s\n", vault.Path())
+	}
+	for _, ref := range refs {
+		field := ref.Field
+		if ref.Context != "" {
+			field = ref.Context + "." + field
+		}
+		if ref.Source == secretvault.SourceLiteral {
+			fmt.Fprintf(out, "%-40s plaintext in %s (run \"secrets seal\")\n", field, cfg.Path)
+			continue
+		}
+		fmt.Fprintf(out, "%-40s %s:%s\n", field, ref.Source, ref.Target)
+	}
+	return nil
+}
+
+func runSecretsSet(args []string, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("secrets set", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	generate := fs.Bool("generate", false, "generate a random secret and print it")
+	fs.Usage = func() {
+		printSecretsHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	positional, err := parseWithPositional(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+	name := positional[0]
+
+	var value string
+	if *generate {
+		value, err = passwordgen.NewGenerator(passwordgen.DefaultPolicy()).Generate()
+	} else {
+		if isInteractiveInput() {
+			fmt.Fprintf(errOut, "Secret %s\n", name)
+		}
+		value, err = readSuppliedPassword(bufio.NewReader(in), errOut, true, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	vault := cfg.Vault()
+	created, err := vault.Set(name, value)
+	if err != nil {
+		return fmt.Errorf("store secret: %w", err)
+	}
+	if created {
+		warnNewVaultKey(errOut, vault.KeyFile())
+	}
+	fmt.Fprintf(out, "Stored %s in %s; reference it as vault:%s\n", name, vault.Path(), name)
+	if *generate {
+		fmt.Fprintf(out, "Secret: %s\n", value)
+	}
+	return nil
+}
+
+func runSecretsRemove(args []string, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("secrets rm", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() { printSecretsHelp(errOut) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+	name := fs.Arg(0)
+	if err := cfg.Vault().Delete(name); err != nil {
+		return fmt.Errorf("remove secret: %w", err)
+	}
+	fmt.Fprintf(out, "Removed %s from %s\n", name, cfg.Vault().Path())
+	return nil
+}
+
+func runSecretsSeal(args []string, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("secrets seal", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() { printSecretsHelp(errOut) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	vault := cfg.Vault()
+	existed := vault.Exists()
+	sealed, err := appconfig.SealSecrets(cfg.Path)
+	if !existed && vault.Exists() {
+		warnNewVaultKey(errOut, vault.KeyFile())
+	}
+	if err != nil {
+		return fmt.Errorf("seal secrets: %w", err)
+	}
+	if len(sealed) == 0 {
+		fmt.Fprintf(out, "No plaintext secrets in %s\n", cfg.Path)
+		return nil
+	}
+	for _, name := range sealed {
+		fmt.Fprintf(out, "Moved %s to vault:%s\n", name, name)
+	}
+	return nil
+}
+
+func runSecretsRotate(args []string, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("secrets rotate", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() { printSecretsHelp(errOut) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	vault := cfg.Vault()
+	if !vault.Exists() {
+		return fmt.Errorf("no vault key at %s; nothing to rotate", vault.KeyFile())
+	}
+	if err := vault.Rotate(); err != nil {
+		return fmt.Errorf("rotate vault key: %w", err)
+	}
+	fmt.Fprintf(out, "Re-encrypted %s with a new key in %s\n", vault.Path(), vault.KeyFile())
+	warnNewVaultKey(errOut, vault.KeyFile())
+	return nil
+}
+
+func warnNewVaultKey(w io.Writer, keyFile string) {
+	fmt.Fprintf(w, "New vault key written to %s; back it up apart from the vault, the secrets cannot be recovered without it\n", keyFile)
+}
+
+func printSecretsHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s secrets [list] [--output text|json]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s secrets set <name> [--generate]   (reads the secret from stdin)\n", os.Args[0])
+	fmt.Fprintf(w, "  %s secrets rm <name>\n", os.Args[0])
+	fmt.Fprintf(w, "  %s secrets seal     Move plaintext secrets of the CLI config into the vault\n", os.Args[0])
+	fmt.Fprintf(w, "  %s secrets rotate   Re-encrypt the vault with a new key\n\n", os.Args[0])
+	fmt.Fprintf(w, "Secret fields of the CLI config accept env:NAME, file:/path (mode 0600) and\n")
+	fmt.Fprintf(w, "vault:name in place of the value.\n\n")
+	fmt.Fprintf(w, "Examples:\n")
+	fmt.Fprintf(w, "  %s secrets seal\n", os.Args[0])
+	fmt.Fprintf(w, "  printf '%%s\\n' \"$SECRET\" | %s secrets set traffic-stats\n\n", os.Args[0])
+}
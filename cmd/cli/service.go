@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/control_service"
+	"vpn/internal/hysteria/domain"
+)
+
+func runService(ctx context.Context, args []string, useCase *control_service.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printServiceHelp(errOut)
+		return exitWithCode(exitUsage)
+	}
+
+	switch args[0] {
+	case "status":
+		return runServiceStatus(ctx, args[1:], useCase, cfg, out, errOut)
+	case control_service.ActionStart, control_service.ActionStop, control_service.ActionRestart, control_service.ActionReload:
+		return runServiceControl(ctx, args[0], args[1:], useCase, cfg, in, out, errOut)
+	case "logs":
+		return runServiceLogs(ctx, args[1:], useCase, out, errOut)
+	default:
+		printServiceHelp(errOut)
+		return fmt.Errorf("unknown service command %q", args[0])
+	}
+}
+
+func runServiceStatus(ctx context.Context, args []string, useCase *control_service.UseCase, cfg appconfig.Config, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("service status", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		printServiceHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	status, err := useCase.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("service status: %w", err)
+	}
+	return printServiceStatus(out, *output, cfg.Service().String(), status)
+}
+
+func runServiceControl(ctx context.Context, action string, args []string, useCase *control_service.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("service "+action, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	yes := fs.Bool("yes", false, "skip confirmation (stop only)")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		printServiceHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if action == control_service.ActionStop && !*yes {
+		if !confirm(bufio.NewReader(in), out, fmt.Sprintf("Stop %s? Connected clients are dropped. [y/N]: ", cfg.Service().String())) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	status, err := useCase.Control(ctx, action)
+	if err != nil {
+		return fmt.Errorf("service %w", err)
+	}
+	return printServiceStatus(out, *output, cfg.Service().String(), status)
+}
+
+func runServiceLogs(ctx context.Context, args []string, useCase *control_service.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("service logs", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	lines := fs.Int("lines", control_service.DefaultLogLines, "number of recent lines to show")
+	follow := fs.Bool("follow", false, "keep printing new lines until interrupted")
+	fs.Usage = func() {
+		printServiceHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := useCase.Logs(ctx, out, *lines, *follow); err != nil {
+		return fmt.Errorf("service logs: %w", err)
+	}
+	return nil
+}
+
+func printServiceStatus(out io.Writer, output, name string, status domain.ServiceStatus) error {
+	if output == "json" {
+		item := map[string]any{
+			"service": name,
+			"manager": status.Manager,
+			"state":   status.State,
+			"active":  status.Active(),
+		}
+		if status.Detail != "" {
+			item["detail"] = status.Detail
+		}
+		if status.PID > 0 {
+			item["pid"] = status.PID
+		}
+		if status.Since != "" {
+			item["since"] = status.Since
+		}
+		return json.NewEncoder(out).Encode(item)
+	}
+	fmt.Fprintf(out, "%s: %s is %s", status.Manager, name, status.State)
+	if status.Detail != "" && status.Detail != status.State {
+		fmt.Fprintf(out, " (%s)", status.Detail)
+	}
+	fmt.Fprintln(out)
+	if status.PID > 0 {
+		fmt.Fprintf(out, "PID:   %d\n", status.PID)
+	}
+	if status.Since != "" {
+		fmt.Fprintf(out, "Since: %s\n", status.Since)
+	}
+	return nil
+}
+
+func printServiceHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s service status|start|stop|restart|reload [flags]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s service logs [--lines N] [--follow]\n\n", os.Args[0])
+	fmt.Fprintf(w, "Controls the Hysteria service through systemd, SysV service, OpenRC or Docker,\n")
+	fmt.Fprintf(w, "detected on the node unless hysteria_service_manager is set. A container set by\n")
+	fmt.Fprintf(w, "hysteria_container or hysteria_compose_service is controlled over the Docker\n")
+	fmt.Fprintf(w, "Engine API. Logs come from journalctl or the container, or from hysteria_log_file\n")
+	fmt.Fprintf(w, "when it is set.\n\n")
+	fmt.Fprintf(w, "Examples:\n")
+	fmt.Fprintf(w, "  %s service status --output json\n", os.Args[0])
+	fmt.Fprintf(w, "  %s service stop --yes\n", os.Args[0])
+	fmt.Fprintf(w, "  %s service logs --lines 200 --follow\n\n", os.Args[0])
+}
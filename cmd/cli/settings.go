@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/get_settings"
+	"vpn/internal/hysteria/app/list_connection_urls"
+	"vpn/internal/hysteria/app/update_settings"
+	"vpn/internal/hysteria/domain"
+)
+
+func runSettings(ctx context.Context, args []string, uc *useCases, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printSettingsHelp(errOut)
+		return exitWithCode(exitUsage)
+	}
+
+	switch args[0] {
+	case "get":
+		return runSettingsGet(ctx, args[1:], uc.getSettings, out, errOut)
+	case "set":
+		return runSettingsSet(ctx, args[1:], false, uc, cfg, in, out, errOut)
+	case "unset":
+		return runSettingsSet(ctx, args[1:], true, uc, cfg, in, out, errOut)
+	default:
+		printSettingsHelp(errOut)
+		return fmt.Errorf("unknown settings command %q", args[0])
+	}
+}
+
+func runSettingsGet(ctx context.Context, args []string, useCase *get_settings.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("settings get", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s settings get [key...] [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Shows server settings outside of auth. Unset keys use the Hysteria default.\n\n")
+		fmt.Fprintf(errOut, "Keys:\n")
+		for _, def := range domain.SettingDefs {
+			fmt.Fprintf(errOut, "  %-22s %s\n", def.Key, def.Description)
+		}
+		fmt.Fprintf(errOut, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	keys, err := parseWithPositional(fs, args)
+	if err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	values, err := useCase.Execute(ctx, keys...)
+	if err != nil {
+		return fmt.Errorf("get settings: %w", err)
+	}
+	if *output == "json" {
+		items := make([]map[string]any, 0, len(values))
+		for _, v := range values {
+			items = append(items, map[string]any{
+				"key":             v.Def.Key,
+				"value":           v.Value,
+				"set":             v.Set,
+				"kind":            v.Def.Kind,
+				"affects_clients": v.Def.AffectsClients,
+			})
+		}
+		return json.NewEncoder(out).Encode(map[string]any{
+			"status":   "ok",
+			"settings": items,
+		})
+	}
+	if len(keys) == 1 {
+		fmt.Fprintln(out, values[0].Value)
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE")
+	for _, v := range values {
+		value := v.Value
+		if !v.Set {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", v.Def.Key, value)
+	}
+	return w.Flush()
+}
+
+func runSettingsSet(ctx context.Context, args []string, unset bool, uc *useCases, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	name := "settings set"
+	if unset {
+		name = "settings unset"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	var generate *bool
+	if !unset {
+		generate = fs.Bool("generate-obfs-password", false, "set obfs.password to a new random password")
+	}
+	yes := fs.Bool("yes", false, "skip confirmation")
+	urls := fs.Bool("urls", false, "print the new client URLs when they changed")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		if unset {
+			fmt.Fprintf(errOut, "  %s settings unset <key>... [flags]\n\n", os.Args[0])
+			fmt.Fprintf(errOut, "Removes keys from the config so Hysteria uses its defaults, then restarts the service.\n\n")
+		} else {
+			fmt.Fprintf(errOut, "  %s settings set <key>=<value>... [flags]\n\n", os.Args[0])
+			fmt.Fprintf(errOut, "Changes server settings in one write and restarts the service. Changing listen\n")
+			fmt.Fprintf(errOut, "or obfs changes every client URL; the new URLs can be printed afterwards.\n\n")
+			fmt.Fprintf(errOut, "Examples:\n")
+			fmt.Fprintf(errOut, "  %s settings set --generate-obfs-password --urls --yes\n", os.Args[0])
+			fmt.Fprintf(errOut, "  %s settings set masquerade.type=proxy masquerade.url=https://example.com\n", os.Args[0])
+			fmt.Fprintf(errOut, "  %s settings set bandwidth.up=\"1 gbps\" udpIdleTimeout=90s\n\n", os.Args[0])
+		}
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	positional, err := parseWithPositional(fs, args)
+	if err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	req := update_settings.Request{GenerateObfsPassword: generate != nil && *generate}
+	for _, arg := range positional {
+		change := domain.SettingChange{Key: arg}
+		if !unset {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				fs.Usage()
+				return fmt.Errorf("expected key=value, got %q", arg)
+			}
+			change = domain.SettingChange{Key: strings.TrimSpace(key), Value: value}
+		}
+		req.Changes = append(req.Changes, change)
+	}
+	if len(req.Changes) == 0 && !req.GenerateObfsPassword {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+
+	reader := bufio.NewReader(in)
+	if !*yes {
+		if settingsAffectClients(req) {
+			fmt.Fprintln(out, "Warning: this changes every client URL; existing clients stop connecting until they get the new URL.")
+		}
+		if !confirm(reader, out, fmt.Sprintf("Apply settings to %s and restart the service? [y/N]: ", cfg.HysteriaConfigPath)) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	result, err := uc.updateSettings.Execute(ctx, req)
+	if !applied(err) {
+		return fmt.Errorf("update settings: %w", err)
+	}
+
+	var clientURLs []list_connection_urls.ClientURL
+	if result.ClientsAffected() {
+		regenerate := *urls
+		if !regenerate && !*yes && *output == "text" {
+			regenerate = confirm(reader, out, "Client URLs changed. Print the new URLs? [y/N]: ")
+		}
+		if regenerate {
+			var urlsErr error
+			if clientURLs, urlsErr = uc.clientURLs.Execute(ctx); urlsErr != nil {
+				return errors.Join(fmt.Errorf("regenerate client urls: %w", urlsErr), wrapErr("update settings", err))
+			}
+		}
+	}
+
+	if *output == "json" {
+		updates := make([]map[string]any, 0, len(result.Updates))
+		for _, u := range result.Updates {
+			updates = append(updates, map[string]any{"key": u.Key, "old": u.Old, "new": u.New})
+		}
+		payload := map[string]any{
+			"status":           "ok",
+			"updates":          updates,
+			"clients_affected": result.ClientsAffected(),
+			"config":           cfg.HysteriaConfigPath,
+		}
+		if clientURLs != nil {
+			items := make([]map[string]string, 0, len(clientURLs))
+			for _, c := range clientURLs {
+				items = append(items, map[string]string{"username": c.Username, "url": c.URL})
+			}
+			payload["client_urls"] = items
+		}
+		if encErr := json.NewEncoder(out).Encode(payload); encErr != nil {
+			return encErr
+		}
+		return wrapErr("update settings", err)
+	}
+
+	if len(result.Updates) == 0 {
+		fmt.Fprintln(out, "Settings already up to date, nothing changed")
+		return wrapErr("update settings", err)
+	}
+	for _, u := range result.Updates {
+		fmt.Fprintf(out, "%s: %s -> %s\n", u.Key, settingLabel(u.Old), settingLabel(u.New))
+	}
+	fmt.Fprintf(out, "Settings updated in %s\n", cfg.HysteriaConfigPath)
+	if result.ClientsAffected() && clientURLs == nil {
+		fmt.Fprintf(out, "Client URLs changed; resend them with \"%s connection\"\n", os.Args[0])
+	}
+	printClientURLs(out, clientURLs)
+	return wrapErr("update settings", err)
+}
+
+// parseWithPositional allows positional arguments anywhere between the flags.
+func parseWithPositional(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if args = fs.Args(); len(args) == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, args[0]), args[1:]
+	}
+}
+
+func settingsAffectClients(req update_settings.Request) bool {
+	if req.GenerateObfsPassword {
+		return true
+	}
+	for _, change := range req.Changes {
+		if def, err := domain.LookupSetting(change.Key); err == nil && def.AffectsClients {
+			return true
+		}
+	}
+	return false
+}
+
+func settingLabel(value string) string {
+	if value == "" {
+		return "(unset)"
+	}
+	return value
+}
+
+func printClientURLs(out io.Writer, urls []list_connection_urls.ClientURL) {
+	if len(urls) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "\nNew client URLs:")
+	for _, c := range urls {
+		if c.Username == "" {
+			fmt.Fprintf(out, "  %s\n", c.URL)
+			continue
+		}
+		fmt.Fprintf(out, "  %s: %s\n", c.Username, c.URL)
+	}
+}
+
+func printSettingsHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s settings get [key...] [--output json]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s settings set <key>=<value>... [--generate-obfs-password] [flags]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s settings unset <key>... [flags]\n\n", os.Args[0])
+	fmt.Fprintf(w, "Reads and changes server settings outside of auth: listen, obfs, masquerade,\n")
+	fmt.Fprintf(w, "bandwidth, ignoreClientBandwidth, udpIdleTimeout and others.\n")
+	fmt.Fprintf(w, "Run \"%s settings get --help\" for the list of keys.\n\n", os.Args[0])
+}
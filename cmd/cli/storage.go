@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/migrate_storage"
+)
+
+func runStorage(ctx context.Context, args []string, useCase *migrate_storage.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printStorageHelp(errOut)
+		return exitWithCode(exitUsage)
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runStorageMigrate(ctx, args[1:], useCase, cfg, in, out, errOut)
+	default:
+		printStorageHelp(errOut)
+		return fmt.Errorf("unknown storage command %q", args[0])
+	}
+}
+
+func runStorageMigrate(ctx context.Context, args []string, useCase *migrate_storage.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("storage migrate", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	from := fs.String("from", cfg.UserBackend, "source user backend: yaml|db")
+	to := fs.String("to", "", "target user backend: yaml|db")
+	yes := fs.Bool("yes", false, "skip confirmation")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		printStorageHelp(errOut)
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if *to == "" {
+		fs.Usage()
+		return exitWithCode(exitUsage)
+	}
+	if !*yes {
+		if !confirm(bufio.NewReader(in), out, fmt.Sprintf("Copy users from %s to %s and make %s the active backend? [y/N]: ", *from, *to, *to)) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	result, err := useCase.Execute(ctx, *from, *to)
+	if !applied(err) {
+		return fmt.Errorf("migrate storage: %w", err)
+	}
+	if *output == "json" {
+		if encErr := json.NewEncoder(out).Encode(map[string]any{
+			"status": "ok",
+			"from":   result.From,
+			"to":     result.To,
+			"users":  result.Users,
+		}); encErr != nil {
+			return encErr
+		}
+		return wrapErr("migrate storage", err)
+	}
+	fmt.Fprintf(out, "Migrated %d users from %s to %s\n", result.Users, result.From, result.To)
+	if result.To == migrate_storage.BackendDB {
+		fmt.Fprintf(out, "Users are stored in %s; auth.userpass is rendered from it on every change\n", cfg.UserDBPath)
+	}
+	return wrapErr("migrate storage", err)
+}
+
+func printStorageHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s storage migrate --from yaml|db --to yaml|db [flags]\n\n", os.Args[0])
+	fmt.Fprintf(w, "db keeps users and their metadata in an embedded database (user_db_path)\n")
+	fmt.Fprintf(w, "and renders auth.userpass from it. The password does not change, so no restart is needed.\n\n")
+	fmt.Fprintf(w, "Examples:\n")
+	fmt.Fprintf(w, "  %s storage migrate --from yaml --to db\n", os.Args[0])
+	fmt.Fprintf(w, "  %s storage migrate --from db --to yaml --yes\n\n", os.Args[0])
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/get_tls_info"
+	"vpn/internal/hysteria/app/issue_self_signed_cert"
+	"vpn/internal/hysteria/domain"
+)
+
+func runTLS(ctx context.Context, args []string, uc *useCases, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printTLSHelp(errOut)
+		return exitWithCode(exitUsage)
+	}
+
+	switch args[0] {
+	case "self-signed":
+		return runTLSSelfSigned(ctx, args[1:], uc.issueSelfSigned, cfg, in, out, errOut)
+	case "info":
+		return runTLSInfo(ctx, args[1:], uc.tlsInfo, out, errOut)
+	default:
+		printTLSHelp(errOut)
+		return fmt.Errorf("unknown tls command %q", args[0])
+	}
+}
+
+func runTLSSelfSigned(ctx context.Context, args []string, useCase *issue_self_signed_cert.UseCase, cfg appconfig.Config, in io.Reader, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("tls self-signed", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	cn := fs.String("cn", "", "certificate common name, usually the server IP or host")
+	ips := fs.String("ip", "", "comma-separated IP addresses for the certificate")
+	dnsNames := fs.String("dns", "", "comma-separated DNS names for the certificate")
+	days := fs.Int("days", issue_self_signed_cert.DefaultDays, "validity in days")
+	certPath := fs.String("cert-path", "", "certificate path on the server (default: tls.cert or server.crt next to the config)")
+	keyPath := fs.String("key-path", "", "key path on the server (default: tls.key or server.key next to the config)")
+	yes := fs.Bool("yes", false, "skip confirmation")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s tls self-signed --cn <host> [--ip <ip>] [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Issues a self-signed certificate and replaces the acme section with tls.cert and\n")
+		fmt.Fprintf(errOut, "tls.key. Client URLs then carry insecure=1 and the pinSHA256 fingerprint.\n\n")
+		fmt.Fprintf(errOut, "Examples:\n")
+		fmt.Fprintf(errOut, "  %s tls self-signed --cn 1.2.3.4 --ip 1.2.3.4 --yes\n", os.Args[0])
+		fmt.Fprintf(errOut, "  %s tls self-signed --cn test.local --dns test.local --days 365\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+	if strings.TrimSpace(*cn) == "" {
+		fs.Usage()
+		return errors.New("--cn is required")
+	}
+	if !*yes {
+		if !confirm(bufio.NewReader(in), out, fmt.Sprintf("Replace the TLS settings in %s with a self-signed certificate for %s? [y/N]: ", cfg.HysteriaConfigPath, *cn)) {
+			return errors.New("operation canceled")
+		}
+	}
+
+	result, err := useCase.Execute(ctx, issue_self_signed_cert.Request{
+		CommonName: *cn,
+		IPs:        splitList(*ips),
+		DNSNames:   splitList(*dnsNames),
+		Days:       *days,
+		CertPath:   *certPath,
+		KeyPath:    *keyPath,
+	})
+	if !applied(err) {
+		return fmt.Errorf("issue self-signed certificate: %w", err)
+	}
+	if *output == "json" {
+		if encErr := json.NewEncoder(out).Encode(map[string]any{
+			"status":     "ok",
+			"cert":       result.CertPath,
+			"key":        result.KeyPath,
+			"pin_sha256": result.PinSHA256,
+			"not_after":  result.NotAfter.UTC().Format(time.RFC3339),
+			"config":     cfg.HysteriaConfigPath,
+		}); encErr != nil {
+			return encErr
+		}
+		return wrapErr("issue self-signed certificate", err)
+	}
+	fmt.Fprintf(out, "Certificate written to %s (key %s)\n", result.CertPath, result.KeyPath)
+	fmt.Fprintf(out, "Valid until: %s\n", result.NotAfter.UTC().Format(time.RFC3339))
+	fmt.Fprintf(out, "pinSHA256: %s\n", result.PinSHA256)
+	fmt.Fprintf(out, "Client URLs now include insecure=1&pinSHA256=...; resend them with connection\n")
+	return wrapErr("issue self-signed certificate", err)
+}
+
+func runTLSInfo(ctx context.Context, args []string, useCase *get_tls_info.UseCase, out, errOut io.Writer) error {
+	fs := flag.NewFlagSet("tls info", flag.ContinueOnError)
+	fs.SetOutput(errOut)
+
+	acmeDir := fs.String("acme-dir", "", "ACME storage directory on the server (default: acme.dir of the config)")
+	output := fs.String("output", "text", "output format: text|json")
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "Usage:\n")
+		fmt.Fprintf(errOut, "  %s tls info [flags]\n\n", os.Args[0])
+		fmt.Fprintf(errOut, "Shows the server certificates and when they expire, for ACME and tls.cert.\n\n")
+		fmt.Fprintf(errOut, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid --output %q (allowed: text|json)", *output)
+	}
+
+	status, err := useCase.Execute(ctx, *acmeDir)
+	if err != nil {
+		return fmt.Errorf("tls info: %w", err)
+	}
+
+	now := time.Now()
+	if *output == "json" {
+		certs := make([]map[string]any, 0, len(status.Certificates))
+		for _, c := range status.Certificates {
+			item := map[string]any{"name": c.Name, "path": c.Path}
+			if c.Err != nil {
+				item["error"] = c.Err.Error()
+			}
+			if c.Cert != nil {
+				item["subject"] = c.Cert.Subject
+				item["dns_names"] = c.Cert.DNSNames
+				item["ips"] = c.Cert.IPs
+				item["not_before"] = c.Cert.NotBefore.UTC().Format(time.RFC3339)
+				item["not_after"] = c.Cert.NotAfter.UTC().Format(time.RFC3339)
+				item["days_left"] = daysLeft(c.Cert.NotAfter, now)
+				item["self_signed"] = c.Cert.SelfSigned
+				item["pin_sha256"] = c.Cert.PinSHA256
+			}
+			certs = append(certs, item)
+		}
+		return json.NewEncoder(out).Encode(map[string]any{
+			"status":       "ok",
+			"mode":         status.Mode,
+			"certificates": certs,
+		})
+	}
+
+	fmt.Fprintf(out, "Mode: %s\n", status.Mode)
+	for _, c := range status.Certificates {
+		fmt.Fprintf(out, "\n%s\n", c.Name)
+		if c.Err != nil {
+			fmt.Fprintf(out, "  error:     %v\n", c.Err)
+			continue
+		}
+		fmt.Fprintf(out, "  path:      %s\n", c.Path)
+		if names := append(append([]string{}, c.Cert.DNSNames...), c.Cert.IPs...); len(names) > 0 {
+			fmt.Fprintf(out, "  names:     %s\n", strings.Join(names, ", "))
+		}
+		fmt.Fprintf(out, "  expires:   %s (%s)\n", c.Cert.NotAfter.UTC().Format(time.RFC3339), expiryLabel(c.Cert, now))
+		if c.Cert.SelfSigned {
+			fmt.Fprintf(out, "  pinSHA256: %s\n", c.Cert.PinSHA256)
+		}
+	}
+	return nil
+}
+
+func daysLeft(notAfter, now time.Time) int {
+	return int(notAfter.Sub(now).Hours() / 24)
+}
+
+func expiryLabel(cert *domain.Certificate, now time.Time) string {
+	if now.After(cert.NotAfter) {
+		return "expired"
+	}
+	label := fmt.Sprintf("%d days left", daysLeft(cert.NotAfter, now))
+	if cert.SelfSigned {
+		label += ", self-signed"
+	}
+	return label
+}
+
+func printTLSHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  %s tls self-signed --cn <host> [--ip <ip>] [flags]\n", os.Args[0])
+	fmt.Fprintf(w, "  %s tls info [--output json]\n\n", os.Args[0])
+	fmt.Fprintf(w, "Manages the server certificate: issue a self-signed one for servers without a\n")
+	fmt.Fprintf(w, "domain, or show when the ACME or tls.cert certificates expire.\n\n")
+}
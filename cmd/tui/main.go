@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -9,6 +10,9 @@ import (
 )
 
 func main() {
+	contextName := flag.String("context", "", "server context to open (default: current_context)")
+	flag.Parse()
+
 	loadResult, err := appconfig.LoadCLI()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: load config: %v\n", err)
@@ -18,7 +22,7 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Config created: %s\n", loadResult.Path)
 	}
 
-	deps, err := tui.BuildDependencies(loadResult.Config)
+	deps, err := tui.BuildForContext(loadResult.Config, *contextName)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -8,21 +8,138 @@ import (
 	"strconv"
 
 	"gopkg.in/yaml.v3"
+
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/utils/passwordgen"
 )
 
 const (
 	envConfigPath = "VPN_CONFIG_PATH"
 )
 
+const DefaultDockerSocket = "/var/run/docker.sock"
+
+const (
+	UserBackendYAML = "yaml"
+	UserBackendHTTP = "http"
+	UserBackendDB   = "db"
+)
+
 type Config struct {
-	HysteriaConfigPath                 string `yaml:"hysteria_config_path"`
-	HysteriaServiceName                string `yaml:"hysteria_service_name"`
-	HysteriaRestartEnabled             bool   `yaml:"hysteria_restart_enabled"`
-	HysteriaRestartCommand             string `yaml:"hysteria_restart_command"`
-	HysteriaTrafficStatsEnabled        bool   `yaml:"hysteria_traffic_stats_enabled"`
-	HysteriaTrafficStatsURL            string `yaml:"hysteria_traffic_stats_url"`
-	HysteriaTrafficStatsSecret         string `yaml:"hysteria_traffic_stats_secret"`
-	HysteriaTrafficStatsTimeoutSeconds int    `yaml:"hysteria_traffic_stats_timeout_seconds"`
+	HysteriaConfigPath                 string          `yaml:"hysteria_config_path"`
+	HysteriaServiceName                string          `yaml:"hysteria_service_name"`
+	HysteriaRestartEnabled             bool            `yaml:"hysteria_restart_enabled"`
+	HysteriaRestartCommand             string          `yaml:"hysteria_restart_command"`
+	HysteriaServiceManager             string          `yaml:"hysteria_service_manager,omitempty"`
+	HysteriaLogFile                    string          `yaml:"hysteria_log_file,omitempty"`
+	HysteriaContainer                  string          `yaml:"hysteria_container,omitempty"`
+	HysteriaComposeProject             string          `yaml:"hysteria_compose_project,omitempty"`
+	HysteriaComposeService             string          `yaml:"hysteria_compose_service,omitempty"`
+	DockerSocket                       string          `yaml:"docker_socket,omitempty"`
+	HysteriaTrafficStatsEnabled        bool            `yaml:"hysteria_traffic_stats_enabled"`
+	HysteriaTrafficStatsURL            string          `yaml:"hysteria_traffic_stats_url"`
+	HysteriaTrafficStatsSecret         string          `yaml:"hysteria_traffic_stats_secret"`
+	HysteriaTrafficStatsTimeoutSeconds int             `yaml:"hysteria_traffic_stats_timeout_seconds"`
+	AuditEnabled                       bool            `yaml:"audit_enabled"`
+	AuditLogPath                       string          `yaml:"audit_log_path"`
+	UserBackend                        string          `yaml:"user_backend"`
+	UserStorePath                      string          `yaml:"user_store_path"`
+	UserDBPath                         string          `yaml:"user_db_path"`
+	AuthServerListen                   string          `yaml:"auth_server_listen"`
+	PasswordPolicy                     PasswordPolicy  `yaml:"password_policy,omitempty"`
+	UsernamePolicy                     UsernamePolicy  `yaml:"username_policy,omitempty"`
+	SecretsVaultPath                   string          `yaml:"secrets_vault_path,omitempty"`
+	SecretsKeyFile                     string          `yaml:"secrets_key_file,omitempty"`
+	CurrentContext                     string          `yaml:"current_context,omitempty"`
+	Contexts                           []ServerContext `yaml:"contexts,omitempty"`
+
+	Path    string `yaml:"-"`
+	Context string `yaml:"-"`
+	// SSH is the ssh:// target of the active context; empty for local nodes.
+	SSH string `yaml:"-"`
+}
+
+// PasswordPolicy configures generated user passwords and the minimum
+// length of supplied ones; empty fields keep the passwordgen defaults.
+type PasswordPolicy struct {
+	Mode             string   `yaml:"mode,omitempty"`
+	Length           int      `yaml:"length,omitempty"`
+	Classes          []string `yaml:"classes,omitempty"`
+	ExcludeAmbiguous bool     `yaml:"exclude_ambiguous,omitempty"`
+	Words            int      `yaml:"words,omitempty"`
+	Separator        string   `yaml:"separator,omitempty"`
+	MinLength        int      `yaml:"min_length,omitempty"`
+}
+
+func (p PasswordPolicy) GeneratorPolicy() passwordgen.Policy {
+	return passwordgen.Policy(p)
+}
+
+// UsernamePolicy configures names accepted for new users; empty fields keep
+// the domain defaults.
+type UsernamePolicy struct {
+	MinLength     int      `yaml:"min_length,omitempty"`
+	MaxLength     int      `yaml:"max_length,omitempty"`
+	Symbols       string   `yaml:"symbols,omitempty"`
+	Reserved      []string `yaml:"reserved,omitempty"`
+	CaseSensitive bool     `yaml:"case_sensitive,omitempty"`
+}
+
+func (p UsernamePolicy) DomainPolicy() domain.UsernamePolicy {
+	return domain.UsernamePolicy(p)
+}
+
+// UserRestartEnabled reports whether user changes need a Hysteria restart.
+// With the http backend users live in the tool's store and the auth server
+// picks changes up on the next connection.
+func (c Config) UserRestartEnabled() bool {
+	return c.HysteriaRestartEnabled && c.UserBackend != UserBackendHTTP
+}
+
+// Service describes how to control the Hysteria service of the node. A
+// configured container implies the docker manager; the docker manager
+// without one takes the service name for the container name.
+func (c Config) Service() domain.ServiceUnit {
+	unit := domain.ServiceUnit{
+		Name:         c.HysteriaServiceName,
+		Manager:      c.HysteriaServiceManager,
+		Host:         c.SSH,
+		LogFile:      c.HysteriaLogFile,
+		Container:    c.container(),
+		DockerSocket: c.DockerSocket,
+	}
+	if unit.DockerSocket == "" {
+		unit.DockerSocket = DefaultDockerSocket
+	}
+	switch {
+	case !unit.Container.IsZero():
+		unit.Manager = domain.ServiceManagerDocker
+	case unit.Manager == domain.ServiceManagerDocker:
+		unit.Container.Name = unit.Name
+	}
+	return unit
+}
+
+func (c Config) container() domain.Container {
+	return domain.Container{Name: c.HysteriaContainer, Project: c.HysteriaComposeProject, Service: c.HysteriaComposeService}
+}
+
+func (c Config) validateService() error {
+	if _, err := domain.ParseServiceManager(c.HysteriaServiceManager); err != nil {
+		return fmt.Errorf("hysteria_service_manager: %w", err)
+	}
+	container := c.container()
+	if err := container.Validate(); err != nil {
+		return fmt.Errorf("hysteria_container: %w", err)
+	}
+	if !container.IsZero() && c.HysteriaServiceManager != "" && c.HysteriaServiceManager != domain.ServiceManagerDocker {
+		return fmt.Errorf("hysteria_service_manager %s cannot control container %s (allowed: docker or unset)", c.HysteriaServiceManager, container)
+	}
+	return nil
+}
+
+func (c Config) AuthServerURL() string {
+	return "http://" + c.AuthServerListen + "/auth"
 }
 
 type CLILoadResult struct {
@@ -54,9 +171,43 @@ func LoadCLI() (CLILoadResult, error) {
 	if err != nil {
 		return CLILoadResult{}, err
 	}
+	restrictConfigMode(path)
 	if err := applyEnvOverrides(&cfg); err != nil {
 		return CLILoadResult{}, err
 	}
+	if cfg.AuditLogPath == "" {
+		cfg.AuditLogPath = filepath.Join(filepath.Dir(path), "audit.jsonl")
+	}
+	if cfg.UserStorePath == "" {
+		cfg.UserStorePath = filepath.Join(filepath.Dir(path), "users.json")
+	}
+	if cfg.UserDBPath == "" {
+		cfg.UserDBPath = filepath.Join(filepath.Dir(path), "users.db")
+	}
+	if cfg.SecretsVaultPath == "" {
+		cfg.SecretsVaultPath = filepath.Join(filepath.Dir(path), "secrets.vault")
+	}
+	if cfg.SecretsKeyFile == "" {
+		cfg.SecretsKeyFile = filepath.Join(filepath.Dir(path), "secrets.key")
+	}
+	switch cfg.UserBackend {
+	case UserBackendYAML, UserBackendHTTP, UserBackendDB:
+	default:
+		return CLILoadResult{}, fmt.Errorf("invalid user_backend %q (allowed: yaml|http|db)", cfg.UserBackend)
+	}
+	if err := cfg.validateService(); err != nil {
+		return CLILoadResult{}, err
+	}
+	if err := cfg.PasswordPolicy.GeneratorPolicy().Validate(); err != nil {
+		return CLILoadResult{}, fmt.Errorf("password_policy: %w", err)
+	}
+	if err := cfg.UsernamePolicy.DomainPolicy().Validate(); err != nil {
+		return CLILoadResult{}, fmt.Errorf("username_policy: %w", err)
+	}
+	if err := cfg.validateContexts(); err != nil {
+		return CLILoadResult{}, err
+	}
+	cfg.Path = path
 
 	return CLILoadResult{
 		Config:     cfg,
@@ -75,6 +226,12 @@ func defaultConfig() Config {
 		HysteriaTrafficStatsURL:            "http://127.0.0.1:9999",
 		HysteriaTrafficStatsSecret:         "",
 		HysteriaTrafficStatsTimeoutSeconds: 2,
+		AuditEnabled:                       true,
+		AuditLogPath:                       "",
+		UserBackend:                        UserBackendYAML,
+		UserStorePath:                      "",
+		UserDBPath:                         "",
+		AuthServerListen:                   "127.0.0.1:18989",
 	}
 }
 
@@ -92,6 +249,119 @@ func readConfigFile(path string) (Config, error) {
 	return cfg, nil
 }
 
+// Update rewrites a single CLI config file without applying env overrides or
+// derived defaults, so only the fields touched by mutate change on disk.
+func Update(path string, mutate func(cfg *Config)) error {
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	mutate(&cfg)
+
+	raw, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	return nil
+}
+
+type BackendWriter struct {
+	path    string
+	context string
+}
+
+func NewBackendWriter(cfg Config) *BackendWriter {
+	return &BackendWriter{path: cfg.Path, context: cfg.Context}
+}
+
+// SetUserBackend stores the backend of the active context, or the top-level
+// user_backend when no context is selected.
+func (w *BackendWriter) SetUserBackend(backend string) error {
+	if w.path == "" {
+		return errors.New("cli config path is unknown")
+	}
+	return Update(w.path, func(cfg *Config) {
+		if w.context == "" {
+			cfg.UserBackend = backend
+			return
+		}
+		for i := range cfg.Contexts {
+			if cfg.Contexts[i].Name == w.context {
+				cfg.Contexts[i].UserBackend = backend
+			}
+		}
+	})
+}
+
+type TrafficStatsWriter struct {
+	path    string
+	context string
+}
+
+func NewTrafficStatsWriter(cfg Config) *TrafficStatsWriter {
+	return &TrafficStatsWriter{path: cfg.Path, context: cfg.Context}
+}
+
+// SetTrafficStats enables the trafficStats API of the active context, or the
+// top-level one when no context is selected. A secret referenced from a
+// file or the vault is replaced there; a new one goes to the vault once it
+// is set up.
+func (w *TrafficStatsWriter) SetTrafficStats(url, secret string) error {
+	if w.path == "" {
+		return errors.New("cli config path is unknown")
+	}
+	current, err := readConfigFile(w.path)
+	if err != nil {
+		return err
+	}
+	current.Path = w.path
+	stored := current.HysteriaTrafficStatsSecret
+	name := trafficStatsSecretName
+	if sc, ok := current.FindContext(w.context); ok && w.context != "" {
+		stored = sc.HysteriaTrafficStatsSecret
+		name += "-" + w.context
+	}
+	ref, err := storeSecret(current.withSecretDefaults().Vault(), stored, name, secret)
+	if err != nil {
+		return fmt.Errorf("store trafficStats secret: %w", err)
+	}
+	return Update(w.path, func(cfg *Config) {
+		if w.context == "" {
+			cfg.HysteriaTrafficStatsEnabled = true
+			cfg.HysteriaTrafficStatsURL = url
+			cfg.HysteriaTrafficStatsSecret = ref
+			return
+		}
+		for i := range cfg.Contexts {
+			if cfg.Contexts[i].Name == w.context {
+				enabled := true
+				cfg.Contexts[i].HysteriaTrafficStatsEnabled = &enabled
+				cfg.Contexts[i].HysteriaTrafficStatsURL = url
+				cfg.Contexts[i].HysteriaTrafficStatsSecret = ref
+			}
+		}
+	})
+}
+
 func ensureDefaultConfigFile(path string) (bool, error) {
 	if _, err := os.Stat(path); err == nil {
 		return false, nil
@@ -107,7 +377,7 @@ func ensureDefaultConfigFile(path string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("marshal default config: %w", err)
 	}
-	if err := os.WriteFile(path, raw, 0o644); err != nil {
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
 		return false, fmt.Errorf("write default config: %w", err)
 	}
 
@@ -148,6 +418,12 @@ func applyEnvOverrides(cfg *Config) error {
 	if v, ok := os.LookupEnv("HYSTERIA_SERVICE_NAME"); ok {
 		cfg.HysteriaServiceName = v
 	}
+	if v, ok := os.LookupEnv("HYSTERIA_SERVICE_MANAGER"); ok {
+		cfg.HysteriaServiceManager = v
+	}
+	if v, ok := os.LookupEnv("HYSTERIA_CONTAINER"); ok {
+		cfg.HysteriaContainer = v
+	}
 	if v, ok := os.LookupEnv("HYSTERIA_RESTART_COMMAND"); ok {
 		cfg.HysteriaRestartCommand = v
 	}
@@ -178,5 +454,30 @@ func applyEnvOverrides(cfg *Config) error {
 		}
 		cfg.HysteriaTrafficStatsTimeoutSeconds = parsed
 	}
+	if v, ok := os.LookupEnv("VPN_AUDIT_ENABLED"); ok {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("parse VPN_AUDIT_ENABLED: %w", err)
+		}
+		cfg.AuditEnabled = parsed
+	}
+	if v, ok := os.LookupEnv("VPN_AUDIT_LOG_PATH"); ok {
+		cfg.AuditLogPath = v
+	}
+	if v, ok := os.LookupEnv("VPN_USER_BACKEND"); ok {
+		cfg.UserBackend = v
+	}
+	if v, ok := os.LookupEnv("VPN_USER_STORE_PATH"); ok {
+		cfg.UserStorePath = v
+	}
+	if v, ok := os.LookupEnv("VPN_USER_DB_PATH"); ok {
+		cfg.UserDBPath = v
+	}
+	if v, ok := os.LookupEnv("VPN_AUTH_SERVER_LISTEN"); ok {
+		cfg.AuthServerListen = v
+	}
+	if v, ok := os.LookupEnv("VPN_CONTEXT"); ok {
+		cfg.CurrentContext = v
+	}
 	return nil
 }
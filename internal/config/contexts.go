@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"vpn/internal/hysteria/domain"
+)
+
+// ServerContext describes one Hysteria node. Empty fields fall back to the
+// top-level settings of the CLI config.
+type ServerContext struct {
+	Name                        string `yaml:"name"`
+	HysteriaConfigPath          string `yaml:"hysteria_config_path,omitempty"`
+	SSH                         string `yaml:"ssh,omitempty"`
+	SSHIdentity                 string `yaml:"ssh_identity,omitempty"`
+	SSHKnownHosts               string `yaml:"ssh_known_hosts,omitempty"`
+	HysteriaServiceName         string `yaml:"hysteria_service_name,omitempty"`
+	HysteriaRestartEnabled      *bool  `yaml:"hysteria_restart_enabled,omitempty"`
+	HysteriaRestartCommand      string `yaml:"hysteria_restart_command,omitempty"`
+	HysteriaServiceManager      string `yaml:"hysteria_service_manager,omitempty"`
+	HysteriaLogFile             string `yaml:"hysteria_log_file,omitempty"`
+	HysteriaContainer           string `yaml:"hysteria_container,omitempty"`
+	HysteriaComposeProject      string `yaml:"hysteria_compose_project,omitempty"`
+	HysteriaComposeService      string `yaml:"hysteria_compose_service,omitempty"`
+	DockerSocket                string `yaml:"docker_socket,omitempty"`
+	HysteriaTrafficStatsEnabled *bool  `yaml:"hysteria_traffic_stats_enabled,omitempty"`
+	HysteriaTrafficStatsURL     string `yaml:"hysteria_traffic_stats_url,omitempty"`
+	HysteriaTrafficStatsSecret  string `yaml:"hysteria_traffic_stats_secret,omitempty"`
+	UserBackend                 string `yaml:"user_backend,omitempty"`
+	UserStorePath               string `yaml:"user_store_path,omitempty"`
+	UserDBPath                  string `yaml:"user_db_path,omitempty"`
+}
+
+const remoteConfigPath = "/etc/hysteria/config.yaml"
+
+var ErrUnknownContext = errors.New("unknown context")
+
+func (c Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for _, sc := range c.Contexts {
+		names = append(names, sc.Name)
+	}
+	return names
+}
+
+func (c Config) FindContext(name string) (ServerContext, bool) {
+	for _, sc := range c.Contexts {
+		if sc.Name == name {
+			return sc, true
+		}
+	}
+	return ServerContext{}, false
+}
+
+// ForContext returns the config a command should run with on the named
+// node. An empty name selects current_context, and without one the
+// top-level settings are used as is.
+func (c Config) ForContext(name string) (Config, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if name == "" {
+		return c.resolveSecrets()
+	}
+	sc, ok := c.FindContext(name)
+	if !ok {
+		return Config{}, fmt.Errorf("%w %q (known: %v)", ErrUnknownContext, name, c.ContextNames())
+	}
+
+	out := c
+	out.Context = name
+	override(&out.HysteriaConfigPath, sc.HysteriaConfigPath)
+	if sc.SSH != "" {
+		target, location, err := sshLocation(sc)
+		if err != nil {
+			return Config{}, fmt.Errorf("context %q: %w", name, err)
+		}
+		out.SSH = target
+		out.HysteriaConfigPath = location
+	}
+	override(&out.HysteriaServiceName, sc.HysteriaServiceName)
+	override(&out.HysteriaRestartCommand, sc.HysteriaRestartCommand)
+	override(&out.HysteriaServiceManager, sc.HysteriaServiceManager)
+	override(&out.HysteriaLogFile, sc.HysteriaLogFile)
+	// A context names its container as a whole, not field by field.
+	if sc.HysteriaContainer != "" || sc.HysteriaComposeProject != "" || sc.HysteriaComposeService != "" {
+		out.HysteriaContainer = sc.HysteriaContainer
+		out.HysteriaComposeProject = sc.HysteriaComposeProject
+		out.HysteriaComposeService = sc.HysteriaComposeService
+	}
+	override(&out.DockerSocket, sc.DockerSocket)
+	override(&out.HysteriaTrafficStatsURL, sc.HysteriaTrafficStatsURL)
+	override(&out.HysteriaTrafficStatsSecret, sc.HysteriaTrafficStatsSecret)
+	override(&out.UserBackend, sc.UserBackend)
+	if sc.HysteriaRestartEnabled != nil {
+		out.HysteriaRestartEnabled = *sc.HysteriaRestartEnabled
+	}
+	if sc.HysteriaTrafficStatsEnabled != nil {
+		out.HysteriaTrafficStatsEnabled = *sc.HysteriaTrafficStatsEnabled
+	}
+	// Every node keeps its own user store next to the CLI config.
+	dir := filepath.Dir(c.Path)
+	out.UserStorePath = filepath.Join(dir, "users-"+name+".json")
+	out.UserDBPath = filepath.Join(dir, "users-"+name+".db")
+	override(&out.UserStorePath, sc.UserStorePath)
+	override(&out.UserDBPath, sc.UserDBPath)
+	if err := out.validateService(); err != nil {
+		return Config{}, fmt.Errorf("context %q: %w", name, err)
+	}
+	if out.SSH != "" && out.UserBackend == UserBackendHTTP {
+		return Config{}, fmt.Errorf("context %q: user_backend http needs the auth server on the node and is not supported over ssh", name)
+	}
+	if out.SSH != "" && out.UserBackend == UserBackendDB {
+		return Config{}, fmt.Errorf("context %q: user_backend db keeps users in a local file the node never reads and is not supported over ssh", name)
+	}
+	out, err := out.resolveSecrets()
+	if err != nil {
+		return Config{}, fmt.Errorf("context %q: %w", name, err)
+	}
+	return out, nil
+}
+
+// sshLocation turns the ssh settings of a context into the target used for
+// commands and the ssh:// location of its Hysteria config.
+func sshLocation(sc ServerContext) (string, string, error) {
+	u, err := url.Parse(sc.SSH)
+	if err != nil || u.Scheme != "ssh" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid ssh %q (expected ssh://user@host[:port])", sc.SSH)
+	}
+	q := url.Values{}
+	if sc.SSHIdentity != "" {
+		q.Set("identity", sc.SSHIdentity)
+	}
+	if sc.SSHKnownHosts != "" {
+		q.Set("known_hosts", sc.SSHKnownHosts)
+	}
+	u.Path = ""
+	u.RawQuery = q.Encode()
+	target := u.String()
+
+	u.Path = sc.HysteriaConfigPath
+	if u.Path == "" {
+		u.Path = remoteConfigPath
+	}
+	return target, u.String(), nil
+}
+
+func (c Config) validateContexts() error {
+	seen := map[string]bool{}
+	for _, sc := range c.Contexts {
+		if sc.Name == "" {
+			return errors.New("context without name")
+		}
+		if seen[sc.Name] {
+			return fmt.Errorf("duplicate context %q", sc.Name)
+		}
+		seen[sc.Name] = true
+		if sc.HysteriaConfigPath == "" && sc.SSH == "" {
+			return fmt.Errorf("context %q: hysteria_config_path or ssh is required", sc.Name)
+		}
+		switch sc.UserBackend {
+		case "", UserBackendYAML, UserBackendHTTP, UserBackendDB:
+		default:
+			return fmt.Errorf("context %q: invalid user_backend %q (allowed: yaml|http|db)", sc.Name, sc.UserBackend)
+		}
+		if _, err := domain.ParseServiceManager(sc.HysteriaServiceManager); err != nil {
+			return fmt.Errorf("context %q: hysteria_service_manager: %w", sc.Name, err)
+		}
+		container := domain.Container{Name: sc.HysteriaContainer, Project: sc.HysteriaComposeProject, Service: sc.HysteriaComposeService}
+		if err := container.Validate(); err != nil {
+			return fmt.Errorf("context %q: hysteria_container: %w", sc.Name, err)
+		}
+	}
+	if c.CurrentContext != "" && !seen[c.CurrentContext] {
+		return fmt.Errorf("current_context: %w %q", ErrUnknownContext, c.CurrentContext)
+	}
+	return nil
+}
+
+// SetCurrentContext stores name as current_context in the CLI config file;
+// an empty name switches back to the top-level settings.
+func SetCurrentContext(path, name string) error {
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.FindContext(name); name != "" && !ok {
+		return fmt.Errorf("%w %q (known: %v)", ErrUnknownContext, name, cfg.ContextNames())
+	}
+	return Update(path, func(cfg *Config) { cfg.CurrentContext = name })
+}
+
+func override(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_ForContext(t *testing.T) {
+	t.Parallel()
+
+	restart := false
+	base := defaultConfig()
+	base.Path = "/etc/vpn/config.yaml"
+	base.UserStorePath = "/etc/vpn/users.json"
+	base.Contexts = []ServerContext{
+		{Name: "nl1", HysteriaConfigPath: "/srv/nl1.yaml", HysteriaTrafficStatsURL: "http://10.0.0.1:9999", HysteriaRestartEnabled: &restart},
+		{Name: "de1", HysteriaConfigPath: "/srv/de1.yaml", UserStorePath: "/var/lib/de1.json"},
+	}
+
+	cfg, err := base.ForContext("")
+	if err != nil || cfg.HysteriaConfigPath != base.HysteriaConfigPath || cfg.Context != "" {
+		t.Fatalf("without current_context the top-level settings must be used: %+v %v", cfg, err)
+	}
+
+	base.CurrentContext = "nl1"
+	cfg, err = base.ForContext("")
+	if err != nil {
+		t.Fatalf("for context: %v", err)
+	}
+	if cfg.Context != "nl1" || cfg.HysteriaConfigPath != "/srv/nl1.yaml" || cfg.HysteriaTrafficStatsURL != "http://10.0.0.1:9999" {
+		t.Fatalf("unexpected nl1 config: %+v", cfg)
+	}
+	if cfg.HysteriaRestartEnabled || cfg.HysteriaServiceName != "hysteria-server" {
+		t.Fatalf("unset fields must fall back to the top level: %+v", cfg)
+	}
+	if cfg.UserStorePath != "/etc/vpn/users-nl1.json" || cfg.UserDBPath != "/etc/vpn/users-nl1.db" {
+		t.Fatalf("each context needs its own store: %s %s", cfg.UserStorePath, cfg.UserDBPath)
+	}
+
+	cfg, err = base.ForContext("de1")
+	if err != nil || cfg.UserStorePath != "/var/lib/de1.json" || !cfg.HysteriaRestartEnabled {
+		t.Fatalf("unexpected de1 config: %+v %v", cfg, err)
+	}
+
+	base.Contexts = append(base.Contexts, ServerContext{Name: "fi1", SSH: "ssh://admin@fi1.example.com:2222", SSHIdentity: "~/.ssh/fi1"})
+	cfg, err = base.ForContext("fi1")
+	if err != nil {
+		t.Fatalf("for ssh context: %v", err)
+	}
+	if cfg.SSH != "ssh://admin@fi1.example.com:2222?identity=~%2F.ssh%2Ffi1" {
+		t.Fatalf("unexpected ssh target: %s", cfg.SSH)
+	}
+	if cfg.HysteriaConfigPath != "ssh://admin@fi1.example.com:2222/etc/hysteria/config.yaml?identity=~%2F.ssh%2Ffi1" {
+		t.Fatalf("unexpected remote config location: %s", cfg.HysteriaConfigPath)
+	}
+
+	base.Contexts = append(base.Contexts, ServerContext{Name: "fi2", SSH: "ssh://admin@fi2.example.com", UserBackend: UserBackendDB})
+	if _, err := base.ForContext("fi2"); err == nil {
+		t.Fatal("user_backend db must be rejected over ssh")
+	}
+
+	if _, err := base.ForContext("us1"); !errors.Is(err, ErrUnknownContext) {
+		t.Fatalf("expected ErrUnknownContext, got %v", err)
+	}
+}
+
+func TestConfig_ValidateContexts(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]Config{
+		"duplicate":       {Contexts: []ServerContext{{Name: "a", HysteriaConfigPath: "x"}, {Name: "a", HysteriaConfigPath: "y"}}},
+		"no target":       {Contexts: []ServerContext{{Name: "a"}}},
+		"unknown current": {CurrentContext: "b", Contexts: []ServerContext{{Name: "a", HysteriaConfigPath: "x"}}},
+		"two containers":  {Contexts: []ServerContext{{Name: "a", HysteriaConfigPath: "x", HysteriaContainer: "hy", HysteriaComposeService: "hysteria"}}},
+	}
+	for name, cfg := range cases {
+		if err := cfg.validateContexts(); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestConfig_ServiceContainer(t *testing.T) {
+	t.Parallel()
+
+	base := defaultConfig()
+	base.HysteriaContainer = "hysteria"
+	base.Contexts = []ServerContext{
+		{Name: "nl1", HysteriaConfigPath: "/etc/hysteria/config.yaml"},
+		{Name: "de1", HysteriaConfigPath: "/etc/hysteria/config.yaml", HysteriaComposeProject: "edge", HysteriaComposeService: "hysteria", DockerSocket: "/run/user/1000/docker.sock"},
+		{Name: "fi1", HysteriaConfigPath: "/etc/hysteria/config.yaml", HysteriaServiceManager: "systemd"},
+	}
+
+	cfg, err := base.ForContext("nl1")
+	if err != nil {
+		t.Fatalf("for context: %v", err)
+	}
+	unit := cfg.Service()
+	if unit.Manager != "docker" || unit.Container.Name != "hysteria" || unit.DockerSocket != DefaultDockerSocket {
+		t.Fatalf("a container must select the docker manager: %+v", unit)
+	}
+
+	cfg, err = base.ForContext("de1")
+	if err != nil {
+		t.Fatalf("for context: %v", err)
+	}
+	unit = cfg.Service()
+	if unit.Container.Name != "" || unit.Container.Project != "edge" || unit.Container.Service != "hysteria" || unit.DockerSocket != "/run/user/1000/docker.sock" {
+		t.Fatalf("a context must replace the container as a whole: %+v", unit)
+	}
+
+	if _, err := base.ForContext("fi1"); err == nil {
+		t.Fatalf("expected error for systemd controlling a container")
+	}
+
+	plain := defaultConfig()
+	plain.HysteriaServiceManager = "docker"
+	if unit := plain.Service(); unit.Container.Name != plain.HysteriaServiceName {
+		t.Fatalf("the docker manager without a container must use the service name: %+v", unit)
+	}
+}
+
+func TestSetCurrentContext(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	raw := "hysteria_config_path: /etc/hysteria/config.yaml\ncontexts:\n  - name: nl1\n    hysteria_config_path: /srv/nl1.yaml\n"
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if err := SetCurrentContext(path, "de1"); !errors.Is(err, ErrUnknownContext) {
+		t.Fatalf("expected ErrUnknownContext, got %v", err)
+	}
+	if err := SetCurrentContext(path, "nl1"); err != nil {
+		t.Fatalf("set current context: %v", err)
+	}
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if cfg.CurrentContext != "nl1" || len(cfg.Contexts) != 1 || cfg.Contexts[0].HysteriaConfigPath != "/srv/nl1.yaml" {
+		t.Fatalf("unexpected config after update: %+v", cfg)
+	}
+}
+
+func TestTrafficStatsWriter_WritesActiveContext(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	raw := "hysteria_config_path: /etc/hysteria/config.yaml\ncontexts:\n  - name: nl1\n    hysteria_config_path: /srv/nl1.yaml\n"
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if err := NewTrafficStatsWriter(Config{Path: path, Context: "nl1"}).SetTrafficStats("http://127.0.0.1:9999", "s3cret"); err != nil {
+		t.Fatalf("set traffic stats: %v", err)
+	}
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if cfg.HysteriaTrafficStatsEnabled {
+		t.Fatal("top-level traffic stats must stay disabled")
+	}
+	cfg.Path = path
+	nl1, err := cfg.ForContext("nl1")
+	if err != nil {
+		t.Fatalf("for context: %v", err)
+	}
+	if !nl1.HysteriaTrafficStatsEnabled || nl1.HysteriaTrafficStatsURL != "http://127.0.0.1:9999" || nl1.HysteriaTrafficStatsSecret != "s3cret" {
+		t.Fatalf("unexpected nl1 stats settings: %+v", nl1)
+	}
+}
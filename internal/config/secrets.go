@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"vpn/internal/utils/secretvault"
+)
+
+// trafficStatsSecretName is the vault entry of the top-level trafficStats
+// secret; contexts use the name with "-<context>" appended.
+const trafficStatsSecretName = "traffic-stats"
+
+// Vault opens the secrets vault of the CLI config.
+func (c Config) Vault() *secretvault.Vault {
+	return secretvault.New(c.SecretsVaultPath, c.SecretsKeyFile)
+}
+
+// withSecretDefaults fills the vault paths for configs read without
+// LoadCLI, e.g. by Update callers.
+func (c Config) withSecretDefaults() Config {
+	dir := filepath.Dir(c.Path)
+	if c.SecretsVaultPath == "" {
+		c.SecretsVaultPath = filepath.Join(dir, "secrets.vault")
+	}
+	if c.SecretsKeyFile == "" {
+		c.SecretsKeyFile = filepath.Join(dir, "secrets.key")
+	}
+	return c
+}
+
+// resolveSecrets replaces env:, file: and vault: references with the
+// secrets they point to.
+func (c Config) resolveSecrets() (Config, error) {
+	if c.HysteriaTrafficStatsSecret == "" {
+		return c, nil
+	}
+	secret, err := secretvault.Resolve(c.HysteriaTrafficStatsSecret, c.Vault())
+	if err != nil {
+		return Config{}, fmt.Errorf("hysteria_traffic_stats_secret: %w", err)
+	}
+	c.HysteriaTrafficStatsSecret = secret
+	return c, nil
+}
+
+// SecretRef describes where one secret of the CLI config comes from.
+type SecretRef struct {
+	Field   string
+	Context string
+	Source  string
+	Target  string
+}
+
+// SecretRefs lists the secrets of the raw config, top-level first.
+func (c Config) SecretRefs() []SecretRef {
+	var refs []SecretRef
+	if c.HysteriaTrafficStatsSecret != "" {
+		source, target := secretvault.ParseRef(c.HysteriaTrafficStatsSecret)
+		refs = append(refs, SecretRef{Field: "hysteria_traffic_stats_secret", Source: source, Target: target})
+	}
+	for _, sc := range c.Contexts {
+		if sc.HysteriaTrafficStatsSecret != "" {
+			source, target := secretvault.ParseRef(sc.HysteriaTrafficStatsSecret)
+			refs = append(refs, SecretRef{Field: "hysteria_traffic_stats_secret", Context: sc.Name, Source: source, Target: target})
+		}
+	}
+	return refs
+}
+
+// SealSecrets moves plaintext secrets of the config file at path into the
+// vault and replaces them with vault: references. It returns the names of
+// the moved entries.
+func SealSecrets(path string) ([]string, error) {
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Path = path
+	vault := cfg.withSecretDefaults().Vault()
+
+	refs := map[string]string{}
+	var sealed []string
+	seal := func(value, name string) error {
+		if source, _ := secretvault.ParseRef(value); value == "" || source != secretvault.SourceLiteral {
+			return nil
+		}
+		if _, err := vault.Set(name, value); err != nil {
+			return err
+		}
+		refs[name] = secretvault.SourceVault + ":" + name
+		sealed = append(sealed, name)
+		return nil
+	}
+	if err := seal(cfg.HysteriaTrafficStatsSecret, trafficStatsSecretName); err != nil {
+		return nil, err
+	}
+	for _, sc := range cfg.Contexts {
+		if err := seal(sc.HysteriaTrafficStatsSecret, trafficStatsSecretName+"-"+sc.Name); err != nil {
+			return nil, err
+		}
+	}
+	if len(sealed) == 0 {
+		return nil, nil
+	}
+	err = Update(path, func(cfg *Config) {
+		if ref, ok := refs[trafficStatsSecretName]; ok {
+			cfg.HysteriaTrafficStatsSecret = ref
+		}
+		for i := range cfg.Contexts {
+			if ref, ok := refs[trafficStatsSecretName+"-"+cfg.Contexts[i].Name]; ok {
+				cfg.Contexts[i].HysteriaTrafficStatsSecret = ref
+			}
+		}
+	})
+	return sealed, err
+}
+
+// storeSecret writes a new secret where the stored value points to and
+// returns the value to keep in the config.
+func storeSecret(vault *secretvault.Vault, stored, name, secret string) (string, error) {
+	source, target := secretvault.ParseRef(stored)
+	switch {
+	case source == secretvault.SourceFile:
+		return stored, secretvault.WritePrivate(target, []byte(secret+"\n"))
+	case source == secretvault.SourceVault:
+		_, err := vault.Set(target, secret)
+		return stored, err
+	case vault.Exists():
+		_, err := vault.Set(name, secret)
+		return secretvault.SourceVault + ":" + name, err
+	default:
+		return secret, nil
+	}
+}
+
+// restrictConfigMode tightens a config file readable by others to 0600,
+// since it may hold plaintext secrets. Failures are left to doctor, which
+// reports the mode.
+func restrictConfigMode(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm()&0o077 == 0 {
+		return
+	}
+	_ = os.Chmod(path, 0o600)
+}
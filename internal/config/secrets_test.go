@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vpn/internal/utils/secretvault"
+)
+
+func TestConfig_ForContextResolvesSecrets(t *testing.T) {
+	dir := t.TempDir()
+	base := defaultConfig()
+	base.Path = filepath.Join(dir, "config.yaml")
+	base.SecretsVaultPath = filepath.Join(dir, "secrets.vault")
+	base.SecretsKeyFile = filepath.Join(dir, "secrets.key")
+	if _, err := base.Vault().Set("traffic-stats-nl1", "from-vault"); err != nil {
+		t.Fatalf("vault set: %v", err)
+	}
+	secretFile := filepath.Join(dir, "stats.secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+	t.Setenv("VPN_TEST_STATS_SECRET", "from-env")
+
+	base.HysteriaTrafficStatsSecret = "env:VPN_TEST_STATS_SECRET"
+	base.Contexts = []ServerContext{
+		{Name: "nl1", HysteriaConfigPath: "/srv/nl1.yaml", HysteriaTrafficStatsSecret: "vault:traffic-stats-nl1"},
+		{Name: "de1", HysteriaConfigPath: "/srv/de1.yaml", HysteriaTrafficStatsSecret: "file:" + secretFile},
+		{Name: "fi1", HysteriaConfigPath: "/srv/fi1.yaml", HysteriaTrafficStatsSecret: "vault:missing"},
+	}
+
+	want := map[string]string{"": "from-env", "nl1": "from-vault", "de1": "from-file"}
+	for name, secret := range want {
+		cfg, err := base.ForContext(name)
+		if err != nil {
+			t.Fatalf("for context %q: %v", name, err)
+		}
+		if cfg.HysteriaTrafficStatsSecret != secret {
+			t.Fatalf("context %q: got secret %q, want %q", name, cfg.HysteriaTrafficStatsSecret, secret)
+		}
+	}
+	if _, err := base.ForContext("fi1"); !errors.Is(err, secretvault.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing vault entry, got %v", err)
+	}
+
+	if err := os.Chmod(secretFile, 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if _, err := base.ForContext("de1"); !errors.Is(err, secretvault.ErrInsecureMode) {
+		t.Fatalf("expected ErrInsecureMode for a world-readable secret file, got %v", err)
+	}
+}
+
+func TestSealSecrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	raw := "hysteria_traffic_stats_secret: top\ncontexts:\n  - name: nl1\n    hysteria_config_path: /srv/nl1.yaml\n    hysteria_traffic_stats_secret: env:NL1_SECRET\n  - name: de1\n    hysteria_config_path: /srv/de1.yaml\n    hysteria_traffic_stats_secret: de1\n"
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	sealed, err := SealSecrets(path)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if len(sealed) != 2 || sealed[0] != "traffic-stats" || sealed[1] != "traffic-stats-de1" {
+		t.Fatalf("unexpected sealed entries: %v", sealed)
+	}
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if cfg.HysteriaTrafficStatsSecret != "vault:traffic-stats" || cfg.Contexts[0].HysteriaTrafficStatsSecret != "env:NL1_SECRET" || cfg.Contexts[1].HysteriaTrafficStatsSecret != "vault:traffic-stats-de1" {
+		t.Fatalf("unexpected references after seal: %+v", cfg)
+	}
+
+	cfg.Path = path
+	de1, err := cfg.withSecretDefaults().ForContext("de1")
+	if err != nil || de1.HysteriaTrafficStatsSecret != "de1" {
+		t.Fatalf("sealed secret must resolve: %q %v", de1.HysteriaTrafficStatsSecret, err)
+	}
+	if sealed, err := SealSecrets(path); err != nil || len(sealed) != 0 {
+		t.Fatalf("second seal must be a no-op: %v %v", sealed, err)
+	}
+}
+
+func TestTrafficStatsWriter_KeepsSecretInVault(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("hysteria_traffic_stats_secret: old\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := SealSecrets(path); err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	if err := NewTrafficStatsWriter(Config{Path: path}).SetTrafficStats("http://127.0.0.1:9999", "new"); err != nil {
+		t.Fatalf("set traffic stats: %v", err)
+	}
+	cfg, err := readConfigFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if cfg.HysteriaTrafficStatsSecret != "vault:traffic-stats" {
+		t.Fatalf("secret must stay a vault reference, got %q", cfg.HysteriaTrafficStatsSecret)
+	}
+	cfg.Path = path
+	if secret, err := cfg.withSecretDefaults().Vault().Get("traffic-stats"); err != nil || secret != "new" {
+		t.Fatalf("vault must hold the new secret: %q %v", secret, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("config must stay 0600: %v %v", info.Mode(), err)
+	}
+}
@@ -7,7 +7,9 @@ import (
 )
 
 type UserRepository interface {
+	ListUsers(ctx context.Context) ([]string, error)
 	AddUser(ctx context.Context, user domain.User) error
+	Checksum(ctx context.Context) (string, error)
 }
 
 type ServiceRestarter interface {
@@ -16,4 +18,9 @@ type ServiceRestarter interface {
 
 type PasswordGenerator interface {
 	Generate() (string, error)
+	Check(password string) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
 }
@@ -1,8 +1,28 @@
 package add_user
 
-import appconfig "vpn/internal/config"
+import (
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/domain"
+	utilpasswordgen "vpn/internal/utils/passwordgen"
+)
 
 func provideConfigPath(cfg appconfig.Config) string     { return cfg.HysteriaConfigPath }
-func provideServiceName(cfg appconfig.Config) string    { return cfg.HysteriaServiceName }
-func provideRestartEnabled(cfg appconfig.Config) bool   { return cfg.HysteriaRestartEnabled }
+func provideUserBackend(cfg appconfig.Config) string    { return cfg.UserBackend }
+func provideUserStorePath(cfg appconfig.Config) string  { return cfg.UserStorePath }
+func provideUserDBPath(cfg appconfig.Config) string     { return cfg.UserDBPath }
+func provideRestartEnabled(cfg appconfig.Config) bool   { return cfg.UserRestartEnabled() }
 func provideRestartCommand(cfg appconfig.Config) string { return cfg.HysteriaRestartCommand }
+func provideAuditEnabled(cfg appconfig.Config) bool     { return cfg.AuditEnabled }
+func provideAuditLogPath(cfg appconfig.Config) string   { return cfg.AuditLogPath }
+
+func providePasswordPolicy(cfg appconfig.Config) utilpasswordgen.Policy {
+	return cfg.PasswordPolicy.GeneratorPolicy()
+}
+
+func provideUsernamePolicy(cfg appconfig.Config) domain.UsernamePolicy {
+	return cfg.UsernamePolicy.DomainPolicy()
+}
+
+func provideService(cfg appconfig.Config) domain.ServiceUnit {
+	return cfg.Service()
+}
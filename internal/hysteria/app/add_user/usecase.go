@@ -2,6 +2,7 @@ package add_user
 
 import (
 	"context"
+	"fmt"
 
 	"vpn/internal/hysteria/domain"
 )
@@ -10,26 +11,76 @@ type UseCase struct {
 	repo      UserRepository
 	restarter ServiceRestarter
 	passwords PasswordGenerator
+	usernames domain.UsernamePolicy
+	audit     AuditLog
 }
 
-func NewUseCase(repo UserRepository, restarter ServiceRestarter, passwords PasswordGenerator) *UseCase {
-	return &UseCase{repo: repo, restarter: restarter, passwords: passwords}
+func NewUseCase(repo UserRepository, restarter ServiceRestarter, passwords PasswordGenerator, usernames domain.UsernamePolicy, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, passwords: passwords, usernames: usernames, audit: audit}
 }
 
-func (u *UseCase) Execute(ctx context.Context, username string) (string, error) {
-	password, err := u.passwords.Generate()
+func (u *UseCase) Execute(ctx context.Context, username string, tags ...string) (domain.User, error) {
+	return u.ExecuteWithPassword(ctx, username, "", tags...)
+}
+
+// ExecuteWithPassword adds the user with the supplied password, checked
+// against the policy, or with a generated one when it is empty. The
+// username is normalized by the username policy; the returned user carries
+// the stored name.
+func (u *UseCase) ExecuteWithPassword(ctx context.Context, username, supplied string, tags ...string) (user domain.User, err error) {
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.repo.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionAddUser, username, hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.repo.Checksum(ctx)
 	if err != nil {
-		return "", err
+		return domain.User{}, err
+	}
+
+	normalized, err := u.usernames.Normalize(username)
+	if err != nil {
+		return domain.User{}, err
 	}
-	user, err := domain.NewUser(username, password)
+	username = normalized
+	existing, err := u.repo.ListUsers(ctx)
 	if err != nil {
-		return "", err
+		return domain.User{}, err
+	}
+	if err := u.usernames.ConflictError(username, existing); err != nil {
+		return domain.User{}, err
+	}
+	password, err := choosePassword(u.passwords, supplied)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user, err = domain.NewUser(username, password)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user.Tags, err = domain.NormalizeTags(tags)
+	if err != nil {
+		return domain.User{}, err
 	}
 	if err := u.repo.AddUser(ctx, user); err != nil {
-		return "", err
+		return domain.User{}, err
 	}
 	if err := u.restarter.Restart(ctx); err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
+
+func choosePassword(passwords PasswordGenerator, supplied string) (string, error) {
+	if supplied == "" {
+		return passwords.Generate()
+	}
+	if err := passwords.Check(supplied); err != nil {
 		return "", err
 	}
-	return password, nil
+	return supplied, nil
 }
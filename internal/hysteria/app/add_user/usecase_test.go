@@ -2,6 +2,8 @@ package add_user
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"vpn/internal/hysteria/domain"
@@ -9,13 +11,26 @@ import (
 
 type repoMock struct {
 	called bool
+	users  []string
 	user   domain.User
+	err    error
+}
+
+func (m *repoMock) ListUsers(context.Context) ([]string, error) {
+	return m.users, nil
 }
 
 func (m *repoMock) AddUser(_ context.Context, user domain.User) error {
 	m.called = true
 	m.user = user
-	return nil
+	return m.err
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	if m.called {
+		return "after", nil
+	}
+	return "before", nil
 }
 
 type restarterMock struct{ called bool }
@@ -31,16 +46,37 @@ func (passwordGeneratorMock) Generate() (string, error) {
 	return "Abc123Abc123Abc123Abc123Abc123Ab", nil
 }
 
+var errWeakPassword = errors.New("weak password")
+
+func (passwordGeneratorMock) Check(password string) error {
+	if password == "weak" {
+		return errWeakPassword
+	}
+	return nil
+}
+
+type auditMock struct {
+	entries []domain.AuditEntry
+	err     error
+}
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return m.err
+}
+
 func TestExecute(t *testing.T) {
 	repo := &repoMock{}
 	restarter := &restarterMock{}
-	uc := NewUseCase(repo, restarter, passwordGeneratorMock{})
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, domain.UsernamePolicy{}, audit)
 
-	password, err := uc.Execute(context.Background(), "alice")
+	ctx := domain.ContextWithActor(context.Background(), domain.Actor{Kind: domain.ActorOS, Name: "root"})
+	user, err := uc.Execute(ctx, "alice")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if password == "" {
+	if user.Username != "alice" || user.Password == "" {
 		t.Fatal("expected generated password")
 	}
 	if !repo.called || !restarter.called {
@@ -49,4 +85,116 @@ func TestExecute(t *testing.T) {
 	if repo.user.Username != "alice" || repo.user.Password == "" {
 		t.Fatalf("unexpected repo payload: %+v", repo.user)
 	}
+	if len(audit.entries) != 1 {
+		t.Fatalf("expected one audit entry, got %+v", audit.entries)
+	}
+	got := audit.entries[0]
+	if got.Action != domain.AuditActionAddUser || got.Username != "alice" || got.Actor != "os:root" || got.Result != domain.AuditResultOK {
+		t.Fatalf("unexpected audit entry: %+v", got)
+	}
+	if got.ConfigHashBefore != "before" || got.ConfigHashAfter != "after" {
+		t.Fatalf("unexpected audit hashes: %+v", got)
+	}
+}
+
+func TestExecuteWithTags(t *testing.T) {
+	repo := &repoMock{}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	if _, err := uc.Execute(context.Background(), "alice", "team-a", " ops", "team-a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.user.Tags) != 2 || repo.user.Tags[0] != "ops" || repo.user.Tags[1] != "team-a" {
+		t.Fatalf("unexpected tags: %+v", repo.user.Tags)
+	}
+
+	if _, err := uc.Execute(context.Background(), "bob", "bad tag"); !errors.Is(err, domain.ErrInvalidTag) {
+		t.Fatalf("expected ErrInvalidTag, got %v", err)
+	}
+}
+
+func TestExecuteWithSuppliedPassword(t *testing.T) {
+	repo := &repoMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, audit)
+
+	user, err := uc.ExecuteWithPassword(context.Background(), "alice", "migrated-secret", "ops")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Password != "migrated-secret" || repo.user.Password != "migrated-secret" || repo.user.Tags[0] != "ops" {
+		t.Fatalf("supplied password must be stored as is: %+v %+v", user, repo.user)
+	}
+
+	repo = &repoMock{}
+	audit = &auditMock{}
+	uc = NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, audit)
+	if _, err := uc.ExecuteWithPassword(context.Background(), "bob", "weak"); !errors.Is(err, errWeakPassword) {
+		t.Fatalf("expected policy error, got %v", err)
+	}
+	if repo.called {
+		t.Fatal("rejected password must not reach the repository")
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Result != domain.AuditResultError {
+		t.Fatalf("expected failed audit entry, got %+v", audit.entries)
+	}
+}
+
+func TestExecuteAppliesUsernamePolicy(t *testing.T) {
+	repo := &repoMock{users: []string{"Bob"}}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, audit)
+
+	user, err := uc.Execute(context.Background(), "  Alice ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Username != "alice" || repo.user.Username != "alice" || audit.entries[0].Username != "alice" {
+		t.Fatalf("expected folded name, got %+v / %+v", user, repo.user)
+	}
+
+	for _, name := range []string{"alice bob", "a:b", strings.Repeat("a", 500), "root", "-alice"} {
+		repo.called = false
+		if _, err := uc.Execute(context.Background(), name); !errors.Is(err, domain.ErrInvalidUsername) {
+			t.Fatalf("%q: expected ErrInvalidUsername, got %v", name, err)
+		}
+		if repo.called {
+			t.Fatalf("%q must not reach the repository", name)
+		}
+	}
+	if _, err := uc.Execute(context.Background(), "bob"); !errors.Is(err, domain.ErrUserAlreadyExists) {
+		t.Fatalf("expected case-insensitive duplicate, got %v", err)
+	}
+
+	sensitive := NewUseCase(&repoMock{users: []string{"Bob"}}, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{CaseSensitive: true}, &auditMock{})
+	if user, err := sensitive.Execute(context.Background(), "bob"); err != nil || user.Username != "bob" {
+		t.Fatalf("case-sensitive policy must allow bob next to Bob: %+v %v", user, err)
+	}
+}
+
+func TestExecuteAuditsFailure(t *testing.T) {
+	repo := &repoMock{err: domain.ErrUserAlreadyExists}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, audit)
+
+	if _, err := uc.Execute(context.Background(), "alice"); !errors.Is(err, domain.ErrUserAlreadyExists) {
+		t.Fatalf("expected ErrUserAlreadyExists, got %v", err)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Result != domain.AuditResultError || audit.entries[0].Error == "" {
+		t.Fatalf("expected failed audit entry, got %+v", audit.entries)
+	}
+}
+
+func TestExecuteKeepsUserWhenAuditFails(t *testing.T) {
+	repo := &repoMock{}
+	restarter := &restarterMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{err: errors.New("disk full")})
+
+	user, err := uc.Execute(context.Background(), "alice")
+	if !errors.Is(err, domain.ErrAuditRecord) {
+		t.Fatalf("expected ErrAuditRecord, got %v", err)
+	}
+	if !restarter.called || user.Username != "alice" || user.Password != repo.user.Password || user.Password == "" {
+		t.Fatalf("applied user must be returned with the audit error: %+v", user)
+	}
 }
@@ -6,23 +6,33 @@ package add_user
 import (
 	"github.com/google/wire"
 	appconfig "vpn/internal/config"
-	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/auditlog"
 	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/hysteria/infra/userstore"
 	utilpasswordgen "vpn/internal/utils/passwordgen"
 )
 
 func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
 	wire.Build(
 		provideConfigPath,
-		provideServiceName,
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		provideService,
 		provideRestartEnabled,
 		provideRestartCommand,
-		configrepo.NewRepository,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		providePasswordPolicy,
+		provideUsernamePolicy,
+		userstore.NewRepository,
 		servicectl.NewRestarter,
+		auditlog.NewLog,
 		utilpasswordgen.NewGenerator,
-		wire.Bind(new(UserRepository), new(*configrepo.Repository)),
+		wire.Bind(new(UserRepository), new(*userstore.Repository)),
 		wire.Bind(new(ServiceRestarter), new(*servicectl.Restarter)),
 		wire.Bind(new(PasswordGenerator), new(*utilpasswordgen.Generator)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
 		NewUseCase,
 	)
 	return nil, nil
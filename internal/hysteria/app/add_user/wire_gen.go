@@ -8,19 +8,28 @@ package add_user
 
 import (
 	appconfig "vpn/internal/config"
-	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/auditlog"
 	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/hysteria/infra/userstore"
 	utilpasswordgen "vpn/internal/utils/passwordgen"
 )
 
 func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
-	string2 := provideConfigPath(cfg)
-	repository := configrepo.NewRepository(string2)
+	string2 := provideUserBackend(cfg)
+	string3 := provideConfigPath(cfg)
+	string4 := provideUserStorePath(cfg)
+	string5 := provideUserDBPath(cfg)
+	repository := userstore.NewRepository(string2, string3, string4, string5)
 	bool2 := provideRestartEnabled(cfg)
-	string3 := provideServiceName(cfg)
-	string4 := provideRestartCommand(cfg)
-	restarter := servicectl.NewRestarter(bool2, string3, string4)
-	generator := utilpasswordgen.NewGenerator()
-	useCase := NewUseCase(repository, restarter, generator)
+	serviceUnit := provideService(cfg)
+	string6 := provideRestartCommand(cfg)
+	restarter := servicectl.NewRestarter(bool2, serviceUnit, string6)
+	policy := providePasswordPolicy(cfg)
+	generator := utilpasswordgen.NewGenerator(policy)
+	usernamePolicy := provideUsernamePolicy(cfg)
+	bool3 := provideAuditEnabled(cfg)
+	string7 := provideAuditLogPath(cfg)
+	log := auditlog.NewLog(bool3, string7)
+	useCase := NewUseCase(repository, restarter, generator, usernamePolicy, log)
 	return useCase, nil
 }
@@ -0,0 +1,7 @@
+package authenticate_user
+
+import "context"
+
+type UserStore interface {
+	Password(ctx context.Context, username string) (string, error)
+}
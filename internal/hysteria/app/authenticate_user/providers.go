@@ -0,0 +1,5 @@
+package authenticate_user
+
+import appconfig "vpn/internal/config"
+
+func provideUserStorePath(cfg appconfig.Config) string { return cfg.UserStorePath }
@@ -0,0 +1,57 @@
+package authenticate_user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type storeMock struct {
+	err error
+}
+
+func (m storeMock) Password(_ context.Context, username string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if username != "alice" {
+		return "", domain.ErrUserNotFound
+	}
+	return "secret:with:colons", nil
+}
+
+func TestExecute(t *testing.T) {
+	uc := NewUseCase(storeMock{})
+
+	cases := []struct {
+		auth   string
+		wantOK bool
+	}{
+		{auth: "alice:secret:with:colons", wantOK: true},
+		{auth: "alice:wrong", wantOK: false},
+		{auth: "bob:secret:with:colons", wantOK: false},
+		{auth: "alice", wantOK: false},
+		{auth: ":secret", wantOK: false},
+	}
+	for _, tc := range cases {
+		id, ok, err := uc.Execute(context.Background(), tc.auth)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.auth, err)
+		}
+		if ok != tc.wantOK {
+			t.Fatalf("%q: expected ok=%v", tc.auth, tc.wantOK)
+		}
+		if ok && id != "alice" {
+			t.Fatalf("%q: unexpected id %q", tc.auth, id)
+		}
+	}
+}
+
+func TestExecuteStoreError(t *testing.T) {
+	uc := NewUseCase(storeMock{err: errors.New("disk failure")})
+	if _, ok, err := uc.Execute(context.Background(), "alice:x"); err == nil || ok {
+		t.Fatalf("expected store error to propagate, got ok=%v err=%v", ok, err)
+	}
+}
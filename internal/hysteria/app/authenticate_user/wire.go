@@ -0,0 +1,20 @@
+//go:build wireinject
+// +build wireinject
+
+package authenticate_user
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideUserStorePath,
+		userstore.NewFileStore,
+		wire.Bind(new(UserStore), new(*userstore.FileStore)),
+		NewUseCase,
+	)
+	return nil, nil
+}
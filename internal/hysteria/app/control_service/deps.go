@@ -0,0 +1,17 @@
+package control_service
+
+import (
+	"context"
+	"io"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type ServiceManager interface {
+	Status(ctx context.Context) (domain.ServiceStatus, error)
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Restart(ctx context.Context) error
+	Reload(ctx context.Context) error
+	Logs(ctx context.Context, w io.Writer, lines int, follow bool) error
+}
@@ -0,0 +1,10 @@
+package control_service
+
+import (
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/domain"
+)
+
+func provideService(cfg appconfig.Config) domain.ServiceUnit {
+	return cfg.Service()
+}
@@ -0,0 +1,64 @@
+package control_service
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const (
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionRestart = "restart"
+	ActionReload  = "reload"
+
+	DefaultLogLines = 100
+)
+
+type UseCase struct {
+	service ServiceManager
+}
+
+func NewUseCase(service ServiceManager) *UseCase {
+	return &UseCase{service: service}
+}
+
+func (u *UseCase) Status(ctx context.Context) (domain.ServiceStatus, error) {
+	return u.service.Status(ctx)
+}
+
+// Control runs action and returns the status the service settled in.
+func (u *UseCase) Control(ctx context.Context, action string) (domain.ServiceStatus, error) {
+	var err error
+	switch action {
+	case ActionStart:
+		err = u.service.Start(ctx)
+	case ActionStop:
+		err = u.service.Stop(ctx)
+	case ActionRestart:
+		err = u.service.Restart(ctx)
+	case ActionReload:
+		err = u.service.Reload(ctx)
+	default:
+		return domain.ServiceStatus{}, fmt.Errorf("unknown service action %q", action)
+	}
+	if err != nil {
+		return domain.ServiceStatus{}, fmt.Errorf("%s: %w", action, err)
+	}
+	return u.service.Status(ctx)
+}
+
+// Logs writes the last lines of the service log to w, DefaultLogLines when
+// lines is not positive, and with follow keeps going until ctx is canceled.
+func (u *UseCase) Logs(ctx context.Context, w io.Writer, lines int, follow bool) error {
+	if lines <= 0 {
+		lines = DefaultLogLines
+	}
+	err := u.service.Logs(ctx, w, lines, follow)
+	if follow && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
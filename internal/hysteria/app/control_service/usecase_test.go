@@ -0,0 +1,86 @@
+package control_service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type serviceMock struct {
+	state   string
+	calls   []string
+	err     error
+	lines   int
+	follow  bool
+	logsErr error
+}
+
+func (m *serviceMock) Status(context.Context) (domain.ServiceStatus, error) {
+	return domain.ServiceStatus{Manager: domain.ServiceManagerSystemd, State: m.state}, nil
+}
+
+func (m *serviceMock) do(call, state string) error {
+	m.calls = append(m.calls, call)
+	if m.err != nil {
+		return m.err
+	}
+	m.state = state
+	return nil
+}
+
+func (m *serviceMock) Start(context.Context) error   { return m.do("start", "active") }
+func (m *serviceMock) Stop(context.Context) error    { return m.do("stop", "inactive") }
+func (m *serviceMock) Restart(context.Context) error { return m.do("restart", "active") }
+func (m *serviceMock) Reload(context.Context) error  { return m.do("reload", "active") }
+
+func (m *serviceMock) Logs(_ context.Context, _ io.Writer, lines int, follow bool) error {
+	m.lines, m.follow = lines, follow
+	return m.logsErr
+}
+
+func TestControl(t *testing.T) {
+	service := &serviceMock{state: "inactive"}
+	uc := NewUseCase(service)
+
+	status, err := uc.Control(context.Background(), ActionStart)
+	if err != nil || !status.Active() {
+		t.Fatalf("expected active service after start: %+v %v", status, err)
+	}
+	status, err = uc.Control(context.Background(), ActionStop)
+	if err != nil || status.Active() {
+		t.Fatalf("expected inactive service after stop: %+v %v", status, err)
+	}
+	if _, err := uc.Control(context.Background(), "enable"); err == nil {
+		t.Fatalf("expected error for unknown action")
+	}
+
+	service.err = errors.New("unit not found")
+	if _, err := uc.Control(context.Background(), ActionReload); !errors.Is(err, service.err) {
+		t.Fatalf("expected reload error, got %v", err)
+	}
+	if len(service.calls) != 3 {
+		t.Fatalf("unexpected calls %v", service.calls)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	service := &serviceMock{}
+	uc := NewUseCase(service)
+
+	if err := uc.Logs(context.Background(), io.Discard, 0, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if service.lines != DefaultLogLines || service.follow {
+		t.Fatalf("expected %d lines without follow, got %d %v", DefaultLogLines, service.lines, service.follow)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	service.logsErr = context.Canceled
+	if err := uc.Logs(ctx, io.Discard, 20, true); err != nil {
+		t.Fatalf("canceling a follow must not fail: %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build wireinject
+// +build wireinject
+
+package control_service
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/servicectl"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideService,
+		servicectl.NewService,
+		wire.Bind(new(ServiceManager), new(*servicectl.Service)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -0,0 +1,19 @@
+package diff_fleet
+
+import (
+	"fmt"
+
+	appconfig "vpn/internal/config"
+)
+
+func BuildUseCase(cfgs []appconfig.Config) (*UseCase, error) {
+	nodes := make([]Node, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		node, err := BuildNode(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", cfg.Context, err)
+		}
+		nodes = append(nodes, node)
+	}
+	return NewUseCase(nodes), nil
+}
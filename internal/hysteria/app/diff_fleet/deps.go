@@ -0,0 +1,16 @@
+package diff_fleet
+
+import "context"
+
+type UserLister interface {
+	ListUsers(ctx context.Context) ([]string, error)
+}
+
+type Node struct {
+	Name  string
+	Users UserLister
+}
+
+func NewNode(name string, users UserLister) Node {
+	return Node{Name: name, Users: users}
+}
@@ -0,0 +1,9 @@
+package diff_fleet
+
+import appconfig "vpn/internal/config"
+
+func provideNodeName(cfg appconfig.Config) string      { return cfg.Context }
+func provideConfigPath(cfg appconfig.Config) string    { return cfg.HysteriaConfigPath }
+func provideUserBackend(cfg appconfig.Config) string   { return cfg.UserBackend }
+func provideUserStorePath(cfg appconfig.Config) string { return cfg.UserStorePath }
+func provideUserDBPath(cfg appconfig.Config) string    { return cfg.UserDBPath }
@@ -0,0 +1,74 @@
+package diff_fleet
+
+import (
+	"context"
+	"sort"
+)
+
+// UserDrift is a user that exists on some nodes but not on others.
+type UserDrift struct {
+	Username string
+	Present  []string
+	Missing  []string
+}
+
+type NodeError struct {
+	Name string
+	Err  error
+}
+
+type Report struct {
+	Nodes  []string
+	Drift  []UserDrift
+	Errors []NodeError
+}
+
+func (r Report) HasDrift() bool {
+	return len(r.Drift) > 0
+}
+
+type UseCase struct {
+	nodes []Node
+}
+
+func NewUseCase(nodes []Node) *UseCase {
+	return &UseCase{nodes: nodes}
+}
+
+// Execute compares user lists across nodes. Unreachable nodes are reported
+// separately and left out of the comparison.
+func (u *UseCase) Execute(ctx context.Context) (Report, error) {
+	var report Report
+	presence := map[string]map[string]bool{}
+	for _, node := range u.nodes {
+		users, err := node.Users.ListUsers(ctx)
+		if err != nil {
+			report.Errors = append(report.Errors, NodeError{Name: node.Name, Err: err})
+			continue
+		}
+		report.Nodes = append(report.Nodes, node.Name)
+		for _, username := range users {
+			if presence[username] == nil {
+				presence[username] = map[string]bool{}
+			}
+			presence[username][node.Name] = true
+		}
+	}
+
+	for username, on := range presence {
+		if len(on) == len(report.Nodes) {
+			continue
+		}
+		drift := UserDrift{Username: username}
+		for _, name := range report.Nodes {
+			if on[name] {
+				drift.Present = append(drift.Present, name)
+			} else {
+				drift.Missing = append(drift.Missing, name)
+			}
+		}
+		report.Drift = append(report.Drift, drift)
+	}
+	sort.Slice(report.Drift, func(i, j int) bool { return report.Drift[i].Username < report.Drift[j].Username })
+	return report, nil
+}
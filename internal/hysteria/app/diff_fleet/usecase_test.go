@@ -0,0 +1,52 @@
+package diff_fleet
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type listerMock struct {
+	users []string
+	err   error
+}
+
+func (m listerMock) ListUsers(context.Context) ([]string, error) {
+	return m.users, m.err
+}
+
+func TestExecute(t *testing.T) {
+	uc := NewUseCase([]Node{
+		NewNode("nl1", listerMock{users: []string{"alice", "bob"}}),
+		NewNode("de1", listerMock{users: []string{"alice", "carol"}}),
+		NewNode("fi1", listerMock{err: errors.New("unreachable")}),
+	})
+
+	report, err := uc.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []UserDrift{
+		{Username: "bob", Present: []string{"nl1"}, Missing: []string{"de1"}},
+		{Username: "carol", Present: []string{"de1"}, Missing: []string{"nl1"}},
+	}
+	if !reflect.DeepEqual(report.Drift, want) {
+		t.Fatalf("unexpected drift: %+v", report.Drift)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Name != "fi1" {
+		t.Fatalf("unreachable node must be reported: %+v", report.Errors)
+	}
+}
+
+func TestExecuteInSync(t *testing.T) {
+	uc := NewUseCase([]Node{
+		NewNode("nl1", listerMock{users: []string{"alice"}}),
+		NewNode("de1", listerMock{users: []string{"alice"}}),
+	})
+
+	report, err := uc.Execute(context.Background())
+	if err != nil || report.HasDrift() {
+		t.Fatalf("expected no drift: %+v %v", report, err)
+	}
+}
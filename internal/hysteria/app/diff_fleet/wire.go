@@ -0,0 +1,24 @@
+//go:build wireinject
+// +build wireinject
+
+package diff_fleet
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildNode(cfg appconfig.Config) (Node, error) {
+	wire.Build(
+		provideNodeName,
+		provideConfigPath,
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		userstore.NewRepository,
+		wire.Bind(new(UserLister), new(*userstore.Repository)),
+		NewNode,
+	)
+	return Node{}, nil
+}
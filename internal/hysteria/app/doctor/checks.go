@@ -0,0 +1,287 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const (
+	// certWarnDays is well inside the 30 days before expiry when ACME
+	// renews, so a warning means renewal is failing.
+	certWarnDays  = 14
+	defaultListen = ":443"
+)
+
+var statusRank = map[string]int{StatusSkip: 0, StatusPass: 1, StatusWarn: 2, StatusFail: 3}
+
+// add records one problem; the check keeps the worst status and the hints
+// of all problems.
+func (c *Check) add(status, detail, hint string) {
+	if statusRank[status] > statusRank[c.Status] {
+		c.Status = status
+	}
+	c.Details = append(c.Details, detail)
+	if hint != "" && !strings.Contains(c.Hint, hint) {
+		if c.Hint != "" {
+			c.Hint += "; "
+		}
+		c.Hint += hint
+	}
+}
+
+func (u *UseCase) checkCLIConfig() Check {
+	path := u.env.CLIConfigPath
+	check := Check{Name: "cli-config", Status: StatusPass}
+	mode, err := u.host.CheckFile(path)
+	if err != nil {
+		check.Status, check.Message = StatusFail, fmt.Sprintf("cannot read %s: %v", path, err)
+		check.Hint = "run as the owner of the CLI config or point VPN_CONFIG_PATH at a readable one"
+		return check
+	}
+	if mode.Perm()&0o077 != 0 {
+		check.add(StatusWarn, fmt.Sprintf("%s has mode %04o and may hold secrets", path, mode.Perm()), "chmod 600 "+path)
+	}
+	if key := u.env.SecretsKeyFile; key != "" {
+		keyMode, err := u.host.CheckFile(key)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			check.add(StatusFail, fmt.Sprintf("cannot read vault key %s: %v", key, err), "run as the owner of the vault key")
+		case keyMode.Perm()&0o077 != 0:
+			check.add(StatusFail, fmt.Sprintf("vault key %s has mode %04o and will not be used", key, keyMode.Perm()), "chmod 600 "+key)
+		}
+	}
+	if check.Status == StatusPass {
+		check.Message = fmt.Sprintf("%s is readable, mode %04o", path, mode.Perm())
+	} else {
+		check.Message = "the CLI config or its secrets are exposed or unreadable"
+	}
+	return check
+}
+
+func (u *UseCase) checkHysteriaConfig(ctx context.Context) Check {
+	path := u.env.HysteriaConfigPath
+	check := Check{Name: "hysteria-config", Status: StatusPass}
+	if u.env.ConfigPathErr != nil {
+		check.Status, check.Message = StatusFail, u.env.ConfigPathErr.Error()
+		check.Hint = "check hysteria_container or hysteria_compose_service and that the config directory is bind-mounted from the host"
+		return check
+	}
+	if !u.env.Remote {
+		mode, err := u.host.CheckFile(path)
+		if err != nil {
+			check.Status, check.Message = StatusFail, fmt.Sprintf("cannot read %s: %v", path, err)
+			check.Hint = "check hysteria_config_path in the CLI config or create the config with config generate"
+			return check
+		}
+		if mode.Perm()&0o007 != 0 {
+			check.add(StatusWarn, fmt.Sprintf("mode %04o lets every local user read the user passwords", mode.Perm()), "chmod 640 "+path)
+		}
+	}
+	issues, err := u.config.Validate(ctx)
+	if err != nil {
+		check.Status, check.Message = StatusFail, err.Error()
+		return check
+	}
+	errs := len(domain.ConfigErrors(issues))
+	for _, issue := range issues {
+		if issue.Severity == domain.ConfigIssueError {
+			check.add(StatusFail, issue.String(), "fix the errors; Hysteria refuses to start with them")
+		} else {
+			check.add(StatusWarn, issue.String(), "")
+		}
+	}
+	switch {
+	case check.Status == StatusPass:
+		check.Message = path + " is valid"
+	case len(issues) > 0:
+		check.Message = fmt.Sprintf("%s: %d errors, %d warnings", path, errs, len(issues)-errs)
+	default:
+		check.Message = path + " is valid but exposed"
+	}
+	return check
+}
+
+func (u *UseCase) checkAuth(ctx context.Context) Check {
+	check := Check{Name: "auth"}
+	auth, err := u.users.AuthInfo(ctx)
+	if err != nil {
+		check.Status, check.Message = StatusFail, fmt.Sprintf("read auth: %v", err)
+		return check
+	}
+	switch {
+	case auth.Mode == domain.AuthModeUserpass:
+		check.Status, check.Message = StatusPass, "auth.type userpass, users are managed by the CLI"
+	case auth.UserStore:
+		check.Status, check.Message = StatusPass, fmt.Sprintf("auth.type %s backed by the CLI user store", auth.Mode)
+		check.Hint = "keep auth-server running next to Hysteria"
+	case auth.Mode == domain.AuthModePassword:
+		check.Status, check.Message = StatusWarn, "auth.type password: every client shares one password"
+		check.Hint = "migrate-auth converts the server to per-user userpass auth"
+	default:
+		check.Status, check.Message = StatusWarn, fmt.Sprintf("auth.type %s is managed outside the CLI, users are read-only", auth.Mode)
+	}
+	return check
+}
+
+func (u *UseCase) checkService(ctx context.Context) Check {
+	name := u.env.ServiceName
+	check := Check{Name: "service"}
+	status, err := u.service.Status(ctx)
+	if err != nil {
+		check.Status, check.Message = StatusFail, fmt.Sprintf("cannot query the service manager: %v", err)
+		check.Hint = "check hysteria_service_name and hysteria_service_manager"
+		return check
+	}
+	if status.Active() {
+		check.Status, check.Message = StatusPass, fmt.Sprintf("%s: %s is active", status.Manager, name)
+		return check
+	}
+	check.Status, check.Message = StatusFail, fmt.Sprintf("%s: %s is %s", status.Manager, name, status.State)
+	check.Hint = "service start; service logs shows why it stopped"
+	return check
+}
+
+func (u *UseCase) checkListenPort(ctx context.Context) Check {
+	check := Check{Name: "listen-port"}
+	if u.env.Remote {
+		check.Status, check.Message = StatusSkip, "not checked on ssh contexts"
+		return check
+	}
+	settings, err := u.config.Settings(ctx)
+	if err != nil {
+		check.Status, check.Message = StatusFail, fmt.Sprintf("read listen: %v", err)
+		return check
+	}
+	listen := defaultListen
+	for _, s := range settings {
+		if s.Def.Key == "listen" && s.Set {
+			listen = s.Value
+		}
+	}
+	bound, err := u.host.UDPPortBound(listen)
+	switch {
+	case err != nil:
+		check.Status, check.Message = StatusWarn, err.Error()
+		check.Hint = "run doctor as root to probe privileged ports"
+	case bound:
+		check.Status, check.Message = StatusPass, fmt.Sprintf("udp %s is bound", listen)
+	default:
+		check.Status, check.Message = StatusFail, fmt.Sprintf("nothing listens on udp %s", listen)
+		check.Hint = "start the service and check its log; a changed listen needs a restart"
+	}
+	return check
+}
+
+func (u *UseCase) checkTrafficStats(ctx context.Context) Check {
+	check := Check{Name: "traffic-stats"}
+	if !u.env.TrafficStats {
+		check.Status, check.Message = StatusSkip, "disabled in the CLI config"
+		return check
+	}
+	snapshot, err := u.stats.Fetch(ctx)
+	switch {
+	case errors.Is(err, domain.ErrTrafficStatsUnauthorized):
+		check.Status, check.Message = StatusFail, fmt.Sprintf("%s rejected the secret", u.env.TrafficStatsURL)
+		check.Hint = "hysteria_traffic_stats_secret must match trafficStats.secret of the server config"
+	case err != nil:
+		check.Status, check.Message = StatusFail, fmt.Sprintf("%s is unreachable: %v", u.env.TrafficStatsURL, err)
+		check.Hint = "set trafficStats.listen in the server config and point hysteria_traffic_stats_url at it"
+	default:
+		online := 0
+		for _, on := range snapshot.Online {
+			if on {
+				online++
+			}
+		}
+		check.Status, check.Message = StatusPass, fmt.Sprintf("%s answers, %d users online", u.env.TrafficStatsURL, online)
+	}
+	return check
+}
+
+func (u *UseCase) checkCertificates(ctx context.Context) Check {
+	check := Check{Name: "certificates", Status: StatusPass}
+	status, err := u.config.TLSStatus(ctx, "")
+	if err != nil {
+		check.Status, check.Message = StatusFail, fmt.Sprintf("read certificates: %v", err)
+		check.Hint = "configure acme or tls in the server config, e.g. with tls self-signed"
+		return check
+	}
+	hint := "replace tls.cert or issue a new one with tls self-signed"
+	if status.Mode == domain.TLSModeACME {
+		hint = "ACME renews 30 days before expiry; the service log shows why renewal fails"
+	}
+
+	now := u.now()
+	var first *domain.Certificate
+	for _, c := range status.Certificates {
+		switch {
+		case c.Err != nil:
+			check.add(StatusFail, fmt.Sprintf("%s: %v", c.Name, c.Err), hint)
+			continue
+		case now.After(c.Cert.NotAfter):
+			check.add(StatusFail, fmt.Sprintf("%s expired on %s", c.Name, c.Cert.NotAfter.Format(time.DateOnly)), hint)
+		case c.Cert.NotAfter.Sub(now) < certWarnDays*24*time.Hour:
+			check.add(StatusWarn, fmt.Sprintf("%s expires on %s", c.Name, c.Cert.NotAfter.Format(time.DateOnly)), hint)
+		}
+		if first == nil || c.Cert.NotAfter.Before(first.NotAfter) {
+			first = c.Cert
+		}
+	}
+	switch {
+	case check.Status != StatusPass:
+		check.Message = fmt.Sprintf("%d of %d certificates need attention", len(check.Details), len(status.Certificates))
+	case first != nil:
+		days := int(first.NotAfter.Sub(now).Hours() / 24)
+		check.Message = fmt.Sprintf("%d certificates valid, the first expires on %s (%d days left)", len(status.Certificates), first.NotAfter.Format(time.DateOnly), days)
+	default:
+		check.Message = "no certificates configured"
+	}
+	return check
+}
+
+// requiredTools are external programs some commands shell out to. A
+// missing optional tool is listed without lowering the status.
+var requiredTools = []struct {
+	name, usedBy, hint string
+	optional           bool
+}{
+	{"qrencode", "QR codes of connection", "install qrencode", false},
+	{"ansible-playbook", "init --ansible", "install ansible-core to use init --ansible", true},
+}
+
+func (u *UseCase) checkTools() Check {
+	check := Check{Name: "tools", Status: StatusPass}
+	var installed []string
+	missing, required := 0, 0
+	for _, tool := range requiredTools {
+		if !tool.optional {
+			required++
+		}
+		if _, err := u.host.LookPath(tool.name); err == nil {
+			installed = append(installed, tool.name)
+			continue
+		}
+		if tool.optional {
+			check.add(StatusPass, fmt.Sprintf("%s not found in PATH, only needed for %s", tool.name, tool.usedBy), tool.hint)
+			continue
+		}
+		missing++
+		check.add(StatusWarn, fmt.Sprintf("%s not found in PATH, needed for %s", tool.name, tool.usedBy), tool.hint)
+	}
+	switch {
+	case missing > 0:
+		check.Message = fmt.Sprintf("%d of %d required tools are missing", missing, required)
+	case len(installed) == len(requiredTools):
+		check.Message = strings.Join(installed, " and ") + " are installed"
+	default:
+		check.Message = "required tools are installed; optional ones are missing"
+	}
+	return check
+}
@@ -0,0 +1,33 @@
+package doctor
+
+import (
+	"context"
+	"io/fs"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UserRepository interface {
+	ListUsers(ctx context.Context) ([]string, error)
+	AuthInfo(ctx context.Context) (domain.AuthInfo, error)
+}
+
+type ConfigRepository interface {
+	Validate(ctx context.Context) ([]domain.ConfigIssue, error)
+	Settings(ctx context.Context) ([]domain.SettingValue, error)
+	TLSStatus(ctx context.Context, acmeDir string) (domain.TLSStatus, error)
+}
+
+type ServiceStatusReader interface {
+	Status(ctx context.Context) (domain.ServiceStatus, error)
+}
+
+type TrafficStatsClient interface {
+	Fetch(ctx context.Context) (domain.TrafficSnapshot, error)
+}
+
+type Host interface {
+	CheckFile(path string) (fs.FileMode, error)
+	LookPath(name string) (string, error)
+	UDPPortBound(addr string) (bool, error)
+}
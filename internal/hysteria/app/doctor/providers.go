@@ -0,0 +1,58 @@
+package doctor
+
+import (
+	"time"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/domain"
+)
+
+func provideConfigPath(cfg appconfig.Config) string    { return cfg.HysteriaConfigPath }
+func provideUserBackend(cfg appconfig.Config) string   { return cfg.UserBackend }
+func provideUserStorePath(cfg appconfig.Config) string { return cfg.UserStorePath }
+func provideUserDBPath(cfg appconfig.Config) string    { return cfg.UserDBPath }
+func provideRemoteHost(cfg appconfig.Config) string    { return cfg.SSH }
+
+func provideService(cfg appconfig.Config) domain.ServiceUnit {
+	return cfg.Service()
+}
+
+func provideUsernamePolicy(cfg appconfig.Config) domain.UsernamePolicy {
+	return cfg.UsernamePolicy.DomainPolicy()
+}
+
+func provideTrafficStatsEnabled(cfg appconfig.Config) bool {
+	return cfg.HysteriaTrafficStatsEnabled
+}
+
+func provideTrafficStatsURL(cfg appconfig.Config) string {
+	return cfg.HysteriaTrafficStatsURL
+}
+
+func provideTrafficStatsSecret(cfg appconfig.Config) string {
+	return cfg.HysteriaTrafficStatsSecret
+}
+
+func provideTrafficStatsTimeout(cfg appconfig.Config) time.Duration {
+	if cfg.HysteriaTrafficStatsTimeoutSeconds <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(cfg.HysteriaTrafficStatsTimeoutSeconds) * time.Second
+}
+
+// ConfigPathError is why hysteria_config_path could not be mapped out of
+// the Hysteria container; doctor reports it instead of refusing to run.
+type ConfigPathError struct{ Err error }
+
+func provideEnvironment(cfg appconfig.Config, pathErr ConfigPathError) Environment {
+	return Environment{
+		CLIConfigPath:      cfg.Path,
+		HysteriaConfigPath: cfg.HysteriaConfigPath,
+		ConfigPathErr:      pathErr.Err,
+		SecretsKeyFile:     cfg.SecretsKeyFile,
+		ServiceName:        cfg.Service().String(),
+		Remote:             cfg.SSH != "",
+		TrafficStats:       cfg.HysteriaTrafficStatsEnabled,
+		TrafficStatsURL:    cfg.HysteriaTrafficStatsURL,
+	}
+}
@@ -0,0 +1,126 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+)
+
+// StatusSkip marks a check that does not apply, e.g. the listen port of a
+// node reached over ssh.
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+type Check struct {
+	Name    string
+	Status  string
+	Message string
+	// Details lists the offending entries, one per line.
+	Details []string
+	Hint    string
+}
+
+type Report struct {
+	Checks []Check
+}
+
+func (r Report) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Environment is what doctor knows about the node from the CLI config.
+type Environment struct {
+	CLIConfigPath      string
+	HysteriaConfigPath string
+	// ConfigPathErr is set when the config path inside the Hysteria
+	// container could not be mapped to the host.
+	ConfigPathErr  error
+	SecretsKeyFile string
+	ServiceName    string
+	// Remote is set for ssh contexts; local file and port checks are skipped.
+	Remote          bool
+	TrafficStats    bool
+	TrafficStatsURL string
+}
+
+type UseCase struct {
+	users     UserRepository
+	config    ConfigRepository
+	service   ServiceStatusReader
+	stats     TrafficStatsClient
+	host      Host
+	usernames domain.UsernamePolicy
+	env       Environment
+	now       func() time.Time
+}
+
+func NewUseCase(users UserRepository, config ConfigRepository, service ServiceStatusReader, stats TrafficStatsClient, host Host, usernames domain.UsernamePolicy, env Environment) *UseCase {
+	return &UseCase{
+		users:     users,
+		config:    config,
+		service:   service,
+		stats:     stats,
+		host:      host,
+		usernames: usernames,
+		env:       env,
+		now:       time.Now,
+	}
+}
+
+// Execute runs every check; one failing never stops the others.
+func (u *UseCase) Execute(ctx context.Context) Report {
+	return Report{Checks: []Check{
+		u.checkCLIConfig(),
+		u.checkHysteriaConfig(ctx),
+		u.checkAuth(ctx),
+		u.checkService(ctx),
+		u.checkListenPort(ctx),
+		u.checkTrafficStats(ctx),
+		u.checkCertificates(ctx),
+		u.checkTools(),
+		u.checkUsernames(ctx),
+	}}
+}
+
+// checkUsernames flags users created before the username policy, or by
+// editing the config by hand. They keep working, so they only warn.
+func (u *UseCase) checkUsernames(ctx context.Context) Check {
+	check := Check{Name: "usernames"}
+	auth, err := u.users.AuthInfo(ctx)
+	if err != nil {
+		check.Status, check.Message = StatusFail, fmt.Sprintf("read users: %v", err)
+		return check
+	}
+	if !auth.ManagesUsers() {
+		check.Status, check.Message = StatusPass, fmt.Sprintf("auth mode %q has no user list", auth.Mode)
+		return check
+	}
+	users, err := u.users.ListUsers(ctx)
+	if err != nil {
+		check.Status, check.Message = StatusFail, fmt.Sprintf("read users: %v", err)
+		return check
+	}
+	issues := u.usernames.Review(users)
+	if len(issues) == 0 {
+		check.Status, check.Message = StatusPass, fmt.Sprintf("%d users comply with the username policy", len(users))
+		return check
+	}
+	check.Status = StatusWarn
+	check.Message = fmt.Sprintf("%d of %d users break the username policy", len(issues), len(users))
+	for _, issue := range issues {
+		check.Details = append(check.Details, fmt.Sprintf("%s: %s", issue.Username, issue.Problem))
+	}
+	check.Hint = "re-create these users under a compliant name, keeping the password with add-user --password-file, or relax username_policy"
+	return check
+}
@@ -0,0 +1,283 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type usersMock struct {
+	auth  domain.AuthInfo
+	users []string
+	err   error
+}
+
+func (m usersMock) ListUsers(context.Context) ([]string, error) {
+	return m.users, m.err
+}
+
+func (m usersMock) AuthInfo(context.Context) (domain.AuthInfo, error) {
+	return m.auth, nil
+}
+
+type configMock struct {
+	issues   []domain.ConfigIssue
+	settings []domain.SettingValue
+	tls      domain.TLSStatus
+	tlsErr   error
+}
+
+func (m configMock) Validate(context.Context) ([]domain.ConfigIssue, error) {
+	return m.issues, nil
+}
+
+func (m configMock) Settings(context.Context) ([]domain.SettingValue, error) {
+	return m.settings, nil
+}
+
+func (m configMock) TLSStatus(context.Context, string) (domain.TLSStatus, error) {
+	return m.tls, m.tlsErr
+}
+
+type serviceMock struct {
+	status domain.ServiceStatus
+	err    error
+}
+
+func (m serviceMock) Status(context.Context) (domain.ServiceStatus, error) {
+	return m.status, m.err
+}
+
+type statsMock struct {
+	online map[string]bool
+	err    error
+}
+
+func (m statsMock) Fetch(context.Context) (domain.TrafficSnapshot, error) {
+	return domain.TrafficSnapshot{Online: m.online}, m.err
+}
+
+type hostMock struct {
+	modes    map[string]fs.FileMode
+	tools    map[string]bool
+	bound    map[string]bool
+	probeErr error
+}
+
+func (m hostMock) CheckFile(path string) (fs.FileMode, error) {
+	mode, ok := m.modes[path]
+	if !ok {
+		return 0, fs.ErrNotExist
+	}
+	return mode, nil
+}
+
+func (m hostMock) LookPath(name string) (string, error) {
+	if !m.tools[name] {
+		return "", fmt.Errorf("%s: not found", name)
+	}
+	return "/usr/bin/" + name, nil
+}
+
+func (m hostMock) UDPPortBound(addr string) (bool, error) {
+	return m.bound[addr], m.probeErr
+}
+
+var testNow = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// healthyUseCase passes every check; tests break one dependency at a time.
+func healthyUseCase() (*UseCase, *configMock, *serviceMock, *statsMock, *hostMock) {
+	config := &configMock{tls: domain.TLSStatus{Mode: domain.TLSModeFile, Certificates: []domain.CertificateStatus{
+		{Name: "/etc/hysteria/cert.pem", Cert: &domain.Certificate{NotAfter: testNow.AddDate(0, 3, 0)}},
+	}}}
+	service := &serviceMock{status: domain.ServiceStatus{Manager: "systemd", State: domain.ServiceStateActive}}
+	stats := &statsMock{online: map[string]bool{"alice": true}}
+	host := &hostMock{
+		modes: map[string]fs.FileMode{"/etc/vpn/config.yaml": 0o600, "/etc/hysteria/config.yaml": 0o640},
+		tools: map[string]bool{"qrencode": true, "ansible-playbook": true},
+		bound: map[string]bool{defaultListen: true},
+	}
+	env := Environment{
+		CLIConfigPath:      "/etc/vpn/config.yaml",
+		HysteriaConfigPath: "/etc/hysteria/config.yaml",
+		SecretsKeyFile:     "/etc/vpn/secrets.key",
+		ServiceName:        "hysteria-server",
+		TrafficStats:       true,
+		TrafficStatsURL:    "http://127.0.0.1:9999",
+	}
+	users := usersMock{auth: domain.AuthInfo{Mode: domain.AuthModeUserpass}, users: []string{"alice"}}
+	useCase := NewUseCase(users, config, service, stats, host, domain.UsernamePolicy{}, env)
+	useCase.now = func() time.Time { return testNow }
+	return useCase, config, service, stats, host
+}
+
+func findCheck(t *testing.T, report Report, name string) Check {
+	t.Helper()
+	for _, c := range report.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("check %q missing from %+v", name, report)
+	return Check{}
+}
+
+func TestExecuteHealthy(t *testing.T) {
+	useCase, _, _, _, _ := healthyUseCase()
+	report := useCase.Execute(context.Background())
+	for _, c := range report.Checks {
+		if c.Status != StatusPass {
+			t.Fatalf("expected every check to pass, got %+v", c)
+		}
+	}
+}
+
+func TestExecuteReportsProblems(t *testing.T) {
+	cases := []struct {
+		name   string
+		check  string
+		status string
+		breaks func(*configMock, *serviceMock, *statsMock, *hostMock)
+	}{
+		{"world-readable cli config", "cli-config", StatusWarn, func(_ *configMock, _ *serviceMock, _ *statsMock, h *hostMock) {
+			h.modes["/etc/vpn/config.yaml"] = 0o644
+		}},
+		{"exposed vault key", "cli-config", StatusFail, func(_ *configMock, _ *serviceMock, _ *statsMock, h *hostMock) {
+			h.modes["/etc/vpn/secrets.key"] = 0o644
+		}},
+		{"unreadable hysteria config", "hysteria-config", StatusFail, func(_ *configMock, _ *serviceMock, _ *statsMock, h *hostMock) {
+			delete(h.modes, "/etc/hysteria/config.yaml")
+		}},
+		{"invalid hysteria config", "hysteria-config", StatusFail, func(c *configMock, _ *serviceMock, _ *statsMock, _ *hostMock) {
+			c.issues = []domain.ConfigIssue{{Line: 3, Path: "listen", Severity: domain.ConfigIssueError, Message: "bad address"}}
+		}},
+		{"inactive service", "service", StatusFail, func(_ *configMock, s *serviceMock, _ *statsMock, _ *hostMock) {
+			s.status.State = "failed"
+		}},
+		{"no service manager", "service", StatusFail, func(_ *configMock, s *serviceMock, _ *statsMock, _ *hostMock) {
+			s.err = errors.New("no supported service manager")
+		}},
+		{"port not bound", "listen-port", StatusFail, func(_ *configMock, _ *serviceMock, _ *statsMock, h *hostMock) {
+			h.bound = map[string]bool{}
+		}},
+		{"custom listen not bound", "listen-port", StatusFail, func(c *configMock, _ *serviceMock, _ *statsMock, _ *hostMock) {
+			def, _ := domain.LookupSetting("listen")
+			c.settings = []domain.SettingValue{{Def: def, Value: ":8443", Set: true}}
+		}},
+		{"port probe denied", "listen-port", StatusWarn, func(_ *configMock, _ *serviceMock, _ *statsMock, h *hostMock) {
+			h.probeErr = errors.New("permission denied")
+		}},
+		{"wrong stats secret", "traffic-stats", StatusFail, func(_ *configMock, _ *serviceMock, s *statsMock, _ *hostMock) {
+			s.err = fmt.Errorf("%w (status 401)", domain.ErrTrafficStatsUnauthorized)
+		}},
+		{"stats unreachable", "traffic-stats", StatusFail, func(_ *configMock, _ *serviceMock, s *statsMock, _ *hostMock) {
+			s.err = errors.New("connection refused")
+		}},
+		{"certificate expires soon", "certificates", StatusWarn, func(c *configMock, _ *serviceMock, _ *statsMock, _ *hostMock) {
+			c.tls.Certificates[0].Cert.NotAfter = testNow.AddDate(0, 0, 5)
+		}},
+		{"certificate expired", "certificates", StatusFail, func(c *configMock, _ *serviceMock, _ *statsMock, _ *hostMock) {
+			c.tls.Certificates[0].Cert.NotAfter = testNow.AddDate(0, 0, -1)
+		}},
+		{"no tls", "certificates", StatusFail, func(c *configMock, _ *serviceMock, _ *statsMock, _ *hostMock) {
+			c.tlsErr = errors.New("neither acme nor tls.cert is configured")
+		}},
+		{"missing qrencode", "tools", StatusWarn, func(_ *configMock, _ *serviceMock, _ *statsMock, h *hostMock) {
+			h.tools["qrencode"] = false
+		}},
+		{"missing ansible is informational", "tools", StatusPass, func(_ *configMock, _ *serviceMock, _ *statsMock, h *hostMock) {
+			h.tools["ansible-playbook"] = false
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			useCase, config, service, stats, host := healthyUseCase()
+			tc.breaks(config, service, stats, host)
+			got := findCheck(t, useCase.Execute(context.Background()), tc.check)
+			if got.Status != tc.status || got.Hint == "" {
+				t.Fatalf("expected %s with a hint, got %+v", tc.status, got)
+			}
+		})
+	}
+}
+
+func TestExecuteSkipsLocalChecksOverSSH(t *testing.T) {
+	useCase, _, _, _, host := healthyUseCase()
+	useCase.env.Remote = true
+	useCase.env.TrafficStats = false
+	delete(host.modes, "/etc/hysteria/config.yaml")
+	host.bound = map[string]bool{}
+
+	report := useCase.Execute(context.Background())
+	if got := findCheck(t, report, "hysteria-config"); got.Status != StatusPass {
+		t.Fatalf("remote config must not be stat'ed locally: %+v", got)
+	}
+	for _, name := range []string{"listen-port", "traffic-stats"} {
+		if got := findCheck(t, report, name); got.Status != StatusSkip {
+			t.Fatalf("%s: expected skip, got %+v", name, got)
+		}
+	}
+	if report.Failed() {
+		t.Fatalf("skipped checks must not fail the report: %+v", report)
+	}
+}
+
+func TestExecuteReportsUnresolvedContainerPath(t *testing.T) {
+	useCase, _, _, _, _ := healthyUseCase()
+	useCase.env.ConfigPathErr = errors.New("resolve config path in container hysteria: /etc/hysteria/config.yaml is not mounted")
+
+	report := useCase.Execute(context.Background())
+	got := findCheck(t, report, "hysteria-config")
+	if got.Status != StatusFail || !strings.Contains(got.Message, "not mounted") || got.Hint == "" {
+		t.Fatalf("expected the resolution error as a failed check, got %+v", got)
+	}
+	if got := findCheck(t, report, "tools"); got.Status != StatusPass {
+		t.Fatalf("other checks must still run: %+v", got)
+	}
+}
+
+func TestCheckUsernames(t *testing.T) {
+	userpass := domain.AuthInfo{Mode: domain.AuthModeUserpass}
+
+	useCase, _, _, _, _ := healthyUseCase()
+
+	useCase.users = usersMock{auth: userpass, users: []string{"alice", "bob"}}
+	report := useCase.Execute(context.Background())
+	if got := findCheck(t, report, "usernames"); got.Status != StatusPass || report.Failed() {
+		t.Fatalf("expected pass, got %+v", got)
+	}
+
+	users := []string{"alice", "Alice", "a b", "root", "bob"}
+	useCase.users = usersMock{auth: userpass, users: users}
+	report = useCase.Execute(context.Background())
+	got := findCheck(t, report, "usernames")
+	if got.Status != StatusWarn || got.Hint == "" || report.Failed() {
+		t.Fatalf("expected warning with hint, got %+v", got)
+	}
+	want := []string{
+		`Alice: must be lower case`,
+		`a b: character ' ' is not allowed (letters, digits and "-_.")`,
+		`alice: differs from "Alice" only in case`,
+		`root: name is reserved`,
+	}
+	if len(got.Details) != len(want) {
+		t.Fatalf("unexpected details: %q", got.Details)
+	}
+	for i := range want {
+		if got.Details[i] != want[i] {
+			t.Fatalf("detail %d: expected %q, got %q", i, want[i], got.Details[i])
+		}
+	}
+
+	useCase.users = usersMock{auth: userpass, err: errors.New("broken yaml")}
+	report = useCase.Execute(context.Background())
+	if !report.Failed() {
+		t.Fatalf("unreadable users must fail: %+v", report)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build wireinject
+// +build wireinject
+
+package doctor
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/hostenv"
+	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/hysteria/infra/trafficstats"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildUseCase(cfg appconfig.Config, pathErr ConfigPathError) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		provideService,
+		provideRemoteHost,
+		provideTrafficStatsEnabled,
+		provideTrafficStatsURL,
+		provideTrafficStatsSecret,
+		provideTrafficStatsTimeout,
+		provideUsernamePolicy,
+		provideEnvironment,
+		userstore.NewRepository,
+		configrepo.NewRepository,
+		servicectl.NewService,
+		trafficstats.NewClient,
+		hostenv.NewHost,
+		wire.Bind(new(UserRepository), new(*userstore.Repository)),
+		wire.Bind(new(ConfigRepository), new(*configrepo.Repository)),
+		wire.Bind(new(ServiceStatusReader), new(*servicectl.Service)),
+		wire.Bind(new(TrafficStatsClient), new(*trafficstats.Client)),
+		wire.Bind(new(Host), new(*hostenv.Host)),
+		NewUseCase,
+	)
+	return nil, nil
+}
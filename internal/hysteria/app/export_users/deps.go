@@ -0,0 +1,11 @@
+package export_users
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UserRepository interface {
+	Users(ctx context.Context) ([]domain.User, error)
+}
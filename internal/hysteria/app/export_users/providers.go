@@ -0,0 +1,8 @@
+package export_users
+
+import appconfig "vpn/internal/config"
+
+func provideConfigPath(cfg appconfig.Config) string    { return cfg.HysteriaConfigPath }
+func provideUserBackend(cfg appconfig.Config) string   { return cfg.UserBackend }
+func provideUserStorePath(cfg appconfig.Config) string { return cfg.UserStorePath }
+func provideUserDBPath(cfg appconfig.Config) string    { return cfg.UserDBPath }
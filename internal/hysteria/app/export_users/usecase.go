@@ -0,0 +1,19 @@
+package export_users
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UseCase struct {
+	repo UserRepository
+}
+
+func NewUseCase(repo UserRepository) *UseCase {
+	return &UseCase{repo: repo}
+}
+
+func (u *UseCase) Execute(ctx context.Context) ([]domain.User, error) {
+	return u.repo.Users(ctx)
+}
@@ -0,0 +1,25 @@
+package export_users
+
+import (
+	"context"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct{}
+
+func (repoMock) Users(context.Context) ([]domain.User, error) {
+	return []domain.User{{Username: "alice", Password: "1"}, {Username: "bob", Password: "2"}}, nil
+}
+
+func TestExecute(t *testing.T) {
+	uc := NewUseCase(repoMock{})
+	users, err := uc.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(users) != 2 || users[0].Password != "1" {
+		t.Fatalf("unexpected users: %#v", users)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build wireinject
+// +build wireinject
+
+package export_users
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		userstore.NewRepository,
+		wire.Bind(new(UserRepository), new(*userstore.Repository)),
+		NewUseCase,
+	)
+	return nil, nil
+}
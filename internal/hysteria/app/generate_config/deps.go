@@ -0,0 +1,11 @@
+package generate_config
+
+type PasswordGenerator interface {
+	Generate() (string, error)
+}
+
+// StatsSettings stores the trafficStats endpoint in the CLI config so
+// list-users --stats works against the generated server right away.
+type StatsSettings interface {
+	SetTrafficStats(url, secret string) error
+}
@@ -0,0 +1,423 @@
+package generate_config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const (
+	TLSModeACME       = "acme"
+	TLSModeSelfSigned = "self-signed"
+
+	ObfsSalamander = "salamander"
+	ObfsNone       = "none"
+
+	MasqueradeProxy  = "proxy"
+	MasqueradeFile   = "file"
+	MasqueradeString = "string"
+)
+
+var ErrInvalidOptions = errors.New("invalid config options")
+
+// Options are the answers of the wizard. Empty bandwidth means unlimited and
+// an empty StatsListen disables the trafficStats API.
+type Options struct {
+	Port              int
+	TLSMode           string
+	ACMEDomains       []string
+	ACMEEmail         string
+	TLSCert           string
+	TLSKey            string
+	Obfs              string
+	ObfsPassword      string
+	MasqueradeType    string
+	MasqueradeURL     string
+	MasqueradeDir     string
+	MasqueradeContent string
+	BandwidthUp       string
+	BandwidthDown     string
+	StatsListen       string
+	StatsSecret       string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Port:              443,
+		TLSMode:           TLSModeACME,
+		TLSCert:           "/etc/hysteria/server.crt",
+		TLSKey:            "/etc/hysteria/server.key",
+		Obfs:              ObfsSalamander,
+		MasqueradeType:    MasqueradeProxy,
+		MasqueradeURL:     "https://news.ycombinator.com/",
+		MasqueradeContent: "ok",
+		StatsListen:       "127.0.0.1:9999",
+	}
+}
+
+// Field is one wizard question. The CLI prompts and the TUI form both walk
+// Fields, so they ask the same questions in the same order.
+type Field struct {
+	Key   string
+	Label string
+	// Applies reports whether the question is relevant for earlier answers.
+	Applies func(o Options) bool
+}
+
+func always(Options) bool { return true }
+
+var Fields = []Field{
+	{Key: "port", Label: "Listen port", Applies: always},
+	{Key: "tls", Label: "TLS mode (acme|self-signed)", Applies: always},
+	{Key: "acme-domains", Label: "ACME domains (comma-separated)", Applies: func(o Options) bool { return o.TLSMode == TLSModeACME }},
+	{Key: "acme-email", Label: "ACME email", Applies: func(o Options) bool { return o.TLSMode == TLSModeACME }},
+	{Key: "tls-cert", Label: "Certificate path on the server", Applies: func(o Options) bool { return o.TLSMode == TLSModeSelfSigned }},
+	{Key: "tls-key", Label: "Key path on the server", Applies: func(o Options) bool { return o.TLSMode == TLSModeSelfSigned }},
+	{Key: "obfs", Label: "Obfuscation (salamander|none)", Applies: always},
+	{Key: "masquerade", Label: "Masquerade type (proxy|file|string)", Applies: always},
+	{Key: "masquerade-url", Label: "Masquerade proxy URL", Applies: func(o Options) bool { return o.MasqueradeType == MasqueradeProxy }},
+	{Key: "masquerade-dir", Label: "Masquerade directory", Applies: func(o Options) bool { return o.MasqueradeType == MasqueradeFile }},
+	{Key: "masquerade-content", Label: "Masquerade response body", Applies: func(o Options) bool { return o.MasqueradeType == MasqueradeString }},
+	{Key: "bandwidth-up", Label: "Bandwidth up, e.g. 1 gbps (empty = unlimited)", Applies: always},
+	{Key: "bandwidth-down", Label: "Bandwidth down, e.g. 1 gbps (empty = unlimited)", Applies: always},
+	{Key: "stats-listen", Label: "trafficStats listen address (off = disabled)", Applies: always},
+}
+
+// Get returns the answer for key as the wizard shows it.
+func (o Options) Get(key string) string {
+	switch key {
+	case "port":
+		return strconv.Itoa(o.Port)
+	case "tls":
+		return o.TLSMode
+	case "acme-domains":
+		return strings.Join(o.ACMEDomains, ",")
+	case "acme-email":
+		return o.ACMEEmail
+	case "tls-cert":
+		return o.TLSCert
+	case "tls-key":
+		return o.TLSKey
+	case "obfs":
+		return o.Obfs
+	case "masquerade":
+		return o.MasqueradeType
+	case "masquerade-url":
+		return o.MasqueradeURL
+	case "masquerade-dir":
+		return o.MasqueradeDir
+	case "masquerade-content":
+		return o.MasqueradeContent
+	case "bandwidth-up":
+		return o.BandwidthUp
+	case "bandwidth-down":
+		return o.BandwidthDown
+	case "stats-listen":
+		return o.StatsListen
+	}
+	return ""
+}
+
+// Set stores one wizard answer and checks it on its own; combinations are
+// checked by Validate.
+func (o *Options) Set(key, value string) error {
+	value = strings.TrimSpace(value)
+	switch key {
+	case "port":
+		port, err := strconv.Atoi(value)
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("%w: port must be 1-65535", ErrInvalidOptions)
+		}
+		o.Port = port
+	case "tls":
+		if value != TLSModeACME && value != TLSModeSelfSigned {
+			return fmt.Errorf("%w: tls must be acme or self-signed", ErrInvalidOptions)
+		}
+		o.TLSMode = value
+	case "acme-domains":
+		var domains []string
+		for _, d := range strings.Split(value, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				domains = append(domains, d)
+			}
+		}
+		if len(domains) == 0 {
+			return fmt.Errorf("%w: acme needs at least one domain", ErrInvalidOptions)
+		}
+		o.ACMEDomains = domains
+	case "acme-email":
+		if !strings.Contains(value, "@") {
+			return fmt.Errorf("%w: acme needs a contact email", ErrInvalidOptions)
+		}
+		o.ACMEEmail = value
+	case "tls-cert":
+		o.TLSCert = value
+	case "tls-key":
+		o.TLSKey = value
+	case "obfs":
+		if value != ObfsSalamander && value != ObfsNone {
+			return fmt.Errorf("%w: obfs must be salamander or none", ErrInvalidOptions)
+		}
+		o.Obfs = value
+	case "masquerade":
+		if value != MasqueradeProxy && value != MasqueradeFile && value != MasqueradeString {
+			return fmt.Errorf("%w: masquerade must be proxy, file or string", ErrInvalidOptions)
+		}
+		o.MasqueradeType = value
+	case "masquerade-url":
+		o.MasqueradeURL = value
+	case "masquerade-dir":
+		o.MasqueradeDir = value
+	case "masquerade-content":
+		o.MasqueradeContent = value
+	case "bandwidth-up", "bandwidth-down":
+		if _, err := domain.ParseBandwidth(value); value != "" && err != nil {
+			return fmt.Errorf("%w: %s %q (expected e.g. 100 mbps or 1 gbps)", ErrInvalidOptions, key, value)
+		}
+		if key == "bandwidth-up" {
+			o.BandwidthUp = value
+		} else {
+			o.BandwidthDown = value
+		}
+	case "stats-listen":
+		if value == "off" {
+			value = ""
+		}
+		o.StatsListen = value
+	default:
+		return fmt.Errorf("%w: unknown option %q", ErrInvalidOptions, key)
+	}
+	return nil
+}
+
+func (o Options) Validate() error {
+	fail := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidOptions, fmt.Sprintf(format, args...))
+	}
+	if o.Port <= 0 || o.Port > 65535 {
+		return fail("port must be 1-65535")
+	}
+	switch o.TLSMode {
+	case TLSModeACME:
+		if len(o.ACMEDomains) == 0 {
+			return fail("acme needs at least one domain")
+		}
+		if !strings.Contains(o.ACMEEmail, "@") {
+			return fail("acme needs a contact email")
+		}
+	case TLSModeSelfSigned:
+		if o.TLSCert == "" || o.TLSKey == "" {
+			return fail("self-signed tls needs certificate and key paths")
+		}
+	default:
+		return fail("tls must be acme or self-signed")
+	}
+	if o.Obfs != ObfsSalamander && o.Obfs != ObfsNone {
+		return fail("obfs must be salamander or none")
+	}
+	switch o.MasqueradeType {
+	case MasqueradeProxy:
+		u, err := url.Parse(o.MasqueradeURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fail("masquerade proxy needs an http(s) URL")
+		}
+	case MasqueradeFile:
+		if o.MasqueradeDir == "" {
+			return fail("masquerade file needs a directory")
+		}
+	case MasqueradeString:
+		if o.MasqueradeContent == "" {
+			return fail("masquerade string needs content")
+		}
+	default:
+		return fail("masquerade must be proxy, file or string")
+	}
+	for _, bw := range []string{o.BandwidthUp, o.BandwidthDown} {
+		if _, err := domain.ParseBandwidth(bw); bw != "" && err != nil {
+			return fail("bandwidth %q (expected e.g. 100 mbps or 1 gbps)", bw)
+		}
+	}
+	if o.StatsListen != "" {
+		if _, port, err := net.SplitHostPort(o.StatsListen); err != nil || port == "" {
+			return fail("stats listen %q must be host:port", o.StatsListen)
+		}
+	}
+	return nil
+}
+
+type Result struct {
+	Config       []byte
+	ObfsPassword string
+	// StatsURL and StatsSecret are empty when trafficStats is disabled.
+	StatsURL    string
+	StatsSecret string
+}
+
+type UseCase struct {
+	passwords PasswordGenerator
+	settings  StatsSettings
+}
+
+func NewUseCase(passwords PasswordGenerator, settings StatsSettings) *UseCase {
+	return &UseCase{passwords: passwords, settings: settings}
+}
+
+// Execute renders a Hysteria server config from opts, generating the obfs
+// password and stats secret when they are not given. With saveStats the
+// trafficStats endpoint is written to the CLI config as well.
+func (u *UseCase) Execute(_ context.Context, opts Options, saveStats bool) (Result, error) {
+	if err := opts.Validate(); err != nil {
+		return Result{}, err
+	}
+	var err error
+	if opts.Obfs == ObfsSalamander && opts.ObfsPassword == "" {
+		if opts.ObfsPassword, err = u.passwords.Generate(); err != nil {
+			return Result{}, err
+		}
+	}
+	if opts.StatsListen != "" && opts.StatsSecret == "" {
+		if opts.StatsSecret, err = u.passwords.Generate(); err != nil {
+			return Result{}, err
+		}
+	}
+
+	raw, err := yaml.Marshal(render(opts))
+	if err != nil {
+		return Result{}, fmt.Errorf("render config: %w", err)
+	}
+	// Re-read the output to make sure it is what the repository expects.
+	var check map[string]any
+	if err := yaml.Unmarshal(raw, &check); err != nil {
+		return Result{}, fmt.Errorf("render config: %w", err)
+	}
+
+	result := Result{Config: raw}
+	if opts.Obfs == ObfsSalamander {
+		result.ObfsPassword = opts.ObfsPassword
+	}
+	if opts.StatsListen != "" {
+		result.StatsURL = statsURL(opts.StatsListen)
+		result.StatsSecret = opts.StatsSecret
+		if saveStats {
+			if err := u.settings.SetTrafficStats(result.StatsURL, result.StatsSecret); err != nil {
+				return result, fmt.Errorf("save traffic stats settings: %w", err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// statsURL is where the CLI reaches the API; wildcard listeners are reached
+// through loopback, which also works over an ssh tunnel.
+func statsURL(listen string) string {
+	host, port, _ := net.SplitHostPort(listen)
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+type serverConfig struct {
+	Listen       string        `yaml:"listen"`
+	ACME         *acmeConfig   `yaml:"acme,omitempty"`
+	TLS          *tlsConfig    `yaml:"tls,omitempty"`
+	Obfs         *obfsConfig   `yaml:"obfs,omitempty"`
+	Auth         authConfig    `yaml:"auth"`
+	Bandwidth    *bandwidth    `yaml:"bandwidth,omitempty"`
+	Masquerade   masquerade    `yaml:"masquerade"`
+	TrafficStats *trafficStats `yaml:"trafficStats,omitempty"`
+}
+
+type acmeConfig struct {
+	Domains []string `yaml:"domains"`
+	Email   string   `yaml:"email"`
+}
+
+type tlsConfig struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+type obfsConfig struct {
+	Type       string     `yaml:"type"`
+	Salamander salamander `yaml:"salamander"`
+}
+
+type salamander struct {
+	Password string `yaml:"password"`
+}
+
+type authConfig struct {
+	Type     string            `yaml:"type"`
+	Userpass map[string]string `yaml:"userpass"`
+}
+
+type bandwidth struct {
+	Up   string `yaml:"up,omitempty"`
+	Down string `yaml:"down,omitempty"`
+}
+
+type masquerade struct {
+	Type   string            `yaml:"type"`
+	Proxy  *proxyMasquerade  `yaml:"proxy,omitempty"`
+	File   *fileMasquerade   `yaml:"file,omitempty"`
+	String *stringMasquerade `yaml:"string,omitempty"`
+}
+
+type proxyMasquerade struct {
+	URL         string `yaml:"url"`
+	RewriteHost bool   `yaml:"rewriteHost"`
+}
+
+type fileMasquerade struct {
+	Dir string `yaml:"dir"`
+}
+
+type stringMasquerade struct {
+	Content    string `yaml:"content"`
+	StatusCode int    `yaml:"statusCode"`
+}
+
+type trafficStats struct {
+	Listen string `yaml:"listen"`
+	Secret string `yaml:"secret"`
+}
+
+func render(o Options) serverConfig {
+	cfg := serverConfig{
+		Listen: ":" + strconv.Itoa(o.Port),
+		Auth:   authConfig{Type: "userpass", Userpass: map[string]string{}},
+	}
+	if o.TLSMode == TLSModeACME {
+		cfg.ACME = &acmeConfig{Domains: o.ACMEDomains, Email: o.ACMEEmail}
+	} else {
+		cfg.TLS = &tlsConfig{Cert: o.TLSCert, Key: o.TLSKey}
+	}
+	if o.Obfs == ObfsSalamander {
+		cfg.Obfs = &obfsConfig{Type: ObfsSalamander, Salamander: salamander{Password: o.ObfsPassword}}
+	}
+	if o.BandwidthUp != "" || o.BandwidthDown != "" {
+		cfg.Bandwidth = &bandwidth{Up: o.BandwidthUp, Down: o.BandwidthDown}
+	}
+	cfg.Masquerade.Type = o.MasqueradeType
+	switch o.MasqueradeType {
+	case MasqueradeProxy:
+		cfg.Masquerade.Proxy = &proxyMasquerade{URL: o.MasqueradeURL, RewriteHost: true}
+	case MasqueradeFile:
+		cfg.Masquerade.File = &fileMasquerade{Dir: o.MasqueradeDir}
+	case MasqueradeString:
+		cfg.Masquerade.String = &stringMasquerade{Content: o.MasqueradeContent, StatusCode: 200}
+	}
+	if o.StatsListen != "" {
+		cfg.TrafficStats = &trafficStats{Listen: o.StatsListen, Secret: o.StatsSecret}
+	}
+	return cfg
+}
@@ -0,0 +1,124 @@
+package generate_config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type passwordsMock struct{ n int }
+
+func (m *passwordsMock) Generate() (string, error) {
+	m.n++
+	return "generated" + strings.Repeat("x", m.n), nil
+}
+
+type settingsMock struct {
+	url, secret string
+	calls       int
+}
+
+func (m *settingsMock) SetTrafficStats(url, secret string) error {
+	m.url, m.secret = url, secret
+	m.calls++
+	return nil
+}
+
+func TestExecuteRendersACMEConfigAndSavesStats(t *testing.T) {
+	settings := &settingsMock{}
+	uc := NewUseCase(&passwordsMock{}, settings)
+
+	opts := DefaultOptions()
+	opts.ACMEDomains = []string{"vpn.example.com"}
+	opts.ACMEEmail = "ops@example.com"
+	opts.BandwidthUp = "100 mbps"
+	opts.StatsListen = ":9999"
+
+	result, err := uc.Execute(context.Background(), opts, true)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(result.Config, &doc); err != nil {
+		t.Fatalf("parse output: %v", err)
+	}
+	if doc["listen"] != ":443" || doc["tls"] != nil {
+		t.Fatalf("unexpected listen/tls: %v", doc)
+	}
+	obfs := doc["obfs"].(map[string]any)["salamander"].(map[string]any)
+	if obfs["password"] != result.ObfsPassword || result.ObfsPassword == "" {
+		t.Fatalf("obfs password not generated: %v", obfs)
+	}
+	if doc["auth"].(map[string]any)["type"] != "userpass" {
+		t.Fatalf("unexpected auth: %v", doc["auth"])
+	}
+	if doc["bandwidth"].(map[string]any)["up"] != "100 mbps" {
+		t.Fatalf("unexpected bandwidth: %v", doc["bandwidth"])
+	}
+	if settings.calls != 1 || settings.url != "http://127.0.0.1:9999" || settings.secret != result.StatsSecret || settings.secret == "" {
+		t.Fatalf("stats not saved: %+v", settings)
+	}
+}
+
+func TestExecuteSelfSignedWithoutObfsAndStats(t *testing.T) {
+	settings := &settingsMock{}
+	opts := DefaultOptions()
+	for key, value := range map[string]string{
+		"port":               "8443",
+		"tls":                TLSModeSelfSigned,
+		"obfs":               ObfsNone,
+		"masquerade":         MasqueradeString,
+		"masquerade-content": "hello",
+		"stats-listen":       "off",
+	} {
+		if err := opts.Set(key, value); err != nil {
+			t.Fatalf("set %s: %v", key, err)
+		}
+	}
+
+	result, err := NewUseCase(&passwordsMock{}, settings).Execute(context.Background(), opts, true)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	out := string(result.Config)
+	for _, want := range []string{"listen: :8443", "cert: /etc/hysteria/server.crt", "content: hello"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("config misses %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"acme:", "obfs:", "trafficStats:", "proxy:"} {
+		if strings.Contains(out, unwanted) {
+			t.Fatalf("config has %q:\n%s", unwanted, out)
+		}
+	}
+	if settings.calls != 0 {
+		t.Fatal("stats saved although disabled")
+	}
+}
+
+func TestOptionsRejectInvalidAnswers(t *testing.T) {
+	opts := DefaultOptions()
+	for key, value := range map[string]string{
+		"port":         "70000",
+		"tls":          "letsencrypt",
+		"obfs":         "xor",
+		"masquerade":   "redirect",
+		"bandwidth-up": "fast",
+	} {
+		if err := opts.Set(key, value); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("set %s=%s: expected ErrInvalidOptions, got %v", key, value, err)
+		}
+	}
+
+	if err := opts.Validate(); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("acme without domains: expected ErrInvalidOptions, got %v", err)
+	}
+	opts.ACMEDomains, opts.ACMEEmail = []string{"vpn.example.com"}, "ops@example.com"
+	opts.MasqueradeURL = "ftp://example.com"
+	if err := opts.Validate(); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("ftp masquerade: expected ErrInvalidOptions, got %v", err)
+	}
+}
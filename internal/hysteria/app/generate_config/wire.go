@@ -0,0 +1,22 @@
+//go:build wireinject
+// +build wireinject
+
+package generate_config
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	utilpasswordgen "vpn/internal/utils/passwordgen"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		utilpasswordgen.DefaultPolicy,
+		utilpasswordgen.NewGenerator,
+		appconfig.NewTrafficStatsWriter,
+		wire.Bind(new(PasswordGenerator), new(*utilpasswordgen.Generator)),
+		wire.Bind(new(StatsSettings), new(*appconfig.TrafficStatsWriter)),
+		NewUseCase,
+	)
+	return nil, nil
+}
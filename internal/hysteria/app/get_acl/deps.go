@@ -0,0 +1,11 @@
+package get_acl
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type ACLRepository interface {
+	ACL(ctx context.Context) (domain.ACL, error)
+}
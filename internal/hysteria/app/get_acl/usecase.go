@@ -0,0 +1,19 @@
+package get_acl
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UseCase struct {
+	repo ACLRepository
+}
+
+func NewUseCase(repo ACLRepository) *UseCase {
+	return &UseCase{repo: repo}
+}
+
+func (u *UseCase) Execute(ctx context.Context) (domain.ACL, error) {
+	return u.repo.ACL(ctx)
+}
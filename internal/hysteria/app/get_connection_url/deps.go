@@ -7,5 +7,7 @@ import (
 )
 
 type ConnectionRepository interface {
+	AuthInfo(ctx context.Context) (domain.AuthInfo, error)
+	ListUsers(ctx context.Context) ([]string, error)
 	GetConnectionConfig(ctx context.Context, username string) (domain.ConnectionConfig, error)
 }
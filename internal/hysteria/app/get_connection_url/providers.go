@@ -1,5 +1,15 @@
 package get_connection_url
 
-import appconfig "vpn/internal/config"
+import (
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/domain"
+)
 
-func provideConfigPath(cfg appconfig.Config) string { return cfg.HysteriaConfigPath }
+func provideConfigPath(cfg appconfig.Config) string    { return cfg.HysteriaConfigPath }
+func provideUserBackend(cfg appconfig.Config) string   { return cfg.UserBackend }
+func provideUserStorePath(cfg appconfig.Config) string { return cfg.UserStorePath }
+func provideUserDBPath(cfg appconfig.Config) string    { return cfg.UserDBPath }
+
+func provideUsernamePolicy(cfg appconfig.Config) domain.UsernamePolicy {
+	return cfg.UsernamePolicy.DomainPolicy()
+}
@@ -2,46 +2,37 @@ package get_connection_url
 
 import (
 	"context"
-	"fmt"
-	"net"
-	"net/url"
-	"strconv"
+
+	"vpn/internal/hysteria/domain"
 )
 
 type UseCase struct {
-	repo ConnectionRepository
+	repo      ConnectionRepository
+	usernames domain.UsernamePolicy
 }
 
-func NewUseCase(repo ConnectionRepository) *UseCase {
-	return &UseCase{repo: repo}
+func NewUseCase(repo ConnectionRepository, usernames domain.UsernamePolicy) *UseCase {
+	return &UseCase{repo: repo, usernames: usernames}
 }
 
+// Execute renders the client URL of the user, matched against the stored
+// names with the username policy; the URL carries the stored name.
 func (u *UseCase) Execute(ctx context.Context, username string) (string, error) {
-	cfg, err := u.repo.GetConnectionConfig(ctx, username)
+	info, err := u.repo.AuthInfo(ctx)
 	if err != nil {
 		return "", err
 	}
-
-	query := make(url.Values)
-	if cfg.SNI != "" {
-		query.Set("sni", cfg.SNI)
-	}
-	if cfg.ObfsType != "" {
-		query.Set("obfs", cfg.ObfsType)
-	}
-	if cfg.ObfsPassword != "" {
-		query.Set("obfs-password", cfg.ObfsPassword)
+	if info.ManagesUsers() {
+		existing, err := u.repo.ListUsers(ctx)
+		if err != nil {
+			return "", err
+		}
+		username = u.usernames.Lookup(username, existing)
 	}
 
-	uURL := &url.URL{
-		Scheme:   "hy2",
-		User:     url.UserPassword(cfg.Username, cfg.Password),
-		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
-		Path:     "/",
-		RawQuery: query.Encode(),
-	}
-	if uURL.Host == "" {
-		return "", fmt.Errorf("invalid connection host")
+	cfg, err := u.repo.GetConnectionConfig(ctx, username)
+	if err != nil {
+		return "", err
 	}
-	return uURL.String(), nil
+	return cfg.URL()
 }
@@ -6,14 +6,18 @@ package get_connection_url
 import (
 	"github.com/google/wire"
 	appconfig "vpn/internal/config"
-	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/userstore"
 )
 
 func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
 	wire.Build(
 		provideConfigPath,
-		configrepo.NewRepository,
-		wire.Bind(new(ConnectionRepository), new(*configrepo.Repository)),
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		provideUsernamePolicy,
+		userstore.NewRepository,
+		wire.Bind(new(ConnectionRepository), new(*userstore.Repository)),
 		NewUseCase,
 	)
 	return nil, nil
@@ -8,12 +8,16 @@ package get_connection_url
 
 import (
 	appconfig "vpn/internal/config"
-	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/userstore"
 )
 
 func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
-	string2 := provideConfigPath(cfg)
-	repository := configrepo.NewRepository(string2)
-	useCase := NewUseCase(repository)
+	string2 := provideUserBackend(cfg)
+	string3 := provideConfigPath(cfg)
+	string4 := provideUserStorePath(cfg)
+	string5 := provideUserDBPath(cfg)
+	repository := userstore.NewRepository(string2, string3, string4, string5)
+	usernamePolicy := provideUsernamePolicy(cfg)
+	useCase := NewUseCase(repository, usernamePolicy)
 	return useCase, nil
 }
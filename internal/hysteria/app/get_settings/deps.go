@@ -0,0 +1,11 @@
+package get_settings
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type SettingsRepository interface {
+	Settings(ctx context.Context) ([]domain.SettingValue, error)
+}
@@ -0,0 +1,37 @@
+package get_settings
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UseCase struct {
+	repo SettingsRepository
+}
+
+func NewUseCase(repo SettingsRepository) *UseCase {
+	return &UseCase{repo: repo}
+}
+
+// Execute returns all settings, or only keys when given, in the order asked.
+func (u *UseCase) Execute(ctx context.Context, keys ...string) ([]domain.SettingValue, error) {
+	for _, key := range keys {
+		if _, err := domain.LookupSetting(key); err != nil {
+			return nil, err
+		}
+	}
+	values, err := u.repo.Settings(ctx)
+	if err != nil || len(keys) == 0 {
+		return values, err
+	}
+	byKey := make(map[string]domain.SettingValue, len(values))
+	for _, v := range values {
+		byKey[v.Def.Key] = v
+	}
+	selected := make([]domain.SettingValue, 0, len(keys))
+	for _, key := range keys {
+		selected = append(selected, byKey[key])
+	}
+	return selected, nil
+}
@@ -0,0 +1,41 @@
+package get_settings
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct{}
+
+func (repoMock) Settings(context.Context) ([]domain.SettingValue, error) {
+	values := make([]domain.SettingValue, 0, len(domain.SettingDefs))
+	for _, def := range domain.SettingDefs {
+		values = append(values, domain.SettingValue{Def: def})
+	}
+	values[0].Value, values[0].Set = ":443", true
+	return values, nil
+}
+
+func TestExecute(t *testing.T) {
+	uc := NewUseCase(repoMock{})
+
+	all, err := uc.Execute(context.Background())
+	if err != nil || len(all) != len(domain.SettingDefs) {
+		t.Fatalf("unexpected result: %d settings, %v", len(all), err)
+	}
+
+	selected, err := uc.Execute(context.Background(), "udpIdleTimeout", "listen")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(selected) != 2 || selected[0].Def.Key != "udpIdleTimeout" || selected[1].Value != ":443" {
+		t.Fatalf("unexpected selection: %+v", selected)
+	}
+
+	if _, err := uc.Execute(context.Background(), "obfs"); !errors.Is(err, domain.ErrUnknownSetting) {
+		t.Fatalf("expected ErrUnknownSetting, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package get_tls_info
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type TLSRepository interface {
+	TLSStatus(ctx context.Context, acmeDir string) (domain.TLSStatus, error)
+}
@@ -0,0 +1,5 @@
+package get_tls_info
+
+import appconfig "vpn/internal/config"
+
+func provideConfigPath(cfg appconfig.Config) string { return cfg.HysteriaConfigPath }
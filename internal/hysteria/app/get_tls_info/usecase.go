@@ -0,0 +1,37 @@
+package get_tls_info
+
+import (
+	"context"
+	"sort"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UseCase struct {
+	repo TLSRepository
+}
+
+func NewUseCase(repo TLSRepository) *UseCase {
+	return &UseCase{repo: repo}
+}
+
+// Execute returns the server certificates, the ones expiring first on top.
+// Certificates that could not be read keep their place at the end.
+func (u *UseCase) Execute(ctx context.Context, acmeDir string) (domain.TLSStatus, error) {
+	status, err := u.repo.TLSStatus(ctx, acmeDir)
+	if err != nil {
+		return domain.TLSStatus{}, err
+	}
+	sort.SliceStable(status.Certificates, func(i, j int) bool {
+		a, b := status.Certificates[i].Cert, status.Certificates[j].Cert
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		default:
+			return a.NotAfter.Before(b.NotAfter)
+		}
+	})
+	return status, nil
+}
@@ -0,0 +1,40 @@
+package get_tls_info
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct{ status domain.TLSStatus }
+
+func (m repoMock) TLSStatus(context.Context, string) (domain.TLSStatus, error) {
+	return m.status, nil
+}
+
+func TestExecuteSortsByExpiry(t *testing.T) {
+	now := time.Now()
+	uc := NewUseCase(repoMock{status: domain.TLSStatus{
+		Mode: domain.TLSModeACME,
+		Certificates: []domain.CertificateStatus{
+			{Name: "missing.example.com", Err: errors.New("not found")},
+			{Name: "late.example.com", Cert: &domain.Certificate{NotAfter: now.Add(60 * 24 * time.Hour)}},
+			{Name: "soon.example.com", Cert: &domain.Certificate{NotAfter: now.Add(5 * 24 * time.Hour)}},
+		},
+	}})
+
+	status, err := uc.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, c := range status.Certificates {
+		names = append(names, c.Name)
+	}
+	if len(names) != 3 || names[0] != "soon.example.com" || names[2] != "missing.example.com" {
+		t.Fatalf("unexpected order: %v", names)
+	}
+}
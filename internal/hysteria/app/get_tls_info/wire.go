@@ -0,0 +1,20 @@
+//go:build wireinject
+// +build wireinject
+
+package get_tls_info
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/configrepo"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		configrepo.NewRepository,
+		wire.Bind(new(TLSRepository), new(*configrepo.Repository)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -24,3 +24,7 @@ func provideTrafficStatsTimeout(cfg appconfig.Config) time.Duration {
 	}
 	return time.Duration(cfg.HysteriaTrafficStatsTimeoutSeconds) * time.Second
 }
+
+func provideRemoteHost(cfg appconfig.Config) string {
+	return cfg.SSH
+}
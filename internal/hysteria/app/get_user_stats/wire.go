@@ -15,6 +15,7 @@ func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
 		provideTrafficStatsURL,
 		provideTrafficStatsSecret,
 		provideTrafficStatsTimeout,
+		provideRemoteHost,
 		trafficstats.NewClient,
 		wire.Bind(new(TrafficStatsRepository), new(*trafficstats.Client)),
 		NewUseCase,
@@ -16,7 +16,8 @@ func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
 	string2 := provideTrafficStatsURL(cfg)
 	string3 := provideTrafficStatsSecret(cfg)
 	duration := provideTrafficStatsTimeout(cfg)
-	client := trafficstats.NewClient(bool2, string2, string3, duration)
+	string4 := provideRemoteHost(cfg)
+	client := trafficstats.NewClient(bool2, string2, string3, duration, string4)
 	useCase := NewUseCase(client)
 	return useCase, nil
 }
@@ -0,0 +1,26 @@
+package import_users
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UserRepository interface {
+	Users(ctx context.Context) ([]domain.User, error)
+	ApplyChanges(ctx context.Context, changes []domain.UserChange) error
+	Checksum(ctx context.Context) (string, error)
+}
+
+type ServiceRestarter interface {
+	Restart(ctx context.Context) error
+}
+
+type PasswordGenerator interface {
+	Generate() (string, error)
+	Check(password string) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
@@ -0,0 +1,178 @@
+package import_users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const (
+	StatusOK      = "ok"
+	StatusPlanned = "planned"
+	StatusInvalid = "invalid"
+)
+
+type Row struct {
+	Line     int
+	Action   string
+	Username string
+	// Password is optional for add and rotate; empty means generate one.
+	Password string
+}
+
+type RowResult struct {
+	Row    Row
+	Status string
+	Error  string
+	// Password is set only for generated passwords; supplied ones are not
+	// echoed back.
+	Password string
+	Supplied bool
+}
+
+type Report struct {
+	Results []RowResult
+	DryRun  bool
+	Applied bool
+}
+
+type UseCase struct {
+	repo      UserRepository
+	restarter ServiceRestarter
+	passwords PasswordGenerator
+	usernames domain.UsernamePolicy
+	audit     AuditLog
+}
+
+func NewUseCase(repo UserRepository, restarter ServiceRestarter, passwords PasswordGenerator, usernames domain.UsernamePolicy, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, passwords: passwords, usernames: usernames, audit: audit}
+}
+
+func (u *UseCase) Execute(ctx context.Context, rows []Row, dryRun bool) (Report, error) {
+	report := Report{Results: make([]RowResult, len(rows)), DryRun: dryRun}
+
+	existing, err := u.repo.Users(ctx)
+	if err != nil {
+		return report, err
+	}
+	names := make([]string, 0, len(existing))
+	for _, user := range existing {
+		names = append(names, user.Username)
+	}
+
+	// Names of added users are normalized in a copy of the rows.
+	rows = append([]Row(nil), rows...)
+	seen := map[string]int{}
+	invalid := false
+	for i := range rows {
+		err := u.validateRow(&rows[i], names, seen)
+		report.Results[i] = RowResult{Row: rows[i], Status: StatusPlanned}
+		if err != nil {
+			report.Results[i].Status = StatusInvalid
+			report.Results[i].Error = err.Error()
+			invalid = true
+		}
+		seen[u.usernames.Key(rows[i].Username)] = rows[i].Line
+	}
+	if invalid {
+		return report, domain.ErrInvalidBatch
+	}
+	if dryRun || len(rows) == 0 {
+		return report, nil
+	}
+
+	changes := make([]domain.UserChange, len(rows))
+	for i, row := range rows {
+		change := domain.UserChange{Action: row.Action, User: domain.User{Username: row.Username}}
+		if row.Action != domain.UserChangeRemove {
+			password := row.Password
+			if password == "" {
+				if password, err = u.passwords.Generate(); err != nil {
+					return report, err
+				}
+			}
+			change.User, err = domain.NewUser(row.Username, password)
+			if err != nil {
+				return report, err
+			}
+		}
+		changes[i] = change
+	}
+
+	// A failed checksum is recorded for every change like a failed write.
+	hashBefore, applyErr := u.repo.Checksum(ctx)
+	if applyErr == nil {
+		applyErr = u.repo.ApplyChanges(ctx, changes)
+	}
+	if applyErr == nil {
+		applyErr = u.restarter.Restart(ctx)
+	}
+	hashAfter, _ := u.repo.Checksum(ctx)
+
+	var auditErr error
+	for _, change := range changes {
+		entry := domain.NewAuditEntry(ctx, change.AuditAction(), change.User.Username, hashBefore, hashAfter, applyErr)
+		auditErr = errors.Join(auditErr, u.audit.Record(ctx, entry))
+	}
+	if applyErr != nil {
+		return report, applyErr
+	}
+
+	report.Applied = true
+	for i := range report.Results {
+		report.Results[i].Status = StatusOK
+		switch {
+		case changes[i].Action == domain.UserChangeRemove:
+		case rows[i].Password != "":
+			report.Results[i].Supplied = true
+		default:
+			report.Results[i].Password = changes[i].User.Password
+		}
+	}
+	if auditErr != nil {
+		return report, fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+	}
+	return report, nil
+}
+
+func (u *UseCase) validateRow(row *Row, existing []string, seen map[string]int) error {
+	if row.Username == "" {
+		return domain.ErrEmptyUsername
+	}
+	// New names are normalized; rotate and remove rows resolve to the
+	// stored name the policy key matches.
+	if row.Action == domain.UserChangeAdd {
+		name, err := u.usernames.Normalize(row.Username)
+		if err != nil {
+			return err
+		}
+		row.Username = name
+	} else {
+		row.Username = u.usernames.Lookup(row.Username, existing)
+	}
+	if line, ok := seen[u.usernames.Key(row.Username)]; ok {
+		return fmt.Errorf("username %q already used on line %d", row.Username, line)
+	}
+	switch row.Action {
+	case domain.UserChangeAdd:
+		if err := u.usernames.ConflictError(row.Username, existing); err != nil {
+			return err
+		}
+	case domain.UserChangeRotate, domain.UserChangeRemove:
+		if !slices.Contains(existing, row.Username) {
+			return domain.ErrUserNotFound
+		}
+	default:
+		return fmt.Errorf("%w: %q (allowed: add|rotate|remove)", domain.ErrInvalidChangeAction, row.Action)
+	}
+	if row.Password == "" {
+		return nil
+	}
+	if row.Action == domain.UserChangeRemove {
+		return errors.New("remove rows must not carry a password")
+	}
+	return u.passwords.Check(row.Password)
+}
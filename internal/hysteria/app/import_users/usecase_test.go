@@ -0,0 +1,201 @@
+package import_users
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct {
+	users        []domain.User
+	applyCalls   int
+	appliedBatch []domain.UserChange
+}
+
+func (m *repoMock) Users(context.Context) ([]domain.User, error) {
+	return m.users, nil
+}
+
+func (m *repoMock) ApplyChanges(_ context.Context, changes []domain.UserChange) error {
+	m.applyCalls++
+	m.appliedBatch = changes
+	return nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct{ calls int }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.calls++
+	return nil
+}
+
+type passwordGeneratorMock struct{}
+
+func (passwordGeneratorMock) Generate() (string, error) {
+	return "Abc123Abc123Abc123Abc123Abc123Ab", nil
+}
+
+var errWeakPassword = errors.New("weak password")
+
+func (passwordGeneratorMock) Check(password string) error {
+	if password == "weak" {
+		return errWeakPassword
+	}
+	return nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestExecuteAppliesBatchWithSingleRestart(t *testing.T) {
+	repo := &repoMock{users: []domain.User{{Username: "alice", Password: "1"}, {Username: "bob", Password: "2"}}}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, domain.UsernamePolicy{}, audit)
+
+	report, err := uc.Execute(context.Background(), []Row{
+		{Line: 1, Action: domain.UserChangeAdd, Username: "carol"},
+		{Line: 2, Action: domain.UserChangeRotate, Username: "alice"},
+		{Line: 3, Action: domain.UserChangeRemove, Username: "bob"},
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !report.Applied || repo.applyCalls != 1 || restarter.calls != 1 {
+		t.Fatalf("expected one write and one restart: report=%+v apply=%d restart=%d", report, repo.applyCalls, restarter.calls)
+	}
+	if len(repo.appliedBatch) != 3 || repo.appliedBatch[0].User.Password == "" {
+		t.Fatalf("unexpected batch: %+v", repo.appliedBatch)
+	}
+	if report.Results[0].Password == "" || report.Results[2].Password != "" {
+		t.Fatalf("unexpected passwords in report: %+v", report.Results)
+	}
+	if len(audit.entries) != 3 || audit.entries[2].Action != domain.AuditActionRemoveUser {
+		t.Fatalf("unexpected audit entries: %+v", audit.entries)
+	}
+}
+
+func TestExecuteRejectsWholeBatchOnInvalidRow(t *testing.T) {
+	repo := &repoMock{users: []domain.User{{Username: "alice", Password: "1"}}}
+	restarter := &restarterMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	report, err := uc.Execute(context.Background(), []Row{
+		{Line: 1, Action: domain.UserChangeAdd, Username: "carol"},
+		{Line: 2, Action: domain.UserChangeAdd, Username: "alice"},
+		{Line: 3, Action: domain.UserChangeRemove, Username: "ghost"},
+		{Line: 4, Action: "rename", Username: "dave"},
+		{Line: 5, Action: domain.UserChangeAdd, Username: "carol"},
+	}, false)
+	if !errors.Is(err, domain.ErrInvalidBatch) {
+		t.Fatalf("expected ErrInvalidBatch, got %v", err)
+	}
+	if repo.applyCalls != 0 || restarter.calls != 0 {
+		t.Fatal("batch must not be applied")
+	}
+	wantStatus := []string{StatusPlanned, StatusInvalid, StatusInvalid, StatusInvalid, StatusInvalid}
+	for i, want := range wantStatus {
+		if report.Results[i].Status != want {
+			t.Fatalf("row %d: expected %s, got %+v", i+1, want, report.Results[i])
+		}
+	}
+}
+
+func TestExecuteWithSuppliedPasswords(t *testing.T) {
+	repo := &repoMock{users: []domain.User{{Username: "alice", Password: "1"}, {Username: "bob", Password: "2"}}}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	report, err := uc.Execute(context.Background(), []Row{
+		{Line: 1, Action: domain.UserChangeAdd, Username: "carol", Password: "carol-old-secret"},
+		{Line: 2, Action: domain.UserChangeRotate, Username: "alice"},
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.appliedBatch[0].User.Password != "carol-old-secret" {
+		t.Fatalf("supplied password must be stored: %+v", repo.appliedBatch[0])
+	}
+	if got := report.Results[0]; !got.Supplied || got.Password != "" {
+		t.Fatalf("supplied password must not be echoed: %+v", got)
+	}
+	if got := report.Results[1]; got.Supplied || got.Password == "" {
+		t.Fatalf("generated password must be reported: %+v", got)
+	}
+
+	repo = &repoMock{users: []domain.User{{Username: "alice", Password: "1"}}}
+	report, err = NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{}).Execute(context.Background(), []Row{
+		{Line: 1, Action: domain.UserChangeAdd, Username: "carol", Password: "weak"},
+		{Line: 2, Action: domain.UserChangeRemove, Username: "alice", Password: "alice-secret"},
+	}, false)
+	if !errors.Is(err, domain.ErrInvalidBatch) || repo.applyCalls != 0 {
+		t.Fatalf("expected rejected batch, got %v (apply=%d)", err, repo.applyCalls)
+	}
+	for i, got := range report.Results {
+		if got.Status != StatusInvalid {
+			t.Fatalf("row %d: expected invalid, got %+v", i+1, got)
+		}
+	}
+}
+
+func TestExecuteNormalizesAddedUsernames(t *testing.T) {
+	repo := &repoMock{users: []domain.User{{Username: "Alice", Password: "1"}}}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	report, err := uc.Execute(context.Background(), []Row{
+		{Line: 1, Action: domain.UserChangeAdd, Username: "Carol"},
+		{Line: 2, Action: domain.UserChangeAdd, Username: "carol"},
+		{Line: 3, Action: domain.UserChangeAdd, Username: "alice"},
+		{Line: 4, Action: domain.UserChangeAdd, Username: "a:b"},
+	}, true)
+	if !errors.Is(err, domain.ErrInvalidBatch) {
+		t.Fatalf("expected ErrInvalidBatch, got %v", err)
+	}
+	if got := report.Results[0]; got.Status != StatusPlanned || got.Row.Username != "carol" {
+		t.Fatalf("expected folded name on row 1: %+v", got)
+	}
+	wantStatus := []string{StatusPlanned, StatusInvalid, StatusInvalid, StatusInvalid}
+	for i, want := range wantStatus {
+		if report.Results[i].Status != want {
+			t.Fatalf("row %d: expected %s, got %+v", i+1, want, report.Results[i])
+		}
+	}
+}
+
+func TestExecuteMatchesExistingByPolicyKey(t *testing.T) {
+	repo := &repoMock{users: []domain.User{{Username: "Alice", Password: "1"}, {Username: "bob", Password: "2"}}}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	_, err := uc.Execute(context.Background(), []Row{
+		{Line: 1, Action: domain.UserChangeRotate, Username: "alice"},
+		{Line: 2, Action: domain.UserChangeRemove, Username: " BOB "},
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.appliedBatch) != 2 || repo.appliedBatch[0].User.Username != "Alice" || repo.appliedBatch[1].User.Username != "bob" {
+		t.Fatalf("expected the stored names: %+v", repo.appliedBatch)
+	}
+}
+
+func TestExecuteDryRun(t *testing.T) {
+	repo := &repoMock{}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	report, err := uc.Execute(context.Background(), []Row{{Line: 1, Action: domain.UserChangeAdd, Username: "carol"}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Applied || repo.applyCalls != 0 || report.Results[0].Status != StatusPlanned {
+		t.Fatalf("dry run must not apply: %+v", report)
+	}
+}
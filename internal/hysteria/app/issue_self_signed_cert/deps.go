@@ -0,0 +1,26 @@
+package issue_self_signed_cert
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type CertificateRepository interface {
+	InstallCertificate(ctx context.Context, certPath, keyPath string, certPEM, keyPEM []byte) (string, string, error)
+	Checksum(ctx context.Context) (string, error)
+}
+
+type ServiceRestarter interface {
+	Restart(ctx context.Context) error
+}
+
+type CertificateGenerator interface {
+	SelfSigned(commonName string, dnsNames []string, ips []net.IP, validity time.Duration) ([]byte, []byte, error)
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
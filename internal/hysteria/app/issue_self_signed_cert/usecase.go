@@ -0,0 +1,138 @@
+package issue_self_signed_cert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const DefaultDays = 3650
+
+var ErrInvalidRequest = errors.New("invalid certificate request")
+
+type Request struct {
+	CommonName string
+	IPs        []string
+	DNSNames   []string
+	Days       int
+	// CertPath and KeyPath override where the files are written on the
+	// server; empty keeps the current tls paths or uses the config directory.
+	CertPath string
+	KeyPath  string
+}
+
+type Result struct {
+	CertPath  string
+	KeyPath   string
+	PinSHA256 string
+	NotAfter  time.Time
+}
+
+type UseCase struct {
+	repo      CertificateRepository
+	restarter ServiceRestarter
+	certs     CertificateGenerator
+	audit     AuditLog
+}
+
+func NewUseCase(repo CertificateRepository, restarter ServiceRestarter, certs CertificateGenerator, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, certs: certs, audit: audit}
+}
+
+// Execute issues a self-signed certificate, switches the server from acme to
+// tls with it and restarts the service. The CN is added as a SAN as well:
+// an IP CN becomes an IP SAN, anything else a DNS name.
+func (u *UseCase) Execute(ctx context.Context, req Request) (result Result, err error) {
+	cn := strings.TrimSpace(req.CommonName)
+	if cn == "" {
+		return Result{}, fmt.Errorf("%w: common name is required", ErrInvalidRequest)
+	}
+	if req.Days == 0 {
+		req.Days = DefaultDays
+	}
+	if req.Days < 0 {
+		return Result{}, fmt.Errorf("%w: days must be positive", ErrInvalidRequest)
+	}
+
+	var ips []net.IP
+	dnsNames := []string{}
+	addName := func(name string) error {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil
+		}
+		if ip := net.ParseIP(name); ip != nil {
+			for _, existing := range ips {
+				if existing.Equal(ip) {
+					return nil
+				}
+			}
+			ips = append(ips, ip)
+			return nil
+		}
+		if strings.ContainsAny(name, " /:") {
+			return fmt.Errorf("%w: %q is neither an IP nor a DNS name", ErrInvalidRequest, name)
+		}
+		for _, existing := range dnsNames {
+			if existing == name {
+				return nil
+			}
+		}
+		dnsNames = append(dnsNames, name)
+		return nil
+	}
+	for _, ip := range req.IPs {
+		if net.ParseIP(strings.TrimSpace(ip)) == nil {
+			return Result{}, fmt.Errorf("%w: invalid IP %q", ErrInvalidRequest, ip)
+		}
+		if err := addName(ip); err != nil {
+			return Result{}, err
+		}
+	}
+	for _, name := range append([]string{cn}, req.DNSNames...) {
+		if err := addName(name); err != nil {
+			return Result{}, err
+		}
+	}
+
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.repo.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionTLSSelfSigned, "", hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.repo.Checksum(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	certPEM, keyPEM, err := u.certs.SelfSigned(cn, dnsNames, ips, time.Duration(req.Days)*24*time.Hour)
+	if err != nil {
+		return Result{}, err
+	}
+	cert, err := domain.ParseCertificatePEM(certPEM)
+	if err != nil {
+		return Result{}, err
+	}
+	certPath, keyPath, err := u.repo.InstallCertificate(ctx, req.CertPath, req.KeyPath, certPEM, keyPEM)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := u.restarter.Restart(ctx); err != nil {
+		return Result{}, err
+	}
+	return Result{
+		CertPath:  certPath,
+		KeyPath:   keyPath,
+		PinSHA256: cert.PinSHA256,
+		NotAfter:  cert.NotAfter,
+	}, nil
+}
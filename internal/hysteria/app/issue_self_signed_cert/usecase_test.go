@@ -0,0 +1,94 @@
+package issue_self_signed_cert
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/utils/certgen"
+)
+
+type repoMock struct {
+	certPEM []byte
+	keyPEM  []byte
+	err     error
+}
+
+func (m *repoMock) InstallCertificate(_ context.Context, certPath, keyPath string, certPEM, keyPEM []byte) (string, string, error) {
+	if m.err != nil {
+		return "", "", m.err
+	}
+	m.certPEM, m.keyPEM = certPEM, keyPEM
+	if certPath == "" {
+		certPath = "/etc/hysteria/server.crt"
+	}
+	if keyPath == "" {
+		keyPath = "/etc/hysteria/server.key"
+	}
+	return certPath, keyPath, nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct{ called bool }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.called = true
+	return nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestExecute(t *testing.T) {
+	repo := &repoMock{}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, certgen.NewGenerator(), audit)
+
+	result, err := uc.Execute(context.Background(), Request{CommonName: "1.2.3.4", IPs: []string{"1.2.3.4", "5.6.7.8"}, DNSNames: []string{"test.local"}, Days: 30})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !restarter.called || len(repo.keyPEM) == 0 {
+		t.Fatal("expected certificate to be installed and service restarted")
+	}
+	cert, err := domain.ParseCertificatePEM(repo.certPEM)
+	if err != nil {
+		t.Fatalf("parse installed certificate: %v", err)
+	}
+	if len(cert.IPs) != 2 || cert.IPs[0] != "1.2.3.4" || len(cert.DNSNames) != 1 || !cert.SelfSigned {
+		t.Fatalf("unexpected certificate: %+v", cert)
+	}
+	if result.PinSHA256 != cert.PinSHA256 || result.CertPath != "/etc/hysteria/server.crt" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if left := time.Until(result.NotAfter); left < 29*24*time.Hour || left > 31*24*time.Hour {
+		t.Fatalf("unexpected expiry %s", result.NotAfter)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditActionTLSSelfSigned {
+		t.Fatalf("unexpected audit entries: %+v", audit.entries)
+	}
+}
+
+func TestExecuteRejectsInvalidIP(t *testing.T) {
+	repo := &repoMock{}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, certgen.NewGenerator(), audit)
+
+	if _, err := uc.Execute(context.Background(), Request{CommonName: "vpn", IPs: []string{"1.2.3"}}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest, got %v", err)
+	}
+	if restarter.called || repo.certPEM != nil || len(audit.entries) != 0 {
+		t.Fatal("invalid request must not touch the server")
+	}
+}
@@ -0,0 +1,34 @@
+//go:build wireinject
+// +build wireinject
+
+package issue_self_signed_cert
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/utils/certgen"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideService,
+		provideRestartEnabled,
+		provideRestartCommand,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		configrepo.NewRepository,
+		servicectl.NewRestarter,
+		auditlog.NewLog,
+		certgen.NewGenerator,
+		wire.Bind(new(CertificateRepository), new(*configrepo.Repository)),
+		wire.Bind(new(ServiceRestarter), new(*servicectl.Restarter)),
+		wire.Bind(new(CertificateGenerator), new(*certgen.Generator)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
+		NewUseCase,
+	)
+	return nil, nil
+}
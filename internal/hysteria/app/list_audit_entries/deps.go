@@ -0,0 +1,11 @@
+package list_audit_entries
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type AuditLogReader interface {
+	Entries(ctx context.Context) ([]domain.AuditEntry, error)
+}
@@ -0,0 +1,6 @@
+package list_audit_entries
+
+import appconfig "vpn/internal/config"
+
+func provideAuditEnabled(cfg appconfig.Config) bool   { return cfg.AuditEnabled }
+func provideAuditLogPath(cfg appconfig.Config) string { return cfg.AuditLogPath }
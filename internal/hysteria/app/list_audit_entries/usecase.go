@@ -0,0 +1,29 @@
+package list_audit_entries
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UseCase struct {
+	repo AuditLogReader
+}
+
+func NewUseCase(repo AuditLogReader) *UseCase {
+	return &UseCase{repo: repo}
+}
+
+func (u *UseCase) Execute(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	entries, err := u.repo.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.AuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		if filter.Matches(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
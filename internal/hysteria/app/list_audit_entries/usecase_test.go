@@ -0,0 +1,42 @@
+package list_audit_entries
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct{ entries []domain.AuditEntry }
+
+func (m repoMock) Entries(context.Context) ([]domain.AuditEntry, error) {
+	return m.entries, nil
+}
+
+func TestExecuteFilters(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	uc := NewUseCase(repoMock{entries: []domain.AuditEntry{
+		{Time: base, Actor: "os:root", Action: domain.AuditActionAddUser, Username: "alice", Result: domain.AuditResultOK},
+		{Time: base.Add(time.Hour), Actor: "tui:bob/42", Action: domain.AuditActionRemoveUser, Username: "alice", Result: domain.AuditResultError},
+		{Time: base.Add(2 * time.Hour), Actor: "os:root", Action: domain.AuditActionAddUser, Username: "carol", Result: domain.AuditResultOK},
+	}})
+
+	got, err := uc.Execute(context.Background(), domain.AuditFilter{Username: "alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("unexpected entries by username: %+v", got)
+	}
+
+	got, _ = uc.Execute(context.Background(), domain.AuditFilter{Actor: "tui"})
+	if len(got) != 1 || got[0].Action != domain.AuditActionRemoveUser {
+		t.Fatalf("unexpected entries by actor kind: %+v", got)
+	}
+
+	got, _ = uc.Execute(context.Background(), domain.AuditFilter{Action: domain.AuditActionAddUser, Since: base.Add(time.Minute)})
+	if len(got) != 1 || got[0].Username != "carol" {
+		t.Fatalf("unexpected entries by action and since: %+v", got)
+	}
+}
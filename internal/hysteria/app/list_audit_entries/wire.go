@@ -0,0 +1,21 @@
+//go:build wireinject
+// +build wireinject
+
+package list_audit_entries
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideAuditEnabled,
+		provideAuditLogPath,
+		auditlog.NewLog,
+		wire.Bind(new(AuditLogReader), new(*auditlog.Log)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -0,0 +1,13 @@
+package list_connection_urls
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type ConnectionRepository interface {
+	AuthInfo(ctx context.Context) (domain.AuthInfo, error)
+	ListUsers(ctx context.Context) ([]string, error)
+	GetConnectionConfig(ctx context.Context, username string) (domain.ConnectionConfig, error)
+}
@@ -0,0 +1,49 @@
+package list_connection_urls
+
+import (
+	"context"
+	"fmt"
+)
+
+type ClientURL struct {
+	// Username is empty for the shared password of auth.type: password.
+	Username string
+	URL      string
+}
+
+type UseCase struct {
+	repo ConnectionRepository
+}
+
+func NewUseCase(repo ConnectionRepository) *UseCase {
+	return &UseCase{repo: repo}
+}
+
+// Execute renders the client URL of every user, for example to hand out new
+// URLs after the obfs password or the port changed.
+func (u *UseCase) Execute(ctx context.Context) ([]ClientURL, error) {
+	info, err := u.repo.AuthInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usernames := []string{""}
+	if info.ManagesUsers() {
+		if usernames, err = u.repo.ListUsers(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	urls := make([]ClientURL, 0, len(usernames))
+	for _, username := range usernames {
+		cfg, err := u.repo.GetConnectionConfig(ctx, username)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", username, err)
+		}
+		url, err := cfg.URL()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", username, err)
+		}
+		urls = append(urls, ClientURL{Username: username, URL: url})
+	}
+	return urls, nil
+}
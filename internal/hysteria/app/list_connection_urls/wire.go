@@ -0,0 +1,23 @@
+//go:build wireinject
+// +build wireinject
+
+package list_connection_urls
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		userstore.NewRepository,
+		wire.Bind(new(ConnectionRepository), new(*userstore.Repository)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -1,7 +1,16 @@
 package list_users
 
-import "context"
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
 
 type UserRepository interface {
 	ListUsers(ctx context.Context) ([]string, error)
+	AuthInfo(ctx context.Context) (domain.AuthInfo, error)
+}
+
+type TrafficStatsRepository interface {
+	Fetch(ctx context.Context) (domain.TrafficSnapshot, error)
 }
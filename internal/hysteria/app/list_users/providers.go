@@ -1,5 +1,35 @@
 package list_users
 
-import appconfig "vpn/internal/config"
+import (
+	"time"
 
-func provideConfigPath(cfg appconfig.Config) string { return cfg.HysteriaConfigPath }
+	appconfig "vpn/internal/config"
+)
+
+func provideConfigPath(cfg appconfig.Config) string    { return cfg.HysteriaConfigPath }
+func provideUserBackend(cfg appconfig.Config) string   { return cfg.UserBackend }
+func provideUserStorePath(cfg appconfig.Config) string { return cfg.UserStorePath }
+func provideUserDBPath(cfg appconfig.Config) string    { return cfg.UserDBPath }
+
+func provideTrafficStatsEnabled(cfg appconfig.Config) bool {
+	return cfg.HysteriaTrafficStatsEnabled
+}
+
+func provideTrafficStatsURL(cfg appconfig.Config) string {
+	return cfg.HysteriaTrafficStatsURL
+}
+
+func provideTrafficStatsSecret(cfg appconfig.Config) string {
+	return cfg.HysteriaTrafficStatsSecret
+}
+
+func provideTrafficStatsTimeout(cfg appconfig.Config) time.Duration {
+	if cfg.HysteriaTrafficStatsTimeoutSeconds <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(cfg.HysteriaTrafficStatsTimeoutSeconds) * time.Second
+}
+
+func provideRemoteHost(cfg appconfig.Config) string {
+	return cfg.SSH
+}
@@ -1,15 +1,67 @@
 package list_users
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type View struct {
+	Auth     domain.AuthInfo
+	ReadOnly bool
+	Users    []string
+	// Warning says why a read-only list may be incomplete, e.g. the traffic
+	// stats API could not be reached.
+	Warning string
+}
 
 type UseCase struct {
-	repo UserRepository
+	repo  UserRepository
+	stats TrafficStatsRepository
 }
 
-func NewUseCase(repo UserRepository) *UseCase {
-	return &UseCase{repo: repo}
+func NewUseCase(repo UserRepository, stats TrafficStatsRepository) *UseCase {
+	return &UseCase{repo: repo, stats: stats}
 }
 
 func (u *UseCase) Execute(ctx context.Context) ([]string, error) {
 	return u.repo.ListUsers(ctx)
 }
+
+// View lists users for any auth mode. Without userpass there are no
+// per-user credentials, so identities reported by the traffic stats API are
+// shown read-only instead.
+func (u *UseCase) View(ctx context.Context) (View, error) {
+	auth, err := u.repo.AuthInfo(ctx)
+	if err != nil {
+		return View{}, err
+	}
+	if auth.ManagesUsers() {
+		users, err := u.repo.ListUsers(ctx)
+		if err != nil {
+			return View{}, err
+		}
+		return View{Auth: auth, Users: users}, nil
+	}
+
+	view := View{Auth: auth, ReadOnly: true}
+	snapshot, err := u.stats.Fetch(ctx)
+	if err != nil {
+		view.Warning = fmt.Sprintf("traffic stats unavailable, identities cannot be listed: %v", err)
+		return view, nil
+	}
+	seen := map[string]bool{}
+	for id := range snapshot.Users {
+		seen[id] = true
+	}
+	for id := range snapshot.Online {
+		seen[id] = true
+	}
+	for id := range seen {
+		view.Users = append(view.Users, id)
+	}
+	sort.Strings(view.Users)
+	return view, nil
+}
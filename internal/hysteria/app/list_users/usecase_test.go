@@ -2,17 +2,45 @@ package list_users
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+
+	"vpn/internal/hysteria/domain"
 )
 
-type repoMock struct{}
+type repoMock struct {
+	mode string
+}
 
 func (repoMock) ListUsers(context.Context) ([]string, error) {
 	return []string{"alice", "bob"}, nil
 }
 
+func (m repoMock) AuthInfo(context.Context) (domain.AuthInfo, error) {
+	mode := m.mode
+	if mode == "" {
+		mode = domain.AuthModeUserpass
+	}
+	return domain.AuthInfo{Mode: mode}, nil
+}
+
+type statsMock struct {
+	err error
+}
+
+func (m statsMock) Fetch(context.Context) (domain.TrafficSnapshot, error) {
+	if m.err != nil {
+		return domain.TrafficSnapshot{}, m.err
+	}
+	return domain.TrafficSnapshot{
+		Users:  map[string]domain.UserTraffic{"user": {RxBytes: 1}},
+		Online: map[string]bool{"device-2": true},
+	}, nil
+}
+
 func TestExecute(t *testing.T) {
-	uc := NewUseCase(repoMock{})
+	uc := NewUseCase(repoMock{}, statsMock{})
 	users, err := uc.Execute(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -21,3 +49,31 @@ func TestExecute(t *testing.T) {
 		t.Fatalf("unexpected users: %#v", users)
 	}
 }
+
+func TestViewUserpass(t *testing.T) {
+	uc := NewUseCase(repoMock{}, statsMock{})
+	view, err := uc.View(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if view.ReadOnly || len(view.Users) != 2 {
+		t.Fatalf("unexpected view: %#v", view)
+	}
+}
+
+func TestViewSharedPasswordIsReadOnly(t *testing.T) {
+	uc := NewUseCase(repoMock{mode: domain.AuthModePassword}, statsMock{})
+	view, err := uc.View(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !view.ReadOnly || len(view.Users) != 2 || view.Users[0] != "device-2" || view.Users[1] != "user" {
+		t.Fatalf("unexpected view: %#v", view)
+	}
+
+	uc = NewUseCase(repoMock{mode: domain.AuthModePassword}, statsMock{err: errors.New("stats disabled")})
+	view, err = uc.View(context.Background())
+	if err != nil || !view.ReadOnly || len(view.Users) != 0 || !strings.Contains(view.Warning, "stats disabled") {
+		t.Fatalf("stats failure must yield empty read-only view with a warning: %#v %v", view, err)
+	}
+}
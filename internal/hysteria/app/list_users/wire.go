@@ -6,14 +6,25 @@ package list_users
 import (
 	"github.com/google/wire"
 	appconfig "vpn/internal/config"
-	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/trafficstats"
+	"vpn/internal/hysteria/infra/userstore"
 )
 
 func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
 	wire.Build(
 		provideConfigPath,
-		configrepo.NewRepository,
-		wire.Bind(new(UserRepository), new(*configrepo.Repository)),
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		provideTrafficStatsEnabled,
+		provideTrafficStatsURL,
+		provideTrafficStatsSecret,
+		provideTrafficStatsTimeout,
+		provideRemoteHost,
+		userstore.NewRepository,
+		trafficstats.NewClient,
+		wire.Bind(new(UserRepository), new(*userstore.Repository)),
+		wire.Bind(new(TrafficStatsRepository), new(*trafficstats.Client)),
 		NewUseCase,
 	)
 	return nil, nil
@@ -8,12 +8,22 @@ package list_users
 
 import (
 	appconfig "vpn/internal/config"
-	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/trafficstats"
+	"vpn/internal/hysteria/infra/userstore"
 )
 
 func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
-	string2 := provideConfigPath(cfg)
-	repository := configrepo.NewRepository(string2)
-	useCase := NewUseCase(repository)
+	string2 := provideUserBackend(cfg)
+	string3 := provideConfigPath(cfg)
+	string4 := provideUserStorePath(cfg)
+	string5 := provideUserDBPath(cfg)
+	repository := userstore.NewRepository(string2, string3, string4, string5)
+	bool2 := provideTrafficStatsEnabled(cfg)
+	string6 := provideTrafficStatsURL(cfg)
+	string7 := provideTrafficStatsSecret(cfg)
+	duration := provideTrafficStatsTimeout(cfg)
+	string8 := provideRemoteHost(cfg)
+	client := trafficstats.NewClient(bool2, string6, string7, duration, string8)
+	useCase := NewUseCase(repository, client)
 	return useCase, nil
 }
@@ -0,0 +1,20 @@
+package migrate_auth
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type AuthRepository interface {
+	MigrateToUserpass(ctx context.Context, username string) (domain.User, error)
+	Checksum(ctx context.Context) (string, error)
+}
+
+type ServiceRestarter interface {
+	Restart(ctx context.Context) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
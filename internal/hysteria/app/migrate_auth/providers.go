@@ -0,0 +1,16 @@
+package migrate_auth
+
+import (
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/domain"
+)
+
+func provideConfigPath(cfg appconfig.Config) string     { return cfg.HysteriaConfigPath }
+func provideRestartEnabled(cfg appconfig.Config) bool   { return cfg.HysteriaRestartEnabled }
+func provideRestartCommand(cfg appconfig.Config) string { return cfg.HysteriaRestartCommand }
+func provideAuditEnabled(cfg appconfig.Config) bool     { return cfg.AuditEnabled }
+func provideAuditLogPath(cfg appconfig.Config) string   { return cfg.AuditLogPath }
+
+func provideService(cfg appconfig.Config) domain.ServiceUnit {
+	return cfg.Service()
+}
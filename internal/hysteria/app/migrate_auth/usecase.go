@@ -0,0 +1,53 @@
+package migrate_auth
+
+import (
+	"context"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UseCase struct {
+	repo      AuthRepository
+	restarter ServiceRestarter
+	audit     AuditLog
+}
+
+func NewUseCase(repo AuthRepository, restarter ServiceRestarter, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, audit: audit}
+}
+
+// Execute converts a shared-password server to userpass. The shared password
+// becomes the password of the given user, so existing clients only need the
+// username added to their URL.
+func (u *UseCase) Execute(ctx context.Context, to, username string) (user domain.User, err error) {
+	if to != domain.AuthModeUserpass {
+		return domain.User{}, fmt.Errorf("%w: migration target %q (only userpass is supported)", domain.ErrUnsupportedAuthMode, to)
+	}
+	if username == "" {
+		return domain.User{}, domain.ErrEmptyUsername
+	}
+
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.repo.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionMigrateAuth, username, hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.repo.Checksum(ctx)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	user, err = u.repo.MigrateToUserpass(ctx, username)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if err := u.restarter.Restart(ctx); err != nil {
+		return domain.User{}, err
+	}
+	return user, nil
+}
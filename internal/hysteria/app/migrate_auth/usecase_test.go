@@ -0,0 +1,64 @@
+package migrate_auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct{ called bool }
+
+func (m *repoMock) MigrateToUserpass(_ context.Context, username string) (domain.User, error) {
+	m.called = true
+	return domain.User{Username: username, Password: "shared"}, nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct{ called bool }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.called = true
+	return nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestExecute(t *testing.T) {
+	repo := &repoMock{}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, audit)
+
+	user, err := uc.Execute(context.Background(), domain.AuthModeUserpass, "default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Username != "default" || user.Password != "shared" || !restarter.called {
+		t.Fatalf("unexpected result: %+v", user)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditActionMigrateAuth {
+		t.Fatalf("unexpected audit entries: %+v", audit.entries)
+	}
+}
+
+func TestExecuteRejectsOtherTargets(t *testing.T) {
+	repo := &repoMock{}
+	uc := NewUseCase(repo, &restarterMock{}, &auditMock{})
+
+	if _, err := uc.Execute(context.Background(), domain.AuthModeHTTP, "default"); !errors.Is(err, domain.ErrUnsupportedAuthMode) {
+		t.Fatalf("expected ErrUnsupportedAuthMode, got %v", err)
+	}
+	if repo.called {
+		t.Fatal("repository must not be touched")
+	}
+}
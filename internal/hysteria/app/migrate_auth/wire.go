@@ -0,0 +1,31 @@
+//go:build wireinject
+// +build wireinject
+
+package migrate_auth
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/servicectl"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideService,
+		provideRestartEnabled,
+		provideRestartCommand,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		configrepo.NewRepository,
+		servicectl.NewRestarter,
+		auditlog.NewLog,
+		wire.Bind(new(AuthRepository), new(*configrepo.Repository)),
+		wire.Bind(new(ServiceRestarter), new(*servicectl.Restarter)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -0,0 +1,21 @@
+package migrate_storage
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type StoreResolver interface {
+	Users(ctx context.Context, backend string) ([]domain.User, error)
+	Replace(ctx context.Context, backend string, users []domain.User) error
+	Checksum(ctx context.Context, backend string) (string, error)
+}
+
+type BackendSettings interface {
+	SetUserBackend(backend string) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
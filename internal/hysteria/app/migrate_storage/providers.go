@@ -0,0 +1,9 @@
+package migrate_storage
+
+import appconfig "vpn/internal/config"
+
+func provideConfigPath(cfg appconfig.Config) string    { return cfg.HysteriaConfigPath }
+func provideUserStorePath(cfg appconfig.Config) string { return cfg.UserStorePath }
+func provideUserDBPath(cfg appconfig.Config) string    { return cfg.UserDBPath }
+func provideAuditEnabled(cfg appconfig.Config) bool    { return cfg.AuditEnabled }
+func provideAuditLogPath(cfg appconfig.Config) string  { return cfg.AuditLogPath }
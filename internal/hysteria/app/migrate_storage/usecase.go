@@ -0,0 +1,89 @@
+package migrate_storage
+
+import (
+	"context"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const (
+	BackendYAML = "yaml"
+	BackendDB   = "db"
+)
+
+var ErrUnsupportedMigration = fmt.Errorf("unsupported storage migration")
+
+type Result struct {
+	From  string
+	To    string
+	Users int
+}
+
+type UseCase struct {
+	stores   StoreResolver
+	settings BackendSettings
+	audit    AuditLog
+}
+
+func NewUseCase(stores StoreResolver, settings BackendSettings, audit AuditLog) *UseCase {
+	return &UseCase{stores: stores, settings: settings, audit: audit}
+}
+
+// Execute copies all users from one storage backend to another and makes the
+// target active. Only backends that serve Hysteria through auth.userpass are
+// supported; the http backend changes the auth mode and goes through
+// switch-backend instead. Credentials do not change, so no restart is needed.
+func (u *UseCase) Execute(ctx context.Context, from, to string) (result Result, err error) {
+	if !servesUserpass(from) || !servesUserpass(to) {
+		return Result{}, fmt.Errorf("%w: %s -> %s (supported: yaml, db; use switch-backend for http)", ErrUnsupportedMigration, from, to)
+	}
+	if from == to {
+		return Result{}, fmt.Errorf("%w: source and target are both %s", ErrUnsupportedMigration, from)
+	}
+
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.stores.Checksum(ctx, to)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionMigrateStorage, "", hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.stores.Checksum(ctx, to)
+	if err != nil {
+		return Result{}, err
+	}
+
+	users, err := u.stores.Users(ctx, from)
+	if err != nil {
+		return Result{}, fmt.Errorf("read %s users: %w", from, err)
+	}
+	if err := u.stores.Replace(ctx, to, users); err != nil {
+		return Result{}, fmt.Errorf("write %s users: %w", to, err)
+	}
+	if to == BackendDB {
+		// auth.userpass is rendered from the database from now on; drop the
+		// metadata comments the yaml backend kept there.
+		if err := u.stores.Replace(ctx, BackendYAML, stripTags(users)); err != nil {
+			return Result{}, fmt.Errorf("render auth.userpass: %w", err)
+		}
+	}
+	if err := u.settings.SetUserBackend(to); err != nil {
+		return Result{}, fmt.Errorf("save cli config: %w", err)
+	}
+	return Result{From: from, To: to, Users: len(users)}, nil
+}
+
+func servesUserpass(backend string) bool {
+	return backend == BackendYAML || backend == BackendDB
+}
+
+func stripTags(users []domain.User) []domain.User {
+	out := make([]domain.User, 0, len(users))
+	for _, user := range users {
+		out = append(out, domain.User{Username: user.Username, Password: user.Password})
+	}
+	return out
+}
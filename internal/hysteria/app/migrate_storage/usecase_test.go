@@ -0,0 +1,97 @@
+package migrate_storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type resolverMock struct {
+	users    map[string][]domain.User
+	replaced []string
+}
+
+func (m *resolverMock) Users(_ context.Context, backend string) ([]domain.User, error) {
+	return m.users[backend], nil
+}
+
+func (m *resolverMock) Replace(_ context.Context, backend string, users []domain.User) error {
+	m.replaced = append(m.replaced, backend)
+	m.users[backend] = users
+	return nil
+}
+
+func (m *resolverMock) Checksum(context.Context, string) (string, error) {
+	return "hash", nil
+}
+
+type settingsMock struct{ backend string }
+
+func (m *settingsMock) SetUserBackend(backend string) error {
+	m.backend = backend
+	return nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestExecuteYAMLToDB(t *testing.T) {
+	stores := &resolverMock{users: map[string][]domain.User{
+		BackendYAML: {{Username: "alice", Password: "a", Tags: []string{"ops"}}, {Username: "bob", Password: "b"}},
+	}}
+	settings := &settingsMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(stores, settings, audit)
+
+	result, err := uc.Execute(context.Background(), BackendYAML, BackendDB)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Users != 2 || settings.backend != BackendDB {
+		t.Fatalf("unexpected result: %+v backend=%s", result, settings.backend)
+	}
+	db := stores.users[BackendDB]
+	if len(db) != 2 || !db[0].HasTag("ops") {
+		t.Fatalf("tags must move to the database: %+v", db)
+	}
+	if yaml := stores.users[BackendYAML]; len(yaml) != 2 || len(yaml[0].Tags) != 0 {
+		t.Fatalf("auth.userpass must be re-rendered without metadata: %+v", yaml)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditActionMigrateStorage {
+		t.Fatalf("unexpected audit: %+v", audit.entries)
+	}
+}
+
+func TestExecuteDBToYAML(t *testing.T) {
+	stores := &resolverMock{users: map[string][]domain.User{
+		BackendDB: {{Username: "alice", Password: "a", Tags: []string{"ops"}}},
+	}}
+	settings := &settingsMock{}
+	uc := NewUseCase(stores, settings, &auditMock{})
+
+	if _, err := uc.Execute(context.Background(), BackendDB, BackendYAML); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stores.replaced) != 1 || stores.replaced[0] != BackendYAML || !stores.users[BackendYAML][0].HasTag("ops") {
+		t.Fatalf("expected yaml to receive users with tags: %+v", stores)
+	}
+	if settings.backend != BackendYAML {
+		t.Fatalf("unexpected backend: %s", settings.backend)
+	}
+}
+
+func TestExecuteRejectsUnsupported(t *testing.T) {
+	uc := NewUseCase(&resolverMock{users: map[string][]domain.User{}}, &settingsMock{}, &auditMock{})
+
+	for _, pair := range [][2]string{{BackendYAML, "http"}, {BackendDB, BackendDB}, {"sqlite", BackendDB}} {
+		if _, err := uc.Execute(context.Background(), pair[0], pair[1]); !errors.Is(err, ErrUnsupportedMigration) {
+			t.Fatalf("%v: expected ErrUnsupportedMigration, got %v", pair, err)
+		}
+	}
+}
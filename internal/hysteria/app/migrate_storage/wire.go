@@ -0,0 +1,29 @@
+//go:build wireinject
+// +build wireinject
+
+package migrate_storage
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideUserStorePath,
+		provideUserDBPath,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		userstore.NewResolver,
+		appconfig.NewBackendWriter,
+		auditlog.NewLog,
+		wire.Bind(new(StoreResolver), new(*userstore.Resolver)),
+		wire.Bind(new(BackendSettings), new(*appconfig.BackendWriter)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -0,0 +1,18 @@
+package provision_server
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/infra/release"
+	"vpn/internal/hysteria/infra/remote"
+)
+
+// BuildUseCase connects to the host given on the command line; the host is
+// usually not part of the CLI config yet, so there is no wire injector.
+func BuildUseCase(ctx context.Context, target remote.Target) (*UseCase, error) {
+	client, err := remote.Connect(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	return NewUseCase(client, release.NewClient("")), nil
+}
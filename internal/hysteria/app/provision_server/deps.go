@@ -0,0 +1,18 @@
+package provision_server
+
+import (
+	"context"
+	"os"
+)
+
+// Host is the server being provisioned. Commands run through sh as root.
+type Host interface {
+	Run(ctx context.Context, command string) ([]byte, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+}
+
+type ReleaseSource interface {
+	Download(ctx context.Context, version, asset string) ([]byte, error)
+	Checksums(ctx context.Context, version string) (map[string]string, error)
+}
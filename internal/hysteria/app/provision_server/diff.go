@@ -0,0 +1,80 @@
+package provision_server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyDiff lists the config keys added in new with "+", removed with "-" and
+// changed with "~". Values are left out: the config carries user passwords
+// and the obfs and traffic stats secrets.
+func keyDiff(old, new []byte) string {
+	a, errA := flattenYAML(old)
+	b, errB := flattenYAML(new)
+	if errA != nil || errB != nil {
+		return "~ (not valid YAML, keys cannot be compared)\n"
+	}
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		va, inA := a[k]
+		vb, inB := b[k]
+		switch {
+		case !inA:
+			sb.WriteString("+ " + k + "\n")
+		case !inB:
+			sb.WriteString("- " + k + "\n")
+		case va != vb:
+			sb.WriteString("~ " + k + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// flattenYAML maps the dotted path of every scalar in data to its value.
+func flattenYAML(data []byte) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	out := map[string]string{}
+	if len(doc.Content) > 0 {
+		flattenNode(doc.Content[0], "", out)
+	}
+	return out, nil
+}
+
+func flattenNode(node *yaml.Node, path string, out map[string]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if path != "" {
+				key = path + "." + key
+			}
+			flattenNode(node.Content[i+1], key, out)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			flattenNode(item, fmt.Sprintf("%s[%d]", path, i), out)
+		}
+	case yaml.AliasNode:
+		flattenNode(node.Alias, path, out)
+	default:
+		out[path] = node.Value
+	}
+}
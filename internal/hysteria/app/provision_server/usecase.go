@@ -0,0 +1,400 @@
+package provision_server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	StatusOK      = "ok"
+	StatusChanged = "changed"
+	StatusPlanned = "would-change"
+	StatusKept    = "kept"
+	StatusFailed  = "failed"
+)
+
+const (
+	DefaultVersion    = "v2.6.1"
+	DefaultConfigPath = "/etc/hysteria/config.yaml"
+
+	binaryPath = "/usr/local/bin/hysteria"
+	unitDir    = "/etc/systemd/system"
+)
+
+var (
+	ErrUnsupportedHost  = errors.New("unsupported host")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// requiredPackages are needed by the service (TLS roots) and by the CLI,
+// which locks the config with flock on every change.
+var requiredPackages = []string{"ca-certificates", "util-linux"}
+
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9@._-]+$`)
+
+// Request describes the desired state of the host.
+type Request struct {
+	Config      []byte
+	ConfigPath  string
+	ServiceName string
+	Version     string
+	// SHA256 pins the binary checksum instead of taking it from the
+	// release's hashes.txt.
+	SHA256 string
+	// Check only reports what would change.
+	Check bool
+	// ForceConfig replaces an existing config that differs from Config.
+	// Without it the config on the host, with the users and settings added
+	// since, is kept.
+	ForceConfig bool
+}
+
+type Step struct {
+	Name   string
+	Status string
+	Detail string
+	// Diff lists the keys an existing config differs in, in check mode.
+	Diff string
+}
+
+type Report struct {
+	Steps []Step
+}
+
+func (r Report) Changed() bool {
+	for _, s := range r.Steps {
+		if s.Status == StatusChanged || s.Status == StatusPlanned {
+			return true
+		}
+	}
+	return false
+}
+
+type UseCase struct {
+	host     Host
+	releases ReleaseSource
+}
+
+func NewUseCase(host Host, releases ReleaseSource) *UseCase {
+	return &UseCase{host: host, releases: releases}
+}
+
+type hostInfo struct {
+	arch           string
+	distro         string
+	packageManager string
+}
+
+// Execute brings the host to the requested state step by step. Every step
+// first inspects the host and only writes when something differs, so a
+// second run reports ok everywhere. It stops at the first failed step.
+func (u *UseCase) Execute(ctx context.Context, req Request) (Report, error) {
+	var report Report
+	if req.ConfigPath == "" {
+		req.ConfigPath = DefaultConfigPath
+	}
+	if req.Version == "" {
+		req.Version = DefaultVersion
+	}
+	if !strings.HasPrefix(req.Version, "v") {
+		req.Version = "v" + req.Version
+	}
+	if !serviceNamePattern.MatchString(req.ServiceName) {
+		return report, fmt.Errorf("invalid service name %q", req.ServiceName)
+	}
+	if !path.IsAbs(req.ConfigPath) {
+		return report, fmt.Errorf("config path %q must be absolute", req.ConfigPath)
+	}
+	if len(req.Config) == 0 {
+		return report, errors.New("hysteria config is empty")
+	}
+
+	record := func(name string, status, detail string, err error) error {
+		if err != nil {
+			report.Steps = append(report.Steps, Step{Name: name, Status: StatusFailed, Detail: err.Error()})
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		report.Steps = append(report.Steps, Step{Name: name, Status: status, Detail: detail})
+		return nil
+	}
+
+	info, err := u.detect(ctx)
+	detail := ""
+	if err == nil {
+		detail = fmt.Sprintf("%s/%s, %s", info.distro, info.arch, info.packageManager)
+	}
+	if err := record("os", StatusOK, detail, err); err != nil {
+		return report, err
+	}
+
+	status, detail, err := u.packages(ctx, info, req.Check)
+	if err := record("packages", status, detail, err); err != nil {
+		return report, err
+	}
+
+	binaryStatus, detail, err := u.binary(ctx, info, req)
+	if err := record("binary", binaryStatus, detail, err); err != nil {
+		return report, err
+	}
+
+	configStatus, detail, diff, err := u.config(ctx, req)
+	if err := record("config", configStatus, detail, err); err != nil {
+		return report, err
+	}
+	report.Steps[len(report.Steps)-1].Diff = diff
+
+	unitStatus, detail, err := u.unit(ctx, req)
+	if err := record("unit", unitStatus, detail, err); err != nil {
+		return report, err
+	}
+
+	changed := binaryStatus != StatusOK || (configStatus != StatusOK && configStatus != StatusKept) || unitStatus != StatusOK
+	status, detail, err = u.service(ctx, req, changed)
+	if err := record("service", status, detail, err); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func (u *UseCase) detect(ctx context.Context) (hostInfo, error) {
+	script := `uname -s; uname -m; . /etc/os-release 2>/dev/null; echo "${ID:-unknown}"; ` +
+		`for pm in apt-get dnf yum apk; do if command -v $pm >/dev/null 2>&1; then echo $pm; break; fi; done; ` +
+		`command -v systemctl >/dev/null 2>&1 && echo systemd || echo no-systemd`
+	out, err := u.host.Run(ctx, script)
+	if err != nil {
+		return hostInfo{}, err
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) < 4 {
+		return hostInfo{}, fmt.Errorf("unexpected os probe output %q", strings.TrimSpace(string(out)))
+	}
+	if lines[0] != "Linux" {
+		return hostInfo{}, fmt.Errorf("%w: %s (only Linux is supported)", ErrUnsupportedHost, lines[0])
+	}
+	info := hostInfo{distro: lines[2]}
+	switch lines[1] {
+	case "x86_64", "amd64":
+		info.arch = "amd64"
+	case "aarch64", "arm64":
+		info.arch = "arm64"
+	case "armv7l", "armv7":
+		info.arch = "arm"
+	case "i386", "i686":
+		info.arch = "386"
+	default:
+		return hostInfo{}, fmt.Errorf("%w: architecture %s", ErrUnsupportedHost, lines[1])
+	}
+	if lines[len(lines)-1] != "systemd" {
+		return hostInfo{}, fmt.Errorf("%w: systemd is required", ErrUnsupportedHost)
+	}
+	if len(lines) == 5 {
+		info.packageManager = lines[3]
+	}
+	return info, nil
+}
+
+func (u *UseCase) packages(ctx context.Context, info hostInfo, check bool) (string, string, error) {
+	var probe, install string
+	switch info.packageManager {
+	case "apt-get":
+		probe = `dpkg-query -W -f='${Status}' "$p" 2>/dev/null | grep -q 'install ok installed'`
+		install = "DEBIAN_FRONTEND=noninteractive apt-get update -q && DEBIAN_FRONTEND=noninteractive apt-get install -y -q"
+	case "dnf", "yum":
+		probe = `rpm -q "$p" >/dev/null 2>&1`
+		install = info.packageManager + " install -y"
+	case "apk":
+		probe = `apk info -e "$p" >/dev/null 2>&1`
+		install = "apk add --no-cache"
+	default:
+		return "", "", fmt.Errorf("%w: no supported package manager (apt-get, dnf, yum, apk)", ErrUnsupportedHost)
+	}
+
+	out, err := u.host.Run(ctx, fmt.Sprintf("for p in %s; do %s || echo \"$p\"; done", strings.Join(requiredPackages, " "), probe))
+	if err != nil {
+		return "", "", err
+	}
+	missing := strings.Fields(string(out))
+	if len(missing) == 0 {
+		return StatusOK, strings.Join(requiredPackages, ", "), nil
+	}
+	detail := "install " + strings.Join(missing, ", ")
+	if check {
+		return StatusPlanned, detail, nil
+	}
+	if _, err := u.host.Run(ctx, install+" "+strings.Join(missing, " ")); err != nil {
+		return "", "", err
+	}
+	return StatusChanged, detail, nil
+}
+
+func (u *UseCase) binary(ctx context.Context, info hostInfo, req Request) (string, string, error) {
+	out, _ := u.host.Run(ctx, binaryPath+" version 2>/dev/null || true")
+	installed := parseVersion(out)
+	if installed == req.Version {
+		return StatusOK, req.Version, nil
+	}
+	detail := fmt.Sprintf("install %s", req.Version)
+	if installed != "" {
+		detail = fmt.Sprintf("upgrade %s -> %s", installed, req.Version)
+	}
+	if req.Check {
+		return StatusPlanned, detail, nil
+	}
+
+	asset := "hysteria-linux-" + info.arch
+	want := strings.ToLower(req.SHA256)
+	if want == "" {
+		sums, err := u.releases.Checksums(ctx, req.Version)
+		if err != nil {
+			return "", "", err
+		}
+		if want = sums[asset]; want == "" {
+			return "", "", fmt.Errorf("no checksum for %s in release %s", asset, req.Version)
+		}
+	}
+	data, err := u.releases.Download(ctx, req.Version, asset)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return "", "", fmt.Errorf("%w for %s: got %s, want %s", ErrChecksumMismatch, asset, got, want)
+	}
+	if err := u.host.WriteFile(binaryPath, data, 0o755); err != nil {
+		return "", "", err
+	}
+	return StatusChanged, detail, nil
+}
+
+// config creates the config when it is missing. An existing config that
+// differs is only replaced with ForceConfig; check mode lists the changed keys.
+func (u *UseCase) config(ctx context.Context, req Request) (string, string, string, error) {
+	current, err := u.host.ReadFile(req.ConfigPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", "", "", err
+	}
+	if err != nil || bytes.Equal(current, req.Config) {
+		status, detail, err := u.file(ctx, req.ConfigPath, req.Config, 0o600, req.Check)
+		return status, detail, "", err
+	}
+	diff := ""
+	if req.Check {
+		diff = keyDiff(current, req.Config)
+	}
+	if !req.ForceConfig {
+		return StatusKept, req.ConfigPath + " differs; pass --force-config to replace it", diff, nil
+	}
+	status, detail, err := u.file(ctx, req.ConfigPath, req.Config, 0o600, req.Check)
+	return status, detail, diff, err
+}
+
+func (u *UseCase) file(ctx context.Context, name string, data []byte, perm os.FileMode, check bool) (string, string, error) {
+	current, err := u.host.ReadFile(name)
+	switch {
+	case err == nil && bytes.Equal(current, data):
+		return StatusOK, name, nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return "", "", err
+	}
+	detail := "update " + name
+	if err != nil {
+		detail = "create " + name
+	}
+	if check {
+		return StatusPlanned, detail, nil
+	}
+	if _, err := u.host.Run(ctx, "mkdir -p "+quote(path.Dir(name))); err != nil {
+		return "", "", err
+	}
+	if err := u.host.WriteFile(name, data, perm); err != nil {
+		return "", "", err
+	}
+	return StatusChanged, detail, nil
+}
+
+func (u *UseCase) unit(ctx context.Context, req Request) (string, string, error) {
+	name := path.Join(unitDir, req.ServiceName+".service")
+	status, detail, err := u.file(ctx, name, renderUnit(req.ConfigPath), 0o644, req.Check)
+	if err != nil || status != StatusChanged {
+		return status, detail, err
+	}
+	if _, err := u.host.Run(ctx, "systemctl daemon-reload"); err != nil {
+		return "", "", err
+	}
+	return status, detail, nil
+}
+
+func (u *UseCase) service(ctx context.Context, req Request, changed bool) (string, string, error) {
+	name := quote(req.ServiceName)
+	out, err := u.host.Run(ctx, fmt.Sprintf(
+		"systemctl is-enabled --quiet %s && echo enabled || echo disabled; systemctl is-active --quiet %s && echo active || echo inactive",
+		name, name,
+	))
+	if err != nil {
+		return "", "", err
+	}
+	state := strings.Fields(string(out))
+	enabled := len(state) > 0 && state[0] == "enabled"
+	active := len(state) > 1 && state[1] == "active"
+
+	var command, detail string
+	switch {
+	case !enabled || !active:
+		command, detail = "systemctl enable --now "+name, "enable and start "+req.ServiceName
+		if active && changed {
+			command, detail = "systemctl enable "+name+" && systemctl restart "+name, "enable and restart "+req.ServiceName
+		}
+	case changed:
+		command, detail = "systemctl restart "+name, "restart "+req.ServiceName
+	default:
+		return StatusOK, req.ServiceName + " enabled and active", nil
+	}
+	if req.Check {
+		return StatusPlanned, detail, nil
+	}
+	if _, err := u.host.Run(ctx, command); err != nil {
+		return "", "", err
+	}
+	return StatusChanged, detail, nil
+}
+
+func renderUnit(configPath string) []byte {
+	return []byte(fmt.Sprintf(`[Unit]
+Description=Hysteria 2 server
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart=%s server --config %s
+Restart=on-failure
+RestartSec=5
+LimitNOFILE=1048576
+
+[Install]
+WantedBy=multi-user.target
+`, binaryPath, configPath))
+}
+
+// parseVersion extracts vX.Y.Z from the "Version:" line of hysteria version.
+func parseVersion(out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "Version" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,264 @@
+package provision_server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+type hostMock struct {
+	system   string
+	missing  string
+	version  string
+	enabled  bool
+	active   bool
+	files    map[string][]byte
+	perms    map[string]os.FileMode
+	commands []string
+}
+
+func newHostMock() *hostMock {
+	return &hostMock{system: "Linux", files: map[string][]byte{}, perms: map[string]os.FileMode{}}
+}
+
+func (h *hostMock) Run(_ context.Context, command string) ([]byte, error) {
+	h.commands = append(h.commands, command)
+	switch {
+	case strings.HasPrefix(command, "uname -s"):
+		return []byte(h.system + "\nx86_64\ndebian\napt-get\nsystemd\n"), nil
+	case strings.HasPrefix(command, "for p in"):
+		return []byte(h.missing), nil
+	case strings.HasPrefix(command, binaryPath+" version"):
+		if h.version == "" {
+			return nil, nil
+		}
+		return []byte("Version:\t" + h.version + "\nBuildDate:\t2025-01-01\n"), nil
+	case strings.HasPrefix(command, "systemctl is-enabled"):
+		state := map[bool]string{true: "enabled", false: "disabled"}[h.enabled] + "\n" +
+			map[bool]string{true: "active", false: "inactive"}[h.active]
+		return []byte(state), nil
+	case strings.Contains(command, "apt-get install"):
+		h.missing = ""
+	case strings.HasPrefix(command, "systemctl enable --now"):
+		h.enabled, h.active = true, true
+	}
+	return nil, nil
+}
+
+func (h *hostMock) ReadFile(name string) ([]byte, error) {
+	data, ok := h.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	return data, nil
+}
+
+func (h *hostMock) WriteFile(name string, data []byte, perm os.FileMode) error {
+	h.files[name] = data
+	h.perms[name] = perm
+	if name == binaryPath {
+		h.version = DefaultVersion
+	}
+	return nil
+}
+
+func (h *hostMock) ran(prefix string) bool {
+	for _, c := range h.commands {
+		if strings.HasPrefix(c, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type releasesMock struct {
+	binary    []byte
+	checksum  string
+	downloads int
+}
+
+func newReleasesMock() *releasesMock {
+	binary := []byte("hysteria-binary")
+	sum := sha256.Sum256(binary)
+	return &releasesMock{binary: binary, checksum: hex.EncodeToString(sum[:])}
+}
+
+func (r *releasesMock) Download(_ context.Context, version, asset string) ([]byte, error) {
+	r.downloads++
+	if version != DefaultVersion || asset != "hysteria-linux-amd64" {
+		return nil, fmt.Errorf("unexpected asset %s %s", version, asset)
+	}
+	return r.binary, nil
+}
+
+func (r *releasesMock) Checksums(context.Context, string) (map[string]string, error) {
+	return map[string]string{"hysteria-linux-amd64": r.checksum}, nil
+}
+
+func statuses(report Report) string {
+	parts := make([]string, 0, len(report.Steps))
+	for _, s := range report.Steps {
+		parts = append(parts, s.Name+"="+s.Status)
+	}
+	return strings.Join(parts, " ")
+}
+
+func request() Request {
+	return Request{Config: []byte("listen: :443\n"), ServiceName: "hysteria-server"}
+}
+
+func TestExecuteProvisionsFreshHostAndIsIdempotent(t *testing.T) {
+	host := newHostMock()
+	host.missing = "util-linux\n"
+	uc := NewUseCase(host, newReleasesMock())
+
+	report, err := uc.Execute(context.Background(), request())
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := "os=ok packages=changed binary=changed config=changed unit=changed service=changed"
+	if got := statuses(report); got != want {
+		t.Fatalf("steps = %s, want %s", got, want)
+	}
+	if host.perms[binaryPath] != 0o755 || host.perms[DefaultConfigPath] != 0o600 {
+		t.Fatalf("unexpected perms: %v", host.perms)
+	}
+	if !strings.Contains(string(host.files["/etc/systemd/system/hysteria-server.service"]), "--config /etc/hysteria/config.yaml") {
+		t.Fatalf("unit does not point at config: %s", host.files["/etc/systemd/system/hysteria-server.service"])
+	}
+	if !host.ran("systemctl daemon-reload") || !host.ran("systemctl enable --now 'hysteria-server'") {
+		t.Fatalf("service not enabled: %v", host.commands)
+	}
+
+	host.commands = nil
+	report, err = uc.Execute(context.Background(), request())
+	if err != nil {
+		t.Fatalf("second execute: %v", err)
+	}
+	if report.Changed() {
+		t.Fatalf("second run changed: %s", statuses(report))
+	}
+	if host.ran("systemctl restart") || host.ran("mkdir") {
+		t.Fatalf("second run wrote: %v", host.commands)
+	}
+}
+
+func TestExecuteRestartsServiceWhenConfigChanges(t *testing.T) {
+	host := newHostMock()
+	uc := NewUseCase(host, newReleasesMock())
+	if _, err := uc.Execute(context.Background(), request()); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	req := request()
+	req.Config = []byte("listen: :8443\n")
+	req.ForceConfig = true
+	report, err := uc.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := "os=ok packages=ok binary=ok config=changed unit=ok service=changed"
+	if got := statuses(report); got != want {
+		t.Fatalf("steps = %s, want %s", got, want)
+	}
+	if !host.ran("systemctl restart 'hysteria-server'") {
+		t.Fatalf("service not restarted: %v", host.commands)
+	}
+}
+
+func TestExecuteKeepsExistingConfig(t *testing.T) {
+	host := newHostMock()
+	uc := NewUseCase(host, newReleasesMock())
+	if _, err := uc.Execute(context.Background(), request()); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	original := string(host.files[DefaultConfigPath])
+	host.commands = nil
+
+	req := request()
+	req.Config = []byte("listen: :8443\nauth:\n  type: userpass\n  userpass:\n    alice: s3cret\n")
+	req.Check = true
+	report, err := uc.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if got := report.Steps[3]; got.Status != StatusKept || got.Diff != "+ auth.type\n+ auth.userpass.alice\n~ listen\n" {
+		t.Fatalf("unexpected config step %+v", got)
+	}
+	if strings.Contains(report.Steps[3].Diff, "s3cret") {
+		t.Fatalf("diff leaks a password: %q", report.Steps[3].Diff)
+	}
+
+	req.Check = false
+	report, err = uc.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := "os=ok packages=ok binary=ok config=kept unit=ok service=ok"
+	if got := statuses(report); got != want {
+		t.Fatalf("steps = %s, want %s", got, want)
+	}
+	if string(host.files[DefaultConfigPath]) != original || host.ran("systemctl restart 'hysteria-server'") {
+		t.Fatalf("existing config replaced: %q %v", host.files[DefaultConfigPath], host.commands)
+	}
+	if report.Changed() || report.Steps[3].Diff != "" {
+		t.Fatalf("kept config counted as a change: %+v", report.Steps[3])
+	}
+}
+
+func TestExecuteCheckModeDoesNotWrite(t *testing.T) {
+	host := newHostMock()
+	host.missing = "ca-certificates\n"
+	releases := newReleasesMock()
+	req := request()
+	req.Check = true
+
+	report, err := NewUseCase(host, releases).Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := "os=ok packages=would-change binary=would-change config=would-change unit=would-change service=would-change"
+	if got := statuses(report); got != want {
+		t.Fatalf("steps = %s, want %s", got, want)
+	}
+	if len(host.files) != 0 || releases.downloads != 0 {
+		t.Fatalf("check mode wrote files %v or downloaded %d", host.files, releases.downloads)
+	}
+	for _, c := range host.commands {
+		if strings.Contains(c, "install -y") || strings.HasPrefix(c, "systemctl enable") || strings.HasPrefix(c, "mkdir") {
+			t.Fatalf("check mode ran %q", c)
+		}
+	}
+}
+
+func TestExecuteRejectsBinaryWithWrongChecksum(t *testing.T) {
+	host := newHostMock()
+	req := request()
+	req.SHA256 = strings.Repeat("0", 64)
+
+	report, err := NewUseCase(host, newReleasesMock()).Execute(context.Background(), req)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if _, ok := host.files[binaryPath]; ok {
+		t.Fatal("binary uploaded despite checksum mismatch")
+	}
+	if got := statuses(report); got != "os=ok packages=ok binary=failed" {
+		t.Fatalf("steps = %s", got)
+	}
+}
+
+func TestExecuteRejectsNonLinuxHost(t *testing.T) {
+	host := newHostMock()
+	host.system = "Darwin"
+
+	_, err := NewUseCase(host, newReleasesMock()).Execute(context.Background(), request())
+	if !errors.Is(err, ErrUnsupportedHost) {
+		t.Fatalf("expected unsupported host, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package reconcile_users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type Result struct {
+	Plan      domain.ReconcilePlan
+	Passwords map[string]string
+}
+
+type UseCase struct {
+	repo      UserRepository
+	restarter ServiceRestarter
+	passwords PasswordGenerator
+	usernames domain.UsernamePolicy
+	audit     AuditLog
+}
+
+func NewUseCase(repo UserRepository, restarter ServiceRestarter, passwords PasswordGenerator, usernames domain.UsernamePolicy, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, passwords: passwords, usernames: usernames, audit: audit}
+}
+
+func (u *UseCase) Plan(ctx context.Context, desired []domain.DesiredUser, prune bool) (domain.ReconcilePlan, error) {
+	live, err := u.repo.Users(ctx)
+	if err != nil {
+		return domain.ReconcilePlan{}, err
+	}
+	return buildPlan(live, desired, prune, u.usernames)
+}
+
+func (u *UseCase) Apply(ctx context.Context, desired []domain.DesiredUser, prune bool) (Result, error) {
+	live, err := u.repo.Users(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	plan, err := buildPlan(live, desired, prune, u.usernames)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Plan: plan, Passwords: map[string]string{}}
+	if !plan.HasChanges() {
+		return result, nil
+	}
+
+	byKey := make(map[string]domain.DesiredUser, len(desired))
+	for _, d := range desired {
+		byKey[u.usernames.Key(d.Username)] = d
+	}
+
+	changes := make([]domain.UserChange, 0, len(plan.Items))
+	restart := false
+	for _, item := range plan.Items {
+		want := byKey[u.usernames.Key(item.Username)]
+		switch item.Action {
+		case domain.PlanDelete:
+			changes = append(changes, domain.UserChange{Action: domain.UserChangeRemove, User: domain.User{Username: item.Username}})
+			restart = true
+			continue
+		case domain.PlanRetag:
+			// Tags are metadata; Hysteria does not read them, so they
+			// need no restart.
+			tags, err := domain.NormalizeTags(want.Tags)
+			if err != nil {
+				return Result{}, err
+			}
+			changes = append(changes, domain.UserChange{Action: domain.UserChangeTags, User: domain.User{Username: item.Username, Tags: tags}})
+			continue
+		}
+		password := want.Password
+		if !want.FixedPassword() {
+			password, err = u.passwords.Generate()
+			if err != nil {
+				return Result{}, err
+			}
+			result.Passwords[item.Username] = password
+		}
+		user, err := domain.NewUser(item.Username, password)
+		if err != nil {
+			return Result{}, err
+		}
+		action := domain.UserChangeRotate
+		if item.Action == domain.PlanCreate {
+			action = domain.UserChangeAdd
+			if user.Tags, err = domain.NormalizeTags(want.Tags); err != nil {
+				return Result{}, err
+			}
+		}
+		changes = append(changes, domain.UserChange{Action: action, User: user})
+		restart = true
+	}
+
+	// A failed checksum is recorded for every change like a failed write.
+	hashBefore, applyErr := u.repo.Checksum(ctx)
+	if applyErr == nil {
+		applyErr = u.repo.ApplyChanges(ctx, changes)
+	}
+	if applyErr == nil && restart {
+		applyErr = u.restarter.Restart(ctx)
+	}
+	hashAfter, _ := u.repo.Checksum(ctx)
+
+	var auditErr error
+	for _, change := range changes {
+		entry := domain.NewAuditEntry(ctx, change.AuditAction(), change.User.Username, hashBefore, hashAfter, applyErr)
+		auditErr = errors.Join(auditErr, u.audit.Record(ctx, entry))
+	}
+	if applyErr != nil {
+		return Result{}, applyErr
+	}
+	if auditErr != nil {
+		return result, fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+	}
+	return result, nil
+}
+
+// buildPlan matches desired users to live ones by the username policy key,
+// so plan items name users as they are stored. It checks the names of users
+// it creates against the policy; users already on the server are left alone
+// even if they break it.
+func buildPlan(live []domain.User, desired []domain.DesiredUser, prune bool, usernames domain.UsernamePolicy) (domain.ReconcilePlan, error) {
+	liveByKey := make(map[string]domain.User, len(live))
+	for _, user := range live {
+		liveByKey[usernames.Key(user.Username)] = user
+	}
+
+	var plan domain.ReconcilePlan
+	wanted := make(map[string]bool, len(desired))
+	keys := make(map[string]string, len(desired))
+	for _, d := range desired {
+		if d.Username == "" {
+			return domain.ReconcilePlan{}, fmt.Errorf("%w: %v", domain.ErrInvalidDesiredState, domain.ErrEmptyUsername)
+		}
+		if other, ok := keys[usernames.Key(d.Username)]; ok {
+			if other == d.Username {
+				return domain.ReconcilePlan{}, fmt.Errorf("%w: user %q listed more than once", domain.ErrInvalidDesiredState, d.Username)
+			}
+			return domain.ReconcilePlan{}, fmt.Errorf("%w: users %q and %q differ only in case", domain.ErrInvalidDesiredState, other, d.Username)
+		}
+		keys[usernames.Key(d.Username)] = d.Username
+
+		current, ok := liveByKey[usernames.Key(d.Username)]
+		if ok {
+			wanted[current.Username] = true
+		}
+		switch {
+		case !ok:
+			plan.Items = append(plan.Items, domain.PlanItem{Action: domain.PlanCreate, Username: d.Username, Reason: "missing on server"})
+		case d.FixedPassword() && current.Password != d.Password:
+			plan.Items = append(plan.Items, domain.PlanItem{Action: domain.PlanRotate, Username: current.Username, Reason: "password differs from desired"})
+		}
+		if !ok || !d.ManagesTags() {
+			continue
+		}
+		tags, err := domain.NormalizeTags(d.Tags)
+		if err != nil {
+			return domain.ReconcilePlan{}, fmt.Errorf("%w: %v", domain.ErrInvalidDesiredState, err)
+		}
+		have, _ := domain.NormalizeTags(current.Tags)
+		if !slices.Equal(tags, have) {
+			reason := fmt.Sprintf("tags differ from desired (%s -> %s)", tagList(have), tagList(tags))
+			plan.Items = append(plan.Items, domain.PlanItem{Action: domain.PlanRetag, Username: current.Username, Reason: reason})
+		}
+	}
+
+	var extra, kept []string
+	for _, user := range live {
+		if !wanted[user.Username] {
+			extra = append(extra, user.Username)
+		}
+		if wanted[user.Username] || !prune {
+			kept = append(kept, user.Username)
+		}
+	}
+	for _, item := range plan.Items {
+		if item.Action != domain.PlanCreate {
+			continue
+		}
+		if err := usernames.Check(item.Username); err != nil {
+			return domain.ReconcilePlan{}, fmt.Errorf("%w: %v", domain.ErrInvalidDesiredState, err)
+		}
+		if err := usernames.ConflictError(item.Username, kept); err != nil {
+			return domain.ReconcilePlan{}, fmt.Errorf("%w: %v", domain.ErrInvalidDesiredState, err)
+		}
+	}
+	sort.Strings(extra)
+	for _, username := range extra {
+		if prune {
+			plan.Items = append(plan.Items, domain.PlanItem{Action: domain.PlanDelete, Username: username, Reason: "not listed in desired state"})
+		} else {
+			plan.Unmanaged = append(plan.Unmanaged, username)
+		}
+	}
+	return plan, nil
+}
+
+func tagList(tags []string) string {
+	if len(tags) == 0 {
+		return "none"
+	}
+	return strings.Join(tags, ",")
+}
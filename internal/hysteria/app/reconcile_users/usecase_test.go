@@ -0,0 +1,190 @@
+package reconcile_users
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct {
+	users      []domain.User
+	applyCalls int
+	changes    []domain.UserChange
+}
+
+func (m *repoMock) Users(context.Context) ([]domain.User, error) {
+	return m.users, nil
+}
+
+func (m *repoMock) ApplyChanges(_ context.Context, changes []domain.UserChange) error {
+	m.applyCalls++
+	m.changes = changes
+	return nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct{ calls int }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.calls++
+	return nil
+}
+
+type passwordGeneratorMock struct{}
+
+func (passwordGeneratorMock) Generate() (string, error) {
+	return "Abc123Abc123Abc123Abc123Abc123Ab", nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func newRepo() *repoMock {
+	return &repoMock{users: []domain.User{
+		{Username: "alice", Password: "old"},
+		{Username: "bob", Password: "keep"},
+		{Username: "mallory", Password: "x"},
+	}}
+}
+
+var desired = []domain.DesiredUser{
+	{Username: "alice", Password: "new"},
+	{Username: "bob", Password: domain.DesiredPasswordGenerate},
+	{Username: "carol"},
+}
+
+func TestPlan(t *testing.T) {
+	uc := NewUseCase(newRepo(), &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	plan, err := uc.Plan(context.Background(), desired, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plan.Count(domain.PlanCreate) != 1 || plan.Count(domain.PlanRotate) != 1 || plan.Count(domain.PlanDelete) != 0 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if len(plan.Unmanaged) != 1 || plan.Unmanaged[0] != "mallory" {
+		t.Fatalf("expected mallory to be unmanaged: %+v", plan)
+	}
+
+	pruned, err := uc.Plan(context.Background(), desired, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pruned.Count(domain.PlanDelete) != 1 || len(pruned.Unmanaged) != 0 {
+		t.Fatalf("expected mallory to be deleted with prune: %+v", pruned)
+	}
+}
+
+func TestPlanRejectsDuplicates(t *testing.T) {
+	uc := NewUseCase(newRepo(), &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	_, err := uc.Plan(context.Background(), []domain.DesiredUser{{Username: "a"}, {Username: "a"}}, false)
+	if !errors.Is(err, domain.ErrInvalidDesiredState) {
+		t.Fatalf("expected ErrInvalidDesiredState, got %v", err)
+	}
+}
+
+func TestPlanChecksNewUsernames(t *testing.T) {
+	uc := NewUseCase(newRepo(), &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	for _, want := range [][]domain.DesiredUser{
+		{{Username: "carol dave"}},
+		{{Username: "Carol"}},
+		{{Username: "carol"}, {Username: "Carol"}},
+	} {
+		if _, err := uc.Plan(context.Background(), want, false); !errors.Is(err, domain.ErrInvalidDesiredState) {
+			t.Fatalf("%+v: expected ErrInvalidDesiredState, got %v", want, err)
+		}
+	}
+}
+
+func TestPlanMatchesLiveUsersByPolicyKey(t *testing.T) {
+	legacy := &repoMock{users: []domain.User{{Username: "Carol", Password: "x"}}}
+	uc := NewUseCase(legacy, &restarterMock{}, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	plan, err := uc.Plan(context.Background(), []domain.DesiredUser{{Username: "carol", Password: "y"}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Items) != 1 || plan.Items[0].Action != domain.PlanRotate || plan.Items[0].Username != "Carol" {
+		t.Fatalf("expected the stored user to be rotated, not recreated: %+v", plan)
+	}
+}
+
+func TestApplyWritesOnceAndRestartsOnce(t *testing.T) {
+	repo := newRepo()
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, domain.UsernamePolicy{}, audit)
+
+	result, err := uc.Apply(context.Background(), desired, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.applyCalls != 1 || restarter.calls != 1 || len(repo.changes) != 3 {
+		t.Fatalf("expected single write/restart with 3 changes: apply=%d restart=%d changes=%+v", repo.applyCalls, restarter.calls, repo.changes)
+	}
+	if repo.changes[0].Action != domain.UserChangeRotate || repo.changes[0].User.Password != "new" {
+		t.Fatalf("expected fixed password rotation first: %+v", repo.changes[0])
+	}
+	if result.Passwords["carol"] == "" || result.Passwords["alice"] != "" {
+		t.Fatalf("only generated passwords should be reported: %+v", result.Passwords)
+	}
+	if len(audit.entries) != 3 {
+		t.Fatalf("expected audit entry per change: %+v", audit.entries)
+	}
+}
+
+func TestApplyNoDrift(t *testing.T) {
+	repo := &repoMock{users: []domain.User{{Username: "alice", Password: "new"}}}
+	restarter := &restarterMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+
+	result, err := uc.Apply(context.Background(), []domain.DesiredUser{{Username: "alice", Password: "new"}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Plan.HasChanges() || repo.applyCalls != 0 || restarter.calls != 0 {
+		t.Fatal("in-sync state must not write or restart")
+	}
+}
+
+func TestPlanAndApplyTags(t *testing.T) {
+	repo := &repoMock{users: []domain.User{
+		{Username: "alice", Password: "a", Tags: []string{"ops"}},
+		{Username: "bob", Password: "b", Tags: []string{"ops"}},
+		{Username: "carol", Password: "c", Tags: []string{"ops"}},
+	}}
+	restarter := &restarterMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, domain.UsernamePolicy{}, &auditMock{})
+	want := []domain.DesiredUser{
+		{Username: "alice", Password: "a", Tags: []string{"team-a", "ops"}},
+		{Username: "bob", Password: "b", Tags: []string{}},
+		{Username: "carol", Password: "c"},
+	}
+
+	result, err := uc.Apply(context.Background(), want, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Plan.Count(domain.PlanRetag) != 2 || len(result.Plan.Items) != 2 {
+		t.Fatalf("expected alice and bob to be retagged: %+v", result.Plan)
+	}
+	if len(repo.changes) != 2 || repo.changes[0].Action != domain.UserChangeTags ||
+		len(repo.changes[0].User.Tags) != 2 || repo.changes[0].User.Tags[0] != "ops" || len(repo.changes[1].User.Tags) != 0 {
+		t.Fatalf("unexpected changes: %+v", repo.changes)
+	}
+	if restarter.calls != 0 {
+		t.Fatalf("tag changes must not restart the service")
+	}
+}
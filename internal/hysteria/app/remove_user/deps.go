@@ -1,11 +1,21 @@
 package remove_user
 
-import "context"
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
 
 type UserRepository interface {
 	RemoveUser(ctx context.Context, username string) error
+	ListUsers(ctx context.Context) ([]string, error)
+	Checksum(ctx context.Context) (string, error)
 }
 
 type ServiceRestarter interface {
 	Restart(ctx context.Context) error
 }
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
@@ -2,6 +2,7 @@ package remove_user
 
 import (
 	"context"
+	"fmt"
 
 	"vpn/internal/hysteria/domain"
 )
@@ -9,16 +10,40 @@ import (
 type UseCase struct {
 	repo      UserRepository
 	restarter ServiceRestarter
+	usernames domain.UsernamePolicy
+	audit     AuditLog
 }
 
-func NewUseCase(repo UserRepository, restarter ServiceRestarter) *UseCase {
-	return &UseCase{repo: repo, restarter: restarter}
+func NewUseCase(repo UserRepository, restarter ServiceRestarter, usernames domain.UsernamePolicy, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, usernames: usernames, audit: audit}
 }
 
-func (u *UseCase) Execute(ctx context.Context, username string) error {
+// Execute removes the user; the name is matched against the stored ones
+// with the username policy, so Alice removes alice unless names are case
+// sensitive.
+func (u *UseCase) Execute(ctx context.Context, username string) (err error) {
 	if username == "" {
 		return domain.ErrEmptyUsername
 	}
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.repo.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionRemoveUser, username, hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.repo.Checksum(ctx)
+	if err != nil {
+		return err
+	}
+	existing, err := u.repo.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	username = u.usernames.Lookup(username, existing)
+
 	if err := u.repo.RemoveUser(ctx, username); err != nil {
 		return err
 	}
@@ -2,16 +2,32 @@ package remove_user
 
 import (
 	"context"
+	"errors"
 	"testing"
+
+	"vpn/internal/hysteria/domain"
 )
 
-type repoMock struct{ called bool }
+type repoMock struct {
+	called      bool
+	removed     string
+	checksumErr error
+}
 
-func (m *repoMock) RemoveUser(_ context.Context, _ string) error {
+func (m *repoMock) RemoveUser(_ context.Context, username string) error {
 	m.called = true
+	m.removed = username
 	return nil
 }
 
+func (m *repoMock) ListUsers(context.Context) ([]string, error) {
+	return []string{"alice", "Bob"}, nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", m.checksumErr
+}
+
 type restarterMock struct{ called bool }
 
 func (m *restarterMock) Restart(context.Context) error {
@@ -19,10 +35,21 @@ func (m *restarterMock) Restart(context.Context) error {
 	return nil
 }
 
+type auditMock struct {
+	entries []domain.AuditEntry
+	err     error
+}
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return m.err
+}
+
 func TestExecute(t *testing.T) {
 	repo := &repoMock{}
 	restarter := &restarterMock{}
-	uc := NewUseCase(repo, restarter)
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, domain.DefaultUsernamePolicy(), audit)
 
 	if err := uc.Execute(context.Background(), "alice"); err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -30,4 +57,52 @@ func TestExecute(t *testing.T) {
 	if !repo.called || !restarter.called {
 		t.Fatal("expected repo and restarter calls")
 	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditActionRemoveUser || audit.entries[0].Username != "alice" {
+		t.Fatalf("unexpected audit entries: %+v", audit.entries)
+	}
+}
+
+func TestExecuteFailsWhenAuditFails(t *testing.T) {
+	uc := NewUseCase(&repoMock{}, &restarterMock{}, domain.DefaultUsernamePolicy(), &auditMock{err: errors.New("disk full")})
+
+	if err := uc.Execute(context.Background(), "alice"); err == nil {
+		t.Fatal("expected audit error")
+	}
+}
+
+func TestExecuteAuditsFailedChecksum(t *testing.T) {
+	repo := &repoMock{checksumErr: errors.New("ssh: connection refused")}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, &restarterMock{}, domain.DefaultUsernamePolicy(), audit)
+
+	if err := uc.Execute(context.Background(), "alice"); err == nil {
+		t.Fatal("expected the checksum error")
+	}
+	if repo.called {
+		t.Fatal("user removed without a checksum")
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Result != domain.AuditResultError {
+		t.Fatalf("expected a failed audit entry, got %+v", audit.entries)
+	}
+}
+
+func TestExecuteFoldsCase(t *testing.T) {
+	repo := &repoMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, &restarterMock{}, domain.DefaultUsernamePolicy(), audit)
+
+	if err := uc.Execute(context.Background(), " Alice "); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.removed != "alice" || audit.entries[0].Username != "alice" {
+		t.Fatalf("expected the stored name alice, got %q", repo.removed)
+	}
+	if err := uc.Execute(context.Background(), "Bob"); err != nil || repo.removed != "Bob" {
+		t.Fatalf("expected the exact legacy name Bob, got %q %v", repo.removed, err)
+	}
+
+	uc = NewUseCase(repo, &restarterMock{}, domain.UsernamePolicy{CaseSensitive: true}, &auditMock{})
+	if err := uc.Execute(context.Background(), "ALICE"); err != nil || repo.removed != "ALICE" {
+		t.Fatalf("case-sensitive names must not fold, got %q %v", repo.removed, err)
+	}
 }
@@ -6,20 +6,29 @@ package remove_user
 import (
 	"github.com/google/wire"
 	appconfig "vpn/internal/config"
-	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/auditlog"
 	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/hysteria/infra/userstore"
 )
 
 func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
 	wire.Build(
 		provideConfigPath,
-		provideServiceName,
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		provideService,
 		provideRestartEnabled,
 		provideRestartCommand,
-		configrepo.NewRepository,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		provideUsernamePolicy,
+		userstore.NewRepository,
 		servicectl.NewRestarter,
-		wire.Bind(new(UserRepository), new(*configrepo.Repository)),
+		auditlog.NewLog,
+		wire.Bind(new(UserRepository), new(*userstore.Repository)),
 		wire.Bind(new(ServiceRestarter), new(*servicectl.Restarter)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
 		NewUseCase,
 	)
 	return nil, nil
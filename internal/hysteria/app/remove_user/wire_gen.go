@@ -8,17 +8,25 @@ package remove_user
 
 import (
 	appconfig "vpn/internal/config"
-	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/auditlog"
 	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/hysteria/infra/userstore"
 )
 
 func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
-	string2 := provideConfigPath(cfg)
-	repository := configrepo.NewRepository(string2)
+	string2 := provideUserBackend(cfg)
+	string3 := provideConfigPath(cfg)
+	string4 := provideUserStorePath(cfg)
+	string5 := provideUserDBPath(cfg)
+	repository := userstore.NewRepository(string2, string3, string4, string5)
 	bool2 := provideRestartEnabled(cfg)
-	string3 := provideServiceName(cfg)
-	string4 := provideRestartCommand(cfg)
-	restarter := servicectl.NewRestarter(bool2, string3, string4)
-	useCase := NewUseCase(repository, restarter)
+	serviceUnit := provideService(cfg)
+	string6 := provideRestartCommand(cfg)
+	restarter := servicectl.NewRestarter(bool2, serviceUnit, string6)
+	bool3 := provideAuditEnabled(cfg)
+	string7 := provideAuditLogPath(cfg)
+	log := auditlog.NewLog(bool3, string7)
+	usernamePolicy := provideUsernamePolicy(cfg)
+	useCase := NewUseCase(repository, restarter, usernamePolicy, log)
 	return useCase, nil
 }
@@ -0,0 +1,7 @@
+package resolve_config_path
+
+import "context"
+
+type PathResolver interface {
+	Resolve(ctx context.Context) (string, error)
+}
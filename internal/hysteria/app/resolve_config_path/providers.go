@@ -0,0 +1,12 @@
+package resolve_config_path
+
+import (
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/domain"
+)
+
+func provideConfigPath(cfg appconfig.Config) string { return cfg.HysteriaConfigPath }
+
+func provideService(cfg appconfig.Config) domain.ServiceUnit {
+	return cfg.Service()
+}
@@ -0,0 +1,18 @@
+package resolve_config_path
+
+import "context"
+
+type UseCase struct {
+	resolver PathResolver
+}
+
+func NewUseCase(resolver PathResolver) *UseCase {
+	return &UseCase{resolver: resolver}
+}
+
+// Execute returns the Hysteria config path the CLI should edit. In a
+// container hysteria_config_path is the path Hysteria reads inside it, and
+// the host file mounted there is returned; otherwise the path is unchanged.
+func (u *UseCase) Execute(ctx context.Context) (string, error) {
+	return u.resolver.Resolve(ctx)
+}
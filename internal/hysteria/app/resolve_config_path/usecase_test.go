@@ -0,0 +1,29 @@
+package resolve_config_path
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type resolverMock struct {
+	path string
+	err  error
+}
+
+func (m resolverMock) Resolve(context.Context) (string, error) {
+	return m.path, m.err
+}
+
+func TestExecute(t *testing.T) {
+	uc := NewUseCase(resolverMock{path: "/srv/hysteria/config.yaml"})
+	got, err := uc.Execute(context.Background())
+	if err != nil || got != "/srv/hysteria/config.yaml" {
+		t.Fatalf("unexpected path %q: %v", got, err)
+	}
+
+	uc = NewUseCase(resolverMock{err: errors.New("no such container")})
+	if _, err := uc.Execute(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+}
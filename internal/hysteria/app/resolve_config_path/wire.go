@@ -0,0 +1,21 @@
+//go:build wireinject
+// +build wireinject
+
+package resolve_config_path
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/servicectl"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideService,
+		provideConfigPath,
+		servicectl.NewConfigPathResolver,
+		wire.Bind(new(PathResolver), new(*servicectl.ConfigPathResolver)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -8,6 +8,8 @@ import (
 
 type UserRepository interface {
 	RotatePassword(ctx context.Context, user domain.User) error
+	ListUsers(ctx context.Context) ([]string, error)
+	Checksum(ctx context.Context) (string, error)
 }
 
 type ServiceRestarter interface {
@@ -16,4 +18,9 @@ type ServiceRestarter interface {
 
 type PasswordGenerator interface {
 	Generate() (string, error)
+	Check(password string) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
 }
@@ -2,6 +2,7 @@ package rotate_password
 
 import (
 	"context"
+	"fmt"
 
 	"vpn/internal/hysteria/domain"
 )
@@ -10,14 +11,42 @@ type UseCase struct {
 	repo      UserRepository
 	restarter ServiceRestarter
 	passwords PasswordGenerator
+	usernames domain.UsernamePolicy
+	audit     AuditLog
 }
 
-func NewUseCase(repo UserRepository, restarter ServiceRestarter, passwords PasswordGenerator) *UseCase {
-	return &UseCase{repo: repo, restarter: restarter, passwords: passwords}
+func NewUseCase(repo UserRepository, restarter ServiceRestarter, passwords PasswordGenerator, usernames domain.UsernamePolicy, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, passwords: passwords, usernames: usernames, audit: audit}
 }
 
 func (u *UseCase) Execute(ctx context.Context, username string) (string, error) {
-	password, err := u.passwords.Generate()
+	return u.ExecuteWithPassword(ctx, username, "")
+}
+
+// ExecuteWithPassword sets the supplied password, checked against the
+// policy, or a generated one when it is empty. The name is matched against
+// the stored ones with the username policy.
+func (u *UseCase) ExecuteWithPassword(ctx context.Context, username, supplied string) (password string, err error) {
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.repo.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionRotatePassword, username, hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.repo.Checksum(ctx)
+	if err != nil {
+		return "", err
+	}
+	existing, err := u.repo.ListUsers(ctx)
+	if err != nil {
+		return "", err
+	}
+	username = u.usernames.Lookup(username, existing)
+
+	password, err = choosePassword(u.passwords, supplied)
 	if err != nil {
 		return "", err
 	}
@@ -33,3 +62,13 @@ func (u *UseCase) Execute(ctx context.Context, username string) (string, error)
 	}
 	return password, nil
 }
+
+func choosePassword(passwords PasswordGenerator, supplied string) (string, error) {
+	if supplied == "" {
+		return passwords.Generate()
+	}
+	if err := passwords.Check(supplied); err != nil {
+		return "", err
+	}
+	return supplied, nil
+}
@@ -2,6 +2,7 @@ package rotate_password
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"vpn/internal/hysteria/domain"
@@ -18,6 +19,14 @@ func (m *repoMock) RotatePassword(_ context.Context, user domain.User) error {
 	return nil
 }
 
+func (m *repoMock) ListUsers(context.Context) ([]string, error) {
+	return []string{"alice"}, nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
 type restarterMock struct{ called bool }
 
 func (m *restarterMock) Restart(context.Context) error {
@@ -31,12 +40,29 @@ func (passwordGeneratorMock) Generate() (string, error) {
 	return "Abc123Abc123Abc123Abc123Abc123Ab", nil
 }
 
+var errWeakPassword = errors.New("weak password")
+
+func (passwordGeneratorMock) Check(password string) error {
+	if password == "weak" {
+		return errWeakPassword
+	}
+	return nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
 func TestExecute(t *testing.T) {
 	repo := &repoMock{}
 	restarter := &restarterMock{}
-	uc := NewUseCase(repo, restarter, passwordGeneratorMock{})
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, domain.DefaultUsernamePolicy(), audit)
 
-	password, err := uc.Execute(context.Background(), "alice")
+	password, err := uc.Execute(context.Background(), "Alice")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -46,4 +72,29 @@ func TestExecute(t *testing.T) {
 	if !repo.called || !restarter.called {
 		t.Fatal("expected repo and restarter calls")
 	}
+	if repo.user.Username != "alice" {
+		t.Fatalf("expected the stored name alice, got %q", repo.user.Username)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditActionRotatePassword {
+		t.Fatalf("unexpected audit entries: %+v", audit.entries)
+	}
+}
+
+func TestExecuteWithSuppliedPassword(t *testing.T) {
+	repo := &repoMock{}
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.DefaultUsernamePolicy(), &auditMock{})
+
+	password, err := uc.ExecuteWithPassword(context.Background(), "alice", "migrated-secret")
+	if err != nil || password != "migrated-secret" {
+		t.Fatalf("unexpected result: %q %v", password, err)
+	}
+
+	repo = &repoMock{}
+	uc = NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, domain.DefaultUsernamePolicy(), &auditMock{})
+	if _, err := uc.ExecuteWithPassword(context.Background(), "alice", "weak"); !errors.Is(err, errWeakPassword) {
+		t.Fatalf("expected policy error, got %v", err)
+	}
+	if repo.called {
+		t.Fatal("rejected password must not reach the repository")
+	}
 }
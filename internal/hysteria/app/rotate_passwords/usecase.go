@@ -0,0 +1,113 @@
+package rotate_passwords
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type Selector struct {
+	All       bool
+	Tag       string
+	Usernames []string
+}
+
+type UseCase struct {
+	repo      UserRepository
+	restarter ServiceRestarter
+	passwords PasswordGenerator
+	audit     AuditLog
+}
+
+func NewUseCase(repo UserRepository, restarter ServiceRestarter, passwords PasswordGenerator, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, passwords: passwords, audit: audit}
+}
+
+func (u *UseCase) Execute(ctx context.Context, selector Selector) ([]domain.User, error) {
+	users, err := u.repo.Users(ctx)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectUsers(users, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]domain.UserChange, 0, len(selected))
+	for _, user := range selected {
+		password, err := u.passwords.Generate()
+		if err != nil {
+			return nil, err
+		}
+		rotated, err := domain.NewUser(user.Username, password)
+		if err != nil {
+			return nil, err
+		}
+		rotated.Tags = user.Tags
+		changes = append(changes, domain.UserChange{Action: domain.UserChangeRotate, User: rotated})
+	}
+
+	// A failed checksum is recorded for every change like a failed write.
+	hashBefore, applyErr := u.repo.Checksum(ctx)
+	if applyErr == nil {
+		applyErr = u.repo.ApplyChanges(ctx, changes)
+	}
+	if applyErr == nil {
+		applyErr = u.restarter.Restart(ctx)
+	}
+	hashAfter, _ := u.repo.Checksum(ctx)
+
+	var auditErr error
+	for _, change := range changes {
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionRotatePassword, change.User.Username, hashBefore, hashAfter, applyErr)
+		auditErr = errors.Join(auditErr, u.audit.Record(ctx, entry))
+	}
+	if applyErr != nil {
+		return nil, applyErr
+	}
+
+	rotated := make([]domain.User, len(changes))
+	for i, change := range changes {
+		rotated[i] = change.User
+	}
+	if auditErr != nil {
+		return rotated, fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+	}
+	return rotated, nil
+}
+
+func selectUsers(users []domain.User, selector Selector) ([]domain.User, error) {
+	byName := make(map[string]domain.User, len(users))
+	for _, user := range users {
+		byName[user.Username] = user
+	}
+
+	var selected []domain.User
+	picked := map[string]bool{}
+	pick := func(user domain.User) {
+		if !picked[user.Username] {
+			picked[user.Username] = true
+			selected = append(selected, user)
+		}
+	}
+
+	for _, user := range users {
+		if selector.All || (selector.Tag != "" && user.HasTag(selector.Tag)) {
+			pick(user)
+		}
+	}
+	for _, username := range selector.Usernames {
+		user, ok := byName[username]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", username, domain.ErrUserNotFound)
+		}
+		pick(user)
+	}
+
+	if len(selected) == 0 {
+		return nil, domain.ErrNoUsersSelected
+	}
+	return selected, nil
+}
@@ -0,0 +1,107 @@
+package rotate_passwords
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct {
+	users      []domain.User
+	applyCalls int
+	changes    []domain.UserChange
+}
+
+func (m *repoMock) Users(context.Context) ([]domain.User, error) {
+	return m.users, nil
+}
+
+func (m *repoMock) ApplyChanges(_ context.Context, changes []domain.UserChange) error {
+	m.applyCalls++
+	m.changes = changes
+	return nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct{ calls int }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.calls++
+	return nil
+}
+
+type passwordGeneratorMock struct{}
+
+func (passwordGeneratorMock) Generate() (string, error) {
+	return "Abc123Abc123Abc123Abc123Abc123Ab", nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func newRepo() *repoMock {
+	return &repoMock{users: []domain.User{
+		{Username: "alice", Password: "1", Tags: []string{"ops"}},
+		{Username: "bob", Password: "2"},
+		{Username: "carol", Password: "3", Tags: []string{"ops", "team-a"}},
+	}}
+}
+
+func TestExecuteAllRestartsOnce(t *testing.T) {
+	repo := newRepo()
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, audit)
+
+	rotated, err := uc.Execute(context.Background(), Selector{All: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rotated) != 3 || repo.applyCalls != 1 || restarter.calls != 1 {
+		t.Fatalf("expected one batch and one restart: rotated=%d apply=%d restart=%d", len(rotated), repo.applyCalls, restarter.calls)
+	}
+	if len(audit.entries) != 3 {
+		t.Fatalf("expected audit entry per user, got %+v", audit.entries)
+	}
+}
+
+func TestExecuteByTag(t *testing.T) {
+	repo := newRepo()
+	uc := NewUseCase(repo, &restarterMock{}, passwordGeneratorMock{}, &auditMock{})
+
+	rotated, err := uc.Execute(context.Background(), Selector{Tag: "ops"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rotated) != 2 || rotated[0].Username != "alice" || rotated[1].Username != "carol" {
+		t.Fatalf("unexpected rotated users: %+v", rotated)
+	}
+	if !rotated[1].HasTag("team-a") {
+		t.Fatalf("tags must be preserved: %+v", rotated[1])
+	}
+}
+
+func TestExecuteNothingSelected(t *testing.T) {
+	repo := newRepo()
+	restarter := &restarterMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, &auditMock{})
+
+	if _, err := uc.Execute(context.Background(), Selector{Tag: "missing"}); !errors.Is(err, domain.ErrNoUsersSelected) {
+		t.Fatalf("expected ErrNoUsersSelected, got %v", err)
+	}
+	if _, err := uc.Execute(context.Background(), Selector{Usernames: []string{"ghost"}}); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+	if repo.applyCalls != 0 || restarter.calls != 0 {
+		t.Fatal("nothing should be applied")
+	}
+}
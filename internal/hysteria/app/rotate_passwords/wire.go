@@ -0,0 +1,38 @@
+//go:build wireinject
+// +build wireinject
+
+package rotate_passwords
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/hysteria/infra/userstore"
+	utilpasswordgen "vpn/internal/utils/passwordgen"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		provideService,
+		provideRestartEnabled,
+		provideRestartCommand,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		providePasswordPolicy,
+		userstore.NewRepository,
+		servicectl.NewRestarter,
+		auditlog.NewLog,
+		utilpasswordgen.NewGenerator,
+		wire.Bind(new(UserRepository), new(*userstore.Repository)),
+		wire.Bind(new(ServiceRestarter), new(*servicectl.Restarter)),
+		wire.Bind(new(PasswordGenerator), new(*utilpasswordgen.Generator)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
+		NewUseCase,
+	)
+	return nil, nil
+}
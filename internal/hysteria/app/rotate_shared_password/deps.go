@@ -0,0 +1,24 @@
+package rotate_shared_password
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type AuthRepository interface {
+	SetSharedPassword(ctx context.Context, password string) error
+	Checksum(ctx context.Context) (string, error)
+}
+
+type ServiceRestarter interface {
+	Restart(ctx context.Context) error
+}
+
+type PasswordGenerator interface {
+	Generate() (string, error)
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
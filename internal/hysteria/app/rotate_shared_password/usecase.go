@@ -0,0 +1,47 @@
+package rotate_shared_password
+
+import (
+	"context"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UseCase struct {
+	repo      AuthRepository
+	restarter ServiceRestarter
+	passwords PasswordGenerator
+	audit     AuditLog
+}
+
+func NewUseCase(repo AuthRepository, restarter ServiceRestarter, passwords PasswordGenerator, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, passwords: passwords, audit: audit}
+}
+
+func (u *UseCase) Execute(ctx context.Context) (password string, err error) {
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.repo.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionRotateShared, "", hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.repo.Checksum(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	password, err = u.passwords.Generate()
+	if err != nil {
+		return "", err
+	}
+	if err := u.repo.SetSharedPassword(ctx, password); err != nil {
+		return "", err
+	}
+	if err := u.restarter.Restart(ctx); err != nil {
+		return "", err
+	}
+	return password, nil
+}
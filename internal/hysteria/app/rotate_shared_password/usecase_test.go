@@ -0,0 +1,81 @@
+package rotate_shared_password
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct {
+	password string
+	err      error
+}
+
+func (m *repoMock) SetSharedPassword(_ context.Context, password string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.password = password
+	return nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct{ called bool }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.called = true
+	return nil
+}
+
+type passwordGeneratorMock struct{}
+
+func (passwordGeneratorMock) Generate() (string, error) {
+	return "Abc123Abc123Abc123Abc123Abc123Ab", nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestExecute(t *testing.T) {
+	repo := &repoMock{}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, audit)
+
+	password, err := uc.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password == "" || repo.password != password || !restarter.called {
+		t.Fatalf("expected shared password to be stored and service restarted")
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditActionRotateShared {
+		t.Fatalf("unexpected audit entries: %+v", audit.entries)
+	}
+}
+
+func TestExecuteWrongMode(t *testing.T) {
+	repo := &repoMock{err: domain.ErrUnsupportedAuthMode}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, audit)
+
+	if _, err := uc.Execute(context.Background()); !errors.Is(err, domain.ErrUnsupportedAuthMode) {
+		t.Fatalf("expected ErrUnsupportedAuthMode, got %v", err)
+	}
+	if restarter.called {
+		t.Fatal("restart must not happen on failure")
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Result != domain.AuditResultError {
+		t.Fatalf("expected failed audit entry: %+v", audit.entries)
+	}
+}
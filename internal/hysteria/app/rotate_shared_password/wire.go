@@ -0,0 +1,35 @@
+//go:build wireinject
+// +build wireinject
+
+package rotate_shared_password
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/servicectl"
+	utilpasswordgen "vpn/internal/utils/passwordgen"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideService,
+		provideRestartEnabled,
+		provideRestartCommand,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		providePasswordPolicy,
+		configrepo.NewRepository,
+		servicectl.NewRestarter,
+		auditlog.NewLog,
+		utilpasswordgen.NewGenerator,
+		wire.Bind(new(AuthRepository), new(*configrepo.Repository)),
+		wire.Bind(new(ServiceRestarter), new(*servicectl.Restarter)),
+		wire.Bind(new(PasswordGenerator), new(*utilpasswordgen.Generator)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -0,0 +1,7 @@
+package serve_auth
+
+import "context"
+
+type Server interface {
+	ListenAndServe(ctx context.Context, addr string) error
+}
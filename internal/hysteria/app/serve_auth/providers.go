@@ -0,0 +1,20 @@
+package serve_auth
+
+import (
+	"log"
+	"os"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/authenticate_user"
+	"vpn/internal/hysteria/infra/authserver"
+)
+
+func provideUserStorePath(cfg appconfig.Config) string { return cfg.UserStorePath }
+
+func provideAuthenticate(uc *authenticate_user.UseCase) authserver.AuthenticateFunc {
+	return uc.Execute
+}
+
+func provideLogger() *log.Logger {
+	return log.New(os.Stderr, "auth-server: ", log.LstdFlags)
+}
@@ -0,0 +1,23 @@
+package serve_auth
+
+import (
+	"context"
+	"errors"
+)
+
+type UseCase struct {
+	server Server
+}
+
+func NewUseCase(server Server) *UseCase {
+	return &UseCase{server: server}
+}
+
+// Execute serves the Hysteria auth.type: http callback on listen until ctx
+// is canceled.
+func (u *UseCase) Execute(ctx context.Context, listen string) error {
+	if listen == "" {
+		return errors.New("listen address is required")
+	}
+	return u.server.ListenAndServe(ctx, listen)
+}
@@ -0,0 +1,33 @@
+package serve_auth
+
+import (
+	"context"
+	"testing"
+)
+
+type serverMock struct{ addr string }
+
+func (m *serverMock) ListenAndServe(_ context.Context, addr string) error {
+	m.addr = addr
+	return nil
+}
+
+func TestExecute(t *testing.T) {
+	server := &serverMock{}
+	if err := NewUseCase(server).Execute(context.Background(), "127.0.0.1:18989"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server.addr != "127.0.0.1:18989" {
+		t.Fatalf("unexpected listen address %q", server.addr)
+	}
+}
+
+func TestExecuteRequiresListen(t *testing.T) {
+	server := &serverMock{}
+	if err := NewUseCase(server).Execute(context.Background(), ""); err == nil {
+		t.Fatal("expected error")
+	}
+	if server.addr != "" {
+		t.Fatal("server must not start without an address")
+	}
+}
@@ -0,0 +1,27 @@
+//go:build wireinject
+// +build wireinject
+
+package serve_auth
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/app/authenticate_user"
+	"vpn/internal/hysteria/infra/authserver"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideUserStorePath,
+		userstore.NewFileStore,
+		wire.Bind(new(authenticate_user.UserStore), new(*userstore.FileStore)),
+		authenticate_user.NewUseCase,
+		provideAuthenticate,
+		provideLogger,
+		authserver.NewServer,
+		wire.Bind(new(Server), new(*authserver.Server)),
+		NewUseCase,
+	)
+	return nil, nil
+}
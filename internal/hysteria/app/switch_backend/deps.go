@@ -0,0 +1,32 @@
+package switch_backend
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type HysteriaConfig interface {
+	AuthInfo(ctx context.Context) (domain.AuthInfo, error)
+	Users(ctx context.Context) ([]domain.User, error)
+	SwitchToHTTPAuth(ctx context.Context, url string) error
+	SwitchToUserpass(ctx context.Context, users []domain.User) error
+	Checksum(ctx context.Context) (string, error)
+}
+
+type UserStore interface {
+	Users(ctx context.Context) ([]domain.User, error)
+	ApplyChanges(ctx context.Context, changes []domain.UserChange) error
+}
+
+type BackendSettings interface {
+	SetUserBackend(backend string) error
+}
+
+type ServiceRestarter interface {
+	Restart(ctx context.Context) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
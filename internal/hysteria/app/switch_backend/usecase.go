@@ -0,0 +1,149 @@
+package switch_backend
+
+import (
+	"context"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const (
+	BackendYAML = "yaml"
+	BackendHTTP = "http"
+)
+
+type Result struct {
+	Backend string
+	Users   int
+}
+
+type UseCase struct {
+	hysteria  HysteriaConfig
+	store     UserStore
+	settings  BackendSettings
+	restarter ServiceRestarter
+	audit     AuditLog
+}
+
+func NewUseCase(hysteria HysteriaConfig, store UserStore, settings BackendSettings, restarter ServiceRestarter, audit AuditLog) *UseCase {
+	return &UseCase{hysteria: hysteria, store: store, settings: settings, restarter: restarter, audit: audit}
+}
+
+// Execute moves users between auth.userpass and the tool's user store and
+// points Hysteria at the matching auth mode. Switching to http makes the
+// store mirror auth.userpass exactly, so users removed while on yaml do not
+// come back. Users are copied before the Hysteria config is rewritten, so a
+// failure never leaves the server without credentials.
+func (u *UseCase) Execute(ctx context.Context, to, authURL string) (result Result, err error) {
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.hysteria.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionSwitchBackend, "", hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.hysteria.Checksum(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	switch to {
+	case BackendHTTP:
+		result, err = u.toHTTP(ctx, authURL)
+	case BackendYAML:
+		result, err = u.toYAML(ctx)
+	default:
+		return Result{}, fmt.Errorf("%w: backend %q (allowed: yaml|http)", domain.ErrUnsupportedAuthMode, to)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	if err := u.settings.SetUserBackend(to); err != nil {
+		return Result{}, fmt.Errorf("save cli config: %w", err)
+	}
+	if err := u.restarter.Restart(ctx); err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
+
+func (u *UseCase) toHTTP(ctx context.Context, authURL string) (Result, error) {
+	if authURL == "" {
+		return Result{}, fmt.Errorf("auth server url is required")
+	}
+	info, err := u.hysteria.AuthInfo(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Without auth.userpass there is nothing to mirror, so the store is kept.
+	if info.Mode != domain.AuthModeUserpass {
+		if err := u.hysteria.SwitchToHTTPAuth(ctx, authURL); err != nil {
+			return Result{}, err
+		}
+		existing, err := u.store.Users(ctx)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Backend: BackendHTTP, Users: len(existing)}, nil
+	}
+
+	users, err := u.hysteria.Users(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	existing, err := u.store.Users(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	changes := mirrorChanges(existing, users)
+	if len(changes) > 0 {
+		if err := u.store.ApplyChanges(ctx, changes); err != nil {
+			return Result{}, fmt.Errorf("import users into store: %w", err)
+		}
+	}
+	if err := u.hysteria.SwitchToHTTPAuth(ctx, authURL); err != nil {
+		return Result{}, err
+	}
+	return Result{Backend: BackendHTTP, Users: len(users)}, nil
+}
+
+func (u *UseCase) toYAML(ctx context.Context) (Result, error) {
+	users, err := u.store.Users(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := u.hysteria.SwitchToUserpass(ctx, users); err != nil {
+		return Result{}, err
+	}
+	return Result{Backend: BackendYAML, Users: len(users)}, nil
+}
+
+// mirrorChanges returns the store changes that make existing equal to want:
+// missing users are added, changed passwords rotated and extra users removed.
+func mirrorChanges(existing, want []domain.User) []domain.UserChange {
+	stored := make(map[string]domain.User, len(existing))
+	for _, user := range existing {
+		stored[user.Username] = user
+	}
+
+	var changes []domain.UserChange
+	for _, user := range want {
+		current, ok := stored[user.Username]
+		switch {
+		case !ok:
+			changes = append(changes, domain.UserChange{Action: domain.UserChangeAdd, User: user})
+		case current.Password != user.Password:
+			changes = append(changes, domain.UserChange{Action: domain.UserChangeRotate, User: user})
+		}
+		delete(stored, user.Username)
+	}
+	for _, user := range existing {
+		if _, ok := stored[user.Username]; ok {
+			changes = append(changes, domain.UserChange{Action: domain.UserChangeRemove, User: domain.User{Username: user.Username}})
+		}
+	}
+	return changes
+}
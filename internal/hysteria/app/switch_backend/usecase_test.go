@@ -0,0 +1,191 @@
+package switch_backend
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type hysteriaMock struct {
+	mode     string
+	users    []domain.User
+	httpURL  string
+	restored []domain.User
+	err      error
+}
+
+func (m *hysteriaMock) AuthInfo(context.Context) (domain.AuthInfo, error) {
+	return domain.AuthInfo{Mode: m.mode}, nil
+}
+
+func (m *hysteriaMock) Users(context.Context) ([]domain.User, error) {
+	return m.users, nil
+}
+
+func (m *hysteriaMock) SwitchToHTTPAuth(_ context.Context, url string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.httpURL = url
+	m.mode = domain.AuthModeHTTP
+	return nil
+}
+
+func (m *hysteriaMock) SwitchToUserpass(_ context.Context, users []domain.User) error {
+	m.restored = users
+	m.mode = domain.AuthModeUserpass
+	return nil
+}
+
+func (m *hysteriaMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type storeMock struct {
+	users   []domain.User
+	changes []domain.UserChange
+}
+
+func (m *storeMock) Users(context.Context) ([]domain.User, error) {
+	return m.users, nil
+}
+
+func (m *storeMock) ApplyChanges(_ context.Context, changes []domain.UserChange) error {
+	m.changes = changes
+	for _, change := range changes {
+		i := slices.IndexFunc(m.users, func(u domain.User) bool { return u.Username == change.User.Username })
+		switch change.Action {
+		case domain.UserChangeAdd:
+			m.users = append(m.users, change.User)
+		case domain.UserChangeRotate:
+			m.users[i] = change.User
+		case domain.UserChangeRemove:
+			m.users = slices.Delete(m.users, i, i+1)
+		}
+	}
+	return nil
+}
+
+type settingsMock struct{ backend string }
+
+func (m *settingsMock) SetUserBackend(backend string) error {
+	m.backend = backend
+	return nil
+}
+
+type restarterMock struct{ calls int }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.calls++
+	return nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestExecuteToHTTP(t *testing.T) {
+	hysteria := &hysteriaMock{mode: domain.AuthModeUserpass, users: []domain.User{
+		{Username: "alice", Password: "a"},
+		{Username: "bob", Password: "b"},
+	}}
+	store := &storeMock{users: []domain.User{{Username: "bob", Password: "stale"}}}
+	settings := &settingsMock{}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(hysteria, store, settings, restarter, audit)
+
+	result, err := uc.Execute(context.Background(), BackendHTTP, "http://127.0.0.1:18989/auth")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Users != 2 || hysteria.httpURL == "" || settings.backend != BackendHTTP || restarter.calls != 1 {
+		t.Fatalf("unexpected switch: %+v %+v %+v", result, hysteria, settings)
+	}
+	if len(store.changes) != 2 || store.changes[0].Action != domain.UserChangeAdd || store.changes[1].Action != domain.UserChangeRotate {
+		t.Fatalf("expected alice added and bob synced: %+v", store.changes)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditActionSwitchBackend {
+		t.Fatalf("unexpected audit: %+v", audit.entries)
+	}
+}
+
+func TestExecuteToHTTPKeepsBackendOnFailure(t *testing.T) {
+	hysteria := &hysteriaMock{mode: domain.AuthModeUserpass, err: errors.New("write failed")}
+	settings := &settingsMock{}
+	restarter := &restarterMock{}
+	uc := NewUseCase(hysteria, &storeMock{}, settings, restarter, &auditMock{})
+
+	if _, err := uc.Execute(context.Background(), BackendHTTP, "http://x/auth"); err == nil {
+		t.Fatal("expected error")
+	}
+	if settings.backend != "" || restarter.calls != 0 {
+		t.Fatal("cli config and service must be untouched on failure")
+	}
+}
+
+func TestExecuteToYAML(t *testing.T) {
+	hysteria := &hysteriaMock{mode: domain.AuthModeHTTP}
+	store := &storeMock{users: []domain.User{{Username: "alice", Password: "a"}}}
+	settings := &settingsMock{}
+	uc := NewUseCase(hysteria, store, settings, &restarterMock{}, &auditMock{})
+
+	result, err := uc.Execute(context.Background(), BackendYAML, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Users != 1 || len(hysteria.restored) != 1 || settings.backend != BackendYAML {
+		t.Fatalf("unexpected switch back: %+v %+v", result, hysteria)
+	}
+}
+
+func TestExecuteRoundTripDropsRemovedUsers(t *testing.T) {
+	hysteria := &hysteriaMock{mode: domain.AuthModeHTTP}
+	store := &storeMock{users: []domain.User{
+		{Username: "alice", Password: "a"},
+		{Username: "bob", Password: "b"},
+	}}
+	uc := NewUseCase(hysteria, store, &settingsMock{}, &restarterMock{}, &auditMock{})
+	ctx := context.Background()
+
+	if _, err := uc.Execute(ctx, BackendYAML, ""); err != nil {
+		t.Fatalf("switch to yaml: %v", err)
+	}
+	// remove-user on the yaml backend edits auth.userpass only.
+	hysteria.users = slices.DeleteFunc(slices.Clone(hysteria.restored), func(u domain.User) bool { return u.Username == "bob" })
+
+	result, err := uc.Execute(ctx, BackendHTTP, "http://127.0.0.1:18989/auth")
+	if err != nil {
+		t.Fatalf("switch to http: %v", err)
+	}
+	if result.Users != 1 || len(store.users) != 1 || store.users[0].Username != "alice" {
+		t.Fatalf("expected the store to hold only alice: %+v %+v", result, store.users)
+	}
+}
+
+func TestExecuteToHTTPKeepsStoreWithoutUserpass(t *testing.T) {
+	hysteria := &hysteriaMock{mode: domain.AuthModeHTTP}
+	store := &storeMock{users: []domain.User{{Username: "alice", Password: "a"}}}
+	uc := NewUseCase(hysteria, store, &settingsMock{}, &restarterMock{}, &auditMock{})
+
+	result, err := uc.Execute(context.Background(), BackendHTTP, "http://127.0.0.1:18989/auth")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Users != 1 || len(store.users) != 1 || store.changes != nil {
+		t.Fatalf("store must be untouched: %+v %+v", result, store)
+	}
+}
+
+func TestExecuteRejectsUnknownBackend(t *testing.T) {
+	uc := NewUseCase(&hysteriaMock{}, &storeMock{}, &settingsMock{}, &restarterMock{}, &auditMock{})
+	if _, err := uc.Execute(context.Background(), "ldap", ""); !errors.Is(err, domain.ErrUnsupportedAuthMode) {
+		t.Fatalf("expected ErrUnsupportedAuthMode, got %v", err)
+	}
+}
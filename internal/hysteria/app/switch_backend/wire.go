@@ -0,0 +1,37 @@
+//go:build wireinject
+// +build wireinject
+
+package switch_backend
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+	"vpn/internal/hysteria/infra/configrepo"
+	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		provideUserStorePath,
+		provideService,
+		provideRestartEnabled,
+		provideRestartCommand,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		configrepo.NewRepository,
+		userstore.NewFileStore,
+		appconfig.NewBackendWriter,
+		servicectl.NewRestarter,
+		auditlog.NewLog,
+		wire.Bind(new(HysteriaConfig), new(*configrepo.Repository)),
+		wire.Bind(new(UserStore), new(*userstore.FileStore)),
+		wire.Bind(new(BackendSettings), new(*appconfig.BackendWriter)),
+		wire.Bind(new(ServiceRestarter), new(*servicectl.Restarter)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
+		NewUseCase,
+	)
+	return nil, nil
+}
@@ -0,0 +1,31 @@
+package sync_user
+
+import (
+	"fmt"
+
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/domain"
+	utilpasswordgen "vpn/internal/utils/passwordgen"
+)
+
+// BuildUseCase wires one node per context config; wire cannot generate the
+// loop, so it lives next to the generated BuildNode.
+func BuildUseCase(cfgs []appconfig.Config) (*UseCase, error) {
+	nodes := make([]Node, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		node, err := BuildNode(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", cfg.Context, err)
+		}
+		nodes = append(nodes, node)
+	}
+	// password_policy and username_policy are top-level settings, the same
+	// in every context.
+	var policy utilpasswordgen.Policy
+	var usernames domain.UsernamePolicy
+	if len(cfgs) > 0 {
+		policy = cfgs[0].PasswordPolicy.GeneratorPolicy()
+		usernames = cfgs[0].UsernamePolicy.DomainPolicy()
+	}
+	return NewUseCase(nodes, utilpasswordgen.NewGenerator(policy), usernames), nil
+}
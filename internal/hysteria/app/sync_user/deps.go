@@ -0,0 +1,39 @@
+package sync_user
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UserRepository interface {
+	ListUsers(ctx context.Context) ([]string, error)
+	AuthInfo(ctx context.Context) (domain.AuthInfo, error)
+	AddUser(ctx context.Context, user domain.User) error
+	RemoveUser(ctx context.Context, username string) error
+	Checksum(ctx context.Context) (string, error)
+}
+
+type ServiceRestarter interface {
+	Restart(ctx context.Context) error
+}
+
+type PasswordGenerator interface {
+	Generate() (string, error)
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
+
+// Node is one server of the fleet with its own config, service and audit log.
+type Node struct {
+	Name      string
+	Users     UserRepository
+	Restarter ServiceRestarter
+	Audit     AuditLog
+}
+
+func NewNode(name string, users UserRepository, restarter ServiceRestarter, audit AuditLog) Node {
+	return Node{Name: name, Users: users, Restarter: restarter, Audit: audit}
+}
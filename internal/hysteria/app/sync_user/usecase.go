@@ -0,0 +1,179 @@
+package sync_user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const (
+	StatusOK             = "ok"
+	StatusFailed         = "failed"
+	StatusSkipped        = "skipped"
+	StatusRolledBack     = "rolled-back"
+	StatusRollbackFailed = "rollback-failed"
+)
+
+var ErrSyncFailed = errors.New("fleet sync failed")
+
+type NodeResult struct {
+	Name   string
+	Status string
+	Err    error
+}
+
+type Result struct {
+	Username string
+	Password string
+	Nodes    []NodeResult
+}
+
+type UseCase struct {
+	nodes     []Node
+	passwords PasswordGenerator
+	usernames domain.UsernamePolicy
+}
+
+func NewUseCase(nodes []Node, passwords PasswordGenerator, usernames domain.UsernamePolicy) *UseCase {
+	return &UseCase{nodes: nodes, passwords: passwords, usernames: usernames}
+}
+
+// Execute creates the same user on every node in two phases. Prepare checks
+// every node without writing; commit adds the user and restarts each node.
+// If any node fails to commit, the user is removed again from every node it
+// was added to, so the fleet ends up either fully synced or unchanged.
+func (u *UseCase) Execute(ctx context.Context, username string, tags ...string) (Result, error) {
+	result := Result{Username: username, Nodes: make([]NodeResult, len(u.nodes))}
+	for i, node := range u.nodes {
+		result.Nodes[i] = NodeResult{Name: node.Name, Status: StatusSkipped}
+	}
+
+	username, err := u.usernames.Normalize(username)
+	if err != nil {
+		return result, err
+	}
+	result.Username = username
+	password, err := u.passwords.Generate()
+	if err != nil {
+		return result, err
+	}
+	user, err := domain.NewUser(username, password)
+	if err != nil {
+		return result, err
+	}
+	if user.Tags, err = domain.NormalizeTags(tags); err != nil {
+		return result, err
+	}
+
+	failed := false
+	for i, node := range u.nodes {
+		if err := prepare(ctx, node, username, u.usernames); err != nil {
+			result.Nodes[i].Status, result.Nodes[i].Err = StatusFailed, fmt.Errorf("prepare: %w", err)
+			failed = true
+		}
+	}
+	if failed {
+		return result, fmt.Errorf("%w: prepare failed, no node was changed", ErrSyncFailed)
+	}
+
+	var committed []int
+	for i, node := range u.nodes {
+		written, err := commit(ctx, node, user)
+		if err != nil {
+			result.Nodes[i].Status, result.Nodes[i].Err = StatusFailed, err
+			failed = true
+			// The user is in the config when only the restart or the audit
+			// failed; a failed write leaves the node as it was, and a
+			// same-named user added there meanwhile is not ours to remove.
+			if written {
+				committed = append(committed, i)
+			}
+			break
+		}
+		result.Nodes[i].Status = StatusOK
+		committed = append(committed, i)
+	}
+	if !failed {
+		result.Password = password
+		return result, nil
+	}
+
+	for _, i := range committed {
+		if err := rollback(ctx, u.nodes[i], username); err != nil {
+			result.Nodes[i].Status = StatusRollbackFailed
+			result.Nodes[i].Err = errors.Join(result.Nodes[i].Err, fmt.Errorf("rollback: %w", err))
+			continue
+		}
+		if result.Nodes[i].Status == StatusOK {
+			result.Nodes[i].Status = StatusRolledBack
+		}
+	}
+	return result, fmt.Errorf("%w: changes were rolled back", ErrSyncFailed)
+}
+
+func prepare(ctx context.Context, node Node, username string, usernames domain.UsernamePolicy) error {
+	auth, err := node.Users.AuthInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if !auth.ManagesUsers() {
+		return domain.ReadOnlyAuthModeError(auth.Mode)
+	}
+	users, err := node.Users.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	return usernames.ConflictError(username, users)
+}
+
+// commit reports whether the user was written to the node, even when a
+// later step failed.
+func commit(ctx context.Context, node Node, user domain.User) (written bool, err error) {
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := node.Users.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionAddUser, user.Username, hashBefore, hashAfter, err)
+		if auditErr := node.Audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = node.Users.Checksum(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if err := node.Users.AddUser(ctx, user); err != nil {
+		return false, err
+	}
+	if err := node.Restarter.Restart(ctx); err != nil {
+		return true, fmt.Errorf("restart: %w", err)
+	}
+	return true, nil
+}
+
+func rollback(ctx context.Context, node Node, username string) (err error) {
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := node.Users.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionRemoveUser, username, hashBefore, hashAfter, err)
+		if auditErr := node.Audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = node.Users.Checksum(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := node.Users.RemoveUser(ctx, username); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	return node.Restarter.Restart(ctx)
+}
@@ -0,0 +1,187 @@
+package sync_user
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct {
+	users   []string
+	mode    string
+	addErr  error
+	added   []domain.User
+	removed []string
+}
+
+func (m *repoMock) ListUsers(context.Context) ([]string, error) {
+	return m.users, nil
+}
+
+func (m *repoMock) AuthInfo(context.Context) (domain.AuthInfo, error) {
+	if m.mode == "" {
+		return domain.AuthInfo{Mode: domain.AuthModeUserpass}, nil
+	}
+	return domain.AuthInfo{Mode: m.mode}, nil
+}
+
+func (m *repoMock) AddUser(_ context.Context, user domain.User) error {
+	if m.addErr != nil {
+		if errors.Is(m.addErr, domain.ErrUserAlreadyExists) {
+			// The conflict comes from a user added concurrently after prepare.
+			m.users = append(m.users, user.Username)
+		}
+		return m.addErr
+	}
+	m.added = append(m.added, user)
+	m.users = append(m.users, user.Username)
+	return nil
+}
+
+func (m *repoMock) RemoveUser(_ context.Context, username string) error {
+	i := slices.Index(m.users, username)
+	if i < 0 {
+		return domain.ErrUserNotFound
+	}
+	m.users = slices.Delete(m.users, i, i+1)
+	m.removed = append(m.removed, username)
+	return nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct {
+	calls     int
+	failFirst error
+}
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.calls++
+	if m.calls == 1 {
+		return m.failFirst
+	}
+	return nil
+}
+
+type passwordGeneratorMock struct{}
+
+func (passwordGeneratorMock) Generate() (string, error) {
+	return "Abc123Abc123Abc123Abc123Abc123Ab", nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+type fleet struct {
+	repos      []*repoMock
+	restarters []*restarterMock
+	audit      *auditMock
+	uc         *UseCase
+}
+
+func newFleet(repos ...*repoMock) fleet {
+	f := fleet{repos: repos, audit: &auditMock{}}
+	var nodes []Node
+	for i, repo := range repos {
+		restarter := &restarterMock{}
+		f.restarters = append(f.restarters, restarter)
+		nodes = append(nodes, NewNode(string(rune('a'+i)), repo, restarter, f.audit))
+	}
+	f.uc = NewUseCase(nodes, passwordGeneratorMock{}, domain.UsernamePolicy{})
+	return f
+}
+
+func TestExecuteAddsUserEverywhere(t *testing.T) {
+	f := newFleet(&repoMock{}, &repoMock{users: []string{"bob"}})
+
+	result, err := f.uc.Execute(context.Background(), "alice", "ops")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Password == "" {
+		t.Fatalf("password must be returned on success")
+	}
+	for i, repo := range f.repos {
+		if len(repo.added) != 1 || repo.added[0].Password != result.Password || !repo.added[0].HasTag("ops") {
+			t.Fatalf("node %d: unexpected added users %+v", i, repo.added)
+		}
+		if f.restarters[i].calls != 1 || result.Nodes[i].Status != StatusOK {
+			t.Fatalf("node %d: restarts=%d status=%s", i, f.restarters[i].calls, result.Nodes[i].Status)
+		}
+	}
+}
+
+func TestExecutePrepareFailureChangesNothing(t *testing.T) {
+	f := newFleet(&repoMock{}, &repoMock{users: []string{"alice"}}, &repoMock{mode: domain.AuthModePassword})
+
+	result, err := f.uc.Execute(context.Background(), "alice")
+	if !errors.Is(err, ErrSyncFailed) {
+		t.Fatalf("expected ErrSyncFailed, got %v", err)
+	}
+	for _, repo := range f.repos {
+		if len(repo.added) != 0 {
+			t.Fatalf("prepare failure must not write: %+v", repo.added)
+		}
+	}
+	if result.Nodes[0].Status != StatusSkipped || result.Nodes[1].Status != StatusFailed || result.Nodes[2].Status != StatusFailed {
+		t.Fatalf("unexpected statuses: %+v", result.Nodes)
+	}
+	if !errors.Is(result.Nodes[1].Err, domain.ErrUserAlreadyExists) || !errors.Is(result.Nodes[2].Err, domain.ErrReadOnlyAuthMode) {
+		t.Fatalf("unexpected node errors: %+v", result.Nodes)
+	}
+	if len(f.audit.entries) != 0 {
+		t.Fatalf("prepare must not audit: %+v", f.audit.entries)
+	}
+}
+
+func TestExecuteRollsBackOnCommitFailure(t *testing.T) {
+	f := newFleet(&repoMock{}, &repoMock{}, &repoMock{})
+	f.restarters[1].failFirst = errors.New("unit failed")
+
+	result, err := f.uc.Execute(context.Background(), "alice")
+	if !errors.Is(err, ErrSyncFailed) {
+		t.Fatalf("expected ErrSyncFailed, got %v", err)
+	}
+	if result.Password != "" {
+		t.Fatalf("password must not be returned after rollback")
+	}
+	for i, repo := range f.repos[:2] {
+		if len(repo.users) != 0 || len(repo.removed) != 1 {
+			t.Fatalf("node %d: user must be rolled back: %+v", i, repo)
+		}
+	}
+	if len(f.repos[2].added) != 0 {
+		t.Fatalf("nodes after the failure must not be touched")
+	}
+	want := []string{StatusRolledBack, StatusFailed, StatusSkipped}
+	for i, status := range want {
+		if result.Nodes[i].Status != status {
+			t.Fatalf("node %d: expected %s, got %s", i, status, result.Nodes[i].Status)
+		}
+	}
+}
+
+func TestExecuteKeepsUserOfAFailedWrite(t *testing.T) {
+	raced := &repoMock{addErr: domain.ErrUserAlreadyExists}
+	f := newFleet(&repoMock{}, raced)
+
+	result, err := f.uc.Execute(context.Background(), "alice")
+	if !errors.Is(err, ErrSyncFailed) {
+		t.Fatalf("expected ErrSyncFailed, got %v", err)
+	}
+	if len(f.repos[0].removed) != 1 || len(raced.removed) != 0 || !slices.Contains(raced.users, "alice") {
+		t.Fatalf("only node a must be rolled back: %+v %+v", f.repos[0], raced)
+	}
+	if result.Nodes[0].Status != StatusRolledBack || result.Nodes[1].Status != StatusFailed {
+		t.Fatalf("unexpected statuses: %+v", result.Nodes)
+	}
+}
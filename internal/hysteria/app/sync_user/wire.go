@@ -0,0 +1,35 @@
+//go:build wireinject
+// +build wireinject
+
+package sync_user
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+	"vpn/internal/hysteria/infra/servicectl"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+func BuildNode(cfg appconfig.Config) (Node, error) {
+	wire.Build(
+		provideNodeName,
+		provideConfigPath,
+		provideUserBackend,
+		provideUserStorePath,
+		provideUserDBPath,
+		provideService,
+		provideRestartEnabled,
+		provideRestartCommand,
+		provideAuditEnabled,
+		provideAuditLogPath,
+		userstore.NewRepository,
+		servicectl.NewRestarter,
+		auditlog.NewLog,
+		wire.Bind(new(UserRepository), new(*userstore.Repository)),
+		wire.Bind(new(ServiceRestarter), new(*servicectl.Restarter)),
+		wire.Bind(new(AuditLog), new(*auditlog.Log)),
+		NewNode,
+	)
+	return Node{}, nil
+}
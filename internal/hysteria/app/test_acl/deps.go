@@ -0,0 +1,11 @@
+package test_acl
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type ACLRepository interface {
+	ACL(ctx context.Context) (domain.ACL, error)
+}
@@ -0,0 +1,5 @@
+package test_acl
+
+import appconfig "vpn/internal/config"
+
+func provideConfigPath(cfg appconfig.Config) string { return cfg.HysteriaConfigPath }
@@ -0,0 +1,56 @@
+package test_acl
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type Result struct {
+	domain.ACLMatch
+	// Rule is the matched rule; empty when the default outbound is used.
+	Rule string
+	// Outbound is the declared outbound traffic goes to; its Type is empty
+	// for the built-in direct and reject.
+	Outbound domain.Outbound
+	Skipped  []string
+	Default  string
+}
+
+type UseCase struct {
+	repo ACLRepository
+}
+
+func NewUseCase(repo ACLRepository) *UseCase {
+	return &UseCase{repo: repo}
+}
+
+// Execute evaluates the inline ACL for target the way the server would.
+// Rules from acl.file are not available to the tool.
+func (u *UseCase) Execute(ctx context.Context, target domain.ACLTarget) (Result, error) {
+	if strings.TrimSpace(target.Host) == "" {
+		return Result{}, errors.New("host is required")
+	}
+	acl, err := u.repo.ACL(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	if acl.File != "" {
+		return Result{}, domain.ErrACLFile
+	}
+
+	match := acl.Match(target)
+	result := Result{ACLMatch: match, Outbound: domain.Outbound{Name: match.Outbound}, Default: acl.DefaultOutbound()}
+	if match.Rule >= 0 {
+		result.Rule = acl.Rules[match.Rule].String()
+	}
+	if i := acl.OutboundIndex(match.Outbound); i >= 0 {
+		result.Outbound = acl.Outbounds[i]
+	}
+	for _, i := range match.Skipped {
+		result.Skipped = append(result.Skipped, acl.Rules[i].String())
+	}
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package test_acl
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct{ acl domain.ACL }
+
+func (m repoMock) ACL(context.Context) (domain.ACL, error) {
+	return m.acl, nil
+}
+
+func mustRules(t *testing.T, lines ...string) []domain.ACLRule {
+	t.Helper()
+	rules := make([]domain.ACLRule, 0, len(lines))
+	for _, line := range lines {
+		rule, err := domain.ParseACLRule(line)
+		if err != nil {
+			t.Fatalf("parse %q: %v", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func TestExecute(t *testing.T) {
+	acl := domain.ACL{
+		Rules: mustRules(t,
+			"reject(geoip:cn)",
+			"reject(all, udp/443)",
+			"de-socks(suffix:netflix.com)",
+			"de-socks(*.bbc.co.uk, tcp/80-443)",
+			"direct(10.0.0.0/8)",
+			"reject(1.1.1.1, */53)",
+		),
+		Outbounds: []domain.Outbound{
+			{Name: "main", Type: domain.OutboundTypeDirect},
+			{Name: "de-socks", Type: domain.OutboundTypeSOCKS5, Addr: "10.1.1.1:1080"},
+		},
+	}
+	uc := NewUseCase(repoMock{acl: acl})
+
+	cases := []struct {
+		name     string
+		target   domain.ACLTarget
+		rule     int
+		outbound string
+	}{
+		{"suffix matches the domain itself", domain.ACLTarget{Host: "netflix.com", Proto: "tcp", Port: 443}, 2, "de-socks"},
+		{"suffix matches subdomains", domain.ACLTarget{Host: "WWW.Netflix.com", Proto: "tcp", Port: 443}, 2, "de-socks"},
+		{"udp 443 is rejected first", domain.ACLTarget{Host: "netflix.com", Proto: "udp", Port: 443}, 1, domain.OutboundReject},
+		{"wildcard needs a subdomain", domain.ACLTarget{Host: "bbc.co.uk", Proto: "tcp", Port: 443}, -1, "main"},
+		{"wildcard with port range", domain.ACLTarget{Host: "www.bbc.co.uk", Proto: "tcp", Port: 80}, 3, "de-socks"},
+		{"port outside range", domain.ACLTarget{Host: "www.bbc.co.uk", Proto: "tcp", Port: 8080}, -1, "main"},
+		{"cidr", domain.ACLTarget{Host: "10.2.3.4", Proto: "tcp", Port: 22}, 4, domain.OutboundDirect},
+		{"resolved ip", domain.ACLTarget{Host: "dns.example", IPs: []net.IP{net.ParseIP("1.1.1.1")}, Proto: "udp", Port: 53}, 5, domain.OutboundReject},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := uc.Execute(context.Background(), tc.target)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Rule != "" != (tc.rule >= 0) || result.ACLMatch.Rule != tc.rule || result.Outbound.Name != tc.outbound {
+				t.Fatalf("got rule %d (%s) -> %s, want rule %d -> %s", result.ACLMatch.Rule, result.Rule, result.Outbound.Name, tc.rule, tc.outbound)
+			}
+			if len(result.Skipped) != 1 || result.Skipped[0] != "reject(geoip:cn)" {
+				t.Fatalf("expected the geoip rule to be skipped, got %v", result.Skipped)
+			}
+		})
+	}
+}
+
+func TestExecuteACLFile(t *testing.T) {
+	_, err := NewUseCase(repoMock{acl: domain.ACL{File: "/etc/hysteria/acl.txt"}}).Execute(context.Background(), domain.ACLTarget{Host: "example.com"})
+	if !errors.Is(err, domain.ErrACLFile) {
+		t.Fatalf("expected ErrACLFile, got %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package update_acl
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type ACLRepository interface {
+	ACL(ctx context.Context) (domain.ACL, error)
+	SaveACL(ctx context.Context, acl domain.ACL) error
+	Checksum(ctx context.Context) (string, error)
+}
+
+type ServiceRestarter interface {
+	Restart(ctx context.Context) error
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
@@ -0,0 +1,164 @@
+package update_acl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+var (
+	ErrNoSuchRule     = errors.New("no such acl rule")
+	ErrNoSuchOutbound = errors.New("no such outbound")
+)
+
+type UseCase struct {
+	repo      ACLRepository
+	restarter ServiceRestarter
+	audit     AuditLog
+}
+
+func NewUseCase(repo ACLRepository, restarter ServiceRestarter, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, audit: audit}
+}
+
+// AddRule inserts a rule before index at; a negative or too large at
+// appends it. Rules are evaluated top to bottom.
+func (u *UseCase) AddRule(ctx context.Context, line string, at int) (domain.ACL, error) {
+	rule, err := domain.ParseACLRule(line)
+	if err != nil {
+		return domain.ACL{}, err
+	}
+	return u.edit(ctx, func(acl *domain.ACL) error {
+		if at < 0 || at > len(acl.Rules) {
+			at = len(acl.Rules)
+		}
+		acl.Rules = insert(acl.Rules, at, rule)
+		return nil
+	})
+}
+
+func (u *UseCase) RemoveRule(ctx context.Context, index int) (domain.ACL, error) {
+	return u.edit(ctx, func(acl *domain.ACL) error {
+		if index < 0 || index >= len(acl.Rules) {
+			return fmt.Errorf("%w #%d", ErrNoSuchRule, index+1)
+		}
+		acl.Rules = append(acl.Rules[:index], acl.Rules[index+1:]...)
+		return nil
+	})
+}
+
+func (u *UseCase) MoveRule(ctx context.Context, from, to int) (domain.ACL, error) {
+	return u.edit(ctx, func(acl *domain.ACL) error {
+		if from < 0 || from >= len(acl.Rules) {
+			return fmt.Errorf("%w #%d", ErrNoSuchRule, from+1)
+		}
+		if to < 0 || to >= len(acl.Rules) {
+			return fmt.Errorf("%w #%d", ErrNoSuchRule, to+1)
+		}
+		rule := acl.Rules[from]
+		acl.Rules = insert(append(acl.Rules[:from], acl.Rules[from+1:]...), to, rule)
+		return nil
+	})
+}
+
+// AddOutbound appends an outbound. Hysteria sends unmatched traffic to the
+// first outbound, so the first proxy is preceded by an explicit direct one
+// to keep the default route.
+func (u *UseCase) AddOutbound(ctx context.Context, outbound domain.Outbound) (domain.ACL, error) {
+	if err := outbound.Validate(); err != nil {
+		return domain.ACL{}, err
+	}
+	return u.edit(ctx, func(acl *domain.ACL) error {
+		if acl.OutboundIndex(outbound.Name) >= 0 {
+			return fmt.Errorf("%w %q: already exists", domain.ErrInvalidOutbound, outbound.Name)
+		}
+		if len(acl.Outbounds) == 0 && outbound.Type != domain.OutboundTypeDirect {
+			acl.Outbounds = append(acl.Outbounds, domain.Outbound{Name: domain.OutboundDirect, Type: domain.OutboundTypeDirect})
+		}
+		acl.Outbounds = append(acl.Outbounds, outbound)
+		return nil
+	})
+}
+
+// RemoveOutbound refuses to remove an outbound that rules still use.
+func (u *UseCase) RemoveOutbound(ctx context.Context, name string) (domain.ACL, error) {
+	return u.edit(ctx, func(acl *domain.ACL) error {
+		i := acl.OutboundIndex(name)
+		if i < 0 {
+			return fmt.Errorf("%w %q", ErrNoSuchOutbound, name)
+		}
+		for n, rule := range acl.Rules {
+			if rule.Outbound == name {
+				return fmt.Errorf("%w: %s is used by rule #%d %s", domain.ErrOutboundInUse, name, n+1, rule)
+			}
+		}
+		acl.Outbounds = append(acl.Outbounds[:i], acl.Outbounds[i+1:]...)
+		return nil
+	})
+}
+
+// MoveOutbound changes the order of outbounds; the first one is the
+// default for traffic no rule matches.
+func (u *UseCase) MoveOutbound(ctx context.Context, name string, to int) (domain.ACL, error) {
+	return u.edit(ctx, func(acl *domain.ACL) error {
+		i := acl.OutboundIndex(name)
+		if i < 0 {
+			return fmt.Errorf("%w %q", ErrNoSuchOutbound, name)
+		}
+		if to < 0 || to >= len(acl.Outbounds) {
+			return fmt.Errorf("%w: position %d is out of range", ErrNoSuchOutbound, to+1)
+		}
+		outbound := acl.Outbounds[i]
+		acl.Outbounds = insert(append(acl.Outbounds[:i], acl.Outbounds[i+1:]...), to, outbound)
+		return nil
+	})
+}
+
+// edit applies change to the current ACL, validates the result and writes
+// it in one write with a restart.
+func (u *UseCase) edit(ctx context.Context, change func(*domain.ACL) error) (acl domain.ACL, err error) {
+	acl, err = u.repo.ACL(ctx)
+	if err != nil {
+		return domain.ACL{}, err
+	}
+	if acl.File != "" {
+		return domain.ACL{}, fmt.Errorf("%w %s", domain.ErrACLFile, acl.File)
+	}
+	if err := change(&acl); err != nil {
+		return domain.ACL{}, err
+	}
+	if err := acl.Validate(); err != nil {
+		return domain.ACL{}, err
+	}
+
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.repo.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionUpdateACL, "", hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.repo.Checksum(ctx)
+	if err != nil {
+		return domain.ACL{}, err
+	}
+
+	if err := u.repo.SaveACL(ctx, acl); err != nil {
+		return domain.ACL{}, err
+	}
+	if err := u.restarter.Restart(ctx); err != nil {
+		return domain.ACL{}, err
+	}
+	return acl, nil
+}
+
+func insert[T any](items []T, at int, item T) []T {
+	items = append(items, item)
+	copy(items[at+1:], items[at:])
+	items[at] = item
+	return items
+}
@@ -0,0 +1,146 @@
+package update_acl
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct {
+	acl   domain.ACL
+	saves int
+}
+
+func (m *repoMock) ACL(context.Context) (domain.ACL, error) {
+	acl := m.acl
+	acl.Rules = append([]domain.ACLRule(nil), m.acl.Rules...)
+	acl.Outbounds = append([]domain.Outbound(nil), m.acl.Outbounds...)
+	return acl, nil
+}
+
+func (m *repoMock) SaveACL(_ context.Context, acl domain.ACL) error {
+	m.acl = acl
+	m.saves++
+	return nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct{ calls int }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.calls++
+	return nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func rules(acl domain.ACL) []string {
+	out := make([]string, 0, len(acl.Rules))
+	for _, r := range acl.Rules {
+		out = append(out, r.String())
+	}
+	return out
+}
+
+func TestRules(t *testing.T) {
+	repo := &repoMock{}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, audit)
+	ctx := context.Background()
+
+	if _, err := uc.AddRule(ctx, "de-socks(suffix:netflix.com)", -1); !errors.Is(err, domain.ErrInvalidACLRule) {
+		t.Fatalf("expected unknown outbound error, got %v", err)
+	}
+	if _, err := uc.AddRule(ctx, "direct(300.1.1.1/8)", -1); !errors.Is(err, domain.ErrInvalidACLRule) {
+		t.Fatalf("expected syntax error, got %v", err)
+	}
+	if repo.saves != 0 || restarter.calls != 0 {
+		t.Fatal("invalid rules must not be written")
+	}
+
+	acl, err := uc.AddOutbound(ctx, domain.Outbound{Name: "de-socks", Type: domain.OutboundTypeSOCKS5, Addr: "10.0.0.1:1080"})
+	if err != nil {
+		t.Fatalf("add outbound: %v", err)
+	}
+	if acl.DefaultOutbound() != domain.OutboundDirect || len(acl.Outbounds) != 2 {
+		t.Fatalf("expected a direct default before the first proxy, got %+v", acl.Outbounds)
+	}
+	for _, line := range []string{"de-socks(suffix:netflix.com)", "reject(geoip:cn)"} {
+		if _, err := uc.AddRule(ctx, line, -1); err != nil {
+			t.Fatalf("add rule: %v", err)
+		}
+	}
+	acl, err = uc.AddRule(ctx, "direct(all, udp/53)", 0)
+	if err != nil {
+		t.Fatalf("add rule at top: %v", err)
+	}
+	if got := rules(acl); len(got) != 3 || got[0] != "direct(all, udp/53)" || got[2] != "reject(geoip:cn)" {
+		t.Fatalf("unexpected rules: %v", got)
+	}
+
+	acl, err = uc.MoveRule(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("move rule: %v", err)
+	}
+	if got := rules(acl); got[0] != "reject(geoip:cn)" || got[1] != "direct(all, udp/53)" {
+		t.Fatalf("unexpected order after move: %v", got)
+	}
+	if _, err := uc.RemoveRule(ctx, 5); !errors.Is(err, ErrNoSuchRule) {
+		t.Fatalf("expected ErrNoSuchRule, got %v", err)
+	}
+
+	if _, err := uc.RemoveOutbound(ctx, "de-socks"); !errors.Is(err, domain.ErrOutboundInUse) {
+		t.Fatalf("expected ErrOutboundInUse, got %v", err)
+	}
+	if _, err := uc.RemoveRule(ctx, 2); err != nil {
+		t.Fatalf("remove rule: %v", err)
+	}
+	acl, err = uc.RemoveOutbound(ctx, "de-socks")
+	if err != nil {
+		t.Fatalf("remove outbound: %v", err)
+	}
+	if len(acl.Outbounds) != 1 || len(acl.Rules) != 2 {
+		t.Fatalf("unexpected acl: %+v", acl)
+	}
+	if restarter.calls != 7 || len(audit.entries) != 7 || audit.entries[0].Action != domain.AuditActionUpdateACL {
+		t.Fatalf("expected one restart and audit entry per write, got %d restarts, %d entries", restarter.calls, len(audit.entries))
+	}
+}
+
+func TestMoveOutbound(t *testing.T) {
+	repo := &repoMock{acl: domain.ACL{Outbounds: []domain.Outbound{
+		{Name: "main", Type: domain.OutboundTypeDirect},
+		{Name: "warp", Type: domain.OutboundTypeSOCKS5, Addr: "127.0.0.1:40000"},
+	}}}
+	uc := NewUseCase(repo, &restarterMock{}, &auditMock{})
+
+	acl, err := uc.MoveOutbound(context.Background(), "warp", 0)
+	if err != nil {
+		t.Fatalf("move outbound: %v", err)
+	}
+	if acl.DefaultOutbound() != "warp" {
+		t.Fatalf("expected warp to become the default, got %s", acl.DefaultOutbound())
+	}
+	if _, err := uc.AddOutbound(context.Background(), domain.Outbound{Name: "main", Type: domain.OutboundTypeDirect}); !errors.Is(err, domain.ErrInvalidOutbound) {
+		t.Fatalf("expected duplicate outbound error, got %v", err)
+	}
+}
+
+func TestACLFile(t *testing.T) {
+	repo := &repoMock{acl: domain.ACL{File: "/etc/hysteria/acl.txt"}}
+	_, err := NewUseCase(repo, &restarterMock{}, &auditMock{}).AddRule(context.Background(), "reject(all)", -1)
+	if !errors.Is(err, domain.ErrACLFile) || repo.saves != 0 {
+		t.Fatalf("expected ErrACLFile without write, got %v", err)
+	}
+}
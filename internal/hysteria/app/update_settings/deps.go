@@ -0,0 +1,25 @@
+package update_settings
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type SettingsRepository interface {
+	Settings(ctx context.Context) ([]domain.SettingValue, error)
+	ApplySettings(ctx context.Context, changes []domain.SettingChange) error
+	Checksum(ctx context.Context) (string, error)
+}
+
+type ServiceRestarter interface {
+	Restart(ctx context.Context) error
+}
+
+type PasswordGenerator interface {
+	Generate() (string, error)
+}
+
+type AuditLog interface {
+	Record(ctx context.Context, entry domain.AuditEntry) error
+}
@@ -0,0 +1,120 @@
+package update_settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type Request struct {
+	Changes []domain.SettingChange
+	// GenerateObfsPassword sets obfs.password to a new random password.
+	GenerateObfsPassword bool
+}
+
+type Result struct {
+	Updates []domain.SettingUpdate
+}
+
+// ClientsAffected reports whether existing client URLs stopped working.
+func (r Result) ClientsAffected() bool {
+	for _, u := range r.Updates {
+		if u.Affected {
+			return true
+		}
+	}
+	return false
+}
+
+type UseCase struct {
+	repo      SettingsRepository
+	restarter ServiceRestarter
+	passwords PasswordGenerator
+	audit     AuditLog
+}
+
+func NewUseCase(repo SettingsRepository, restarter ServiceRestarter, passwords PasswordGenerator, audit AuditLog) *UseCase {
+	return &UseCase{repo: repo, restarter: restarter, passwords: passwords, audit: audit}
+}
+
+// Execute applies the changes in one write and restarts the service.
+// Values equal to the current ones are skipped; with nothing left to change
+// the config is not touched.
+func (u *UseCase) Execute(ctx context.Context, req Request) (result Result, err error) {
+	changes := make([]domain.SettingChange, 0, len(req.Changes)+1)
+	seen := map[string]bool{}
+	for _, change := range req.Changes {
+		def, err := domain.LookupSetting(change.Key)
+		if err != nil {
+			return Result{}, err
+		}
+		if seen[def.Key] {
+			return Result{}, fmt.Errorf("%w: %s is set twice", domain.ErrInvalidSetting, def.Key)
+		}
+		seen[def.Key] = true
+		value, err := def.Normalize(change.Value)
+		if err != nil {
+			return Result{}, err
+		}
+		changes = append(changes, domain.SettingChange{Key: def.Key, Value: value})
+	}
+	if req.GenerateObfsPassword {
+		if seen["obfs.password"] {
+			return Result{}, fmt.Errorf("%w: obfs.password is both set and generated", domain.ErrInvalidSetting)
+		}
+		password, err := u.passwords.Generate()
+		if err != nil {
+			return Result{}, err
+		}
+		changes = append(changes, domain.SettingChange{Key: "obfs.password", Value: password})
+	}
+	if len(changes) == 0 {
+		return Result{}, errors.New("no settings to change")
+	}
+
+	current, err := u.repo.Settings(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	values := make(map[string]string, len(current))
+	for _, v := range current {
+		values[v.Def.Key] = v.Value
+	}
+	var pending []domain.SettingChange
+	for _, change := range changes {
+		def, _ := domain.LookupSetting(change.Key)
+		old := values[change.Key]
+		if old == change.Value || (change.Value == domain.SettingNone && old == "") {
+			continue
+		}
+		pending = append(pending, change)
+		result.Updates = append(result.Updates, domain.SettingUpdate{Key: change.Key, Old: old, New: change.Value, Affected: def.AffectsClients})
+	}
+	if len(pending) == 0 {
+		return Result{}, nil
+	}
+
+	var hashBefore string
+	defer func() {
+		hashAfter, _ := u.repo.Checksum(ctx)
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionUpdateSettings, "", hashBefore, hashAfter, err)
+		if auditErr := u.audit.Record(ctx, entry); auditErr != nil && err == nil {
+			err = fmt.Errorf("%w: %w", domain.ErrAuditRecord, auditErr)
+		}
+	}()
+
+	hashBefore, err = u.repo.Checksum(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err := u.repo.ApplySettings(ctx, pending); err != nil {
+		return Result{}, err
+	}
+	if err := u.restarter.Restart(ctx); err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package update_settings
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct {
+	values  map[string]string
+	applied []domain.SettingChange
+}
+
+func (m *repoMock) Settings(context.Context) ([]domain.SettingValue, error) {
+	values := make([]domain.SettingValue, 0, len(domain.SettingDefs))
+	for _, def := range domain.SettingDefs {
+		value, set := m.values[def.Key]
+		values = append(values, domain.SettingValue{Def: def, Value: value, Set: set})
+	}
+	return values, nil
+}
+
+func (m *repoMock) ApplySettings(_ context.Context, changes []domain.SettingChange) error {
+	m.applied = append(m.applied, changes...)
+	return nil
+}
+
+func (m *repoMock) Checksum(context.Context) (string, error) {
+	return "hash", nil
+}
+
+type restarterMock struct{ called bool }
+
+func (m *restarterMock) Restart(context.Context) error {
+	m.called = true
+	return nil
+}
+
+type passwordGeneratorMock struct{}
+
+func (passwordGeneratorMock) Generate() (string, error) {
+	return "Abc123Abc123Abc123Abc123Abc123Ab", nil
+}
+
+type auditMock struct{ entries []domain.AuditEntry }
+
+func (m *auditMock) Record(_ context.Context, entry domain.AuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestExecute(t *testing.T) {
+	repo := &repoMock{values: map[string]string{"listen": ":443", "obfs.password": "old-secret"}}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, audit)
+
+	_, err := uc.Execute(context.Background(), Request{
+		Changes: []domain.SettingChange{{Key: "ignoreClientBandwidth", Value: "yes"}},
+	})
+	if !errors.Is(err, domain.ErrInvalidSetting) || restarter.called {
+		t.Fatalf("expected ErrInvalidSetting without restart, got %v", err)
+	}
+
+	result, err := uc.Execute(context.Background(), Request{
+		Changes: []domain.SettingChange{
+			{Key: "listen", Value: "443"},
+			{Key: "ignoreClientBandwidth", Value: "1"},
+		},
+		GenerateObfsPassword: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// listen "443" normalizes to the current ":443" and is skipped.
+	if len(repo.applied) != 2 || repo.applied[0] != (domain.SettingChange{Key: "ignoreClientBandwidth", Value: "true"}) {
+		t.Fatalf("unexpected applied changes: %+v", repo.applied)
+	}
+	if !result.ClientsAffected() || result.Updates[1].Old != "old-secret" || !restarter.called {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != domain.AuditActionUpdateSettings {
+		t.Fatalf("unexpected audit entries: %+v", audit.entries)
+	}
+}
+
+func TestExecuteNoChanges(t *testing.T) {
+	repo := &repoMock{values: map[string]string{"udpIdleTimeout": "60s"}}
+	restarter := &restarterMock{}
+	audit := &auditMock{}
+	uc := NewUseCase(repo, restarter, passwordGeneratorMock{}, audit)
+
+	result, err := uc.Execute(context.Background(), Request{Changes: []domain.SettingChange{{Key: "udpIdleTimeout", Value: "60s"}, {Key: "obfs.type", Value: "none"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Updates) != 0 || repo.applied != nil || restarter.called || len(audit.entries) != 0 {
+		t.Fatalf("nothing should change: %+v", result)
+	}
+}
+
+func TestExecuteUnknownSetting(t *testing.T) {
+	uc := NewUseCase(&repoMock{}, &restarterMock{}, passwordGeneratorMock{}, &auditMock{})
+	if _, err := uc.Execute(context.Background(), Request{Changes: []domain.SettingChange{{Key: "auth.type", Value: "password"}}}); !errors.Is(err, domain.ErrUnknownSetting) {
+		t.Fatalf("expected ErrUnknownSetting, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package validate_config
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type ConfigValidator interface {
+	Validate(ctx context.Context) ([]domain.ConfigIssue, error)
+}
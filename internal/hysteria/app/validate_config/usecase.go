@@ -0,0 +1,36 @@
+package validate_config
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type Result struct {
+	Issues []domain.ConfigIssue
+}
+
+// Valid reports whether Hysteria would accept the config; warnings count
+// only with strict.
+func (r Result) Valid(strict bool) bool {
+	if strict {
+		return len(r.Issues) == 0
+	}
+	return len(domain.ConfigErrors(r.Issues)) == 0
+}
+
+type UseCase struct {
+	validator ConfigValidator
+}
+
+func NewUseCase(validator ConfigValidator) *UseCase {
+	return &UseCase{validator: validator}
+}
+
+func (u *UseCase) Execute(ctx context.Context) (Result, error) {
+	issues, err := u.validator.Validate(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Issues: issues}, nil
+}
@@ -0,0 +1,35 @@
+package validate_config
+
+import (
+	"context"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type validatorMock struct{ issues []domain.ConfigIssue }
+
+func (m validatorMock) Validate(context.Context) ([]domain.ConfigIssue, error) {
+	return m.issues, nil
+}
+
+func TestExecute(t *testing.T) {
+	warning := domain.ConfigIssue{Line: 3, Column: 1, Path: "speedtest", Severity: domain.ConfigIssueWarning, Message: "unknown key"}
+	failure := domain.ConfigIssue{Line: 5, Column: 9, Path: "listen", Severity: domain.ConfigIssueError, Message: "invalid port"}
+
+	result, err := NewUseCase(validatorMock{issues: []domain.ConfigIssue{warning}}).Execute(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Valid(false) || result.Valid(true) {
+		t.Fatalf("warnings must only fail strict validation: %+v", result)
+	}
+
+	result, err = NewUseCase(validatorMock{issues: []domain.ConfigIssue{warning, failure}}).Execute(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Valid(false) {
+		t.Fatalf("errors must fail validation: %+v", result)
+	}
+}
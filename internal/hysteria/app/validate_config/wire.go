@@ -0,0 +1,20 @@
+//go:build wireinject
+// +build wireinject
+
+package validate_config
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/configrepo"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideConfigPath,
+		configrepo.NewRepository,
+		wire.Bind(new(ConfigValidator), new(*configrepo.Repository)),
+		NewUseCase,
+	)
+	return nil, nil
+}
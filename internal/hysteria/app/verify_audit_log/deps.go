@@ -0,0 +1,11 @@
+package verify_audit_log
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type AuditLogVerifier interface {
+	Verify(ctx context.Context) (domain.AuditVerification, error)
+}
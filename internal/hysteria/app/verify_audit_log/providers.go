@@ -0,0 +1,6 @@
+package verify_audit_log
+
+import appconfig "vpn/internal/config"
+
+func provideAuditEnabled(cfg appconfig.Config) bool   { return cfg.AuditEnabled }
+func provideAuditLogPath(cfg appconfig.Config) string { return cfg.AuditLogPath }
@@ -0,0 +1,19 @@
+package verify_audit_log
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type UseCase struct {
+	repo AuditLogVerifier
+}
+
+func NewUseCase(repo AuditLogVerifier) *UseCase {
+	return &UseCase{repo: repo}
+}
+
+func (u *UseCase) Execute(ctx context.Context) (domain.AuditVerification, error) {
+	return u.repo.Verify(ctx)
+}
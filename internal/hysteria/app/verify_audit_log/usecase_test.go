@@ -0,0 +1,25 @@
+package verify_audit_log
+
+import (
+	"context"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type repoMock struct{}
+
+func (repoMock) Verify(context.Context) (domain.AuditVerification, error) {
+	return domain.AuditVerification{Entries: 3, Valid: false, BrokenAt: 2, Reason: "entry hash mismatch"}, nil
+}
+
+func TestExecute(t *testing.T) {
+	uc := NewUseCase(repoMock{})
+	got, err := uc.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Valid || got.BrokenAt != 2 {
+		t.Fatalf("unexpected verification: %+v", got)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build wireinject
+// +build wireinject
+
+package verify_audit_log
+
+import (
+	"github.com/google/wire"
+	appconfig "vpn/internal/config"
+	"vpn/internal/hysteria/infra/auditlog"
+)
+
+func BuildUseCase(cfg appconfig.Config) (*UseCase, error) {
+	wire.Build(
+		provideAuditEnabled,
+		provideAuditLogPath,
+		auditlog.NewLog,
+		wire.Bind(new(AuditLogVerifier), new(*auditlog.Log)),
+		NewUseCase,
+	)
+	return nil, nil
+}
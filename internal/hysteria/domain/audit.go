@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	ActorOS      = "os"
+	ActorToken   = "token"
+	ActorTUI     = "tui"
+	ActorUnknown = "unknown"
+)
+
+const (
+	AuditActionAddUser        = "add-user"
+	AuditActionRotatePassword = "rotate-password"
+	AuditActionRemoveUser     = "remove-user"
+	AuditActionRotateShared   = "rotate-shared-password"
+	AuditActionMigrateAuth    = "migrate-auth"
+	AuditActionSwitchBackend  = "switch-backend"
+	AuditActionMigrateStorage = "migrate-storage"
+	AuditActionTLSSelfSigned  = "tls-self-signed"
+	AuditActionUpdateSettings = "update-settings"
+	AuditActionUpdateACL      = "update-acl"
+	AuditActionSetTags        = "set-tags"
+)
+
+const (
+	AuditResultOK    = "ok"
+	AuditResultError = "error"
+)
+
+// ErrAuditRecord wraps a failure to record the audit entry of a change that
+// was applied; the result returned with it is valid and must reach the user.
+var ErrAuditRecord = errors.New("record audit")
+
+type Actor struct {
+	Kind string
+	Name string
+}
+
+func (a Actor) String() string {
+	if a.Kind == "" {
+		return ActorUnknown
+	}
+	if a.Name == "" {
+		return a.Kind
+	}
+	return a.Kind + ":" + a.Name
+}
+
+type actorContextKey struct{}
+
+func ContextWithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorContextKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Kind: ActorUnknown}
+}
+
+type AuditEntry struct {
+	Time             time.Time `json:"time"`
+	Actor            string    `json:"actor"`
+	Action           string    `json:"action"`
+	Username         string    `json:"username"`
+	Result           string    `json:"result"`
+	Error            string    `json:"error,omitempty"`
+	ConfigHashBefore string    `json:"config_hash_before"`
+	ConfigHashAfter  string    `json:"config_hash_after"`
+	PrevHash         string    `json:"prev_hash"`
+	Hash             string    `json:"hash"`
+}
+
+func NewAuditEntry(ctx context.Context, action, username, hashBefore, hashAfter string, err error) AuditEntry {
+	entry := AuditEntry{
+		Actor:            ActorFromContext(ctx).String(),
+		Action:           action,
+		Username:         username,
+		Result:           AuditResultOK,
+		ConfigHashBefore: hashBefore,
+		ConfigHashAfter:  hashAfter,
+	}
+	if err != nil {
+		entry.Result = AuditResultError
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+type AuditFilter struct {
+	Username string
+	Action   string
+	Actor    string
+	Result   string
+	Since    time.Time
+	Until    time.Time
+}
+
+func (f AuditFilter) Matches(entry AuditEntry) bool {
+	if f.Username != "" && entry.Username != f.Username {
+		return false
+	}
+	if f.Action != "" && entry.Action != f.Action {
+		return false
+	}
+	if f.Actor != "" && entry.Actor != f.Actor && !strings.HasPrefix(entry.Actor, f.Actor+":") {
+		return false
+	}
+	if f.Result != "" && entry.Result != f.Result {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+type AuditVerification struct {
+	Entries  int
+	Valid    bool
+	BrokenAt int
+	Reason   string
+}
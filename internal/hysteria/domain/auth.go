@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	AuthModeUserpass = "userpass"
+	AuthModePassword = "password"
+	AuthModeHTTP     = "http"
+	AuthModeCommand  = "command"
+)
+
+var (
+	ErrUnsupportedAuthMode = errors.New("unsupported auth mode")
+	ErrReadOnlyAuthMode    = errors.New("users are read-only in this auth mode")
+)
+
+type AuthInfo struct {
+	Mode      string
+	Password  string
+	HTTPURL   string
+	Command   string
+	UserStore bool
+}
+
+func (a AuthInfo) ManagesUsers() bool {
+	return a.Mode == AuthModeUserpass || a.UserStore
+}
+
+func ParseAuthMode(mode string) (string, error) {
+	switch mode {
+	case AuthModeUserpass, AuthModePassword, AuthModeHTTP, AuthModeCommand:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedAuthMode, mode)
+	}
+}
+
+func ReadOnlyAuthModeError(mode string) error {
+	return fmt.Errorf("%w: auth.type is %q, per-user management requires userpass", ErrReadOnlyAuthMode, mode)
+}
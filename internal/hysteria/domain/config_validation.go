@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ConfigIssueError   = "error"
+	ConfigIssueWarning = "warning"
+)
+
+var ErrInvalidConfig = errors.New("invalid hysteria config")
+
+// ConfigIssue is a problem found in the Hysteria server config. Line and
+// Column are 1-based positions in the YAML file, zero when unknown.
+type ConfigIssue struct {
+	Line     int
+	Column   int
+	Path     string
+	Severity string
+	Message  string
+}
+
+func (i ConfigIssue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d: ", i.Line, i.Column)
+	}
+	if i.Path != "" {
+		b.WriteString(i.Path + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// ConfigErrors returns only the issues Hysteria would refuse to start with.
+func ConfigErrors(issues []ConfigIssue) []ConfigIssue {
+	var errs []ConfigIssue
+	for _, issue := range issues {
+		if issue.Severity == ConfigIssueError {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
+
+// ConfigValidationError is returned instead of writing a config with errors.
+type ConfigValidationError struct {
+	Issues []ConfigIssue
+}
+
+func (e *ConfigValidationError) Error() string {
+	parts := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		parts = append(parts, issue.String())
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidConfig, strings.Join(parts, "; "))
+}
+
+func (e *ConfigValidationError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+var bandwidthUnits = map[string]uint64{
+	"b": 1, "bps": 1,
+	"k": 1_000, "kb": 1_000, "kbps": 1_000,
+	"m": 1_000_000, "mb": 1_000_000, "mbps": 1_000_000,
+	"g": 1_000_000_000, "gb": 1_000_000_000, "gbps": 1_000_000_000,
+	"t": 1_000_000_000_000, "tb": 1_000_000_000_000, "tbps": 1_000_000_000_000,
+}
+
+// ParseBandwidth converts a Hysteria bandwidth string such as "100 mbps" to
+// bits per second. Like Hysteria it requires an integer and a unit.
+func ParseBandwidth(value string) (uint64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	split := strings.IndexFunc(value, func(r rune) bool { return r < '0' || r > '9' })
+	if split <= 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q (expected e.g. 100 mbps or 1 gbps)", value)
+	}
+	number, err := strconv.ParseUint(value[:split], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q: %w", value, err)
+	}
+	unit, ok := bandwidthUnits[strings.TrimSpace(value[split:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid bandwidth unit in %q (allowed: bps, kbps, mbps, gbps, tbps)", value)
+	}
+	return number * unit, nil
+}
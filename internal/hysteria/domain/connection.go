@@ -1,5 +1,13 @@
 package domain
 
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
 type ConnectionConfig struct {
 	Username     string
 	Password     string
@@ -8,4 +16,48 @@ type ConnectionConfig struct {
 	SNI          string
 	ObfsType     string
 	ObfsPassword string
+	// Insecure and PinSHA256 are set for self-signed certificates.
+	Insecure  bool
+	PinSHA256 string
+}
+
+// URL renders the hy2:// client URL. Without a username the password is
+// the shared one of auth.type: password.
+func (c ConnectionConfig) URL() (string, error) {
+	// JoinHostPort brackets IPv6 itself; anything URL-structural in the
+	// host would change what the client connects to.
+	if c.Host == "" || strings.ContainsAny(c.Host, "/?#@[] ") {
+		return "", fmt.Errorf("invalid connection host %q", c.Host)
+	}
+
+	query := make(url.Values)
+	if c.SNI != "" {
+		query.Set("sni", c.SNI)
+	}
+	if c.ObfsType != "" {
+		query.Set("obfs", c.ObfsType)
+	}
+	if c.ObfsPassword != "" {
+		query.Set("obfs-password", c.ObfsPassword)
+	}
+	if c.Insecure {
+		query.Set("insecure", "1")
+	}
+	if c.PinSHA256 != "" {
+		query.Set("pinSHA256", c.PinSHA256)
+	}
+
+	user := url.UserPassword(c.Username, c.Password)
+	if c.Username == "" {
+		user = url.User(c.Password)
+	}
+
+	u := &url.URL{
+		Scheme:   "hy2",
+		User:     user,
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/",
+		RawQuery: query.Encode(),
+	}
+	return u.String(), nil
 }
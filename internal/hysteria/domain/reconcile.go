@@ -0,0 +1,55 @@
+package domain
+
+import "errors"
+
+const DesiredPasswordGenerate = "generate"
+
+const (
+	PlanCreate = "create"
+	PlanRotate = "rotate"
+	PlanRetag  = "retag"
+	PlanDelete = "delete"
+)
+
+var ErrInvalidDesiredState = errors.New("invalid desired state")
+
+type DesiredUser struct {
+	Username string
+	Password string
+	Tags     []string
+}
+
+func (d DesiredUser) FixedPassword() bool {
+	return d.Password != "" && d.Password != DesiredPasswordGenerate
+}
+
+// ManagesTags reports whether the desired state lists tags for the user;
+// "tags: []" clears them, an omitted key leaves them alone.
+func (d DesiredUser) ManagesTags() bool {
+	return d.Tags != nil
+}
+
+type PlanItem struct {
+	Action   string
+	Username string
+	Reason   string
+}
+
+type ReconcilePlan struct {
+	Items     []PlanItem
+	Unmanaged []string
+}
+
+func (p ReconcilePlan) HasChanges() bool {
+	return len(p.Items) > 0
+}
+
+func (p ReconcilePlan) Count(action string) int {
+	n := 0
+	for _, item := range p.Items {
+		if item.Action == action {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	ServiceManagerSystemd = "systemd"
+	ServiceManagerSysV    = "sysv"
+	ServiceManagerOpenRC  = "openrc"
+	ServiceManagerDocker  = "docker"
+)
+
+const ServiceStateActive = "active"
+
+var (
+	ErrUnsupportedServiceManager = errors.New("unsupported service manager")
+	ErrInvalidContainer          = errors.New("invalid container")
+)
+
+// ServiceUnit says how to reach the Hysteria service. An empty Manager is
+// detected on the host; Host is an ssh:// target for remote nodes. LogFile
+// is read instead of the manager's logs when set, and is the only source
+// for sysv and OpenRC, which keep none. The docker manager controls
+// Container through the Docker Engine API on DockerSocket.
+type ServiceUnit struct {
+	Name         string
+	Manager      string
+	Host         string
+	LogFile      string
+	Container    Container
+	DockerSocket string
+}
+
+// String names the service as its manager knows it.
+func (u ServiceUnit) String() string {
+	if !u.Container.IsZero() {
+		return u.Container.String()
+	}
+	return u.Name
+}
+
+// Container is the Docker container Hysteria runs in, named directly or
+// found by its docker compose Service, optionally within Project.
+type Container struct {
+	Name    string
+	Project string
+	Service string
+}
+
+func (c Container) IsZero() bool {
+	return c == Container{}
+}
+
+func (c Container) Validate() error {
+	switch {
+	case c.Name != "" && (c.Project != "" || c.Service != ""):
+		return fmt.Errorf("%w: set either a container name or a compose service", ErrInvalidContainer)
+	case c.Project != "" && c.Service == "":
+		return fmt.Errorf("%w: compose project %q needs a compose service", ErrInvalidContainer, c.Project)
+	}
+	return nil
+}
+
+func (c Container) String() string {
+	switch {
+	case c.Name != "":
+		return c.Name
+	case c.Project != "":
+		return c.Project + "/" + c.Service
+	default:
+		return c.Service
+	}
+}
+
+// ServiceStatus is what the service manager reports about the Hysteria
+// service. State is in systemd terms: active, inactive, failed, ...;
+// Detail is the manager's own word for it, e.g. running or exited.
+type ServiceStatus struct {
+	Manager string
+	State   string
+	Detail  string
+	PID     int
+	Since   string
+}
+
+func (s ServiceStatus) Active() bool {
+	return s.State == ServiceStateActive
+}
+
+func ParseServiceManager(manager string) (string, error) {
+	switch manager {
+	case "", ServiceManagerSystemd, ServiceManagerSysV, ServiceManagerOpenRC, ServiceManagerDocker:
+		return manager, nil
+	default:
+		return "", fmt.Errorf("%w %q (allowed: systemd, sysv, openrc, docker)", ErrUnsupportedServiceManager, manager)
+	}
+}
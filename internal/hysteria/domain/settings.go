@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SettingString    = "string"
+	SettingBool      = "bool"
+	SettingDuration  = "duration"
+	SettingBandwidth = "bandwidth"
+	SettingAddress   = "address"
+	SettingEnum      = "enum"
+)
+
+// SettingNone removes the whole section for obfs.type and masquerade.type.
+const SettingNone = "none"
+
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidSetting = errors.New("invalid setting value")
+)
+
+// SettingDef describes a server setting outside of auth. Path is its place in
+// the Hysteria config; AffectsClients marks settings that are part of every
+// client URL.
+type SettingDef struct {
+	Key            string
+	Path           string
+	Kind           string
+	Values         []string
+	Description    string
+	AffectsClients bool
+}
+
+var SettingDefs = []SettingDef{
+	{Key: "listen", Path: "listen", Kind: SettingAddress, Description: "UDP address the server listens on, e.g. :443", AffectsClients: true},
+	{Key: "obfs.type", Path: "obfs.type", Kind: SettingEnum, Values: []string{"salamander", SettingNone}, Description: "Obfuscation; none removes the obfs section", AffectsClients: true},
+	{Key: "obfs.password", Path: "obfs.salamander.password", Kind: SettingString, Description: "Salamander password", AffectsClients: true},
+	{Key: "masquerade.type", Path: "masquerade.type", Kind: SettingEnum, Values: []string{"proxy", "file", "string", SettingNone}, Description: "What non-Hysteria HTTP/3 clients see"},
+	{Key: "masquerade.url", Path: "masquerade.proxy.url", Kind: SettingString, Description: "Site proxied by masquerade type proxy"},
+	{Key: "masquerade.rewriteHost", Path: "masquerade.proxy.rewriteHost", Kind: SettingBool, Description: "Rewrite the Host header for the proxied site"},
+	{Key: "masquerade.dir", Path: "masquerade.file.dir", Kind: SettingString, Description: "Directory served by masquerade type file"},
+	{Key: "masquerade.content", Path: "masquerade.string.content", Kind: SettingString, Description: "Body returned by masquerade type string"},
+	{Key: "masquerade.listenHTTP", Path: "masquerade.listenHTTP", Kind: SettingAddress, Description: "Also serve masquerade over HTTP/1.1 on this TCP address"},
+	{Key: "masquerade.listenHTTPS", Path: "masquerade.listenHTTPS", Kind: SettingAddress, Description: "Also serve masquerade over HTTPS on this TCP address"},
+	{Key: "masquerade.forceHTTPS", Path: "masquerade.forceHTTPS", Kind: SettingBool, Description: "Redirect HTTP masquerade requests to HTTPS"},
+	{Key: "bandwidth.up", Path: "bandwidth.up", Kind: SettingBandwidth, Description: "Server upload limit, e.g. 1 gbps"},
+	{Key: "bandwidth.down", Path: "bandwidth.down", Kind: SettingBandwidth, Description: "Server download limit, e.g. 1 gbps"},
+	{Key: "ignoreClientBandwidth", Path: "ignoreClientBandwidth", Kind: SettingBool, Description: "Ignore bandwidth hints sent by clients"},
+	{Key: "udpIdleTimeout", Path: "udpIdleTimeout", Kind: SettingDuration, Description: "Idle timeout of UDP sessions, e.g. 60s"},
+	{Key: "disableUDP", Path: "disableUDP", Kind: SettingBool, Description: "Disable UDP forwarding"},
+	{Key: "speedTest", Path: "speedTest", Kind: SettingBool, Description: "Enable the built-in speed test server"},
+}
+
+func LookupSetting(key string) (SettingDef, error) {
+	for _, def := range SettingDefs {
+		if def.Key == key {
+			return def, nil
+		}
+	}
+	return SettingDef{}, fmt.Errorf("%w %q", ErrUnknownSetting, key)
+}
+
+// Normalize checks a value for the setting and returns it in the form that
+// is written to the config. An empty value unsets the setting.
+func (d SettingDef) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	fail := func(format string, args ...any) (string, error) {
+		return "", fmt.Errorf("%w: %s: %s", ErrInvalidSetting, d.Key, fmt.Sprintf(format, args...))
+	}
+	switch d.Kind {
+	case SettingBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fail("expected true or false, got %q", value)
+		}
+		return strconv.FormatBool(b), nil
+	case SettingDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fail("expected a duration like 60s, got %q", value)
+		}
+	case SettingBandwidth:
+		if _, err := ParseBandwidth(value); err != nil {
+			return fail("%v", err)
+		}
+	case SettingAddress:
+		if _, err := strconv.Atoi(value); err == nil {
+			value = ":" + value
+		}
+		_, port, err := net.SplitHostPort(value)
+		if n, convErr := strconv.Atoi(port); err != nil || convErr != nil || n < 1 || n > 65535 {
+			return fail("expected host:port or :port, got %q", value)
+		}
+	case SettingEnum:
+		for _, allowed := range d.Values {
+			if value == allowed {
+				return value, nil
+			}
+		}
+		return fail("expected one of %s, got %q", strings.Join(d.Values, ", "), value)
+	}
+	return value, nil
+}
+
+// SettingValue is the current value of a setting; Set is false when the key
+// is absent from the config and Hysteria uses its default.
+type SettingValue struct {
+	Def   SettingDef
+	Value string
+	Set   bool
+}
+
+type SettingChange struct {
+	Key   string
+	Value string
+}
+
+// SettingUpdate is an applied change with the value it replaced.
+type SettingUpdate struct {
+	Key      string
+	Old      string
+	New      string
+	Affected bool
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"time"
+)
+
+const (
+	TLSModeACME = "acme"
+	TLSModeFile = "tls"
+)
+
+// Certificate is what the CLI shows about a server certificate.
+type Certificate struct {
+	Subject    string
+	DNSNames   []string
+	IPs        []string
+	NotBefore  time.Time
+	NotAfter   time.Time
+	PinSHA256  string
+	SelfSigned bool
+}
+
+// CertificateStatus is one certificate of the server config; Err is set
+// when it could not be read.
+type CertificateStatus struct {
+	Name string
+	Path string
+	Cert *Certificate
+	Err  error
+}
+
+type TLSStatus struct {
+	Mode         string
+	Certificates []CertificateStatus
+}
+
+// ParseCertificatePEM reads the first certificate of a PEM bundle.
+func ParseCertificatePEM(data []byte) (Certificate, error) {
+	block, rest := pem.Decode(data)
+	for block != nil && block.Type != "CERTIFICATE" {
+		block, rest = pem.Decode(rest)
+	}
+	if block == nil {
+		return Certificate{}, errors.New("no PEM certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return Certificate{}, err
+	}
+	ips := make([]string, 0, len(cert.IPAddresses))
+	for _, ip := range cert.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	return Certificate{
+		Subject:    cert.Subject.CommonName,
+		DNSNames:   append([]string{}, cert.DNSNames...),
+		IPs:        ips,
+		NotBefore:  cert.NotBefore,
+		NotAfter:   cert.NotAfter,
+		PinSHA256:  PinSHA256(cert.Raw),
+		SelfSigned: isSelfSigned(cert),
+	}, nil
+}
+
+// isSelfSigned does not use CheckSignatureFrom: that also requires the CA
+// flag, which leaf certificates made for a single server don't have.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// PinSHA256 is the certificate fingerprint clients pass as pinSHA256.
+func PinSHA256(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
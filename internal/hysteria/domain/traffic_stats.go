@@ -1,5 +1,10 @@
 package domain
 
+import "errors"
+
+// ErrTrafficStatsUnauthorized means the trafficStats API rejected the secret.
+var ErrTrafficStatsUnauthorized = errors.New("trafficStats rejected the secret")
+
 type UserTraffic struct {
 	RxBytes uint64
 	TxBytes uint64
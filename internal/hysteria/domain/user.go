@@ -1,26 +1,96 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
 
 var (
 	ErrEmptyUsername     = errors.New("username is required")
 	ErrEmptyPassword     = errors.New("password is required")
 	ErrUserAlreadyExists = errors.New("user already exists")
 	ErrUserNotFound      = errors.New("user not found")
+	ErrInvalidTag        = errors.New("invalid tag")
+	ErrNoUsersSelected   = errors.New("no users selected")
 )
 
 type User struct {
 	Username string
 	Password string
+	Tags     []string
+}
+
+func (u User) HasTag(tag string) bool {
+	for _, t := range u.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if strings.ContainsAny(tag, ",;#:= \t") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result, nil
 }
 
 func NewUser(username, password string) (User, error) {
 	if username == "" {
 		return User{}, ErrEmptyUsername
 	}
+	if err := checkUsernameCharacters(username); err != nil {
+		return User{}, err
+	}
 	if password == "" {
 		return User{}, ErrEmptyPassword
 	}
 
 	return User{Username: username, Password: password}, nil
 }
+
+const (
+	UserChangeAdd    = "add"
+	UserChangeRotate = "rotate"
+	UserChangeRemove = "remove"
+	UserChangeTags   = "tags"
+)
+
+var (
+	ErrInvalidChangeAction = errors.New("invalid change action")
+	ErrInvalidBatch        = errors.New("batch contains invalid rows")
+)
+
+type UserChange struct {
+	Action string
+	User   User
+}
+
+func (c UserChange) AuditAction() string {
+	switch c.Action {
+	case UserChangeAdd:
+		return AuditActionAddUser
+	case UserChangeRotate:
+		return AuditActionRotatePassword
+	case UserChangeRemove:
+		return AuditActionRemoveUser
+	case UserChangeTags:
+		return AuditActionSetTags
+	default:
+		return c.Action
+	}
+}
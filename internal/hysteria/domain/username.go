@@ -0,0 +1,216 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidUsername       = errors.New("invalid username")
+	ErrInvalidUsernamePolicy = errors.New("invalid username policy")
+)
+
+const (
+	defaultUsernameMinLength = 2
+	defaultUsernameMaxLength = 32
+	maxUsernameLength        = 64
+	defaultUsernameSymbols   = "-_."
+	// usernameSymbolChoices are safe as YAML keys, in URL userinfo and as
+	// trafficStats keys; ':' would also split userpass auth.
+	usernameSymbolChoices = "-_.~"
+)
+
+var defaultReservedUsernames = []string{"admin", "all", "default", "none", "root"}
+
+// UsernamePolicy restricts names of new users. Names are ASCII letters and
+// digits plus Symbols, starting with a letter or digit. Unless
+// CaseSensitive is set, names are folded to lower case so Alice and alice
+// cannot both exist. Zero fields take the defaults.
+type UsernamePolicy struct {
+	MinLength     int
+	MaxLength     int
+	Symbols       string
+	Reserved      []string
+	CaseSensitive bool
+}
+
+func DefaultUsernamePolicy() UsernamePolicy {
+	return UsernamePolicy{}.withDefaults()
+}
+
+func (p UsernamePolicy) withDefaults() UsernamePolicy {
+	if p.MinLength == 0 {
+		p.MinLength = defaultUsernameMinLength
+	}
+	if p.MaxLength == 0 {
+		p.MaxLength = defaultUsernameMaxLength
+	}
+	if p.Symbols == "" {
+		p.Symbols = defaultUsernameSymbols
+	}
+	if p.Reserved == nil {
+		p.Reserved = defaultReservedUsernames
+	}
+	return p
+}
+
+func (p UsernamePolicy) Validate() error {
+	p = p.withDefaults()
+	if p.MinLength < 1 || p.MaxLength > maxUsernameLength || p.MinLength > p.MaxLength {
+		return fmt.Errorf("%w: length %d..%d must fit in 1..%d", ErrInvalidUsernamePolicy, p.MinLength, p.MaxLength, maxUsernameLength)
+	}
+	for _, r := range p.Symbols {
+		if !strings.ContainsRune(usernameSymbolChoices, r) {
+			return fmt.Errorf("%w: symbol %q is not one of %q", ErrInvalidUsernamePolicy, r, usernameSymbolChoices)
+		}
+	}
+	return nil
+}
+
+// Key is the form used to compare names for duplicates.
+func (p UsernamePolicy) Key(username string) string {
+	if p.CaseSensitive {
+		return username
+	}
+	return strings.ToLower(username)
+}
+
+// Normalize trims and folds a name typed by the operator and checks it.
+func (p UsernamePolicy) Normalize(username string) (string, error) {
+	username = p.Key(strings.TrimSpace(username))
+	if err := p.Check(username); err != nil {
+		return "", err
+	}
+	return username, nil
+}
+
+// Check validates a name exactly as given, without trimming or folding.
+func (p UsernamePolicy) Check(username string) error {
+	if username == "" {
+		return ErrEmptyUsername
+	}
+	if problem := p.problem(username); problem != "" {
+		return fmt.Errorf("%w %q: %s", ErrInvalidUsername, username, problem)
+	}
+	return nil
+}
+
+func (p UsernamePolicy) problem(username string) string {
+	p = p.withDefaults()
+	if n := len(username); n < p.MinLength || n > p.MaxLength {
+		return fmt.Sprintf("length %d is outside %d..%d", n, p.MinLength, p.MaxLength)
+	}
+	for i := 0; i < len(username); i++ {
+		c := username[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c >= 'A' && c <= 'Z':
+			if !p.CaseSensitive {
+				return "must be lower case"
+			}
+		case strings.IndexByte(p.Symbols, c) >= 0:
+			if i == 0 {
+				return "must start with a letter or digit"
+			}
+		default:
+			return fmt.Sprintf("character %q is not allowed (letters, digits and %q)", c, p.Symbols)
+		}
+	}
+	for _, reserved := range p.Reserved {
+		if p.Key(username) == p.Key(reserved) {
+			return "name is reserved"
+		}
+	}
+	return ""
+}
+
+// Lookup returns the existing name username refers to: an exact match, or
+// else the name equal to it under Key. Without a match the trimmed input is
+// returned and the repository reports the user as not found.
+func (p UsernamePolicy) Lookup(username string, existing []string) string {
+	username = strings.TrimSpace(username)
+	if slices.Contains(existing, username) {
+		return username
+	}
+	if name, ok := p.Conflict(username, existing); ok {
+		return name
+	}
+	return username
+}
+
+// checkUsernameCharacters applies the rules every policy shares: ASCII
+// letters, digits and the symbols a policy may allow, starting with a
+// letter or digit.
+func checkUsernameCharacters(username string) error {
+	if len(username) > maxUsernameLength {
+		return fmt.Errorf("%w %q: longer than %d", ErrInvalidUsername, username, maxUsernameLength)
+	}
+	for i := 0; i < len(username); i++ {
+		c := username[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte(usernameSymbolChoices, c) >= 0:
+			if i == 0 {
+				return fmt.Errorf("%w %q: must start with a letter or digit", ErrInvalidUsername, username)
+			}
+		default:
+			return fmt.Errorf("%w %q: character %q is not allowed (letters, digits and %q)", ErrInvalidUsername, username, c, usernameSymbolChoices)
+		}
+	}
+	return nil
+}
+
+// Conflict returns the existing name that collides with username, which
+// differs from it only in case when folding is on.
+func (p UsernamePolicy) Conflict(username string, existing []string) (string, bool) {
+	key := p.Key(username)
+	for _, name := range existing {
+		if p.Key(name) == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ConflictError reports a new name that collides with an existing one.
+func (p UsernamePolicy) ConflictError(username string, existing []string) error {
+	name, ok := p.Conflict(username, existing)
+	switch {
+	case !ok:
+		return nil
+	case name == username:
+		return ErrUserAlreadyExists
+	default:
+		return fmt.Errorf("%w: %q differs from %q only in case", ErrUserAlreadyExists, username, name)
+	}
+}
+
+type UsernameIssue struct {
+	Username string
+	Problem  string
+}
+
+// Review lists existing names that break the policy or collide with each
+// other after folding. Such users keep working; the list is for doctor.
+func (p UsernamePolicy) Review(usernames []string) []UsernameIssue {
+	sorted := append([]string(nil), usernames...)
+	sort.Strings(sorted)
+
+	var issues []UsernameIssue
+	first := map[string]string{}
+	for _, name := range sorted {
+		if problem := p.problem(name); problem != "" {
+			issues = append(issues, UsernameIssue{Username: name, Problem: problem})
+		}
+		key := p.Key(name)
+		if other, ok := first[key]; ok {
+			issues = append(issues, UsernameIssue{Username: name, Problem: fmt.Sprintf("differs from %q only in case", other)})
+			continue
+		}
+		first[key] = name
+	}
+	return issues
+}
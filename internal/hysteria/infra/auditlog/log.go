@@ -0,0 +1,196 @@
+package auditlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+)
+
+const maxLineSize = 1 << 20
+
+type Log struct {
+	enabled bool
+	path    string
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+func NewLog(enabled bool, path string) *Log {
+	return &Log{enabled: enabled, path: path, now: time.Now}
+}
+
+func (l *Log) Record(_ context.Context, entry domain.AuditEntry) error {
+	if !l.enabled || l.path == "" {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("create audit dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("lock audit log: %w", err)
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	prevHash, err := lastHash(f)
+	if err != nil {
+		return err
+	}
+
+	if entry.Time.IsZero() {
+		entry.Time = l.now()
+	}
+	entry.Time = entry.Time.UTC()
+	entry.PrevHash = prevHash
+	entry.Hash, err = computeHash(entry)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal audit entry: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+func (l *Log) Entries(_ context.Context) ([]domain.AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []domain.AuditEntry
+	err := l.scan(func(_ int, entry domain.AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+func (l *Log) Verify(_ context.Context) (domain.AuditVerification, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := domain.AuditVerification{Valid: true}
+	prevHash := ""
+	err := l.scan(func(lineNo int, entry domain.AuditEntry) error {
+		result.Entries++
+		if !result.Valid {
+			return nil
+		}
+		if entry.PrevHash != prevHash {
+			result.Valid = false
+			result.BrokenAt = lineNo
+			result.Reason = "prev_hash does not match previous entry"
+			return nil
+		}
+		want, err := computeHash(entry)
+		if err != nil {
+			return err
+		}
+		if entry.Hash != want {
+			result.Valid = false
+			result.BrokenAt = lineNo
+			result.Reason = "entry hash mismatch"
+			return nil
+		}
+		prevHash = entry.Hash
+		return nil
+	})
+	if err != nil {
+		return domain.AuditVerification{}, err
+	}
+	return result, nil
+}
+
+func (l *Log) scan(fn func(lineNo int, entry domain.AuditEntry) error) error {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry domain.AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("parse audit log line %d: %w", lineNo, err)
+		}
+		if err := fn(lineNo, entry); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+	return nil
+}
+
+func lastHash(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("seek audit log: %w", err)
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	var last []byte
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			last = append(last[:0], line...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("read audit log: %w", err)
+	}
+	if last == nil {
+		return "", nil
+	}
+	var entry domain.AuditEntry
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return "", fmt.Errorf("parse last audit entry: %w", err)
+	}
+	return entry.Hash, nil
+}
+
+func computeHash(entry domain.AuditEntry) (string, error) {
+	entry.Hash = ""
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("marshal audit entry: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
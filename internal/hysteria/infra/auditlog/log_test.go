@@ -0,0 +1,92 @@
+package auditlog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+func TestLog_RecordChainsEntries(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	log := NewLog(true, path)
+	ctx := domain.ContextWithActor(context.Background(), domain.Actor{Kind: domain.ActorOS, Name: "root"})
+
+	for _, username := range []string{"alice", "bob", "carol"} {
+		entry := domain.NewAuditEntry(ctx, domain.AuditActionAddUser, username, "before", "after", nil)
+		if err := log.Record(ctx, entry); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	entries, err := log.Entries(ctx)
+	if err != nil {
+		t.Fatalf("entries: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if entries[0].PrevHash != "" || entries[1].PrevHash != entries[0].Hash || entries[2].PrevHash != entries[1].Hash {
+		t.Fatalf("entries are not chained: %+v", entries)
+	}
+	if entries[0].Actor != "os:root" || entries[0].Time.IsZero() {
+		t.Fatalf("unexpected first entry: %+v", entries[0])
+	}
+
+	result, err := log.Verify(ctx)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !result.Valid || result.Entries != 3 {
+		t.Fatalf("expected valid chain: %+v", result)
+	}
+}
+
+func TestLog_VerifyDetectsTampering(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(true, path)
+	ctx := context.Background()
+
+	for _, username := range []string{"alice", "bob"} {
+		if err := log.Record(ctx, domain.NewAuditEntry(ctx, domain.AuditActionRemoveUser, username, "", "", nil)); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	tampered := strings.Replace(string(raw), `"username":"bob"`, `"username":"mallory"`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	result, err := log.Verify(ctx)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if result.Valid || result.BrokenAt != 2 {
+		t.Fatalf("expected tampering at line 2: %+v", result)
+	}
+}
+
+func TestLog_DisabledIsNoop(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log := NewLog(false, path)
+	if err := log.Record(context.Background(), domain.AuditEntry{Action: domain.AuditActionAddUser}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no audit file, got: %v", err)
+	}
+}
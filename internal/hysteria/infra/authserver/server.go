@@ -0,0 +1,110 @@
+package authserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+const maxRequestSize = 64 << 10
+
+// AuthenticateFunc validates a client auth string and returns the client ID.
+type AuthenticateFunc func(ctx context.Context, auth string) (id string, ok bool, err error)
+
+// Request is the body Hysteria posts for auth.type: http.
+type Request struct {
+	Addr string `json:"addr"`
+	Auth string `json:"auth"`
+	Tx   uint64 `json:"tx"`
+}
+
+type Response struct {
+	OK bool   `json:"ok"`
+	ID string `json:"id"`
+}
+
+type Server struct {
+	authenticate AuthenticateFunc
+	logger       *log.Logger
+}
+
+func NewServer(authenticate AuthenticateFunc, logger *log.Logger) *Server {
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	return &Server{authenticate: authenticate, logger: logger}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	id, ok, err := s.authenticate(r.Context(), req.Auth)
+	if err != nil {
+		s.logger.Printf("auth error addr=%s: %v", req.Addr, err)
+		http.Error(w, "auth backend error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		s.logger.Printf("auth rejected addr=%s", req.Addr)
+		id = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(Response{OK: ok, ID: id})
+}
+
+// ListenAndServe serves the auth callback on addr until ctx is canceled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	mux := http.NewServeMux()
+	mux.Handle("/auth", s)
+	mux.Handle("/", s)
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("shutdown auth server: %w", err)
+		}
+		return nil
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("serve auth: %w", err)
+	}
+}
@@ -0,0 +1,144 @@
+package authserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"vpn/internal/hysteria/app/authenticate_user"
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/hysteria/infra/userstore"
+)
+
+// hysteriaAuth sends the same request Hysteria makes for auth.type: http.
+func hysteriaAuth(t *testing.T, url, auth string) (int, Response) {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]any{"addr": "203.0.113.7:51820", "auth": auth, "tx": 12345})
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var out Response
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return resp.StatusCode, out
+}
+
+func TestServer_EndToEnd(t *testing.T) {
+	t.Parallel()
+
+	store := userstore.NewFileStore(filepath.Join(t.TempDir(), "users.json"))
+	ctx := context.Background()
+	if err := store.AddUser(ctx, domain.User{Username: "alice", Password: "s3cret"}); err != nil {
+		t.Fatalf("add user: %v", err)
+	}
+
+	srv := httptest.NewServer(NewServer(authenticate_user.NewUseCase(store).Execute, nil))
+	defer srv.Close()
+	url := srv.URL + "/auth"
+
+	status, resp := hysteriaAuth(t, url, "alice:s3cret")
+	if status != http.StatusOK || !resp.OK || resp.ID != "alice" {
+		t.Fatalf("expected alice to authenticate: %d %+v", status, resp)
+	}
+
+	status, resp = hysteriaAuth(t, url, "alice:wrong")
+	if status != http.StatusOK || resp.OK || resp.ID != "" {
+		t.Fatalf("expected rejection: %d %+v", status, resp)
+	}
+
+	// Changes in the store apply to the next request without any restart.
+	if err := store.RotatePassword(ctx, domain.User{Username: "alice", Password: "rotated"}); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if err := store.AddUser(ctx, domain.User{Username: "bob", Password: "b0b"}); err != nil {
+		t.Fatalf("add bob: %v", err)
+	}
+	if _, resp = hysteriaAuth(t, url, "alice:s3cret"); resp.OK {
+		t.Fatal("old password must be rejected after rotation")
+	}
+	if _, resp = hysteriaAuth(t, url, "alice:rotated"); !resp.OK {
+		t.Fatal("rotated password must be accepted")
+	}
+	if _, resp = hysteriaAuth(t, url, "bob:b0b"); !resp.OK || resp.ID != "bob" {
+		t.Fatalf("new user must be accepted: %+v", resp)
+	}
+
+	if err := store.RemoveUser(ctx, "bob"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, resp = hysteriaAuth(t, url, "bob:b0b"); resp.OK {
+		t.Fatal("removed user must be rejected")
+	}
+}
+
+func TestServer_RejectsBadRequests(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(NewServer(func(context.Context, string) (string, bool, error) {
+		return "", false, errors.New("store down")
+	}, nil))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/auth")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(srv.URL+"/auth", "application/json", bytes.NewReader([]byte("{")))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+
+	if status, _ := hysteriaAuth(t, srv.URL+"/auth", "alice:x"); status != http.StatusInternalServerError {
+		t.Fatalf("expected 500 on backend error, got %d", status)
+	}
+}
+
+func TestServer_ServeStopsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(func(context.Context, string) (string, bool, error) { return "u", true, nil }, nil).Serve(ctx, ln)
+	}()
+
+	if _, resp := hysteriaAuth(t, "http://"+ln.Addr().String()+"/auth", "u:p"); !resp.OK {
+		t.Fatalf("expected ok from served listener: %+v", resp)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
@@ -0,0 +1,163 @@
+package configrepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vpn/internal/hysteria/domain"
+)
+
+// ACL reads acl.inline and outbounds.
+func (r *Repository) ACL(_ context.Context) (domain.ACL, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, root, err := r.readRoot()
+	if err != nil {
+		return domain.ACL{}, err
+	}
+	return readACL(root)
+}
+
+// SaveACL replaces acl.inline and outbounds. Outbounds that already exist
+// are matched by name and keep the keys the tool does not model.
+func (r *Repository) SaveACL(_ context.Context, acl domain.ACL) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return err
+	}
+	current, err := readACL(root)
+	if err != nil {
+		return err
+	}
+	if current.File != "" {
+		return fmt.Errorf("%w %s; edit that file or move the rules to acl.inline", domain.ErrACLFile, current.File)
+	}
+
+	if len(acl.Rules) == 0 {
+		deletePath(root, []string{"acl", "inline"})
+	} else {
+		// Unchanged rules keep their original spelling and comments.
+		written := map[string]*yaml.Node{}
+		if seq := findMappingValue(findMappingValue(root, "acl"), "inline"); seq != nil && seq.Kind == yaml.SequenceNode {
+			for _, item := range seq.Content {
+				if rule, err := domain.ParseACLRule(item.Value); err == nil {
+					written[rule.String()] = item
+				}
+			}
+		}
+		inline := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, rule := range acl.Rules {
+			node := written[rule.String()]
+			if node == nil {
+				node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: rule.String()}
+			}
+			inline.Content = append(inline.Content, node)
+		}
+		*ensureMappingValue(ensureMapping(root, "acl"), "inline") = *inline
+	}
+
+	if len(acl.Outbounds) == 0 {
+		deleteMappingKey(root, "outbounds")
+		return r.writeDoc(doc)
+	}
+	existing := map[string]*yaml.Node{}
+	if seq := findMappingValue(root, "outbounds"); seq != nil && seq.Kind == yaml.SequenceNode {
+		for _, item := range seq.Content {
+			if name := scalarValue(findMappingValue(item, "name")); name != "" {
+				existing[name] = item
+			}
+		}
+	}
+	outbounds := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, o := range acl.Outbounds {
+		node := existing[o.Name]
+		if node == nil {
+			node = outboundNode(o)
+		}
+		outbounds.Content = append(outbounds.Content, node)
+	}
+	*ensureMappingValue(root, "outbounds") = *outbounds
+	return r.writeDoc(doc)
+}
+
+func readACL(root *yaml.Node) (domain.ACL, error) {
+	var acl domain.ACL
+	section := findMappingValue(root, "acl")
+	acl.File = scalarValue(findMappingValue(section, "file"))
+	if inline := findMappingValue(section, "inline"); inline != nil && inline.Kind == yaml.SequenceNode {
+		for i, item := range inline.Content {
+			rule, err := domain.ParseACLRule(item.Value)
+			if err != nil {
+				return domain.ACL{}, fmt.Errorf("acl.inline[%d]: %w", i, err)
+			}
+			acl.Rules = append(acl.Rules, rule)
+		}
+	}
+	if seq := findMappingValue(root, "outbounds"); seq != nil && seq.Kind == yaml.SequenceNode {
+		for _, item := range seq.Content {
+			acl.Outbounds = append(acl.Outbounds, readOutbound(item))
+		}
+	}
+	return acl, nil
+}
+
+func readOutbound(node *yaml.Node) domain.Outbound {
+	o := domain.Outbound{
+		Name: scalarValue(findMappingValue(node, "name")),
+		Type: strings.ToLower(scalarValue(findMappingValue(node, "type"))),
+	}
+	section := findMappingValue(node, o.Type)
+	switch o.Type {
+	case domain.OutboundTypeDirect:
+		o.Mode = scalarValue(findMappingValue(section, "mode"))
+	case domain.OutboundTypeSOCKS5:
+		o.Addr = scalarValue(findMappingValue(section, "addr"))
+		o.Username = scalarValue(findMappingValue(section, "username"))
+		o.Password = scalarValue(findMappingValue(section, "password"))
+	case domain.OutboundTypeHTTP:
+		o.URL = scalarValue(findMappingValue(section, "url"))
+		o.Insecure = scalarValue(findMappingValue(section, "insecure")) == "true"
+	}
+	return o
+}
+
+func outboundNode(o domain.Outbound) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setMappingScalar(node, "name", o.Name)
+	setMappingScalar(node, "type", o.Type)
+	section := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	switch o.Type {
+	case domain.OutboundTypeDirect:
+		if o.Mode != "" {
+			setMappingScalar(section, "mode", o.Mode)
+		}
+	case domain.OutboundTypeSOCKS5:
+		setMappingScalar(section, "addr", o.Addr)
+		if o.Username != "" {
+			setMappingScalar(section, "username", o.Username)
+			setMappingScalar(section, "password", o.Password)
+		}
+	case domain.OutboundTypeHTTP:
+		setMappingScalar(section, "url", o.URL)
+		if o.Insecure {
+			section.Content = append(section.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "insecure"},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"},
+			)
+		}
+	}
+	if len(section.Content) > 0 {
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: o.Type}, section)
+	}
+	return node
+}
@@ -0,0 +1,144 @@
+package configrepo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+func TestRepository_SaveACL(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := `listen: :443
+acme:
+  domains:
+    - hy.example.com
+auth:
+  type: userpass
+  userpass:
+    valera: "321"
+acl:
+  inline:
+    - reject(geoip:cn)   # keep this comment
+    - warp( suffix:openai.com )
+outbounds:
+  - name: warp
+    type: socks5
+    socks5:
+      addr: 127.0.0.1:40000
+      dialTimeout: 5s
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	repo := NewRepository(path)
+
+	acl, err := repo.ACL(context.Background())
+	if err != nil {
+		t.Fatalf("acl: %v", err)
+	}
+	if len(acl.Rules) != 2 || acl.Rules[1].Outbound != "warp" || acl.Rules[1].Address != "suffix:openai.com" {
+		t.Fatalf("unexpected rules: %+v", acl.Rules)
+	}
+	if len(acl.Outbounds) != 1 || acl.Outbounds[0].Addr != "127.0.0.1:40000" {
+		t.Fatalf("unexpected outbounds: %+v", acl.Outbounds)
+	}
+
+	rule, _ := domain.ParseACLRule("de-http(suffix:bbc.co.uk, tcp/443)")
+	acl.Rules = append(acl.Rules, rule)
+	acl.Outbounds = append([]domain.Outbound{{Name: "de-http", Type: domain.OutboundTypeHTTP, URL: "http://u:p@10.0.0.2:8080", Insecure: true}}, acl.Outbounds...)
+	if err := repo.SaveACL(context.Background(), acl); err != nil {
+		t.Fatalf("save acl: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		"- reject(geoip:cn) # keep this comment",
+		"- warp( suffix:openai.com )",
+		"- de-http(suffix:bbc.co.uk, tcp/443)",
+		"dialTimeout: 5s",
+		"url: http://u:p@10.0.0.2:8080",
+		"insecure: true",
+	} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected %q in config:\n%s", want, content)
+		}
+	}
+	if strings.Index(content, "name: de-http") > strings.Index(content, "name: warp") {
+		t.Fatalf("expected de-http to be the first outbound:\n%s", content)
+	}
+
+	acl.Rules, acl.Outbounds = nil, nil
+	if err := repo.SaveACL(context.Background(), acl); err != nil {
+		t.Fatalf("clear acl: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if strings.Contains(string(data), "acl:") || strings.Contains(string(data), "outbounds:") {
+		t.Fatalf("expected acl and outbounds to be removed:\n%s", data)
+	}
+}
+
+func TestRepository_SaveACLFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := "acme:\n  domains: [a.example]\nauth:\n  type: password\n  password: x\nacl:\n  file: /etc/hysteria/acl.txt\n"
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	err := NewRepository(path).SaveACL(context.Background(), domain.ACL{})
+	if !errors.Is(err, domain.ErrACLFile) {
+		t.Fatalf("expected ErrACLFile, got %v", err)
+	}
+}
+
+func TestValidateACL(t *testing.T) {
+	t.Parallel()
+
+	config := `acme:
+  domains: [a.example]
+auth:
+  type: password
+  password: x
+acl:
+  inline:
+    - warp(suffix:openai.com)
+    - proxy(all, tcp/99999)
+    - nowhere(all)
+outbounds:
+  - name: warp
+    type: socks5
+    socks5:
+      addr: no-port
+  - name: warp
+    type: direct
+`
+	var messages []string
+	for _, issue := range Validate([]byte(config)) {
+		messages = append(messages, issue.String())
+	}
+	joined := strings.Join(messages, "\n")
+	for _, want := range []string{
+		`9:7: acl.inline[1]: invalid acl rule "proxy(all, tcp/99999)": invalid port "99999"`,
+		`10:7: acl.inline[2]: unknown outbound "nowhere"`,
+		`12:5: outbounds[0]: invalid outbound "warp": socks5 address must be host:port`,
+		`16:5: outbounds[1].name: outbound "warp" is declared twice`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected %q in issues:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "acl.inline[0]") {
+		t.Fatalf("valid rule reported:\n%s", joined)
+	}
+}
@@ -0,0 +1,158 @@
+package configrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"vpn/internal/hysteria/infra/remote"
+)
+
+type files interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	Lock(path string) (func(), error)
+}
+
+type localFiles struct{}
+
+func (localFiles) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// WriteFile replaces path through a temp file and a rename. An existing
+// file keeps its mode and owner, e.g. 0640 root:hysteria, as it did with
+// os.WriteFile; perm applies to new files only.
+func (localFiles) WriteFile(path string, data []byte, perm os.FileMode) error {
+	info, statErr := os.Stat(path)
+	if statErr == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if statErr == nil {
+		if err := chownLike(tmp, info); err != nil {
+			tmp.Close()
+			if errors.Is(err, fs.ErrPermission) {
+				// Only root may hand the file to another owner; writing in
+				// place keeps it.
+				return os.WriteFile(path, data, perm)
+			}
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// chownLike gives f the owner and group of info unless it already has them.
+func chownLike(f *os.File, info fs.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	current, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if cur, ok := current.Sys().(*syscall.Stat_t); ok && cur.Uid == st.Uid && cur.Gid == st.Gid {
+		return nil
+	}
+	return f.Chown(int(st.Uid), int(st.Gid))
+}
+
+// Lock takes an exclusive flock on path+".lock", so CLI and TUI processes
+// editing the same config do not lose each other's changes.
+func (localFiles) Lock(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// remoteFiles connects lazily so building use cases for a remote context
+// does not dial the host until a command actually needs the config.
+type remoteFiles struct {
+	location string
+	once     sync.Once
+	client   *remote.Client
+	err      error
+}
+
+func (f *remoteFiles) path() string {
+	_, path, err := remote.Parse(f.location)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func (f *remoteFiles) connect() (*remote.Client, error) {
+	f.once.Do(func() {
+		target, path, err := remote.Parse(f.location)
+		if err == nil && path == "" {
+			err = fmt.Errorf("ssh location %q has no config path", f.location)
+		}
+		if err != nil {
+			f.err = err
+			return
+		}
+		f.client, f.err = remote.Connect(context.Background(), target)
+	})
+	return f.client, f.err
+}
+
+func (f *remoteFiles) ReadFile(path string) ([]byte, error) {
+	c, err := f.connect()
+	if err != nil {
+		return nil, err
+	}
+	return c.ReadFile(path)
+}
+
+func (f *remoteFiles) WriteFile(path string, data []byte, perm os.FileMode) error {
+	c, err := f.connect()
+	if err != nil {
+		return err
+	}
+	return c.WriteFile(path, data, perm)
+}
+
+func (f *remoteFiles) Lock(path string) (func(), error) {
+	c, err := f.connect()
+	if err != nil {
+		return nil, err
+	}
+	return c.Lock(path)
+}
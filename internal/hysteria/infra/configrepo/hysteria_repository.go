@@ -2,6 +2,8 @@ package configrepo
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net"
@@ -14,34 +16,49 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"vpn/internal/hysteria/domain"
+	"vpn/internal/hysteria/infra/remote"
 )
 
 type Repository struct {
-	path string
-	mu   sync.Mutex
+	path  string
+	files files
+	mu    sync.Mutex
+	// known holds the validation errors of the config as last read, so a
+	// write is only refused for errors it introduces.
+	known map[string]bool
 }
 
+// NewRepository opens the Hysteria config at path, which is either a local
+// file or an ssh://user@host/path location on a remote node.
 func NewRepository(path string) *Repository {
-	return &Repository{path: path}
+	if remote.IsRemote(path) {
+		files := &remoteFiles{location: path}
+		return &Repository{path: files.path(), files: files}
+	}
+	return &Repository{path: path, files: localFiles{}}
 }
 
 func (r *Repository) AddUser(_ context.Context, user domain.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	doc, root, err := r.readRoot()
 	if err != nil {
 		return err
 	}
 
-	auth := ensureMappingValue(root, "auth")
-
-	authType := findMappingValue(auth, "type")
-	if authType == nil || authType.Value != "userpass" {
-		return errors.New("auth.type must be userpass")
+	info, err := readAuthInfo(root)
+	if err != nil {
+		return err
+	}
+	if !info.ManagesUsers() {
+		return domain.ReadOnlyAuthModeError(info.Mode)
 	}
 
-	userPass := ensureMappingValue(auth, "userpass")
+	userPass := ensureMappingValue(findMappingValue(root, "auth"), "userpass")
 
 	if userPass.Kind != yaml.MappingNode {
 		return errors.New("auth.userpass must be a map")
@@ -51,44 +68,26 @@ func (r *Repository) AddUser(_ context.Context, user domain.User) error {
 		return domain.ErrUserAlreadyExists
 	}
 
-	userPass.Content = append(userPass.Content,
-		&yaml.Node{Kind: yaml.ScalarNode, Value: user.Username, Tag: "!!str"},
-		&yaml.Node{Kind: yaml.ScalarNode, Value: user.Password, Tag: "!!str"},
-	)
-
-	result, err := yaml.Marshal(doc)
-	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(r.path, result, 0o600); err != nil {
-		return fmt.Errorf("write config: %w", err)
-	}
+	appendUser(userPass, user)
 
-	return nil
+	return r.writeDoc(doc)
 }
 
 func (r *Repository) RotatePassword(_ context.Context, user domain.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	doc, root, err := r.readRoot()
+	unlock, err := r.lock()
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	auth := findMappingValue(root, "auth")
-	if auth == nil {
-		return errors.New("auth section not found")
-	}
-	authType := findMappingValue(auth, "type")
-	if authType == nil || authType.Value != "userpass" {
-		return errors.New("auth.type must be userpass")
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return err
 	}
 
-	userPass := findMappingValue(auth, "userpass")
-	if userPass == nil || userPass.Kind != yaml.MappingNode {
-		return errors.New("auth.userpass must be a map")
+	userPass, err := findUserPass(root)
+	if err != nil {
+		return err
 	}
 
 	passwordNode := findMappingValue(userPass, user.Username)
@@ -98,48 +97,31 @@ func (r *Repository) RotatePassword(_ context.Context, user domain.User) error {
 	passwordNode.Value = user.Password
 	passwordNode.Tag = "!!str"
 
-	result, err := yaml.Marshal(doc)
-	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(r.path, result, 0o600); err != nil {
-		return fmt.Errorf("write config: %w", err)
-	}
-
-	return nil
+	return r.writeDoc(doc)
 }
 
 func (r *Repository) RemoveUser(_ context.Context, username string) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	doc, root, err := r.readRoot()
 	if err != nil {
 		return err
 	}
 
-	auth := findMappingValue(root, "auth")
-	if auth == nil {
-		return errors.New("auth section not found")
-	}
-	userPass := findMappingValue(auth, "userpass")
-	if userPass == nil || userPass.Kind != yaml.MappingNode {
-		return errors.New("auth.userpass must be a map")
+	userPass, err := findUserPass(root)
+	if err != nil {
+		return err
 	}
 
 	if !deleteMappingKey(userPass, username) {
 		return domain.ErrUserNotFound
 	}
 
-	result, err := yaml.Marshal(doc)
-	if err != nil {
-		return fmt.Errorf("marshal config: %w", err)
-	}
-	if err := os.WriteFile(r.path, result, 0o600); err != nil {
-		return fmt.Errorf("write config: %w", err)
-	}
-	return nil
+	return r.writeDoc(doc)
 }
 
 func (r *Repository) ListUsers(_ context.Context) ([]string, error) {
@@ -151,13 +133,9 @@ func (r *Repository) ListUsers(_ context.Context) ([]string, error) {
 		return nil, err
 	}
 
-	auth := findMappingValue(root, "auth")
-	if auth == nil {
-		return nil, errors.New("auth section not found")
-	}
-	userPass := findMappingValue(auth, "userpass")
-	if userPass == nil || userPass.Kind != yaml.MappingNode {
-		return nil, errors.New("auth.userpass must be a map")
+	userPass, err := findUserPass(root)
+	if err != nil {
+		return nil, err
 	}
 
 	users := make([]string, 0, len(userPass.Content)/2)
@@ -168,6 +146,80 @@ func (r *Repository) ListUsers(_ context.Context) ([]string, error) {
 	return users, nil
 }
 
+func (r *Repository) Users(_ context.Context) ([]domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, root, err := r.readRoot()
+	if err != nil {
+		return nil, err
+	}
+	userPass, err := findUserPass(root)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]domain.User, 0, len(userPass.Content)/2)
+	for i := 0; i < len(userPass.Content)-1; i += 2 {
+		users = append(users, domain.User{
+			Username: userPass.Content[i].Value,
+			Password: userPass.Content[i+1].Value,
+			Tags:     readUserTags(userPass.Content[i+1]),
+		})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (r *Repository) ApplyChanges(_ context.Context, changes []domain.UserChange) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return err
+	}
+	userPass, err := findUserPass(root)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		username := change.User.Username
+		switch change.Action {
+		case domain.UserChangeAdd:
+			if findMappingValue(userPass, username) != nil {
+				return fmt.Errorf("%s: %w", username, domain.ErrUserAlreadyExists)
+			}
+			appendUser(userPass, change.User)
+		case domain.UserChangeRotate:
+			passwordNode := findMappingValue(userPass, username)
+			if passwordNode == nil {
+				return fmt.Errorf("%s: %w", username, domain.ErrUserNotFound)
+			}
+			passwordNode.Value = change.User.Password
+			passwordNode.Tag = "!!str"
+		case domain.UserChangeRemove:
+			if !deleteMappingKey(userPass, username) {
+				return fmt.Errorf("%s: %w", username, domain.ErrUserNotFound)
+			}
+		case domain.UserChangeTags:
+			passwordNode := findMappingValue(userPass, username)
+			if passwordNode == nil {
+				return fmt.Errorf("%s: %w", username, domain.ErrUserNotFound)
+			}
+			writeUserTags(passwordNode, change.User.Tags)
+		default:
+			return fmt.Errorf("%w: %q", domain.ErrInvalidChangeAction, change.Action)
+		}
+	}
+
+	return r.writeDoc(doc)
+}
+
 func (r *Repository) GetConnectionConfig(_ context.Context, username string) (domain.ConnectionConfig, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -177,46 +229,240 @@ func (r *Repository) GetConnectionConfig(_ context.Context, username string) (do
 		return domain.ConnectionConfig{}, err
 	}
 
+	username, password, err := connectionCredentials(root, username)
+	if err != nil {
+		return domain.ConnectionConfig{}, err
+	}
+
+	cfg, err := r.readServerConfig(root)
+	if err != nil {
+		return domain.ConnectionConfig{}, err
+	}
+	cfg.Username = username
+	cfg.Password = password
+	return cfg, nil
+}
+
+// ServerConfig returns connection settings without credentials, for user
+// backends that keep passwords outside of the Hysteria config.
+func (r *Repository) ServerConfig(_ context.Context) (domain.ConnectionConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, root, err := r.readRoot()
+	if err != nil {
+		return domain.ConnectionConfig{}, err
+	}
+	return r.readServerConfig(root)
+}
+
+func (r *Repository) AuthInfo(_ context.Context) (domain.AuthInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, root, err := r.readRoot()
+	if err != nil {
+		return domain.AuthInfo{}, err
+	}
+	return readAuthInfo(root)
+}
+
+func (r *Repository) SetSharedPassword(_ context.Context, password string) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return err
+	}
+	info, err := readAuthInfo(root)
+	if err != nil {
+		return err
+	}
+	if info.Mode != domain.AuthModePassword {
+		return fmt.Errorf("%w: auth.type is %q, shared password requires password", domain.ErrUnsupportedAuthMode, info.Mode)
+	}
+
+	setMappingScalar(findMappingValue(root, "auth"), "password", password)
+	return r.writeDoc(doc)
+}
+
+func (r *Repository) MigrateToUserpass(_ context.Context, username string) (domain.User, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return domain.User{}, err
+	}
+	defer unlock()
+
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return domain.User{}, err
+	}
+	info, err := readAuthInfo(root)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if info.Mode != domain.AuthModePassword {
+		return domain.User{}, fmt.Errorf("%w: migration from auth.type %q to userpass", domain.ErrUnsupportedAuthMode, info.Mode)
+	}
+	user, err := domain.NewUser(username, info.Password)
+	if err != nil {
+		return domain.User{}, err
+	}
+
 	auth := findMappingValue(root, "auth")
-	if auth == nil {
-		return domain.ConnectionConfig{}, errors.New("auth section not found")
+	setMappingScalar(auth, "type", domain.AuthModeUserpass)
+	deleteMappingKey(auth, "password")
+	deleteMappingKey(auth, "userpass")
+	userPass := ensureMappingValue(auth, "userpass")
+	appendUser(userPass, user)
+
+	if err := r.writeDoc(doc); err != nil {
+		return domain.User{}, err
 	}
-	userPass := findMappingValue(auth, "userpass")
-	if userPass == nil || userPass.Kind != yaml.MappingNode {
-		return domain.ConnectionConfig{}, errors.New("auth.userpass must be a map")
+	return user, nil
+}
+
+func (r *Repository) SwitchToHTTPAuth(_ context.Context, url string) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	passwordNode := findMappingValue(userPass, username)
-	if passwordNode == nil {
-		return domain.ConnectionConfig{}, domain.ErrUserNotFound
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return err
+	}
+	info, err := readAuthInfo(root)
+	if err != nil {
+		return err
+	}
+	if info.Mode != domain.AuthModeUserpass && info.Mode != domain.AuthModeHTTP {
+		return fmt.Errorf("%w: switch from auth.type %q to http", domain.ErrUnsupportedAuthMode, info.Mode)
 	}
 
-	host, err := readHost(root)
+	auth := findMappingValue(root, "auth")
+	setMappingScalar(auth, "type", domain.AuthModeHTTP)
+	deleteMappingKey(auth, "userpass")
+	deleteMappingKey(auth, "http")
+	http := ensureMappingValue(auth, "http")
+	setMappingScalar(http, "url", url)
+
+	return r.writeDoc(doc)
+}
+
+func (r *Repository) SwitchToUserpass(_ context.Context, users []domain.User) error {
+	unlock, err := r.lock()
 	if err != nil {
-		return domain.ConnectionConfig{}, err
+		return err
 	}
+	defer unlock()
 
-	port, err := readPort(root)
+	doc, root, err := r.readRoot()
 	if err != nil {
-		return domain.ConnectionConfig{}, err
+		return err
+	}
+	info, err := readAuthInfo(root)
+	if err != nil {
+		return err
+	}
+	if info.Mode != domain.AuthModeHTTP {
+		return fmt.Errorf("%w: switch from auth.type %q to userpass", domain.ErrUnsupportedAuthMode, info.Mode)
 	}
 
-	obfsType, obfsPassword := readObfs(root)
-	sni := host
+	auth := findMappingValue(root, "auth")
+	setMappingScalar(auth, "type", domain.AuthModeUserpass)
+	deleteMappingKey(auth, "http")
+	userPass := ensureMappingValue(auth, "userpass")
+	for _, user := range users {
+		appendUser(userPass, user)
+	}
 
-	return domain.ConnectionConfig{
-		Username:     username,
-		Password:     passwordNode.Value,
-		Host:         host,
-		Port:         port,
-		SNI:          sni,
-		ObfsType:     obfsType,
-		ObfsPassword: obfsPassword,
+	return r.writeDoc(doc)
+}
+
+// RenderUserpass replaces auth.userpass with the given users. Tags are kept
+// as comments only when withTags is set; stores that own metadata leave the
+// Hysteria config with bare credentials.
+func (r *Repository) RenderUserpass(_ context.Context, users []domain.User, withTags bool) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return err
+	}
+	userPass, err := findUserPass(root)
+	if err != nil {
+		return err
+	}
+
+	sorted := append([]domain.User(nil), users...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Username < sorted[j].Username })
+	userPass.Content = nil
+	for _, user := range sorted {
+		if !withTags {
+			user.Tags = nil
+		}
+		appendUser(userPass, user)
+	}
+
+	return r.writeDoc(doc)
+}
+
+func (r *Repository) Checksum(_ context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	raw, err := r.files.ReadFile(r.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("read config: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (r *Repository) writeDoc(doc *yaml.Node) error {
+	if err := r.checkDocument(doc); err != nil {
+		return err
+	}
+	result, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if err := r.files.WriteFile(r.path, result, 0o600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
+
+// lock serializes writers in this process and, through a flock next to the
+// config, with other CLI and TUI processes editing the same node.
+func (r *Repository) lock() (func(), error) {
+	r.mu.Lock()
+	release, err := r.files.Lock(r.path)
+	if err != nil {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("lock config: %w", err)
+	}
+	return func() {
+		release()
+		r.mu.Unlock()
 	}, nil
 }
 
 func (r *Repository) readRoot() (*yaml.Node, *yaml.Node, error) {
-	raw, err := os.ReadFile(r.path)
+	raw, err := r.files.ReadFile(r.path)
 	if err != nil {
 		return nil, nil, fmt.Errorf("read config: %w", err)
 	}
@@ -230,9 +476,121 @@ func (r *Repository) readRoot() (*yaml.Node, *yaml.Node, error) {
 		return nil, nil, errors.New("invalid hysteria config format")
 	}
 
+	r.known = map[string]bool{}
+	for _, issue := range domain.ConfigErrors(validateDocument(&doc)) {
+		r.known[issueKey(issue)] = true
+	}
 	return &doc, doc.Content[0], nil
 }
 
+func (r *Repository) readServerConfig(root *yaml.Node) (domain.ConnectionConfig, error) {
+	port, err := readPort(root)
+	if err != nil {
+		return domain.ConnectionConfig{}, err
+	}
+	obfsType, obfsPassword := readObfs(root)
+	cfg := domain.ConnectionConfig{
+		Port:         port,
+		ObfsType:     obfsType,
+		ObfsPassword: obfsPassword,
+	}
+
+	if findMappingValue(root, "acme") == nil && findMappingValue(root, "tls") != nil {
+		if err := r.readFileTLS(root, &cfg); err != nil {
+			return domain.ConnectionConfig{}, err
+		}
+		return cfg, nil
+	}
+
+	host, err := readHost(root)
+	if err != nil {
+		return domain.ConnectionConfig{}, err
+	}
+	cfg.Host = host
+	cfg.SNI = host
+	return cfg, nil
+}
+
+func appendUser(userPass *yaml.Node, user domain.User) {
+	passwordNode := &yaml.Node{Kind: yaml.ScalarNode, Value: user.Password, Tag: "!!str"}
+	if len(user.Tags) > 0 {
+		writeUserTags(passwordNode, user.Tags)
+	}
+	userPass.Content = append(userPass.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: user.Username, Tag: "!!str"},
+		passwordNode,
+	)
+}
+
+func findUserPass(root *yaml.Node) (*yaml.Node, error) {
+	info, err := readAuthInfo(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.ManagesUsers() {
+		return nil, domain.ReadOnlyAuthModeError(info.Mode)
+	}
+	userPass := findMappingValue(findMappingValue(root, "auth"), "userpass")
+	if userPass == nil || userPass.Kind != yaml.MappingNode {
+		return nil, errors.New("auth.userpass must be a map")
+	}
+	return userPass, nil
+}
+
+func readAuthInfo(root *yaml.Node) (domain.AuthInfo, error) {
+	auth := findMappingValue(root, "auth")
+	if auth == nil {
+		return domain.AuthInfo{}, errors.New("auth section not found")
+	}
+	authType := findMappingValue(auth, "type")
+	if authType == nil || strings.TrimSpace(authType.Value) == "" {
+		return domain.AuthInfo{}, errors.New("auth.type is required")
+	}
+	mode, err := domain.ParseAuthMode(strings.TrimSpace(authType.Value))
+	if err != nil {
+		return domain.AuthInfo{}, err
+	}
+
+	info := domain.AuthInfo{Mode: mode}
+	switch mode {
+	case domain.AuthModePassword:
+		if node := findMappingValue(auth, "password"); node != nil {
+			info.Password = node.Value
+		}
+	case domain.AuthModeHTTP:
+		if node := findMappingValue(findMappingValue(auth, "http"), "url"); node != nil {
+			info.HTTPURL = node.Value
+		}
+	case domain.AuthModeCommand:
+		if node := findMappingValue(auth, "command"); node != nil {
+			info.Command = node.Value
+		}
+	}
+	return info, nil
+}
+
+func connectionCredentials(root *yaml.Node, username string) (string, string, error) {
+	info, err := readAuthInfo(root)
+	if err != nil {
+		return "", "", err
+	}
+	if info.Mode == domain.AuthModePassword {
+		if info.Password == "" {
+			return "", "", errors.New("auth.password is empty")
+		}
+		return "", info.Password, nil
+	}
+	userPass, err := findUserPass(root)
+	if err != nil {
+		return "", "", err
+	}
+	passwordNode := findMappingValue(userPass, username)
+	if passwordNode == nil {
+		return "", "", domain.ErrUserNotFound
+	}
+	return username, passwordNode.Value, nil
+}
+
 func findMappingValue(mapping *yaml.Node, key string) *yaml.Node {
 	if mapping == nil || mapping.Kind != yaml.MappingNode {
 		return nil
@@ -259,6 +617,20 @@ func ensureMappingValue(mapping *yaml.Node, key string) *yaml.Node {
 	return valueNode
 }
 
+func setMappingScalar(mapping *yaml.Node, key, value string) {
+	if node := findMappingValue(mapping, key); node != nil {
+		node.Kind = yaml.ScalarNode
+		node.Value = value
+		node.Tag = "!!str"
+		node.Content = nil
+		return
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key, Tag: "!!str"},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value, Tag: "!!str"},
+	)
+}
+
 func deleteMappingKey(mapping *yaml.Node, key string) bool {
 	if mapping == nil || mapping.Kind != yaml.MappingNode {
 		return false
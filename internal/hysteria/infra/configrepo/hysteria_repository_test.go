@@ -21,12 +21,12 @@ func TestRepository_AddUser(t *testing.T) {
 		path := filepath.Join(dir, "config.yaml")
 		seed := `acme:
   domains:
-    - v1.fr.lerner.dev
-  email: lerner1796@gmail.com
+    - hy.example.com
+  email: admin@example.com
 auth:
   type: "userpass"
   userpass:
-    lerner: "123"
+    carol: "123"
 masquerade:
   type: proxy
 `
@@ -64,7 +64,7 @@ masquerade:
 		seed := `auth:
   type: "userpass"
   userpass:
-    lerner: "123"
+    carol: "123"
 `
 
 		if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
@@ -72,11 +72,43 @@ masquerade:
 		}
 
 		repo := NewRepository(path)
-		err := repo.AddUser(context.Background(), domain.User{Username: "lerner", Password: "456"})
+		err := repo.AddUser(context.Background(), domain.User{Username: "carol", Password: "456"})
 		if !errors.Is(err, domain.ErrUserAlreadyExists) {
 			t.Fatalf("expected ErrUserAlreadyExists, got: %v", err)
 		}
 	})
+
+	t.Run("keeps the mode of the existing config", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.yaml")
+		seed := `auth:
+  type: "userpass"
+  userpass:
+    carol: "123"
+`
+
+		if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+			t.Fatalf("write seed: %v", err)
+		}
+		if err := os.Chmod(path, 0o640); err != nil {
+			t.Fatalf("chmod seed: %v", err)
+		}
+
+		repo := NewRepository(path)
+		if err := repo.AddUser(context.Background(), domain.User{Username: "valera", Password: "456"}); err != nil {
+			t.Fatalf("add user: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("stat config: %v", err)
+		}
+		if info.Mode().Perm() != 0o640 {
+			t.Fatalf("expected mode 0640, got %o", info.Mode().Perm())
+		}
+	})
 }
 
 func TestRepository_GetConnectionConfig(t *testing.T) {
@@ -87,7 +119,7 @@ func TestRepository_GetConnectionConfig(t *testing.T) {
 	seed := `listen: :443
 acme:
   domains:
-    - v1.fr.lerner.dev
+    - hy.example.com
 auth:
   type: "userpass"
   userpass:
@@ -110,10 +142,10 @@ obfs:
 	if got.Username != "valera" || got.Password != "321" {
 		t.Fatalf("unexpected credentials: %+v", got)
 	}
-	if got.Host != "v1.fr.lerner.dev" || got.Port != 443 {
+	if got.Host != "hy.example.com" || got.Port != 443 {
 		t.Fatalf("unexpected endpoint: %+v", got)
 	}
-	if got.SNI != "v1.fr.lerner.dev" {
+	if got.SNI != "hy.example.com" {
 		t.Fatalf("unexpected sni: %+v", got)
 	}
 	if got.ObfsType != "salamander" || got.ObfsPassword != "32111" {
@@ -253,3 +285,310 @@ func TestRepository_ListUsers(t *testing.T) {
 		t.Fatalf("unexpected users: %#v", users)
 	}
 }
+
+func TestRepository_Checksum(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	repo := NewRepository(path)
+
+	empty, err := repo.Checksum(context.Background())
+	if err != nil || empty != "" {
+		t.Fatalf("expected empty checksum for missing file, got %q (%v)", empty, err)
+	}
+
+	seed := `auth:
+  type: "userpass"
+  userpass:
+    alice: "111"
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	before, err := repo.Checksum(context.Background())
+	if err != nil || before == "" {
+		t.Fatalf("checksum: %q (%v)", before, err)
+	}
+	if err := repo.AddUser(context.Background(), domain.User{Username: "bob", Password: "222"}); err != nil {
+		t.Fatalf("add user: %v", err)
+	}
+	after, err := repo.Checksum(context.Background())
+	if err != nil {
+		t.Fatalf("checksum: %v", err)
+	}
+	if after == before {
+		t.Fatal("expected checksum to change after write")
+	}
+}
+
+func TestRepository_ApplyChanges(t *testing.T) {
+	t.Parallel()
+
+	seed := `auth:
+  type: "userpass"
+  userpass:
+    alice: "111"
+    bob: "222"
+masquerade:
+  type: proxy
+`
+
+	t.Run("applies all changes in one write", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+			t.Fatalf("write seed: %v", err)
+		}
+		repo := NewRepository(path)
+		err := repo.ApplyChanges(context.Background(), []domain.UserChange{
+			{Action: domain.UserChangeAdd, User: domain.User{Username: "carol", Password: "333"}},
+			{Action: domain.UserChangeRotate, User: domain.User{Username: "alice", Password: "999"}},
+			{Action: domain.UserChangeRemove, User: domain.User{Username: "bob"}},
+		})
+		if err != nil {
+			t.Fatalf("apply changes: %v", err)
+		}
+		users, err := repo.Users(context.Background())
+		if err != nil {
+			t.Fatalf("users: %v", err)
+		}
+		if len(users) != 2 || users[0].Username != "alice" || users[0].Password != "999" || users[1].Username != "carol" || users[1].Password != "333" {
+			t.Fatalf("unexpected users: %+v", users)
+		}
+	})
+
+	t.Run("leaves config untouched when any change fails", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+			t.Fatalf("write seed: %v", err)
+		}
+		repo := NewRepository(path)
+		err := repo.ApplyChanges(context.Background(), []domain.UserChange{
+			{Action: domain.UserChangeAdd, User: domain.User{Username: "carol", Password: "333"}},
+			{Action: domain.UserChangeRemove, User: domain.User{Username: "ghost"}},
+		})
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got: %v", err)
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read config: %v", err)
+		}
+		if string(raw) != seed {
+			t.Fatalf("config should be untouched: %s", raw)
+		}
+	})
+}
+
+func TestRepository_UserTags(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := `auth:
+  type: "userpass"
+  userpass:
+    alice: "111" # tags: ops,team-a
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	repo := NewRepository(path)
+	if err := repo.AddUser(context.Background(), domain.User{Username: "bob", Password: "222", Tags: []string{"team-a"}}); err != nil {
+		t.Fatalf("add user: %v", err)
+	}
+	if err := repo.RotatePassword(context.Background(), domain.User{Username: "alice", Password: "999"}); err != nil {
+		t.Fatalf("rotate password: %v", err)
+	}
+
+	users, err := repo.Users(context.Background())
+	if err != nil {
+		t.Fatalf("users: %v", err)
+	}
+	if len(users) != 2 || !users[0].HasTag("ops") || !users[0].HasTag("team-a") || !users[1].HasTag("team-a") {
+		t.Fatalf("unexpected tags: %+v", users)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(raw), `bob: "222" # tags: team-a`) {
+		t.Fatalf("expected tags comment for new user: %s", raw)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 2 {
+		t.Fatalf("expected only the config and its lock file, got %d entries", len(entries))
+	}
+
+	retag := []domain.UserChange{
+		{Action: domain.UserChangeTags, User: domain.User{Username: "alice", Tags: []string{"ops"}}},
+		{Action: domain.UserChangeTags, User: domain.User{Username: "bob"}},
+	}
+	if err := repo.ApplyChanges(context.Background(), retag); err != nil {
+		t.Fatalf("retag: %v", err)
+	}
+	raw, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(raw), `alice: "999" # tags: ops`+"\n") || !strings.Contains(string(raw), `bob: "222"`+"\n") {
+		t.Fatalf("expected tags to be replaced and cleared: %s", raw)
+	}
+}
+
+func TestRepository_AuthModes(t *testing.T) {
+	t.Parallel()
+
+	const sharedSeed = `acme:
+  domains:
+    - hy.example.com
+auth:
+  type: password
+  password: shared-secret
+`
+
+	t.Run("reads auth info and rejects user management outside userpass", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(sharedSeed), 0o600); err != nil {
+			t.Fatalf("write seed: %v", err)
+		}
+		repo := NewRepository(path)
+
+		info, err := repo.AuthInfo(context.Background())
+		if err != nil {
+			t.Fatalf("auth info: %v", err)
+		}
+		if info.Mode != domain.AuthModePassword || info.Password != "shared-secret" {
+			t.Fatalf("unexpected auth info: %+v", info)
+		}
+		if _, err := repo.ListUsers(context.Background()); !errors.Is(err, domain.ErrReadOnlyAuthMode) {
+			t.Fatalf("expected ErrReadOnlyAuthMode from ListUsers, got %v", err)
+		}
+		if err := repo.AddUser(context.Background(), domain.User{Username: "a", Password: "b"}); !errors.Is(err, domain.ErrReadOnlyAuthMode) {
+			t.Fatalf("expected ErrReadOnlyAuthMode from AddUser, got %v", err)
+		}
+
+		cfg, err := repo.GetConnectionConfig(context.Background(), "anyone")
+		if err != nil {
+			t.Fatalf("connection config: %v", err)
+		}
+		if cfg.Username != "" || cfg.Password != "shared-secret" {
+			t.Fatalf("expected shared credentials: %+v", cfg)
+		}
+	})
+
+	t.Run("rotates shared password", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(sharedSeed), 0o600); err != nil {
+			t.Fatalf("write seed: %v", err)
+		}
+		repo := NewRepository(path)
+
+		if err := repo.SetSharedPassword(context.Background(), "rotated"); err != nil {
+			t.Fatalf("set shared password: %v", err)
+		}
+		info, err := repo.AuthInfo(context.Background())
+		if err != nil || info.Password != "rotated" {
+			t.Fatalf("expected rotated password: %+v %v", info, err)
+		}
+	})
+
+	t.Run("migrates shared password to userpass", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(sharedSeed), 0o600); err != nil {
+			t.Fatalf("write seed: %v", err)
+		}
+		repo := NewRepository(path)
+
+		user, err := repo.MigrateToUserpass(context.Background(), "default")
+		if err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		if user.Username != "default" || user.Password != "shared-secret" {
+			t.Fatalf("unexpected migrated user: %+v", user)
+		}
+		users, err := repo.Users(context.Background())
+		if err != nil {
+			t.Fatalf("users: %v", err)
+		}
+		if len(users) != 1 || users[0].Username != "default" || users[0].Password != "shared-secret" {
+			t.Fatalf("unexpected users after migration: %+v", users)
+		}
+		raw, _ := os.ReadFile(path)
+		if strings.Contains(string(raw), "password: shared-secret") {
+			t.Fatalf("auth.password must be removed: %s", raw)
+		}
+
+		if _, err := repo.MigrateToUserpass(context.Background(), "default"); !errors.Is(err, domain.ErrUnsupportedAuthMode) {
+			t.Fatalf("second migration must fail with ErrUnsupportedAuthMode, got %v", err)
+		}
+	})
+
+	t.Run("rejects unknown auth type", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("auth:\n  type: ldap\n"), 0o600); err != nil {
+			t.Fatalf("write seed: %v", err)
+		}
+		if _, err := NewRepository(path).AuthInfo(context.Background()); !errors.Is(err, domain.ErrUnsupportedAuthMode) {
+			t.Fatalf("expected ErrUnsupportedAuthMode, got %v", err)
+		}
+	})
+}
+
+func TestRepository_SwitchAuthBackend(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := `acme:
+  domains:
+    - hy.example.com
+auth:
+  type: userpass
+  userpass:
+    alice: "a"
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	repo := NewRepository(path)
+	ctx := context.Background()
+
+	if err := repo.SwitchToHTTPAuth(ctx, "http://127.0.0.1:18989/auth"); err != nil {
+		t.Fatalf("switch to http: %v", err)
+	}
+	info, err := repo.AuthInfo(ctx)
+	if err != nil {
+		t.Fatalf("auth info: %v", err)
+	}
+	if info.Mode != domain.AuthModeHTTP || info.HTTPURL != "http://127.0.0.1:18989/auth" {
+		t.Fatalf("unexpected auth info: %+v", info)
+	}
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "userpass") {
+		t.Fatalf("userpass must be removed after switching to http: %s", raw)
+	}
+	server, err := repo.ServerConfig(ctx)
+	if err != nil || server.Host != "hy.example.com" || server.Port != 443 || server.Password != "" {
+		t.Fatalf("unexpected server config: %+v %v", server, err)
+	}
+
+	if err := repo.SwitchToUserpass(ctx, []domain.User{{Username: "alice", Password: "a"}, {Username: "bob", Password: "b"}}); err != nil {
+		t.Fatalf("switch to userpass: %v", err)
+	}
+	users, err := repo.Users(ctx)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("unexpected users after switch back: %+v %v", users, err)
+	}
+	if err := repo.SwitchToUserpass(ctx, nil); !errors.Is(err, domain.ErrUnsupportedAuthMode) {
+		t.Fatalf("expected ErrUnsupportedAuthMode, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package configrepo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/hysteria/infra/remote/remotetest"
+)
+
+func TestRepository_RemoteConfig(t *testing.T) {
+	t.Parallel()
+
+	server := remotetest.NewServer(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := "listen: :443\nauth:\n  type: userpass\n  userpass:\n    alice: a\n"
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+
+	repo := NewRepository(server.Location(path))
+	ctx := context.Background()
+
+	// Concurrent writers from different repositories must not lose updates.
+	var wg sync.WaitGroup
+	for _, name := range []string{"bob", "carol", "dave"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := NewRepository(server.Location(path)).AddUser(ctx, domain.User{Username: name, Password: "p"}); err != nil {
+				t.Errorf("add %s: %v", name, err)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	users, err := repo.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("list users: %v", err)
+	}
+	if strings.Join(users, ",") != "alice,bob,carol,dave" {
+		t.Fatalf("unexpected users: %v", users)
+	}
+
+	sum, err := repo.Checksum(ctx)
+	if err != nil || sum == "" {
+		t.Fatalf("checksum: %q %v", sum, err)
+	}
+	if _, err := os.Stat(path + ".lock"); err != nil {
+		t.Fatalf("writes must take the remote lock: %v", err)
+	}
+}
+
+func TestRepository_LocalLock(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := "auth:\n  type: userpass\n  userpass:\n    alice: a\n"
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+
+	// Separate repositories stand in for separate CLI and TUI processes.
+	var wg sync.WaitGroup
+	for _, name := range []string{"bob", "carol", "dave"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := NewRepository(path).AddUser(context.Background(), domain.User{Username: name, Password: "p"}); err != nil {
+				t.Errorf("add %s: %v", name, err)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	users, err := NewRepository(path).ListUsers(context.Background())
+	if err != nil || strings.Join(users, ",") != "alice,bob,carol,dave" {
+		t.Fatalf("unexpected users %v: %v", users, err)
+	}
+	if _, err := os.Stat(path + ".lock"); err != nil {
+		t.Fatalf("writes must take the local lock: %v", err)
+	}
+}
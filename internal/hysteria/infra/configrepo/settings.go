@@ -0,0 +1,134 @@
+package configrepo
+
+import (
+	"context"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vpn/internal/hysteria/domain"
+)
+
+// Settings reads every setting of domain.SettingDefs from the config.
+func (r *Repository) Settings(_ context.Context) ([]domain.SettingValue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, root, err := r.readRoot()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]domain.SettingValue, 0, len(domain.SettingDefs))
+	for _, def := range domain.SettingDefs {
+		value, set := readSetting(root, def)
+		values = append(values, domain.SettingValue{Def: def, Value: value, Set: set})
+	}
+	return values, nil
+}
+
+// ApplySettings writes normalized setting values in one write. An empty
+// value removes the key together with sections left empty.
+func (r *Repository) ApplySettings(_ context.Context, changes []domain.SettingChange) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		def, err := domain.LookupSetting(change.Key)
+		if err != nil {
+			return err
+		}
+		applySetting(root, def, change.Value)
+	}
+	return r.writeDoc(doc)
+}
+
+func readSetting(root *yaml.Node, def domain.SettingDef) (string, bool) {
+	if def.Key == "obfs.password" {
+		// Configs written by older versions keep the password inline.
+		if salamander := findMappingValue(findMappingValue(root, "obfs"), "salamander"); salamander != nil && salamander.Kind == yaml.ScalarNode {
+			return salamander.Value, salamander.Value != ""
+		}
+	}
+	node := root
+	for _, key := range strings.Split(def.Path, ".") {
+		if node = findMappingValue(node, key); node == nil {
+			return "", false
+		}
+	}
+	if node.Kind != yaml.ScalarNode || node.ShortTag() == "!!null" {
+		return "", false
+	}
+	return node.Value, true
+}
+
+func applySetting(root *yaml.Node, def domain.SettingDef, value string) {
+	path := strings.Split(def.Path, ".")
+	switch {
+	case value == domain.SettingNone && (def.Key == "obfs.type" || def.Key == "masquerade.type"):
+		deleteMappingKey(root, path[0])
+		return
+	case value == "":
+		deletePath(root, path)
+		return
+	case def.Key == "obfs.password":
+		// A password only makes sense for salamander, the one obfs type.
+		obfs := ensureMapping(root, "obfs")
+		if t := findMappingValue(obfs, "type"); t == nil || t.Value == "" || t.Value == "plain" {
+			setMappingScalar(obfs, "type", "salamander")
+		}
+	}
+
+	node := root
+	for _, key := range path[:len(path)-1] {
+		node = ensureMapping(node, key)
+	}
+	tag := "!!str"
+	if def.Kind == domain.SettingBool {
+		tag = "!!bool"
+	}
+	leaf := path[len(path)-1]
+	if existing := findMappingValue(node, leaf); existing != nil {
+		style := existing.Style
+		if existing.Kind != yaml.ScalarNode || tag != "!!str" {
+			style = 0
+		}
+		*existing = yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value, Style: style, Line: existing.Line, Column: existing.Column}
+		return
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: leaf},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value},
+	)
+}
+
+// ensureMapping returns the mapping under key, replacing a scalar or null
+// value with an empty mapping.
+func ensureMapping(mapping *yaml.Node, key string) *yaml.Node {
+	node := ensureMappingValue(mapping, key)
+	if node.Kind != yaml.MappingNode {
+		*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: node.Line, Column: node.Column}
+	}
+	return node
+}
+
+func deletePath(root *yaml.Node, path []string) {
+	parents := []*yaml.Node{root}
+	for _, key := range path[:len(path)-1] {
+		next := findMappingValue(parents[len(parents)-1], key)
+		if next == nil || next.Kind != yaml.MappingNode {
+			return
+		}
+		parents = append(parents, next)
+	}
+	deleteMappingKey(parents[len(parents)-1], path[len(path)-1])
+	for i := len(parents) - 1; i > 0 && len(parents[i].Content) == 0; i-- {
+		deleteMappingKey(parents[i-1], path[i-1])
+	}
+}
@@ -0,0 +1,117 @@
+package configrepo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+func TestRepository_Settings(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := `listen: :443
+acme:
+  domains:
+    - hy.example.com
+obfs:
+  type: salamander
+  salamander: "32111"
+auth:
+  type: "userpass"
+  userpass:
+    valera: "321"
+masquerade:
+  type: proxy
+  proxy:
+    url: https://news.ycombinator.com/
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	repo := NewRepository(path)
+
+	values, err := repo.Settings(context.Background())
+	if err != nil {
+		t.Fatalf("settings: %v", err)
+	}
+	got := map[string]domain.SettingValue{}
+	for _, v := range values {
+		got[v.Def.Key] = v
+	}
+	if got["obfs.password"].Value != "32111" || got["masquerade.url"].Value != "https://news.ycombinator.com/" || got["udpIdleTimeout"].Set {
+		t.Fatalf("unexpected settings: %+v", got)
+	}
+
+	err = repo.ApplySettings(context.Background(), []domain.SettingChange{
+		{Key: "obfs.password", Value: "new-secret"},
+		{Key: "masquerade.url", Value: ""},
+		{Key: "masquerade.type", Value: "string"},
+		{Key: "masquerade.content", Value: "hello"},
+		{Key: "bandwidth.up", Value: "1 gbps"},
+		{Key: "ignoreClientBandwidth", Value: "true"},
+	})
+	if err != nil {
+		t.Fatalf("apply settings: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	content := string(raw)
+	for _, want := range []string{"salamander:\n        password: new-secret", "ignoreClientBandwidth: true", "content: hello", "up: 1 gbps", "valera: \"321\""} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected %q in config:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "proxy:") {
+		t.Fatalf("empty masquerade.proxy section should be removed:\n%s", content)
+	}
+
+	if err := repo.ApplySettings(context.Background(), []domain.SettingChange{{Key: "obfs.type", Value: domain.SettingNone}}); err != nil {
+		t.Fatalf("disable obfs: %v", err)
+	}
+	values, err = repo.Settings(context.Background())
+	if err != nil {
+		t.Fatalf("settings: %v", err)
+	}
+	for _, v := range values {
+		if strings.HasPrefix(v.Def.Key, "obfs.") && v.Set {
+			t.Fatalf("obfs should be removed: %+v", v)
+		}
+	}
+}
+
+func TestRepository_ApplySettingsRejectsInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := `acme:
+  domains:
+    - hy.example.com
+auth:
+  type: "userpass"
+  userpass:
+    valera: "321"
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+
+	err := NewRepository(path).ApplySettings(context.Background(), []domain.SettingChange{{Key: "obfs.type", Value: "salamander"}})
+	if !errors.Is(err, domain.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig for salamander without password, got %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if string(raw) != seed {
+		t.Fatalf("config should be untouched: %s", raw)
+	}
+}
@@ -0,0 +1,208 @@
+package configrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"vpn/internal/hysteria/domain"
+)
+
+// acmeIssuers are the certmagic storage folders of the CAs Hysteria uses.
+var acmeIssuers = []string{
+	"acme-v02.api.letsencrypt.org-directory",
+	"acme.zerossl.com-v2-dv90",
+}
+
+// InstallCertificate writes a certificate and key next to the config (or to
+// the given paths) and switches the config from acme to tls with them.
+// Empty paths keep the current tls.cert/tls.key or default to server.crt and
+// server.key in the config directory.
+func (r *Repository) InstallCertificate(_ context.Context, certPath, keyPath string, certPEM, keyPEM []byte) (string, string, error) {
+	unlock, err := r.lock()
+	if err != nil {
+		return "", "", err
+	}
+	defer unlock()
+
+	doc, root, err := r.readRoot()
+	if err != nil {
+		return "", "", err
+	}
+
+	tls := findMappingValue(root, "tls")
+	if certPath == "" {
+		certPath = scalarValue(findMappingValue(tls, "cert"))
+	}
+	if keyPath == "" {
+		keyPath = scalarValue(findMappingValue(tls, "key"))
+	}
+	dir := path.Dir(r.path)
+	if certPath == "" {
+		certPath = path.Join(dir, "server.crt")
+	}
+	if keyPath == "" {
+		keyPath = path.Join(dir, "server.key")
+	}
+
+	deleteMappingKey(root, "acme")
+	tls = ensureMappingValue(root, "tls")
+	setMappingScalar(tls, "cert", certPath)
+	setMappingScalar(tls, "key", keyPath)
+	// Validate before the key and certificate land on the server.
+	if err := r.checkDocument(doc); err != nil {
+		return "", "", err
+	}
+
+	if err := r.files.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return "", "", fmt.Errorf("write key: %w", err)
+	}
+	if err := r.files.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return "", "", fmt.Errorf("write certificate: %w", err)
+	}
+	return certPath, keyPath, r.writeDoc(doc)
+}
+
+// TLSStatus reads the certificates the server presents: the tls.cert file,
+// or the certificates ACME stored for each domain under acmeDir. An empty
+// acmeDir uses acme.dir of the config.
+func (r *Repository) TLSStatus(_ context.Context, acmeDir string) (domain.TLSStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, root, err := r.readRoot()
+	if err != nil {
+		return domain.TLSStatus{}, err
+	}
+
+	if acme := findMappingValue(root, "acme"); acme != nil {
+		status := domain.TLSStatus{Mode: domain.TLSModeACME}
+		if acmeDir == "" {
+			acmeDir = scalarValue(findMappingValue(acme, "dir"))
+		}
+		domains := findMappingValue(acme, "domains")
+		if domains == nil || domains.Kind != yaml.SequenceNode || len(domains.Content) == 0 {
+			return domain.TLSStatus{}, errors.New("acme.domains[0] is required")
+		}
+		for _, node := range domains.Content {
+			status.Certificates = append(status.Certificates, r.acmeCertificate(strings.TrimSpace(node.Value), acmeDir))
+		}
+		return status, nil
+	}
+
+	certPath := scalarValue(findMappingValue(findMappingValue(root, "tls"), "cert"))
+	if certPath == "" {
+		return domain.TLSStatus{}, errors.New("neither acme nor tls.cert is configured")
+	}
+	return domain.TLSStatus{
+		Mode:         domain.TLSModeFile,
+		Certificates: []domain.CertificateStatus{r.readCertificate(certPath, certPath)},
+	}, nil
+}
+
+func (r *Repository) acmeCertificate(name, acmeDir string) domain.CertificateStatus {
+	var dirs []string
+	switch {
+	case path.IsAbs(acmeDir):
+		dirs = []string{acmeDir}
+	default:
+		// Relative dirs depend on the working directory of the service;
+		// try the config directory and the usual service homes.
+		if acmeDir == "" {
+			acmeDir = "acme"
+		}
+		for _, base := range []string{path.Dir(r.path), "/var/lib/hysteria", "/root", "/"} {
+			dirs = append(dirs, path.Join(base, acmeDir))
+		}
+	}
+
+	var tried []string
+	for _, dir := range dirs {
+		for _, issuer := range acmeIssuers {
+			p := path.Join(dir, "certificates", issuer, name, name+".crt")
+			status := r.readCertificate(name, p)
+			if !errors.Is(status.Err, os.ErrNotExist) {
+				return status
+			}
+			tried = append(tried, path.Join(dir, "certificates"))
+		}
+	}
+	return domain.CertificateStatus{
+		Name: name,
+		Err:  fmt.Errorf("no ACME certificate found in %s: %w", strings.Join(dedupe(tried), ", "), os.ErrNotExist),
+	}
+}
+
+func (r *Repository) readCertificate(name, certPath string) domain.CertificateStatus {
+	status := domain.CertificateStatus{Name: name, Path: certPath}
+	raw, err := r.files.ReadFile(certPath)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	cert, err := domain.ParseCertificatePEM(raw)
+	if err != nil {
+		status.Err = fmt.Errorf("%s: %w", certPath, err)
+		return status
+	}
+	status.Cert = &cert
+	return status
+}
+
+// readFileTLS fills host, SNI and the pin from the tls.cert file. Clients
+// connect to the first IP of the certificate, or its DNS name. Self-signed
+// certificates need insecure=1 together with the pin.
+func (r *Repository) readFileTLS(root *yaml.Node, cfg *domain.ConnectionConfig) error {
+	certPath := scalarValue(findMappingValue(findMappingValue(root, "tls"), "cert"))
+	if certPath == "" {
+		return errors.New("tls.cert is required")
+	}
+	status := r.readCertificate(certPath, certPath)
+	if status.Err != nil {
+		return fmt.Errorf("read tls.cert: %w", status.Err)
+	}
+	cert := status.Cert
+	if len(cert.DNSNames) > 0 {
+		cfg.SNI = cert.DNSNames[0]
+	}
+	switch {
+	case len(cert.IPs) > 0:
+		cfg.Host = cert.IPs[0]
+	case cfg.SNI != "":
+		cfg.Host = cfg.SNI
+	default:
+		cfg.Host = cert.Subject
+	}
+	if cfg.Host == "" {
+		return errors.New("tls.cert has no IP, DNS name or common name")
+	}
+	if cert.SelfSigned {
+		cfg.Insecure = true
+		cfg.PinSHA256 = cert.PinSHA256
+	}
+	return nil
+}
+
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return strings.TrimSpace(node.Value)
+}
+
+func dedupe(items []string) []string {
+	seen := map[string]bool{}
+	out := items[:0]
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
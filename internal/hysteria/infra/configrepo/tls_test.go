@@ -0,0 +1,116 @@
+package configrepo
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/utils/certgen"
+)
+
+func TestRepository_InstallCertificate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	seed := `listen: :8443
+acme:
+  domains:
+    - hy.example.com
+  email: admin@example.com
+auth:
+  type: "userpass"
+  userpass:
+    valera: "321"
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+
+	certPEM, keyPEM, err := certgen.NewGenerator().SelfSigned("1.2.3.4", nil, []net.IP{net.ParseIP("1.2.3.4")}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	repo := NewRepository(path)
+	certPath, keyPath, err := repo.InstallCertificate(context.Background(), "", "", certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("install certificate: %v", err)
+	}
+	if certPath != filepath.Join(dir, "server.crt") || keyPath != filepath.Join(dir, "server.key") {
+		t.Fatalf("unexpected paths: %s %s", certPath, keyPath)
+	}
+	if info, err := os.Stat(keyPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("key not written with 0600: %v %v", info, err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if content := string(raw); strings.Contains(content, "acme:") || !strings.Contains(content, "cert: "+certPath) || !strings.Contains(content, "valera:") {
+		t.Fatalf("unexpected config: %s", content)
+	}
+
+	got, err := repo.GetConnectionConfig(context.Background(), "valera")
+	if err != nil {
+		t.Fatalf("get connection config: %v", err)
+	}
+	cert, _ := domain.ParseCertificatePEM(certPEM)
+	if got.Host != "1.2.3.4" || got.Port != 8443 || got.SNI != "" || !got.Insecure || got.PinSHA256 != cert.PinSHA256 {
+		t.Fatalf("unexpected connection config: %+v", got)
+	}
+
+	status, err := repo.TLSStatus(context.Background(), "")
+	if err != nil {
+		t.Fatalf("tls status: %v", err)
+	}
+	if status.Mode != domain.TLSModeFile || len(status.Certificates) != 1 || status.Certificates[0].Cert == nil {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestRepository_TLSStatusACME(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	seed := `acme:
+  domains:
+    - hy.example.com
+    - hy2.example.com
+  dir: certs
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	certPEM, _, err := certgen.NewGenerator().SelfSigned("hy.example.com", []string{"hy.example.com"}, nil, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	certDir := filepath.Join(dir, "certs", "certificates", "acme-v02.api.letsencrypt.org-directory", "hy.example.com")
+	if err := os.MkdirAll(certDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(certDir, "hy.example.com.crt"), certPEM, 0o600); err != nil {
+		t.Fatalf("write cert: %v", err)
+	}
+
+	status, err := NewRepository(path).TLSStatus(context.Background(), "")
+	if err != nil {
+		t.Fatalf("tls status: %v", err)
+	}
+	if status.Mode != domain.TLSModeACME || len(status.Certificates) != 2 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if c := status.Certificates[0]; c.Err != nil || c.Cert == nil || c.Cert.Subject != "hy.example.com" {
+		t.Fatalf("unexpected first certificate: %+v", c)
+	}
+	if c := status.Certificates[1]; c.Err == nil || c.Cert != nil {
+		t.Fatalf("expected missing second certificate: %+v", c)
+	}
+}
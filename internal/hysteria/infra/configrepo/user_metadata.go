@@ -0,0 +1,65 @@
+package configrepo
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const metaTags = "tags"
+
+func readUserMeta(passwordNode *yaml.Node) map[string]string {
+	meta := map[string]string{}
+	comment := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(passwordNode.LineComment), "#"))
+	if comment == "" {
+		return meta
+	}
+	for _, part := range strings.Split(comment, ";") {
+		key, value, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		meta[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return meta
+}
+
+func writeUserMeta(passwordNode *yaml.Node, meta map[string]string) {
+	keys := make([]string, 0, len(meta))
+	for key, value := range meta {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		passwordNode.LineComment = ""
+		return
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+": "+meta[key])
+	}
+	passwordNode.LineComment = "# " + strings.Join(parts, "; ")
+}
+
+func readUserTags(passwordNode *yaml.Node) []string {
+	raw := readUserMeta(passwordNode)[metaTags]
+	if raw == "" {
+		return nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(raw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func writeUserTags(passwordNode *yaml.Node, tags []string) {
+	meta := readUserMeta(passwordNode)
+	meta[metaTags] = strings.Join(tags, ",")
+	writeUserMeta(passwordNode, meta)
+}
@@ -0,0 +1,585 @@
+package configrepo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"vpn/internal/hysteria/domain"
+)
+
+// Validate checks a Hysteria server config the way the server reads it:
+// known keys, value types, one of acme/tls, obfs, auth, listen addresses
+// and bandwidth units. Unknown keys are warnings, Hysteria ignores them.
+func Validate(data []byte) []domain.ConfigIssue {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []domain.ConfigIssue{parseIssue(err)}
+	}
+	return validateDocument(&doc)
+}
+
+// Validate reads the config of the repository and validates it.
+func (r *Repository) Validate(_ context.Context) ([]domain.ConfigIssue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	raw, err := r.files.ReadFile(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return Validate(raw), nil
+}
+
+// checkDocument is run before every write. It refuses errors the change
+// introduces; problems the config already had when it was read don't block
+// unrelated edits, and warnings never do.
+func (r *Repository) checkDocument(doc *yaml.Node) error {
+	var introduced []domain.ConfigIssue
+	for _, issue := range domain.ConfigErrors(validateDocument(doc)) {
+		if !r.known[issueKey(issue)] {
+			introduced = append(introduced, issue)
+		}
+	}
+	if len(introduced) > 0 {
+		return &domain.ConfigValidationError{Issues: introduced}
+	}
+	return nil
+}
+
+// issueKey identifies an issue across edits, which move line numbers.
+func issueKey(issue domain.ConfigIssue) string {
+	return issue.Path + "\x00" + issue.Message
+}
+
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+func parseIssue(err error) domain.ConfigIssue {
+	issue := domain.ConfigIssue{Severity: domain.ConfigIssueError, Message: err.Error()}
+	if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+		issue.Line, _ = strconv.Atoi(m[1])
+		issue.Message = m[2]
+	}
+	return issue
+}
+
+type validator struct {
+	issues []domain.ConfigIssue
+}
+
+func (v *validator) report(node *yaml.Node, severity, path, format string, args ...any) {
+	issue := domain.ConfigIssue{Path: path, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	v.issues = append(v.issues, issue)
+}
+
+func (v *validator) errorf(node *yaml.Node, path, format string, args ...any) {
+	v.report(node, domain.ConfigIssueError, path, format, args...)
+}
+
+func (v *validator) warnf(node *yaml.Node, path, format string, args ...any) {
+	v.report(node, domain.ConfigIssueWarning, path, format, args...)
+}
+
+var topLevelKeys = map[string]func(v *validator, node *yaml.Node){
+	"listen":                func(v *validator, n *yaml.Node) { v.address(n, "listen") },
+	"tls":                   (*validator).tls,
+	"acme":                  (*validator).acme,
+	"obfs":                  (*validator).obfs,
+	"quic":                  func(v *validator, n *yaml.Node) { v.kind(n, "quic", yaml.MappingNode) },
+	"bandwidth":             (*validator).bandwidth,
+	"ignoreClientBandwidth": func(v *validator, n *yaml.Node) { v.boolean(n, "ignoreClientBandwidth") },
+	"speedTest":             func(v *validator, n *yaml.Node) { v.boolean(n, "speedTest") },
+	"disableUDP":            func(v *validator, n *yaml.Node) { v.boolean(n, "disableUDP") },
+	"udpIdleTimeout":        func(v *validator, n *yaml.Node) { v.duration(n, "udpIdleTimeout") },
+	"auth":                  (*validator).auth,
+	"resolver":              func(v *validator, n *yaml.Node) { v.kind(n, "resolver", yaml.MappingNode) },
+	"sniff":                 func(v *validator, n *yaml.Node) { v.kind(n, "sniff", yaml.MappingNode) },
+	"acl":                   (*validator).acl,
+	"outbounds":             (*validator).outbounds,
+	"trafficStats":          (*validator).trafficStats,
+	"masquerade":            (*validator).masquerade,
+}
+
+func validateDocument(doc *yaml.Node) []domain.ConfigIssue {
+	v := &validator{}
+	if len(doc.Content) == 0 {
+		v.errorf(nil, "", "config is empty")
+		return v.issues
+	}
+	root := doc.Content[0]
+	if !v.kind(root, "", yaml.MappingNode) {
+		return v.issues
+	}
+	v.duplicates(root, "")
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		check, ok := topLevelKeys[key.Value]
+		if !ok {
+			v.warnf(key, key.Value, "unknown key, Hysteria ignores it")
+			continue
+		}
+		check(v, value)
+	}
+
+	acmeKey, _ := lookup(root, "acme")
+	tlsKey, _ := lookup(root, "tls")
+	switch {
+	case acmeKey != nil && tlsKey != nil:
+		v.errorf(tlsKey, "tls", "acme and tls are mutually exclusive")
+	case acmeKey == nil && tlsKey == nil:
+		v.errorf(root, "", "either acme or tls is required")
+	}
+	if authKey, _ := lookup(root, "auth"); authKey == nil {
+		v.errorf(root, "", "auth is required")
+	}
+	v.aclOutbounds(root)
+
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		a, b := v.issues[i], v.issues[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.issues
+}
+
+func (v *validator) tls(node *yaml.Node) {
+	if !v.kind(node, "tls", yaml.MappingNode) {
+		return
+	}
+	v.knownKeys(node, "tls", "cert", "key", "sniGuard")
+	v.requiredString(node, "tls", "cert")
+	v.requiredString(node, "tls", "key")
+}
+
+func (v *validator) acme(node *yaml.Node) {
+	if !v.kind(node, "acme", yaml.MappingNode) {
+		return
+	}
+	_, domains := lookup(node, "domains")
+	if domains == nil {
+		v.errorf(node, "acme.domains", "at least one domain is required")
+		return
+	}
+	if !v.kind(domains, "acme.domains", yaml.SequenceNode) {
+		return
+	}
+	if len(domains.Content) == 0 {
+		v.errorf(domains, "acme.domains", "at least one domain is required")
+	}
+	for i, item := range domains.Content {
+		path := fmt.Sprintf("acme.domains[%d]", i)
+		if v.kind(item, path, yaml.ScalarNode) && strings.TrimSpace(item.Value) == "" {
+			v.errorf(item, path, "domain is empty")
+		}
+	}
+	if _, email := lookup(node, "email"); email != nil && v.kind(email, "acme.email", yaml.ScalarNode) {
+		if email.Value != "" && !strings.Contains(email.Value, "@") {
+			v.errorf(email, "acme.email", "invalid email %q", email.Value)
+		}
+	}
+}
+
+func (v *validator) obfs(node *yaml.Node) {
+	if !v.kind(node, "obfs", yaml.MappingNode) {
+		return
+	}
+	v.knownKeys(node, "obfs", "type", "salamander")
+	_, typeNode := lookup(node, "type")
+	obfsType := ""
+	if typeNode != nil && v.kind(typeNode, "obfs.type", yaml.ScalarNode) {
+		obfsType = strings.TrimSpace(typeNode.Value)
+	}
+	switch strings.ToLower(obfsType) {
+	case "", "plain":
+	case "salamander":
+		_, salamander := lookup(node, "salamander")
+		if salamander == nil {
+			v.errorf(node, "obfs.salamander.password", "salamander needs a password")
+			return
+		}
+		if salamander.Kind == yaml.ScalarNode {
+			// Older configs written by this CLI keep the password inline;
+			// it is still read, but Hysteria wants salamander.password.
+			v.warnf(salamander, "obfs.salamander", "expected a mapping with password")
+			v.salamanderPassword(salamander, "obfs.salamander")
+			return
+		}
+		if !v.kind(salamander, "obfs.salamander", yaml.MappingNode) {
+			return
+		}
+		_, password := lookup(salamander, "password")
+		if password == nil {
+			v.errorf(salamander, "obfs.salamander.password", "salamander needs a password")
+			return
+		}
+		v.salamanderPassword(password, "obfs.salamander.password")
+	default:
+		v.errorf(typeNode, "obfs.type", "unsupported obfs type %q (allowed: salamander, plain)", obfsType)
+	}
+}
+
+func (v *validator) salamanderPassword(node *yaml.Node, path string) {
+	if !v.kind(node, path, yaml.ScalarNode) {
+		return
+	}
+	if len(node.Value) < 4 {
+		v.errorf(node, path, "password must be at least 4 bytes")
+	}
+}
+
+var authSections = []string{domain.AuthModePassword, domain.AuthModeUserpass, domain.AuthModeHTTP, domain.AuthModeCommand}
+
+func (v *validator) auth(node *yaml.Node) {
+	if !v.kind(node, "auth", yaml.MappingNode) {
+		return
+	}
+	v.knownKeys(node, "auth", append([]string{"type"}, authSections...)...)
+	_, typeNode := lookup(node, "type")
+	if typeNode == nil {
+		v.errorf(node, "auth.type", "auth.type is required")
+		return
+	}
+	if !v.kind(typeNode, "auth.type", yaml.ScalarNode) {
+		return
+	}
+	mode, err := domain.ParseAuthMode(strings.TrimSpace(typeNode.Value))
+	if err != nil {
+		v.errorf(typeNode, "auth.type", "%v (allowed: %s)", err, strings.Join(authSections, ", "))
+		return
+	}
+
+	switch mode {
+	case domain.AuthModePassword:
+		v.requiredString(node, "auth", "password")
+	case domain.AuthModeUserpass:
+		_, users := lookup(node, "userpass")
+		if users == nil || !v.kind(users, "auth.userpass", yaml.MappingNode) {
+			break
+		}
+		for i := 0; i+1 < len(users.Content); i += 2 {
+			name, password := users.Content[i], users.Content[i+1]
+			path := "auth.userpass." + name.Value
+			if strings.TrimSpace(name.Value) == "" {
+				v.errorf(name, "auth.userpass", "username is empty")
+			}
+			if v.kind(password, path, yaml.ScalarNode) && password.Value == "" {
+				v.errorf(password, path, "password is empty")
+			}
+		}
+	case domain.AuthModeHTTP:
+		_, http := lookup(node, "http")
+		if http == nil {
+			v.errorf(node, "auth.http.url", "http auth needs a url")
+			break
+		}
+		if !v.kind(http, "auth.http", yaml.MappingNode) {
+			break
+		}
+		v.knownKeys(http, "auth.http", "url", "insecure")
+		if value := v.requiredString(http, "auth.http", "url"); value != nil {
+			v.httpURL(value, "auth.http.url")
+		}
+		if _, insecure := lookup(http, "insecure"); insecure != nil {
+			v.boolean(insecure, "auth.http.insecure")
+		}
+	case domain.AuthModeCommand:
+		v.requiredString(node, "auth", "command")
+	}
+
+	for _, section := range authSections {
+		if key, _ := lookup(node, section); key != nil && section != mode {
+			v.warnf(key, "auth."+section, "ignored because auth.type is %s", mode)
+		}
+	}
+}
+
+func (v *validator) bandwidth(node *yaml.Node) {
+	if !v.kind(node, "bandwidth", yaml.MappingNode) {
+		return
+	}
+	v.knownKeys(node, "bandwidth", "up", "down")
+	for _, key := range []string{"up", "down"} {
+		path := "bandwidth." + key
+		_, value := lookup(node, key)
+		if value == nil || !v.kind(value, path, yaml.ScalarNode) || value.Value == "" {
+			continue
+		}
+		if _, err := domain.ParseBandwidth(value.Value); err != nil {
+			v.errorf(value, path, "%v", err)
+		}
+	}
+}
+
+func (v *validator) trafficStats(node *yaml.Node) {
+	if !v.kind(node, "trafficStats", yaml.MappingNode) {
+		return
+	}
+	v.knownKeys(node, "trafficStats", "listen", "secret")
+	if _, listen := lookup(node, "listen"); listen != nil {
+		v.address(listen, "trafficStats.listen")
+	}
+}
+
+func (v *validator) masquerade(node *yaml.Node) {
+	if !v.kind(node, "masquerade", yaml.MappingNode) {
+		return
+	}
+	v.knownKeys(node, "masquerade", "type", "file", "proxy", "string", "listenHTTP", "listenHTTPS", "forceHTTPS")
+	_, typeNode := lookup(node, "type")
+	masqueradeType := ""
+	if typeNode != nil && v.kind(typeNode, "masquerade.type", yaml.ScalarNode) {
+		masqueradeType = strings.TrimSpace(typeNode.Value)
+	}
+	required := map[string]string{"file": "dir", "proxy": "url", "string": "content"}
+	switch masqueradeType {
+	case "":
+	case "file", "proxy", "string":
+		_, section := lookup(node, masqueradeType)
+		if section == nil {
+			v.errorf(node, "masquerade."+masqueradeType+"."+required[masqueradeType], "required for masquerade type %s", masqueradeType)
+			return
+		}
+		if !v.kind(section, "masquerade."+masqueradeType, yaml.MappingNode) {
+			return
+		}
+		value := v.requiredString(section, "masquerade."+masqueradeType, required[masqueradeType])
+		if value != nil && masqueradeType == "proxy" {
+			v.httpURL(value, "masquerade.proxy.url")
+		}
+	default:
+		v.errorf(typeNode, "masquerade.type", "unsupported masquerade type %q (allowed: file, proxy, string)", masqueradeType)
+	}
+	for _, key := range []string{"listenHTTP", "listenHTTPS"} {
+		if _, listen := lookup(node, key); listen != nil {
+			v.address(listen, "masquerade."+key)
+		}
+	}
+	if _, force := lookup(node, "forceHTTPS"); force != nil {
+		v.boolean(force, "masquerade.forceHTTPS")
+	}
+}
+
+func (v *validator) acl(node *yaml.Node) {
+	if !v.kind(node, "acl", yaml.MappingNode) {
+		return
+	}
+	v.knownKeys(node, "acl", "file", "inline", "geoip", "geosite", "geoUpdateInterval")
+	fileKey, _ := lookup(node, "file")
+	inlineKey, inline := lookup(node, "inline")
+	if fileKey != nil && inlineKey != nil {
+		v.errorf(inlineKey, "acl.inline", "acl.file and acl.inline are mutually exclusive")
+	}
+	if inline != nil && v.kind(inline, "acl.inline", yaml.SequenceNode) {
+		for i, item := range inline.Content {
+			path := fmt.Sprintf("acl.inline[%d]", i)
+			if !v.kind(item, path, yaml.ScalarNode) {
+				continue
+			}
+			if _, err := domain.ParseACLRule(item.Value); err != nil {
+				v.errorf(item, path, "%v", err)
+			}
+		}
+	}
+	if _, interval := lookup(node, "geoUpdateInterval"); interval != nil {
+		v.duration(interval, "acl.geoUpdateInterval")
+	}
+}
+
+func (v *validator) outbounds(node *yaml.Node) {
+	if !v.kind(node, "outbounds", yaml.SequenceNode) {
+		return
+	}
+	for i, item := range node.Content {
+		path := fmt.Sprintf("outbounds[%d]", i)
+		if !v.kind(item, path, yaml.MappingNode) {
+			continue
+		}
+		v.knownKeys(item, path, "name", "type", "direct", "socks5", "http")
+		if v.requiredString(item, path, "name") == nil || v.requiredString(item, path, "type") == nil {
+			continue
+		}
+		if err := readOutbound(item).Validate(); err != nil {
+			v.errorf(item, path, "%v", err)
+		}
+	}
+}
+
+// aclOutbounds checks what Hysteria checks at startup: outbound names are
+// unique and inline rules only use declared or built-in outbounds.
+func (v *validator) aclOutbounds(root *yaml.Node) {
+	var acl domain.ACL
+	names := map[string]bool{}
+	if _, seq := lookup(root, "outbounds"); seq != nil && seq.Kind == yaml.SequenceNode {
+		for i, item := range seq.Content {
+			o := readOutbound(item)
+			if o.Name == "" {
+				continue
+			}
+			if names[o.Name] {
+				v.errorf(item, fmt.Sprintf("outbounds[%d].name", i), "outbound %q is declared twice", o.Name)
+			}
+			names[o.Name] = true
+			acl.Outbounds = append(acl.Outbounds, o)
+		}
+	}
+	_, section := lookup(root, "acl")
+	_, inline := lookup(section, "inline")
+	if inline == nil || inline.Kind != yaml.SequenceNode {
+		return
+	}
+	for i, item := range inline.Content {
+		rule, err := domain.ParseACLRule(item.Value)
+		if err == nil && !acl.HasOutbound(rule.Outbound) {
+			v.errorf(item, fmt.Sprintf("acl.inline[%d]", i), "unknown outbound %q", rule.Outbound)
+		}
+	}
+}
+
+// address accepts what Hysteria listens on: host:port or :port.
+func (v *validator) address(node *yaml.Node, path string) {
+	if !v.kind(node, path, yaml.ScalarNode) {
+		return
+	}
+	value := strings.TrimSpace(node.Value)
+	if value == "" {
+		return
+	}
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		v.errorf(node, path, "invalid address %q (expected host:port or :port)", value)
+		return
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		v.errorf(node, path, "invalid port %q", port)
+	}
+}
+
+func (v *validator) httpURL(node *yaml.Node, path string) {
+	u, err := url.Parse(strings.TrimSpace(node.Value))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.errorf(node, path, "invalid URL %q (expected http:// or https://)", node.Value)
+	}
+}
+
+func (v *validator) boolean(node *yaml.Node, path string) {
+	if v.kind(node, path, yaml.ScalarNode) && node.ShortTag() != "!!bool" {
+		v.errorf(node, path, "expected true or false, got %q", node.Value)
+	}
+}
+
+func (v *validator) duration(node *yaml.Node, path string) {
+	if !v.kind(node, path, yaml.ScalarNode) {
+		return
+	}
+	if _, err := time.ParseDuration(strings.TrimSpace(node.Value)); err != nil {
+		v.errorf(node, path, "invalid duration %q (expected e.g. 60s or 2m)", node.Value)
+	}
+}
+
+// requiredString reports a missing or empty key and returns its value node
+// when it is set.
+func (v *validator) requiredString(mapping *yaml.Node, path, key string) *yaml.Node {
+	path += "." + key
+	_, value := lookup(mapping, key)
+	if value == nil {
+		v.errorf(mapping, path, "required")
+		return nil
+	}
+	if !v.kind(value, path, yaml.ScalarNode) {
+		return nil
+	}
+	if strings.TrimSpace(value.Value) == "" {
+		v.errorf(value, path, "must not be empty")
+		return nil
+	}
+	return value
+}
+
+var kindNames = map[yaml.Kind]string{
+	yaml.MappingNode:  "a mapping",
+	yaml.SequenceNode: "a list",
+	yaml.ScalarNode:   "a value",
+}
+
+func (v *validator) kind(node *yaml.Node, path string, want yaml.Kind) bool {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if node.Kind == want {
+		return true
+	}
+	if want == yaml.MappingNode && node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		// "section:" with nothing under it is an empty mapping for Hysteria.
+		return false
+	}
+	name := path
+	if name == "" {
+		name = "config"
+	}
+	v.errorf(node, path, "%s must be %s", name, kindNames[want])
+	return false
+}
+
+func (v *validator) knownKeys(mapping *yaml.Node, path string, keys ...string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		known := false
+		for _, k := range keys {
+			if key.Value == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			v.warnf(key, path+"."+key.Value, "unknown key, Hysteria ignores it")
+		}
+	}
+}
+
+// duplicates walks every mapping: yaml.Node keeps repeated keys, and only
+// one of them would reach Hysteria.
+func (v *validator) duplicates(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		seen := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			child := strings.TrimPrefix(path+"."+key.Value, ".")
+			if seen[key.Value] {
+				v.errorf(key, child, "duplicate key")
+			}
+			seen[key.Value] = true
+			v.duplicates(node.Content[i+1], child)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			v.duplicates(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func lookup(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,150 @@
+package configrepo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("accepts a complete config", func(t *testing.T) {
+		t.Parallel()
+
+		issues := Validate([]byte(`listen: :443
+acme:
+  domains:
+    - hy.example.com
+  email: admin@example.com
+obfs:
+  type: salamander
+  salamander:
+    password: "32111"
+auth:
+  type: userpass
+  userpass:
+    valera: "321"
+bandwidth:
+  up: 1 gbps
+  down: 500mbps
+ignoreClientBandwidth: false
+udpIdleTimeout: 60s
+masquerade:
+  type: proxy
+  proxy:
+    url: https://news.ycombinator.com/
+trafficStats:
+  listen: 127.0.0.1:9999
+`))
+		if len(issues) != 0 {
+			t.Fatalf("unexpected issues: %v", issues)
+		}
+	})
+
+	t.Run("reports errors with positions", func(t *testing.T) {
+		t.Parallel()
+
+		issues := Validate([]byte(`listen: :70000
+acme:
+  domains: []
+tls:
+  cert: /etc/hysteria/server.crt
+obfs:
+  type: salamander
+  salamander:
+    password: "abc"
+auth:
+  type: password
+  userpass:
+    valera: "321"
+bandwidth:
+  up: 1.5 gbps
+ignoreClientBandwidth: "yes"
+speedtest: true
+`))
+		got := map[string]string{}
+		for _, issue := range issues {
+			got[issue.String()] = issue.Severity
+		}
+		want := map[string]string{
+			`1:9: listen: invalid port "70000"`:                                 domain.ConfigIssueError,
+			`3:12: acme.domains: at least one domain is required`:               domain.ConfigIssueError,
+			`4:1: tls: acme and tls are mutually exclusive`:                     domain.ConfigIssueError,
+			`5:3: tls.key: required`:                                            domain.ConfigIssueError,
+			`9:15: obfs.salamander.password: password must be at least 4 bytes`: domain.ConfigIssueError,
+			`11:3: auth.password: required`:                                     domain.ConfigIssueError,
+			`12:3: auth.userpass: ignored because auth.type is password`:        domain.ConfigIssueWarning,
+			`17:1: speedtest: unknown key, Hysteria ignores it`:                 domain.ConfigIssueWarning,
+		}
+		for text, severity := range want {
+			if got[text] != severity {
+				t.Errorf("missing %s issue %q in %v", severity, text, issues)
+			}
+		}
+		var bandwidth, boolean bool
+		for _, issue := range issues {
+			bandwidth = bandwidth || (issue.Path == "bandwidth.up" && issue.Line == 15)
+			boolean = boolean || (issue.Path == "ignoreClientBandwidth" && issue.Line == 16)
+		}
+		if !bandwidth || !boolean {
+			t.Errorf("expected bandwidth and bool errors: %v", issues)
+		}
+	})
+
+	t.Run("reports yaml syntax errors", func(t *testing.T) {
+		t.Parallel()
+
+		issues := Validate([]byte("auth:\n  type: userpass\n   userpass: {}\n"))
+		if len(issues) != 1 || issues[0].Line != 3 || issues[0].Severity != domain.ConfigIssueError {
+			t.Fatalf("unexpected issues: %v", issues)
+		}
+	})
+}
+
+func TestRepository_WriteRejectsNewErrors(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	seed := `listen: :443
+auth:
+  type: userpass
+  userpass:
+    alice: "111"
+`
+	if err := os.WriteFile(path, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write seed: %v", err)
+	}
+	repo := NewRepository(path)
+
+	// The seed lacks acme/tls already; that must not block user edits.
+	if err := repo.AddUser(context.Background(), domain.User{Username: "bob", Password: "222"}); err != nil {
+		t.Fatalf("add user: %v", err)
+	}
+
+	repo.mu.Lock()
+	doc, root, err := repo.readRoot()
+	if err != nil {
+		repo.mu.Unlock()
+		t.Fatalf("read root: %v", err)
+	}
+	setMappingScalar(root, "listen", "443")
+	err = repo.writeDoc(doc)
+	repo.mu.Unlock()
+
+	if !errors.Is(err, domain.ErrInvalidConfig) || !strings.Contains(err.Error(), "listen") || strings.Contains(err.Error(), "acme") {
+		t.Fatalf("expected only the new listen error, got %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(raw), "listen: :443") {
+		t.Fatalf("config must not be written: %s", raw)
+	}
+}
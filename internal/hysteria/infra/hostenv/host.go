@@ -0,0 +1,51 @@
+package hostenv
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Host answers questions about the machine the CLI runs on.
+type Host struct{}
+
+func NewHost() *Host {
+	return &Host{}
+}
+
+// CheckFile opens the file to prove it is readable and returns its mode.
+func (h *Host) CheckFile(path string) (fs.FileMode, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Mode(), nil
+}
+
+func (h *Host) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// UDPPortBound tries to bind addr: EADDRINUSE means a server already
+// listens there. Binding a privileged port without root fails with a
+// permission error, which is returned as is.
+func (h *Host) UDPPortBound(addr string) (bool, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err == nil {
+		conn.Close()
+		return false, nil
+	}
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return true, nil
+	}
+	return false, fmt.Errorf("probe udp %s: %w", addr, err)
+}
@@ -0,0 +1,40 @@
+package hostenv
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUDPPortBound(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := conn.LocalAddr().String()
+
+	bound, err := NewHost().UDPPortBound(addr)
+	if err != nil || !bound {
+		t.Fatalf("expected %s to be bound: %v %v", addr, bound, err)
+	}
+	conn.Close()
+	bound, err = NewHost().UDPPortBound(addr)
+	if err != nil || bound {
+		t.Fatalf("expected %s to be free: %v %v", addr, bound, err)
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("listen: :443\n"), 0o640); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	mode, err := NewHost().CheckFile(path)
+	if err != nil || mode.Perm() != 0o640 {
+		t.Fatalf("unexpected mode %v: %v", mode, err)
+	}
+	if _, err := NewHost().CheckFile(path + ".missing"); !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://github.com/apernet/hysteria/releases/download"
+
+// maxAssetSize bounds a downloaded binary; releases are around 20 MiB.
+const maxAssetSize = 128 << 20
+
+// Client downloads Hysteria release assets. Releases are tagged app/vX.Y.Z
+// and ship a hashes.txt with the sha256 of every binary.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (c *Client) Download(ctx context.Context, version, asset string) ([]byte, error) {
+	return c.get(ctx, version, asset)
+}
+
+// Checksums returns the sha256 of each asset of version keyed by file name.
+func (c *Client) Checksums(ctx context.Context, version string) (map[string]string, error) {
+	raw, err := c.get(ctx, version, "hashes.txt")
+	if err != nil {
+		return nil, err
+	}
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		sums[path.Base(strings.TrimPrefix(fields[len(fields)-1], "*"))] = strings.ToLower(fields[0])
+	}
+	return sums, scanner.Err()
+}
+
+func (c *Client) get(ctx context.Context, version, asset string) ([]byte, error) {
+	u := fmt.Sprintf("%s/%s/%s", c.baseURL, url.PathEscape("app/"+version), asset)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", asset, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: unexpected status %d", u, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", asset, err)
+	}
+	if len(data) > maxAssetSize {
+		return nil, fmt.Errorf("download %s: larger than %d bytes", asset, maxAssetSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,39 @@
+package release
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDownloadsAssetsOfTaggedRelease(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/app%2Fv2.6.1/hashes.txt":
+			w.Write([]byte("ABC123  build/hysteria-linux-amd64\ndef456 *build/hysteria-linux-arm64\n\n"))
+		case "/app%2Fv2.6.1/hysteria-linux-amd64":
+			w.Write([]byte("binary"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	sums, err := c.Checksums(context.Background(), "v2.6.1")
+	if err != nil {
+		t.Fatalf("checksums: %v", err)
+	}
+	if sums["hysteria-linux-amd64"] != "abc123" || sums["hysteria-linux-arm64"] != "def456" {
+		t.Fatalf("unexpected checksums: %v", sums)
+	}
+
+	data, err := c.Download(context.Background(), "v2.6.1", "hysteria-linux-amd64")
+	if err != nil || string(data) != "binary" {
+		t.Fatalf("download = %q, %v", data, err)
+	}
+	if _, err := c.Download(context.Background(), "v9.9.9", "hysteria-linux-amd64"); err == nil {
+		t.Fatal("expected error for missing release")
+	}
+}
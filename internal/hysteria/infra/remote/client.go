@@ -0,0 +1,351 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	dialTimeout = 10 * time.Second
+	// exitNotExist is returned by the read script when the file is missing.
+	exitNotExist = 44
+)
+
+// Client runs file operations and commands on one host over a single SSH
+// connection.
+type Client struct {
+	target Target
+	conn   *ssh.Client
+}
+
+var pool = struct {
+	sync.Mutex
+	clients map[Target]*Client
+}{clients: map[Target]*Client{}}
+
+// Connect returns a shared client for target, dialing it on first use, so
+// every repository of a command reuses one connection per host.
+func Connect(ctx context.Context, target Target) (*Client, error) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if c, ok := pool.clients[target]; ok {
+		return c, nil
+	}
+	c, err := Dial(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	pool.clients[target] = c
+	return c, nil
+}
+
+func Dial(ctx context.Context, target Target) (*Client, error) {
+	cfg, err := clientConfig(target)
+	if err != nil {
+		return nil, err
+	}
+	d := net.Dialer{Timeout: dialTimeout}
+	raw, err := d.DialContext(ctx, "tcp", target.Addr())
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %w", target, err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(raw, target.Addr(), cfg)
+	if err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("ssh %s: %w", target, err)
+	}
+	return &Client{target: target, conn: ssh.NewClient(sshConn, chans, reqs)}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// ReadFile returns the contents of path; a missing file yields an error
+// wrapping os.ErrNotExist.
+func (c *Client) ReadFile(name string) ([]byte, error) {
+	q := Quote(name)
+	script := fmt.Sprintf("if [ -e %s ]; then cat -- %s; else exit %d; fi", q, q, exitNotExist)
+	out, err := c.output(script, nil)
+	if err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitStatus() == exitNotExist {
+			return nil, fmt.Errorf("%s:%s: %w", c.target, name, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("%s: read %s: %w", c.target, name, err)
+	}
+	return out, nil
+}
+
+// WriteFile uploads data to a temp file next to path and renames it into
+// place, so readers on the host never see a partial config. An existing
+// file keeps its mode and owner; perm applies to new files only. When the
+// owner cannot be copied, i.e. without root, the file is written in place,
+// which keeps it too.
+func (c *Client) WriteFile(name string, data []byte, perm os.FileMode) error {
+	tmpl := path.Join(path.Dir(name), "."+path.Base(name)+".XXXXXX")
+	q := Quote(name)
+	script := fmt.Sprintf(
+		`set -e; tmp=$(mktemp %s); trap 'rm -f "$tmp"' EXIT; cat > "$tmp"; `+
+			`if [ -e %s ]; then chmod "$(stat -c %%a %s)" "$tmp"; chown "$(stat -c %%u:%%g %s)" "$tmp" 2>/dev/null || { cat "$tmp" > %s; exit 0; }; `+
+			`else chmod %o "$tmp"; fi; mv -f "$tmp" %s; trap - EXIT`,
+		Quote(tmpl), q, q, q, q, perm.Perm(), q,
+	)
+	if _, err := c.output(script, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("%s: write %s: %w", c.target, name, err)
+	}
+	return nil
+}
+
+// Lock takes an exclusive flock on path+".lock" on the host. The lock lives
+// as long as the SSH session, so a crashed CLI never leaves it behind.
+func (c *Client) Lock(name string) (func(), error) {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("%s: lock %s: %w", c.target, name, err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("%s: lock %s: %w", c.target, name, err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("%s: lock %s: %w", c.target, name, err)
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	script := fmt.Sprintf("exec 9>>%s && flock -x 9 && echo locked && cat >/dev/null", Quote(name+".lock"))
+	if err := session.Start(script); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("%s: lock %s: %w", c.target, name, err)
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "locked" {
+		session.Close()
+		return nil, fmt.Errorf("%s: lock %s: %s", c.target, name, strings.TrimSpace(stderr.String()))
+	}
+	return func() {
+		stdin.Close()
+		session.Wait()
+		session.Close()
+	}, nil
+}
+
+// Run executes a shell command on the host and returns its combined output.
+func (c *Client) Run(ctx context.Context, command string) ([]byte, error) {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.target, err)
+	}
+	defer session.Close()
+
+	// Unlike exec.Cmd, ssh copies stdout and stderr concurrently.
+	var buf lockedBuffer
+	session.Stdout = &buf
+	session.Stderr = &buf
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+	select {
+	case err := <-done:
+		out := buf.Bytes()
+		if err != nil {
+			return out, fmt.Errorf("%s: %s: %w (%s)", c.target, command, err, strings.TrimSpace(string(out)))
+		}
+		return out, nil
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		return buf.Bytes(), ctx.Err()
+	}
+}
+
+// Stream runs a shell command on the host and copies its output to w as it
+// arrives, until the command exits or ctx is canceled. It suits commands
+// that never end on their own, such as following a log.
+func (c *Client) Stream(ctx context.Context, command string, w io.Writer) error {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.target, err)
+	}
+	defer session.Close()
+
+	out := &lockedWriter{w: w}
+	session.Stdout = out
+	session.Stderr = out
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", c.target, command, err)
+		}
+		return nil
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		return ctx.Err()
+	}
+}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+// DialContext opens a TCP or unix socket connection from the host, which
+// lets HTTP clients reach services bound to the host's loopback such as the
+// trafficStats API, or the Docker Engine API on its socket.
+func (c *Client) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := c.conn.Dial(network, addr)
+		ch <- result{conn, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-ch; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) output(script string, stdin io.Reader) ([]byte, error) {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(script); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitStatus() == exitNotExist {
+			return nil, err
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w (%s)", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+func clientConfig(target Target) (*ssh.ClientConfig, error) {
+	home, _ := os.UserHomeDir()
+
+	knownHostsPath := target.KnownHosts
+	if knownHostsPath == "" {
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeys, err := knownhosts.New(expandHome(knownHostsPath, home))
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: load known_hosts: %w", target, err)
+	}
+
+	var signers []ssh.Signer
+	identities := []string{target.Identity}
+	if target.Identity == "" {
+		identities = []string{
+			filepath.Join(home, ".ssh", "id_ed25519"),
+			filepath.Join(home, ".ssh", "id_ecdsa"),
+			filepath.Join(home, ".ssh", "id_rsa"),
+		}
+	}
+	for _, name := range identities {
+		raw, err := os.ReadFile(expandHome(name, home))
+		if err != nil {
+			if target.Identity != "" {
+				return nil, fmt.Errorf("ssh %s: read identity: %w", target, err)
+			}
+			continue
+		}
+		signer, err := ssh.ParsePrivateKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("ssh %s: parse identity %s: %w", target, name, err)
+		}
+		signers = append(signers, signer)
+	}
+
+	auth := []ssh.AuthMethod{}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("ssh %s: no identity file or ssh-agent available", target)
+	}
+
+	return &ssh.ClientConfig{
+		User:            target.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         dialTimeout,
+	}, nil
+}
+
+func expandHome(p, home string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		return filepath.Join(home, strings.TrimPrefix(p, "~"))
+	}
+	return p
+}
+
+// Quote makes s a single shell word.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
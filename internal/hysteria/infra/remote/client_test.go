@@ -0,0 +1,167 @@
+package remote_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vpn/internal/hysteria/infra/remote"
+	"vpn/internal/hysteria/infra/remote/remotetest"
+)
+
+func connect(t *testing.T) (*remote.Client, string) {
+	t.Helper()
+
+	server := remotetest.NewServer(t)
+	target, _, err := remote.Parse(server.Target())
+	if err != nil {
+		t.Fatalf("parse target: %v", err)
+	}
+	client, err := remote.Dial(context.Background(), target)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, t.TempDir()
+}
+
+func TestClient_ReadWriteFile(t *testing.T) {
+	t.Parallel()
+
+	client, dir := connect(t)
+	path := filepath.Join(dir, "config.yaml")
+
+	if _, err := client.ReadFile(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected ErrNotExist, got %v", err)
+	}
+	if err := client.WriteFile(path, []byte("listen: :443\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	data, err := client.ReadFile(path)
+	if err != nil || string(data) != "listen: :443\n" {
+		t.Fatalf("unexpected content %q: %v", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 file: %v %v", info, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("temp files must be renamed away, got %d entries", len(entries))
+	}
+
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if err := client.WriteFile(path, []byte("listen: :8443\n"), 0o600); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	info, err = os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o640 {
+		t.Fatalf("rewrite must keep the 0640 mode: %v %v", info, err)
+	}
+}
+
+func TestClient_Lock(t *testing.T) {
+	t.Parallel()
+
+	client, dir := connect(t)
+	path := filepath.Join(dir, "config.yaml")
+
+	unlock, err := client.Lock(path)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	acquired := make(chan func())
+	go func() {
+		second, err := client.Lock(path)
+		if err != nil {
+			t.Errorf("second lock: %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("second lock must wait for the first one")
+	case <-time.After(200 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case second := <-acquired:
+		if second != nil {
+			second()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("second lock was not acquired after unlock")
+	}
+}
+
+func TestClient_RunAndTunnel(t *testing.T) {
+	t.Parallel()
+
+	client, _ := connect(t)
+	out, err := client.Run(context.Background(), "echo hello")
+	if err != nil || strings.TrimSpace(string(out)) != "hello" {
+		t.Fatalf("unexpected run result %q: %v", out, err)
+	}
+	if _, err := client.Run(context.Background(), "exit 3"); err == nil {
+		t.Fatalf("expected error for failing command")
+	}
+
+	var streamed strings.Builder
+	if err := client.Stream(context.Background(), "echo one; echo two", &streamed); err != nil || streamed.String() != "one\ntwo\n" {
+		t.Fatalf("unexpected stream result %q: %v", streamed.String(), err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := client.Stream(ctx, "sleep 10", io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the stream to stop with the context, got %v", err)
+	}
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "tunnelled")
+	}))
+	defer api.Close()
+
+	httpClient := &http.Client{Transport: &http.Transport{DialContext: client.DialContext}}
+	resp, err := httpClient.Get(api.URL)
+	if err != nil {
+		t.Fatalf("get through tunnel: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "tunnelled" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	target, path, err := remote.Parse("ssh://admin@example.com:2222/etc/hysteria/config.yaml?identity=~/.ssh/key")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if target.User != "admin" || target.Addr() != "example.com:2222" || target.Identity != "~/.ssh/key" || path != "/etc/hysteria/config.yaml" {
+		t.Fatalf("unexpected target %+v path %q", target, path)
+	}
+
+	target, _, err = remote.Parse("ssh://example.com")
+	if err != nil || target.User != "root" || target.Port != 22 {
+		t.Fatalf("unexpected defaults %+v: %v", target, err)
+	}
+	if _, _, err := remote.Parse("http://example.com"); err == nil {
+		t.Fatalf("expected error for non-ssh scheme")
+	}
+}
@@ -0,0 +1,64 @@
+package remote
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const scheme = "ssh"
+
+// Target is an SSH destination written as
+// ssh://user@host[:port][/path][?identity=key&known_hosts=file].
+type Target struct {
+	User       string
+	Host       string
+	Port       int
+	Identity   string
+	KnownHosts string
+}
+
+// IsRemote reports whether location points to a file on an SSH host.
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, scheme+"://")
+}
+
+// Parse splits an ssh:// location into the host to connect to and the path
+// on it. The path is empty for bare targets.
+func Parse(location string) (Target, string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return Target{}, "", fmt.Errorf("parse ssh target: %w", err)
+	}
+	if u.Scheme != scheme || u.Hostname() == "" {
+		return Target{}, "", fmt.Errorf("invalid ssh target %q (expected ssh://user@host[:port])", location)
+	}
+	t := Target{
+		User:       u.User.Username(),
+		Host:       u.Hostname(),
+		Port:       22,
+		Identity:   u.Query().Get("identity"),
+		KnownHosts: u.Query().Get("known_hosts"),
+	}
+	if t.User == "" {
+		t.User = "root"
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil || port <= 0 || port > 65535 {
+			return Target{}, "", fmt.Errorf("invalid ssh port %q", p)
+		}
+		t.Port = port
+	}
+	return t, u.Path, nil
+}
+
+func (t Target) Addr() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+func (t Target) String() string {
+	return fmt.Sprintf("%s://%s@%s", scheme, t.User, t.Addr())
+}
@@ -0,0 +1,334 @@
+package servicectl
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/hysteria/infra/remote"
+)
+
+// engineTimeout bounds every API call but logs; stopping a container waits
+// up to its stop timeout, 10 seconds by default.
+const engineTimeout = time.Minute
+
+var ErrNoContainer = errors.New("container not found")
+
+// engine is a client of the Docker Engine API on its unix socket. For
+// ssh:// hosts the socket is dialed through the SSH connection, so neither
+// side needs the docker binary.
+type engine struct {
+	socket string
+	http   *http.Client
+}
+
+func newEngine(socket, host string) *engine {
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+	if host != "" {
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			target, _, err := remote.Parse(host)
+			if err != nil {
+				return nil, err
+			}
+			client, err := remote.Connect(ctx, target)
+			if err != nil {
+				return nil, err
+			}
+			return client.DialContext(ctx, "unix", socket)
+		}
+	}
+	return &engine{socket: socket, http: &http.Client{Transport: &http.Transport{DialContext: dial}}}
+}
+
+// do sends one request and decodes the JSON answer into out unless it is
+// nil. 304 Not Modified, e.g. starting a running container, is success.
+func (e *engine) do(ctx context.Context, method, endpoint string, query url.Values, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, engineTimeout)
+	defer cancel()
+	resp, err := e.send(ctx, method, endpoint, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("docker %s %s: decode: %w", method, endpoint, err)
+	}
+	return nil
+}
+
+func (e *engine) send(ctx context.Context, method, endpoint string, query url.Values) (*http.Response, error) {
+	target := "http://docker" + endpoint
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("docker engine at %s: %w", e.socket, err)
+	}
+	if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+	if apiErr.Message == "" {
+		apiErr.Message = resp.Status
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNoContainer, apiErr.Message)
+	}
+	return nil, fmt.Errorf("docker %s %s: %s", method, endpoint, apiErr.Message)
+}
+
+type containerJSON struct {
+	ID    string `json:"Id"`
+	State struct {
+		Status    string
+		Pid       int
+		StartedAt string
+	}
+	Config struct {
+		Tty bool
+	}
+	Mounts []struct {
+		Source      string
+		Destination string
+	}
+}
+
+var dockerStates = map[string]string{
+	"running":    domain.ServiceStateActive,
+	"restarting": "activating",
+	"created":    "inactive",
+	"paused":     "inactive",
+	"exited":     "inactive",
+	"dead":       "failed",
+}
+
+// docker controls the container of a unit over the Docker Engine API.
+type docker struct {
+	container domain.Container
+	engine    *engine
+}
+
+func newDocker(unit domain.ServiceUnit) docker {
+	return docker{container: unit.Container, engine: newEngine(unit.DockerSocket, unit.Host)}
+}
+
+// id returns what the API accepts for the container: its name, or the ID of
+// the container docker compose labeled with the service and project.
+func (m docker) id(ctx context.Context) (string, error) {
+	if m.container.Name != "" {
+		return m.container.Name, nil
+	}
+	labels := []string{"com.docker.compose.service=" + m.container.Service}
+	if m.container.Project != "" {
+		labels = append(labels, "com.docker.compose.project="+m.container.Project)
+	}
+	filters, err := json.Marshal(map[string][]string{"label": labels})
+	if err != nil {
+		return "", err
+	}
+	var list []struct {
+		ID string `json:"Id"`
+	}
+	query := url.Values{"all": {"1"}, "filters": {string(filters)}}
+	if err := m.engine.do(ctx, http.MethodGet, "/containers/json", query, &list); err != nil {
+		return "", err
+	}
+	switch len(list) {
+	case 0:
+		return "", fmt.Errorf("%w: no container of compose service %s", ErrNoContainer, m.container)
+	case 1:
+		return list[0].ID, nil
+	default:
+		return "", fmt.Errorf("compose service %s has %d containers; set hysteria_container to pick one", m.container, len(list))
+	}
+}
+
+func (m docker) inspect(ctx context.Context) (containerJSON, error) {
+	id, err := m.id(ctx)
+	if err != nil {
+		return containerJSON{}, err
+	}
+	var c containerJSON
+	err = m.engine.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, &c)
+	return c, err
+}
+
+func (m docker) post(ctx context.Context, action string, query url.Values) error {
+	id, err := m.id(ctx)
+	if err != nil {
+		return err
+	}
+	return m.engine.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/"+action, query, nil)
+}
+
+func (m docker) Status(ctx context.Context) (domain.ServiceStatus, error) {
+	c, err := m.inspect(ctx)
+	if err != nil {
+		return domain.ServiceStatus{}, err
+	}
+	status := domain.ServiceStatus{Manager: domain.ServiceManagerDocker, State: dockerStates[c.State.Status], Detail: c.State.Status}
+	if status.State == "" {
+		status.State = c.State.Status
+	}
+	if status.Active() {
+		status.PID = c.State.Pid
+		status.Since = c.State.StartedAt
+	}
+	return status, nil
+}
+
+func (m docker) Start(ctx context.Context) error {
+	return m.post(ctx, "start", nil)
+}
+
+func (m docker) Stop(ctx context.Context) error {
+	return m.post(ctx, "stop", nil)
+}
+
+func (m docker) Restart(ctx context.Context) error {
+	return m.post(ctx, "restart", nil)
+}
+
+// Reload sends SIGHUP, the closest a container has to a reload.
+func (m docker) Reload(ctx context.Context) error {
+	return m.post(ctx, "kill", url.Values{"signal": {"HUP"}})
+}
+
+func (m docker) Logs(ctx context.Context, w io.Writer, lines int, follow bool) error {
+	c, err := m.inspect(ctx)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"stdout": {"1"}, "stderr": {"1"}, "tail": {strconv.Itoa(lines)}}
+	if follow {
+		query.Set("follow", "1")
+	}
+	resp, err := m.engine.send(ctx, http.MethodGet, "/containers/"+url.PathEscape(c.ID)+"/logs", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if c.Config.Tty {
+		_, err = io.Copy(w, resp.Body)
+	} else {
+		err = demux(w, resp.Body)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// HostPath maps p inside the container to the host path of the mount
+// holding it; the deepest mount wins.
+func (m docker) HostPath(ctx context.Context, p string) (string, error) {
+	c, err := m.inspect(ctx)
+	if err != nil {
+		return "", err
+	}
+	best := -1
+	for i, mount := range c.Mounts {
+		dest := strings.TrimRight(mount.Destination, "/")
+		if p != dest && !strings.HasPrefix(p, dest+"/") {
+			continue
+		}
+		if best < 0 || len(dest) > len(strings.TrimRight(c.Mounts[best].Destination, "/")) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", fmt.Errorf("%s is not mounted into container %s from the host", p, m.container)
+	}
+	mount := c.Mounts[best]
+	return path.Join(mount.Source, strings.TrimPrefix(p, strings.TrimRight(mount.Destination, "/"))), nil
+}
+
+// demux copies a log stream of a container without a TTY to w: stdout and
+// stderr arrive in frames of an 8-byte header, whose last four bytes are the
+// big-endian payload size, and the payload.
+func demux(w io.Writer, r io.Reader) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if _, err := io.CopyN(w, r, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+			return err
+		}
+	}
+}
+
+// ConfigPathResolver resolves the config path of one service unit.
+type ConfigPathResolver struct {
+	unit     domain.ServiceUnit
+	location string
+}
+
+func NewConfigPathResolver(unit domain.ServiceUnit, location string) *ConfigPathResolver {
+	return &ConfigPathResolver{unit: unit, location: location}
+}
+
+// Resolve returns the host path of the config, see ResolveConfigPath.
+func (r *ConfigPathResolver) Resolve(ctx context.Context) (string, error) {
+	return ResolveConfigPath(ctx, r.unit, r.location)
+}
+
+// ResolveConfigPath maps location, the config path Hysteria reads inside
+// the container of unit, to the file on the host it is mounted from;
+// ssh:// locations keep their host. Without a container location is
+// returned as is.
+func ResolveConfigPath(ctx context.Context, unit domain.ServiceUnit, location string) (string, error) {
+	if unit.Container.IsZero() {
+		return location, nil
+	}
+	inner := location
+	var u *url.URL
+	if remote.IsRemote(location) {
+		var err error
+		if u, err = url.Parse(location); err != nil {
+			return "", err
+		}
+		inner = u.Path
+	}
+	hostPath, err := newDocker(unit).HostPath(ctx, inner)
+	if err != nil {
+		return "", fmt.Errorf("resolve config path in container %s: %w", unit.Container, err)
+	}
+	if u == nil {
+		return hostPath, nil
+	}
+	u.Path = hostPath
+	return u.String(), nil
+}
@@ -0,0 +1,201 @@
+package servicectl
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+// fakeEngine serves the part of the Docker Engine API the docker manager
+// uses on a unix socket and records the requests.
+type fakeEngine struct {
+	socket string
+	tty    bool
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func newFakeEngine(t *testing.T) *fakeEngine {
+	t.Helper()
+	e := &fakeEngine{socket: filepath.Join(t.TempDir(), "docker.sock")}
+	ln, err := net.Listen("unix", e.socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(e.serve)}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return e
+}
+
+func (e *fakeEngine) serve(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	e.calls = append(e.calls, r.Method+" "+r.URL.RequestURI())
+	e.mu.Unlock()
+
+	switch r.Method + " " + r.URL.Path {
+	case "GET /containers/json":
+		if r.URL.Query().Get("filters") != `{"label":["com.docker.compose.service=hysteria","com.docker.compose.project=edge"]}` {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprint(w, `[{"Id":"c0ffee","Names":["/edge-hysteria-1"]}]`)
+	case "GET /containers/c0ffee/json", "GET /containers/hysteria/json":
+		fmt.Fprintf(w, `{"Id":"c0ffee","State":{"Status":"running","Pid":4242,"StartedAt":"2026-10-19T09:12:01.5Z"},"Config":{"Tty":%t},"Mounts":[
+			{"Type":"bind","Source":"/srv/edge","Destination":"/etc"},
+			{"Type":"bind","Source":"/srv/edge/hysteria","Destination":"/etc/hysteria/"},
+			{"Type":"volume","Source":"/var/lib/docker/volumes/acme/_data","Destination":"/acme"}]}`, e.tty)
+	case "POST /containers/c0ffee/start":
+		w.WriteHeader(http.StatusNotModified)
+	case "POST /containers/c0ffee/stop", "POST /containers/c0ffee/restart", "POST /containers/c0ffee/kill":
+		w.WriteHeader(http.StatusNoContent)
+	case "GET /containers/c0ffee/logs":
+		if e.tty {
+			fmt.Fprint(w, "server up\nclient connected\n")
+			return
+		}
+		w.Write(frame(1, "server up\n"))
+		w.Write(frame(2, "client connected\n"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"No such container: missing"}`)
+	}
+}
+
+func (e *fakeEngine) requests() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.calls...)
+}
+
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestDocker_Compose(t *testing.T) {
+	engine := newFakeEngine(t)
+	unit := domain.ServiceUnit{
+		Name:         "hysteria-server",
+		Manager:      domain.ServiceManagerDocker,
+		Container:    domain.Container{Project: "edge", Service: "hysteria"},
+		DockerSocket: engine.socket,
+	}
+	svc := newService(unit, &fakeRunner{})
+	ctx := context.Background()
+
+	status, err := svc.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	want := domain.ServiceStatus{Manager: "docker", State: "active", Detail: "running", PID: 4242, Since: "2026-10-19T09:12:01.5Z"}
+	if status != want {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	for _, step := range []func(context.Context) error{svc.Start, svc.Stop, svc.Reload} {
+		if err := step(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := newRestarter(true, svc, "").Restart(ctx); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	var logs bytes.Buffer
+	if err := svc.Logs(ctx, &logs, 50, false); err != nil {
+		t.Fatalf("logs: %v", err)
+	}
+	if logs.String() != "server up\nclient connected\n" {
+		t.Fatalf("unexpected logs %q", logs.String())
+	}
+
+	list := "GET /containers/json?all=1&filters=%7B%22label%22%3A%5B%22com.docker.compose.service%3Dhysteria%22%2C%22com.docker.compose.project%3Dedge%22%5D%7D"
+	wantCalls := []string{
+		list, "GET /containers/c0ffee/json",
+		list, "POST /containers/c0ffee/start",
+		list, "POST /containers/c0ffee/stop",
+		list, "POST /containers/c0ffee/kill?signal=HUP",
+		list, "POST /containers/c0ffee/restart",
+		list, "GET /containers/c0ffee/json",
+		"GET /containers/c0ffee/logs?stderr=1&stdout=1&tail=50",
+	}
+	if got := engine.requests(); !reflect.DeepEqual(got, wantCalls) {
+		t.Fatalf("unexpected requests:\n%v", got)
+	}
+}
+
+func TestDocker_TTYLogs(t *testing.T) {
+	engine := newFakeEngine(t)
+	engine.tty = true
+	unit := domain.ServiceUnit{Container: domain.Container{Name: "hysteria"}, DockerSocket: engine.socket}
+
+	var logs bytes.Buffer
+	if err := newDocker(unit).Logs(context.Background(), &logs, 20, true); err != nil {
+		t.Fatalf("logs: %v", err)
+	}
+	if logs.String() != "server up\nclient connected\n" {
+		t.Fatalf("unexpected logs %q", logs.String())
+	}
+	if got := engine.requests()[1]; got != "GET /containers/c0ffee/logs?follow=1&stderr=1&stdout=1&tail=20" {
+		t.Fatalf("unexpected logs request %s", got)
+	}
+}
+
+func TestDocker_Errors(t *testing.T) {
+	engine := newFakeEngine(t)
+	ctx := context.Background()
+
+	_, err := newDocker(domain.ServiceUnit{Container: domain.Container{Name: "missing"}, DockerSocket: engine.socket}).Status(ctx)
+	if !errors.Is(err, ErrNoContainer) {
+		t.Fatalf("expected ErrNoContainer, got %v", err)
+	}
+	err = newDocker(domain.ServiceUnit{Container: domain.Container{Service: "hysteria"}, DockerSocket: engine.socket}).Start(ctx)
+	if !errors.Is(err, ErrNoContainer) {
+		t.Fatalf("expected ErrNoContainer for an unknown compose service, got %v", err)
+	}
+	_, err = newDocker(domain.ServiceUnit{Container: domain.Container{Name: "hysteria"}, DockerSocket: engine.socket + ".gone"}).Status(ctx)
+	if err == nil {
+		t.Fatalf("expected an error without a docker socket")
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	engine := newFakeEngine(t)
+	unit := domain.ServiceUnit{Container: domain.Container{Name: "hysteria"}, DockerSocket: engine.socket}
+	ctx := context.Background()
+
+	tests := []struct {
+		location string
+		want     string
+	}{
+		{"/etc/hysteria/config.yaml", "/srv/edge/hysteria/config.yaml"},
+		{"/etc/hysteria.yaml", "/srv/edge/hysteria.yaml"},
+		{"ssh://root@fi1.example.com:22/etc/hysteria/config.yaml?identity=%2Froot%2F.ssh%2Fid", "ssh://root@fi1.example.com:22/srv/edge/hysteria/config.yaml?identity=%2Froot%2F.ssh%2Fid"},
+	}
+	for _, tt := range tests {
+		got, err := ResolveConfigPath(ctx, unit, tt.location)
+		if err != nil || got != tt.want {
+			t.Fatalf("%s: expected %s, got %q %v", tt.location, tt.want, got, err)
+		}
+	}
+
+	if _, err := ResolveConfigPath(ctx, unit, "/opt/hysteria/config.yaml"); err == nil {
+		t.Fatalf("expected an error for a path outside every mount")
+	}
+	got, err := ResolveConfigPath(ctx, domain.ServiceUnit{Name: "hysteria-server"}, "/etc/hysteria/config.yaml")
+	if err != nil || got != "/etc/hysteria/config.yaml" {
+		t.Fatalf("expected the path unchanged without a container, got %q %v", got, err)
+	}
+}
@@ -0,0 +1,328 @@
+package servicectl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"vpn/internal/hysteria/domain"
+)
+
+var (
+	ErrNoServiceManager = errors.New("no supported service manager found")
+	ErrNoServiceLogs    = errors.New("service manager keeps no logs")
+)
+
+// ServiceManager controls one service through systemd, SysV init, OpenRC
+// or Docker.
+type ServiceManager interface {
+	Status(ctx context.Context) (domain.ServiceStatus, error)
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Restart(ctx context.Context) error
+	Reload(ctx context.Context) error
+	// Logs writes the last lines of the service log to w and, with follow,
+	// keeps writing new ones until ctx is canceled.
+	Logs(ctx context.Context, w io.Writer, lines int, follow bool) error
+}
+
+// Service is the ServiceManager of a unit. The manager is detected on
+// first use unless the unit names one; a configured log file replaces the
+// manager's logs.
+type Service struct {
+	unit   domain.ServiceUnit
+	runner CommandRunner
+
+	mu      sync.Mutex
+	manager ServiceManager
+}
+
+func NewService(unit domain.ServiceUnit) *Service {
+	return newService(unit, newRunner(unit.Host))
+}
+
+func newService(unit domain.ServiceUnit, runner CommandRunner) *Service {
+	return &Service{unit: unit, runner: runner}
+}
+
+func (s *Service) resolve(ctx context.Context) (ServiceManager, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.manager != nil {
+		return s.manager, nil
+	}
+	kind := s.unit.Manager
+	if kind == "" {
+		var err error
+		if kind, err = detect(ctx, s.runner); err != nil {
+			return nil, fmt.Errorf("%w for %q; set hysteria_service_manager", err, s.unit.Name)
+		}
+	}
+	manager, err := newManager(s.unit, kind, s.runner)
+	if err != nil {
+		return nil, err
+	}
+	s.manager = manager
+	return manager, nil
+}
+
+// detect prefers systemd only when it actually runs: containers often ship
+// systemctl without it.
+func detect(ctx context.Context, runner CommandRunner) (string, error) {
+	if runner.Exists(ctx, "systemctl") {
+		out, _ := runner.Output(ctx, "systemctl", "is-system-running")
+		switch firstLine(out) {
+		case "running", "degraded", "starting", "initializing", "maintenance", "stopping":
+			return domain.ServiceManagerSystemd, nil
+		}
+	}
+	if runner.Exists(ctx, "rc-service") {
+		return domain.ServiceManagerOpenRC, nil
+	}
+	if runner.Exists(ctx, "service") {
+		return domain.ServiceManagerSysV, nil
+	}
+	return "", ErrNoServiceManager
+}
+
+func newManager(unit domain.ServiceUnit, kind string, runner CommandRunner) (ServiceManager, error) {
+	switch kind {
+	case domain.ServiceManagerSystemd:
+		return systemd{name: unit.Name, runner: runner}, nil
+	case domain.ServiceManagerSysV:
+		return sysv{name: unit.Name, runner: runner}, nil
+	case domain.ServiceManagerOpenRC:
+		return openrc{name: unit.Name, runner: runner}, nil
+	case domain.ServiceManagerDocker:
+		return newDocker(unit), nil
+	default:
+		_, err := domain.ParseServiceManager(kind)
+		return nil, err
+	}
+}
+
+func (s *Service) Status(ctx context.Context) (domain.ServiceStatus, error) {
+	manager, err := s.resolve(ctx)
+	if err != nil {
+		return domain.ServiceStatus{}, err
+	}
+	return manager.Status(ctx)
+}
+
+func (s *Service) Start(ctx context.Context) error {
+	manager, err := s.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return manager.Start(ctx)
+}
+
+func (s *Service) Stop(ctx context.Context) error {
+	manager, err := s.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return manager.Stop(ctx)
+}
+
+func (s *Service) Restart(ctx context.Context) error {
+	manager, err := s.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return manager.Restart(ctx)
+}
+
+func (s *Service) Reload(ctx context.Context) error {
+	manager, err := s.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return manager.Reload(ctx)
+}
+
+func (s *Service) Logs(ctx context.Context, w io.Writer, lines int, follow bool) error {
+	if s.unit.LogFile != "" {
+		return tailFile(ctx, s.runner, w, s.unit.LogFile, lines, follow)
+	}
+	manager, err := s.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return manager.Logs(ctx, w, lines, follow)
+}
+
+type systemd struct {
+	name   string
+	runner CommandRunner
+}
+
+func (m systemd) Status(ctx context.Context) (domain.ServiceStatus, error) {
+	out, err := m.runner.Output(ctx, "systemctl", "show", m.name, "--property=LoadState,ActiveState,SubState,MainPID,ActiveEnterTimestamp")
+	if err != nil {
+		return domain.ServiceStatus{}, err
+	}
+	props := parseProperties(out)
+	status := domain.ServiceStatus{
+		Manager: domain.ServiceManagerSystemd,
+		State:   props["ActiveState"],
+		Detail:  props["SubState"],
+	}
+	if props["LoadState"] == "not-found" {
+		status.Detail = "unit not found"
+	}
+	if pid, _ := strconv.Atoi(props["MainPID"]); pid > 0 {
+		status.PID = pid
+	}
+	if status.Active() {
+		status.Since = props["ActiveEnterTimestamp"]
+	}
+	return status, nil
+}
+
+func (m systemd) Start(ctx context.Context) error {
+	return run(ctx, m.runner, "systemctl", "start", m.name)
+}
+
+func (m systemd) Stop(ctx context.Context) error {
+	return run(ctx, m.runner, "systemctl", "stop", m.name)
+}
+
+func (m systemd) Restart(ctx context.Context) error {
+	return run(ctx, m.runner, "systemctl", "restart", m.name)
+}
+
+// Reload falls back to a restart: the Hysteria unit has no ExecReload.
+func (m systemd) Reload(ctx context.Context) error {
+	return run(ctx, m.runner, "systemctl", "reload-or-restart", m.name)
+}
+
+func (m systemd) Logs(ctx context.Context, w io.Writer, lines int, follow bool) error {
+	args := []string{"-u", m.name, "-n", strconv.Itoa(lines), "--no-pager"}
+	if follow {
+		args = append(args, "-f")
+	}
+	return m.runner.Stream(ctx, w, "journalctl", args...)
+}
+
+type sysv struct {
+	name   string
+	runner CommandRunner
+}
+
+// Status relies on the LSB exit code: 0 means running.
+func (m sysv) Status(ctx context.Context) (domain.ServiceStatus, error) {
+	out, err := m.runner.Output(ctx, "service", m.name, "status")
+	status := domain.ServiceStatus{Manager: domain.ServiceManagerSysV, State: "inactive", Detail: firstLine(out)}
+	if err == nil {
+		status.State = domain.ServiceStateActive
+	}
+	return status, nil
+}
+
+func (m sysv) Start(ctx context.Context) error {
+	return run(ctx, m.runner, "service", m.name, "start")
+}
+
+func (m sysv) Stop(ctx context.Context) error {
+	return run(ctx, m.runner, "service", m.name, "stop")
+}
+
+func (m sysv) Restart(ctx context.Context) error {
+	return run(ctx, m.runner, "service", m.name, "restart")
+}
+
+func (m sysv) Reload(ctx context.Context) error {
+	return run(ctx, m.runner, "service", m.name, "reload")
+}
+
+func (m sysv) Logs(context.Context, io.Writer, int, bool) error {
+	return fmt.Errorf("%w: sysv; set hysteria_log_file", ErrNoServiceLogs)
+}
+
+type openrc struct {
+	name   string
+	runner CommandRunner
+}
+
+var openrcStates = map[string]string{
+	"started":  domain.ServiceStateActive,
+	"stopped":  "inactive",
+	"crashed":  "failed",
+	"starting": "activating",
+	"stopping": "deactivating",
+}
+
+// Status parses " * status: started"; rc-service exits non-zero for a
+// stopped service, so the output decides.
+func (m openrc) Status(ctx context.Context) (domain.ServiceStatus, error) {
+	out, err := m.runner.Output(ctx, "rc-service", m.name, "status")
+	_, word, ok := strings.Cut(firstLine(out), "status: ")
+	if !ok {
+		if err == nil {
+			err = fmt.Errorf("rc-service %s status: unexpected output %q", m.name, firstLine(out))
+		}
+		return domain.ServiceStatus{}, err
+	}
+	word = strings.TrimSpace(word)
+	state, known := openrcStates[word]
+	if !known {
+		state = word
+	}
+	return domain.ServiceStatus{Manager: domain.ServiceManagerOpenRC, State: state, Detail: word}, nil
+}
+
+func (m openrc) Start(ctx context.Context) error {
+	return run(ctx, m.runner, "rc-service", m.name, "start")
+}
+
+func (m openrc) Stop(ctx context.Context) error {
+	return run(ctx, m.runner, "rc-service", m.name, "stop")
+}
+
+func (m openrc) Restart(ctx context.Context) error {
+	return run(ctx, m.runner, "rc-service", m.name, "restart")
+}
+
+func (m openrc) Reload(ctx context.Context) error {
+	return run(ctx, m.runner, "rc-service", m.name, "reload")
+}
+
+func (m openrc) Logs(context.Context, io.Writer, int, bool) error {
+	return fmt.Errorf("%w: openrc; set hysteria_log_file", ErrNoServiceLogs)
+}
+
+func tailFile(ctx context.Context, runner CommandRunner, w io.Writer, path string, lines int, follow bool) error {
+	args := []string{"-n", strconv.Itoa(lines)}
+	if follow {
+		args = append(args, "-F")
+	}
+	return runner.Stream(ctx, w, "tail", append(args, path)...)
+}
+
+func run(ctx context.Context, runner CommandRunner, name string, args ...string) error {
+	_, err := runner.Output(ctx, name, args...)
+	return err
+}
+
+func parseProperties(out []byte) map[string]string {
+	props := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			props[key] = strings.TrimSpace(value)
+		}
+	}
+	return props
+}
+
+func firstLine(out []byte) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,219 @@
+package servicectl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type fakeResult struct {
+	out string
+	err error
+}
+
+// fakeRunner records every command and answers from a table keyed by the
+// full command line.
+type fakeRunner struct {
+	installed map[string]bool
+	results   map[string]fakeResult
+	calls     []string
+}
+
+func (r *fakeRunner) Output(_ context.Context, name string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	r.calls = append(r.calls, line)
+	res := r.results[line]
+	return []byte(res.out), res.err
+}
+
+func (r *fakeRunner) Stream(ctx context.Context, w io.Writer, name string, args ...string) error {
+	out, err := r.Output(ctx, name, args...)
+	w.Write(out)
+	return err
+}
+
+func (r *fakeRunner) Exists(_ context.Context, name string) bool {
+	return r.installed[name]
+}
+
+func TestService_Commands(t *testing.T) {
+	tests := []struct {
+		manager string
+		want    []string
+	}{
+		{domain.ServiceManagerSystemd, []string{
+			"systemctl start hysteria-server",
+			"systemctl stop hysteria-server",
+			"systemctl restart hysteria-server",
+			"systemctl reload-or-restart hysteria-server",
+			"journalctl -u hysteria-server -n 50 --no-pager -f",
+		}},
+		{domain.ServiceManagerSysV, []string{
+			"service hysteria-server start",
+			"service hysteria-server stop",
+			"service hysteria-server restart",
+			"service hysteria-server reload",
+		}},
+		{domain.ServiceManagerOpenRC, []string{
+			"rc-service hysteria-server start",
+			"rc-service hysteria-server stop",
+			"rc-service hysteria-server restart",
+			"rc-service hysteria-server reload",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.manager, func(t *testing.T) {
+			runner := &fakeRunner{}
+			svc := newService(domain.ServiceUnit{Name: "hysteria-server", Manager: tt.manager}, runner)
+			ctx := context.Background()
+			for _, step := range []func(context.Context) error{svc.Start, svc.Stop, svc.Restart, svc.Reload} {
+				if err := step(ctx); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			err := svc.Logs(ctx, io.Discard, 50, true)
+			if errors.Is(err, ErrNoServiceLogs) {
+				err = nil
+			}
+			if err != nil {
+				t.Fatalf("logs: %v", err)
+			}
+			if !reflect.DeepEqual(runner.calls, tt.want) {
+				t.Fatalf("unexpected commands:\n%s", strings.Join(runner.calls, "\n"))
+			}
+		})
+	}
+}
+
+func TestService_Detect(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []string
+		running   string
+		want      string
+	}{
+		{"systemd", []string{"systemctl", "service"}, "running", domain.ServiceManagerSystemd},
+		{"degraded systemd", []string{"systemctl"}, "degraded", domain.ServiceManagerSystemd},
+		{"systemctl without systemd", []string{"systemctl", "service"}, "offline", domain.ServiceManagerSysV},
+		{"openrc", []string{"rc-service", "service"}, "", domain.ServiceManagerOpenRC},
+		{"none", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fakeRunner{
+				installed: map[string]bool{},
+				results:   map[string]fakeResult{"systemctl is-system-running": {out: tt.running + "\n"}},
+			}
+			for _, name := range tt.installed {
+				runner.installed[name] = true
+			}
+			got, err := detect(context.Background(), runner)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNoServiceManager) {
+					t.Fatalf("expected ErrNoServiceManager, got %q %v", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("expected %s, got %q %v", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestService_Status(t *testing.T) {
+	failed := errors.New("exit status 3")
+	tests := []struct {
+		manager string
+		command string
+		result  fakeResult
+		want    domain.ServiceStatus
+	}{
+		{
+			domain.ServiceManagerSystemd,
+			"systemctl show hysteria-server --property=LoadState,ActiveState,SubState,MainPID,ActiveEnterTimestamp",
+			fakeResult{out: "LoadState=loaded\nActiveState=active\nSubState=running\nMainPID=812\nActiveEnterTimestamp=Mon 2026-10-19 09:12:01 UTC\n"},
+			domain.ServiceStatus{Manager: "systemd", State: "active", Detail: "running", PID: 812, Since: "Mon 2026-10-19 09:12:01 UTC"},
+		},
+		{
+			domain.ServiceManagerSystemd,
+			"systemctl show hysteria-server --property=LoadState,ActiveState,SubState,MainPID,ActiveEnterTimestamp",
+			fakeResult{out: "LoadState=not-found\nActiveState=inactive\nSubState=dead\nMainPID=0\n"},
+			domain.ServiceStatus{Manager: "systemd", State: "inactive", Detail: "unit not found"},
+		},
+		{
+			domain.ServiceManagerSysV,
+			"service hysteria-server status",
+			fakeResult{out: "hysteria-server is not running\n", err: failed},
+			domain.ServiceStatus{Manager: "sysv", State: "inactive", Detail: "hysteria-server is not running"},
+		},
+		{
+			domain.ServiceManagerOpenRC,
+			"rc-service hysteria-server status",
+			fakeResult{out: " * status: crashed\n", err: failed},
+			domain.ServiceStatus{Manager: "openrc", State: "failed", Detail: "crashed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.manager, func(t *testing.T) {
+			runner := &fakeRunner{results: map[string]fakeResult{tt.command: tt.result}}
+			got, err := newService(domain.ServiceUnit{Name: "hysteria-server", Manager: tt.manager}, runner).Status(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected status: %+v", got)
+			}
+		})
+	}
+}
+
+func TestService_LogFile(t *testing.T) {
+	runner := &fakeRunner{results: map[string]fakeResult{
+		"tail -n 20 /var/log/hysteria.log": {out: "server up\n"},
+	}}
+	unit := domain.ServiceUnit{Name: "hysteria-server", Manager: domain.ServiceManagerSysV, LogFile: "/var/log/hysteria.log"}
+	var buf bytes.Buffer
+	if err := newService(unit, runner).Logs(context.Background(), &buf, 20, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "server up\n" {
+		t.Fatalf("unexpected logs %q", buf.String())
+	}
+
+	unit.LogFile = ""
+	err := newService(unit, runner).Logs(context.Background(), &buf, 20, false)
+	if !errors.Is(err, ErrNoServiceLogs) {
+		t.Fatalf("expected ErrNoServiceLogs, got %v", err)
+	}
+}
+
+func TestRestarter(t *testing.T) {
+	runner := &fakeRunner{installed: map[string]bool{"service": true}}
+	unit := domain.ServiceUnit{Name: "hysteria-server"}
+
+	if err := newRestarter(false, newService(unit, runner), "").Restart(context.Background()); err != nil || len(runner.calls) != 0 {
+		t.Fatalf("disabled restarter ran %v: %v", runner.calls, err)
+	}
+	if err := newRestarter(true, newService(unit, runner), "").Restart(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := newRestarter(true, newService(unit, runner), " kill -HUP 1 ").Restart(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"service hysteria-server restart", "sh -lc kill -HUP 1"}
+	if !reflect.DeepEqual(runner.calls, want) {
+		t.Fatalf("unexpected commands: %v", runner.calls)
+	}
+
+	err := newRestarter(true, newService(unit, &fakeRunner{}), "").Restart(context.Background())
+	if !errors.Is(err, ErrNoServiceManager) {
+		t.Fatalf("expected ErrNoServiceManager, got %v", err)
+	}
+}
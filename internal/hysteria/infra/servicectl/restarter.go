@@ -2,65 +2,41 @@ package servicectl
 
 import (
 	"context"
-	"fmt"
-	"os/exec"
+	"errors"
 	"runtime"
 	"strings"
-)
-
-type CommandRunner interface {
-	Run(ctx context.Context, name string, args ...string) error
-}
 
-type ExecCommandRunner struct{}
+	"vpn/internal/hysteria/domain"
+)
 
-func (r ExecCommandRunner) Run(ctx context.Context, name string, args ...string) error {
-	cmd := exec.CommandContext(ctx, name, args...)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("%s %s: %w (%s)", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
-	}
-	return nil
+type Restarter struct {
+	enabled     bool
+	overrideCmd string
+	service     *Service
 }
 
-type Restarter struct {
-	enabled       bool
-	serviceName   string
-	overrideCmd   string
-	commandRunner CommandRunner
+// NewRestarter restarts the service of unit through its service manager,
+// or runs overrideCmd instead when it is set; remote units restart on
+// their ssh:// host.
+func NewRestarter(enabled bool, unit domain.ServiceUnit, overrideCmd string) *Restarter {
+	return newRestarter(enabled, NewService(unit), overrideCmd)
 }
 
-func NewRestarter(enabled bool, serviceName, overrideCmd string) *Restarter {
-	return &Restarter{
-		enabled:       enabled,
-		serviceName:   serviceName,
-		overrideCmd:   strings.TrimSpace(overrideCmd),
-		commandRunner: ExecCommandRunner{},
-	}
+func newRestarter(enabled bool, service *Service, overrideCmd string) *Restarter {
+	return &Restarter{enabled: enabled, overrideCmd: strings.TrimSpace(overrideCmd), service: service}
 }
 
 func (r *Restarter) Restart(ctx context.Context) error {
 	if !r.enabled {
 		return nil
 	}
-
 	if r.overrideCmd != "" {
-		return r.commandRunner.Run(ctx, "sh", "-lc", r.overrideCmd)
-	}
-
-	if _, err := exec.LookPath("systemctl"); err == nil {
-		return r.commandRunner.Run(ctx, "systemctl", "restart", r.serviceName)
+		return run(ctx, r.service.runner, "sh", "-lc", r.overrideCmd)
 	}
-
-	if _, err := exec.LookPath("service"); err == nil {
-		return r.commandRunner.Run(ctx, "service", r.serviceName, "restart")
+	err := r.service.Restart(ctx)
+	// Homebrew services are not a ServiceManager; they only restart.
+	if errors.Is(err, ErrNoServiceManager) && r.service.unit.Host == "" && runtime.GOOS == "darwin" && r.service.runner.Exists(ctx, "brew") {
+		return run(ctx, r.service.runner, "brew", "services", "restart", r.service.unit.Name)
 	}
-
-	if runtime.GOOS == "darwin" {
-		if _, err := exec.LookPath("brew"); err == nil {
-			return r.commandRunner.Run(ctx, "brew", "services", "restart", r.serviceName)
-		}
-	}
-
-	return fmt.Errorf("no supported service manager found for restarting %q", r.serviceName)
+	return err
 }
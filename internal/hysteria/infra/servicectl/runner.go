@@ -0,0 +1,106 @@
+package servicectl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"vpn/internal/hysteria/infra/remote"
+)
+
+// CommandRunner runs service manager commands on the node, locally or over
+// ssh, so every manager works the same on both.
+type CommandRunner interface {
+	// Output returns the combined output of the command, also when it fails:
+	// status commands report a stopped service through the exit code.
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+	// Stream copies the output to w as it arrives, until the command exits
+	// or ctx is canceled.
+	Stream(ctx context.Context, w io.Writer, name string, args ...string) error
+	// Exists reports whether the program is installed.
+	Exists(ctx context.Context, name string) bool
+}
+
+func newRunner(host string) CommandRunner {
+	if host != "" {
+		return RemoteCommandRunner{host: host}
+	}
+	return ExecCommandRunner{}
+}
+
+type ExecCommandRunner struct{}
+
+func (r ExecCommandRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("%s %s: %w (%s)", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+func (r ExecCommandRunner) Stream(ctx context.Context, w io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func (r ExecCommandRunner) Exists(_ context.Context, name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// RemoteCommandRunner runs commands on an ssh:// host.
+type RemoteCommandRunner struct {
+	host string
+}
+
+func (r RemoteCommandRunner) client(ctx context.Context) (*remote.Client, error) {
+	target, _, err := remote.Parse(r.host)
+	if err != nil {
+		return nil, err
+	}
+	return remote.Connect(ctx, target)
+}
+
+func (r RemoteCommandRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	client, err := r.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.Run(ctx, shellCommand(name, args))
+}
+
+func (r RemoteCommandRunner) Stream(ctx context.Context, w io.Writer, name string, args ...string) error {
+	client, err := r.client(ctx)
+	if err != nil {
+		return err
+	}
+	return client.Stream(ctx, shellCommand(name, args), w)
+}
+
+func (r RemoteCommandRunner) Exists(ctx context.Context, name string) bool {
+	client, err := r.client(ctx)
+	if err != nil {
+		return false
+	}
+	_, err = client.Run(ctx, "command -v "+remote.Quote(name)+" >/dev/null")
+	return err == nil
+}
+
+func shellCommand(name string, args []string) string {
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, remote.Quote(name))
+	for _, arg := range args {
+		quoted = append(quoted, remote.Quote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"vpn/internal/hysteria/domain"
+	"vpn/internal/hysteria/infra/remote"
 )
 
 type Client struct {
@@ -19,15 +21,35 @@ type Client struct {
 	http    *http.Client
 }
 
-func NewClient(enabled bool, url, secret string, timeout time.Duration) *Client {
+// NewClient queries the trafficStats API. With an ssh:// host the requests
+// are tunnelled through that host, so a listener on its loopback works.
+func NewClient(enabled bool, url, secret string, timeout time.Duration, host string) *Client {
 	if timeout <= 0 {
 		timeout = 2 * time.Second
 	}
+	httpClient := &http.Client{Timeout: timeout}
+	if host != "" {
+		httpClient.Transport = &http.Transport{DialContext: tunnel(host)}
+	}
 	return &Client{
 		enabled: enabled,
 		url:     strings.TrimRight(url, "/"),
 		secret:  secret,
-		http:    &http.Client{Timeout: timeout},
+		http:    httpClient,
+	}
+}
+
+func tunnel(host string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		target, _, err := remote.Parse(host)
+		if err != nil {
+			return nil, err
+		}
+		client, err := remote.Connect(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		return client.DialContext(ctx, network, addr)
 	}
 }
 
@@ -72,6 +94,9 @@ func (c *Client) getJSON(ctx context.Context, url string) (any, error) {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("%w (status %d)", domain.ErrTrafficStatsUnauthorized, resp.StatusCode)
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
 	}
@@ -1,8 +1,17 @@
 package trafficstats
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/hysteria/infra/remote/remotetest"
 )
 
 func TestExtractTrafficUsers(t *testing.T) {
@@ -40,3 +49,44 @@ func TestExtractOnlineUsersFromCountMap(t *testing.T) {
 		t.Fatalf("bob should be offline: %+v", online)
 	}
 }
+
+func TestClientFetchThroughSSH(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/traffic":
+			fmt.Fprint(w, `{"alice":{"tx":10,"rx":20}}`)
+		case "/online":
+			fmt.Fprint(w, `{"alice":1}`)
+		}
+	}))
+	defer api.Close()
+	server := remotetest.NewServer(t)
+
+	client := NewClient(true, api.URL, "", time.Second, server.Target())
+	snapshot, err := client.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if snapshot.Users["alice"].RxBytes != 20 || !snapshot.Online["alice"] {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+}
+
+func TestClientFetchRejectedSecret(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "right" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer api.Close()
+
+	_, err := NewClient(true, api.URL, "wrong", time.Second, "").Fetch(context.Background())
+	if !errors.Is(err, domain.ErrTrafficStatsUnauthorized) {
+		t.Fatalf("expected ErrTrafficStatsUnauthorized, got %v", err)
+	}
+	if _, err := NewClient(true, api.URL, "right", time.Second, "").Fetch(context.Background()); err != nil {
+		t.Fatalf("fetch with the right secret: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package userstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"vpn/internal/hysteria/domain"
+)
+
+var usersBucket = []byte("users")
+
+type boltUser struct {
+	Password string            `json:"password"`
+	Tags     []string          `json:"tags,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+}
+
+// BoltStore keeps users in an embedded bbolt database. The database is opened
+// per operation so the CLI, the TUI and the auth server can share the file.
+type BoltStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewBoltStore(path string) *BoltStore {
+	return &BoltStore{path: path}
+}
+
+func (s *BoltStore) Users(_ context.Context) ([]domain.User, error) {
+	var users []domain.User
+	err := s.view(func(b *bolt.Bucket) error {
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var rec boltUser
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("decode user %q: %w", k, err)
+			}
+			users = append(users, domain.User{Username: string(k), Password: rec.Password, Tags: rec.Tags})
+			return nil
+		})
+	})
+	return users, err
+}
+
+func (s *BoltStore) Password(_ context.Context, username string) (string, error) {
+	var password string
+	err := s.view(func(b *bolt.Bucket) error {
+		if b == nil {
+			return domain.ErrUserNotFound
+		}
+		v := b.Get([]byte(username))
+		if v == nil {
+			return domain.ErrUserNotFound
+		}
+		var rec boltUser
+		if err := json.Unmarshal(v, &rec); err != nil {
+			return fmt.Errorf("decode user %q: %w", username, err)
+		}
+		password = rec.Password
+		return nil
+	})
+	return password, err
+}
+
+func (s *BoltStore) ApplyChanges(_ context.Context, changes []domain.UserChange) error {
+	return s.update(func(b *bolt.Bucket) error {
+		for _, change := range changes {
+			key := []byte(change.User.Username)
+			current := b.Get(key)
+			switch change.Action {
+			case domain.UserChangeAdd:
+				if current != nil {
+					return fmt.Errorf("%s: %w", change.User.Username, domain.ErrUserAlreadyExists)
+				}
+				if err := putUser(b, change.User.Username, boltUser{Password: change.User.Password, Tags: change.User.Tags}); err != nil {
+					return err
+				}
+			case domain.UserChangeRotate:
+				if err := updateUser(b, change.User.Username, func(rec *boltUser) { rec.Password = change.User.Password }); err != nil {
+					return err
+				}
+			case domain.UserChangeTags:
+				if err := updateUser(b, change.User.Username, func(rec *boltUser) { rec.Tags = change.User.Tags }); err != nil {
+					return err
+				}
+			case domain.UserChangeRemove:
+				if current == nil {
+					return fmt.Errorf("%s: %w", change.User.Username, domain.ErrUserNotFound)
+				}
+				if err := b.Delete(key); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("%w: %q", domain.ErrInvalidChangeAction, change.Action)
+			}
+		}
+		return nil
+	})
+}
+
+// updateUser decodes the stored record of username, applies edit and writes
+// it back.
+func updateUser(b *bolt.Bucket, username string, edit func(rec *boltUser)) error {
+	current := b.Get([]byte(username))
+	if current == nil {
+		return fmt.Errorf("%s: %w", username, domain.ErrUserNotFound)
+	}
+	var rec boltUser
+	if err := json.Unmarshal(current, &rec); err != nil {
+		return fmt.Errorf("decode user %q: %w", username, err)
+	}
+	edit(&rec)
+	return putUser(b, username, rec)
+}
+
+func (s *BoltStore) Replace(_ context.Context, users []domain.User) error {
+	return s.update(func(b *bolt.Bucket) error {
+		var keys [][]byte
+		if err := b.ForEach(func(k, _ []byte) error {
+			keys = append(keys, append([]byte(nil), k...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, u := range users {
+			if err := putUser(b, u.Username, boltUser{Password: u.Password, Tags: u.Tags}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Checksum hashes the stored records rather than the file, which bbolt
+// rewrites in place and pads with free pages.
+func (s *BoltStore) Checksum(_ context.Context) (string, error) {
+	if _, err := os.Stat(s.path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	h := sha256.New()
+	err := s.view(func(b *bolt.Bucket) error {
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			h.Write(k)
+			h.Write([]byte{0})
+			h.Write(v)
+			h.Write([]byte{'\n'})
+			return nil
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// view runs fn with the users bucket, or nil when nothing was stored yet.
+func (s *BoltStore) view(fn func(b *bolt.Bucket) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.path); errors.Is(err, os.ErrNotExist) {
+		return fn(nil)
+	}
+	db, err := s.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(usersBucket))
+	})
+}
+
+func (s *BoltStore) update(fn func(b *bolt.Bucket) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("create user db dir: %w", err)
+	}
+	db, err := s.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(usersBucket)
+		if err != nil {
+			return fmt.Errorf("create users bucket: %w", err)
+		}
+		return fn(b)
+	})
+}
+
+func (s *BoltStore) open(readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(s.path, 0o600, &bolt.Options{Timeout: 2 * time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("open user db: %w", err)
+	}
+	return db, nil
+}
+
+func putUser(b *bolt.Bucket, username string, rec boltUser) error {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode user %q: %w", username, err)
+	}
+	return b.Put([]byte(username), raw)
+}
@@ -0,0 +1,91 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+func TestBoltStore_ApplyChanges(t *testing.T) {
+	t.Parallel()
+
+	store := NewBoltStore(filepath.Join(t.TempDir(), "users.db"))
+	ctx := context.Background()
+
+	users, err := store.Users(ctx)
+	if err != nil || len(users) != 0 {
+		t.Fatalf("missing database must read as empty: %+v %v", users, err)
+	}
+
+	err = store.ApplyChanges(ctx, []domain.UserChange{
+		{Action: domain.UserChangeAdd, User: domain.User{Username: "bob", Password: "b"}},
+		{Action: domain.UserChangeAdd, User: domain.User{Username: "alice", Password: "a", Tags: []string{"ops"}}},
+		{Action: domain.UserChangeRotate, User: domain.User{Username: "bob", Password: "b2"}},
+	})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	users, err = store.Users(ctx)
+	if err != nil {
+		t.Fatalf("users: %v", err)
+	}
+	if len(users) != 2 || users[0].Username != "alice" || !users[0].HasTag("ops") || users[1].Password != "b2" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	before, _ := store.Checksum(ctx)
+	err = store.ApplyChanges(ctx, []domain.UserChange{
+		{Action: domain.UserChangeRemove, User: domain.User{Username: "alice"}},
+		{Action: domain.UserChangeAdd, User: domain.User{Username: "bob", Password: "x"}},
+	})
+	if !errors.Is(err, domain.ErrUserAlreadyExists) {
+		t.Fatalf("expected ErrUserAlreadyExists, got %v", err)
+	}
+	if after, _ := store.Checksum(ctx); after != before {
+		t.Fatalf("failed batch must not change the database")
+	}
+
+	if err := store.Replace(ctx, []domain.User{{Username: "carol", Password: "c"}}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if password, err := store.Password(ctx, "carol"); err != nil || password != "c" {
+		t.Fatalf("unexpected password: %q %v", password, err)
+	}
+	if _, err := store.Password(ctx, "alice"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestRepository_DBBackendRendersUserpass(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	seed := "listen: :443\nauth:\n  type: userpass\n  userpass:\n    old: secret\n"
+	if err := os.WriteFile(configPath, []byte(seed), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	repo := NewRepository(backendDB, configPath, filepath.Join(dir, "users.json"), filepath.Join(dir, "users.db"))
+	ctx := context.Background()
+
+	if err := repo.AddUser(ctx, domain.User{Username: "alice", Password: "a", Tags: []string{"ops"}}); err != nil {
+		t.Fatalf("add user: %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), "alice: a") || strings.Contains(string(data), "old:") {
+		t.Fatalf("auth.userpass must be rendered from the database:\n%s", data)
+	}
+
+	users, err := repo.Users(ctx)
+	if err != nil || len(users) != 1 || !users[0].HasTag("ops") {
+		t.Fatalf("unexpected users: %+v %v", users, err)
+	}
+}
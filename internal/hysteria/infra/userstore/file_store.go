@@ -0,0 +1,253 @@
+package userstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+
+	"vpn/internal/hysteria/domain"
+)
+
+type storedUser struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+type storeFile struct {
+	Users []storedUser `json:"users"`
+}
+
+// FileStore keeps users in a JSON file owned by this tool. Reads always hit
+// the file so a long-running auth server sees CLI changes immediately.
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) AddUser(ctx context.Context, user domain.User) error {
+	return s.ApplyChanges(ctx, []domain.UserChange{{Action: domain.UserChangeAdd, User: user}})
+}
+
+func (s *FileStore) RotatePassword(ctx context.Context, user domain.User) error {
+	return s.ApplyChanges(ctx, []domain.UserChange{{Action: domain.UserChangeRotate, User: user}})
+}
+
+func (s *FileStore) RemoveUser(ctx context.Context, username string) error {
+	return s.ApplyChanges(ctx, []domain.UserChange{{Action: domain.UserChangeRemove, User: domain.User{Username: username}}})
+}
+
+func (s *FileStore) ListUsers(ctx context.Context) ([]string, error) {
+	users, err := s.Users(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	return names, nil
+}
+
+func (s *FileStore) Users(_ context.Context) ([]domain.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]domain.User, 0, len(file.Users))
+	for _, u := range file.Users {
+		users = append(users, domain.User{Username: u.Username, Password: u.Password, Tags: u.Tags})
+	}
+	return users, nil
+}
+
+func (s *FileStore) Password(_ context.Context, username string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	for _, u := range file.Users {
+		if u.Username == username {
+			return u.Password, nil
+		}
+	}
+	return "", domain.ErrUserNotFound
+}
+
+func (s *FileStore) ApplyChanges(_ context.Context, changes []domain.UserChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+	index := make(map[string]int, len(file.Users))
+	for i, u := range file.Users {
+		index[u.Username] = i
+	}
+
+	for _, change := range changes {
+		username := change.User.Username
+		i, exists := index[username]
+		switch change.Action {
+		case domain.UserChangeAdd:
+			if exists {
+				return fmt.Errorf("%s: %w", username, domain.ErrUserAlreadyExists)
+			}
+			index[username] = len(file.Users)
+			file.Users = append(file.Users, storedUser{Username: username, Password: change.User.Password, Tags: change.User.Tags})
+		case domain.UserChangeRotate:
+			if !exists {
+				return fmt.Errorf("%s: %w", username, domain.ErrUserNotFound)
+			}
+			file.Users[i].Password = change.User.Password
+		case domain.UserChangeTags:
+			if !exists {
+				return fmt.Errorf("%s: %w", username, domain.ErrUserNotFound)
+			}
+			file.Users[i].Tags = change.User.Tags
+		case domain.UserChangeRemove:
+			if !exists {
+				return fmt.Errorf("%s: %w", username, domain.ErrUserNotFound)
+			}
+			file.Users = append(file.Users[:i], file.Users[i+1:]...)
+			delete(index, username)
+			for name, j := range index {
+				if j > i {
+					index[name] = j - 1
+				}
+			}
+		default:
+			return fmt.Errorf("%w: %q", domain.ErrInvalidChangeAction, change.Action)
+		}
+	}
+
+	return s.write(file)
+}
+
+func (s *FileStore) Replace(_ context.Context, users []domain.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file := storeFile{Users: make([]storedUser, 0, len(users))}
+	for _, u := range users {
+		file.Users = append(file.Users, storedUser{Username: u.Username, Password: u.Password, Tags: u.Tags})
+	}
+	return s.write(file)
+}
+
+func (s *FileStore) Checksum(_ context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("read user store: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (s *FileStore) read() (storeFile, error) {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return storeFile{}, nil
+		}
+		return storeFile{}, fmt.Errorf("read user store: %w", err)
+	}
+	var file storeFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return storeFile{}, fmt.Errorf("parse user store: %w", err)
+	}
+	return file, nil
+}
+
+func (s *FileStore) write(file storeFile) error {
+	sort.Slice(file.Users, func(i, j int) bool { return file.Users[i].Username < file.Users[j].Username })
+	if file.Users == nil {
+		file.Users = []storedUser{}
+	}
+	raw, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal user store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write user store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(raw, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write user store: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write user store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write user store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write user store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("write user store: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, fmt.Errorf("create user store dir: %w", err)
+	}
+	f, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("lock user store: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock user store: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
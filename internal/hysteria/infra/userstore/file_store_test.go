@@ -0,0 +1,70 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"vpn/internal/hysteria/domain"
+)
+
+func TestFileStore_ApplyChanges(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "users.json")
+	store := NewFileStore(path)
+	ctx := context.Background()
+
+	users, err := store.Users(ctx)
+	if err != nil || len(users) != 0 {
+		t.Fatalf("missing store must read as empty: %+v %v", users, err)
+	}
+
+	err = store.ApplyChanges(ctx, []domain.UserChange{
+		{Action: domain.UserChangeAdd, User: domain.User{Username: "bob", Password: "b"}},
+		{Action: domain.UserChangeAdd, User: domain.User{Username: "alice", Password: "a", Tags: []string{"ops"}}},
+		{Action: domain.UserChangeAdd, User: domain.User{Username: "carol", Password: "c"}},
+		{Action: domain.UserChangeRemove, User: domain.User{Username: "bob"}},
+		{Action: domain.UserChangeRotate, User: domain.User{Username: "carol", Password: "c2"}},
+	})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	users, err = store.Users(ctx)
+	if err != nil {
+		t.Fatalf("users: %v", err)
+	}
+	if len(users) != 2 || users[0].Username != "alice" || !users[0].HasTag("ops") || users[1].Password != "c2" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 store, got %v", info.Mode().Perm())
+	}
+
+	before, _ := store.Checksum(ctx)
+	err = store.ApplyChanges(ctx, []domain.UserChange{
+		{Action: domain.UserChangeAdd, User: domain.User{Username: "dave", Password: "d"}},
+		{Action: domain.UserChangeRemove, User: domain.User{Username: "missing"}},
+	})
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+	if after, _ := store.Checksum(ctx); after != before {
+		t.Fatal("failed batch must not modify the store")
+	}
+
+	if _, err := store.Password(ctx, "dave"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("expected dave to be absent, got %v", err)
+	}
+	if password, err := store.Password(ctx, "alice"); err != nil || password != "a" {
+		t.Fatalf("unexpected password: %q %v", password, err)
+	}
+}
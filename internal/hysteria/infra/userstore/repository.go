@@ -0,0 +1,155 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/hysteria/infra/configrepo"
+)
+
+const (
+	backendHTTP = "http"
+	backendDB   = "db"
+)
+
+// Repository routes user operations to the backend selected in the CLI
+// config: auth.userpass in the Hysteria config, the JSON store served to
+// Hysteria by the auth server, or the embedded database that auth.userpass is
+// rendered from after every change.
+type Repository struct {
+	config *configrepo.Repository
+	store  Store
+	render bool
+}
+
+func NewRepository(backend, configPath, storePath, dbPath string) *Repository {
+	repo := &Repository{config: configrepo.NewRepository(configPath)}
+	switch backend {
+	case backendHTTP:
+		repo.store = NewFileStore(storePath)
+	case backendDB:
+		repo.store = NewBoltStore(dbPath)
+		repo.render = true
+	}
+	return repo
+}
+
+func (r *Repository) AddUser(ctx context.Context, user domain.User) error {
+	if r.store == nil {
+		return r.config.AddUser(ctx, user)
+	}
+	return unwrapSingle(r.ApplyChanges(ctx, []domain.UserChange{{Action: domain.UserChangeAdd, User: user}}))
+}
+
+func (r *Repository) RotatePassword(ctx context.Context, user domain.User) error {
+	if r.store == nil {
+		return r.config.RotatePassword(ctx, user)
+	}
+	return unwrapSingle(r.ApplyChanges(ctx, []domain.UserChange{{Action: domain.UserChangeRotate, User: user}}))
+}
+
+func (r *Repository) RemoveUser(ctx context.Context, username string) error {
+	if r.store == nil {
+		return r.config.RemoveUser(ctx, username)
+	}
+	return unwrapSingle(r.ApplyChanges(ctx, []domain.UserChange{{Action: domain.UserChangeRemove, User: domain.User{Username: username}}}))
+}
+
+func (r *Repository) ListUsers(ctx context.Context) ([]string, error) {
+	if r.store == nil {
+		return r.config.ListUsers(ctx)
+	}
+	users, err := r.store.Users(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	return names, nil
+}
+
+func (r *Repository) Users(ctx context.Context) ([]domain.User, error) {
+	if r.store == nil {
+		return r.config.Users(ctx)
+	}
+	return r.store.Users(ctx)
+}
+
+func (r *Repository) ApplyChanges(ctx context.Context, changes []domain.UserChange) error {
+	if r.store == nil {
+		return r.config.ApplyChanges(ctx, changes)
+	}
+	if !r.render {
+		return r.store.ApplyChanges(ctx, changes)
+	}
+
+	before, err := r.store.Users(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.store.ApplyChanges(ctx, changes); err != nil {
+		return err
+	}
+	after, err := r.store.Users(ctx)
+	if err == nil {
+		err = r.config.RenderUserpass(ctx, after, false)
+	}
+	if err != nil {
+		if restoreErr := r.store.Replace(ctx, before); restoreErr != nil {
+			return errors.Join(fmt.Errorf("render auth.userpass: %w", err), fmt.Errorf("restore user db: %w", restoreErr))
+		}
+		return fmt.Errorf("render auth.userpass: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) Checksum(ctx context.Context) (string, error) {
+	if r.store == nil {
+		return r.config.Checksum(ctx)
+	}
+	return r.store.Checksum(ctx)
+}
+
+func (r *Repository) AuthInfo(ctx context.Context) (domain.AuthInfo, error) {
+	info, err := r.config.AuthInfo(ctx)
+	if err != nil {
+		return domain.AuthInfo{}, err
+	}
+	info.UserStore = r.store != nil
+	return info, nil
+}
+
+func (r *Repository) GetConnectionConfig(ctx context.Context, username string) (domain.ConnectionConfig, error) {
+	if r.store == nil {
+		return r.config.GetConnectionConfig(ctx, username)
+	}
+	password, err := r.store.Password(ctx, username)
+	if err != nil {
+		return domain.ConnectionConfig{}, err
+	}
+	cfg, err := r.config.ServerConfig(ctx)
+	if err != nil {
+		return domain.ConnectionConfig{}, err
+	}
+	cfg.Username = username
+	cfg.Password = password
+	return cfg, nil
+}
+
+// unwrapSingle keeps single-user operations returning the bare sentinel
+// errors, as the YAML repository does, instead of the "username: err" form
+// used for batches.
+func unwrapSingle(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrUserAlreadyExists):
+		return domain.ErrUserAlreadyExists
+	case errors.Is(err, domain.ErrUserNotFound):
+		return domain.ErrUserNotFound
+	default:
+		return err
+	}
+}
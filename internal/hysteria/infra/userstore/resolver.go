@@ -0,0 +1,68 @@
+package userstore
+
+import (
+	"context"
+	"fmt"
+
+	"vpn/internal/hysteria/domain"
+	"vpn/internal/hysteria/infra/configrepo"
+)
+
+const backendYAML = "yaml"
+
+// Resolver gives access to every storage backend by name, for copying users
+// between them regardless of which one is active.
+type Resolver struct {
+	config *configrepo.Repository
+	file   *FileStore
+	bolt   *BoltStore
+}
+
+func NewResolver(configPath, storePath, dbPath string) *Resolver {
+	return &Resolver{
+		config: configrepo.NewRepository(configPath),
+		file:   NewFileStore(storePath),
+		bolt:   NewBoltStore(dbPath),
+	}
+}
+
+func (r *Resolver) Users(ctx context.Context, backend string) ([]domain.User, error) {
+	switch backend {
+	case backendYAML:
+		return r.config.Users(ctx)
+	case backendHTTP:
+		return r.file.Users(ctx)
+	case backendDB:
+		return r.bolt.Users(ctx)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// Replace makes backend hold exactly users. For yaml this rewrites
+// auth.userpass and keeps tags as comments.
+func (r *Resolver) Replace(ctx context.Context, backend string, users []domain.User) error {
+	switch backend {
+	case backendYAML:
+		return r.config.RenderUserpass(ctx, users, true)
+	case backendHTTP:
+		return r.file.Replace(ctx, users)
+	case backendDB:
+		return r.bolt.Replace(ctx, users)
+	default:
+		return fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+func (r *Resolver) Checksum(ctx context.Context, backend string) (string, error) {
+	switch backend {
+	case backendYAML:
+		return r.config.Checksum(ctx)
+	case backendHTTP:
+		return r.file.Checksum(ctx)
+	case backendDB:
+		return r.bolt.Checksum(ctx)
+	default:
+		return "", fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
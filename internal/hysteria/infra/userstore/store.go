@@ -0,0 +1,16 @@
+package userstore
+
+import (
+	"context"
+
+	"vpn/internal/hysteria/domain"
+)
+
+// Store is a user storage backend owned by this tool.
+type Store interface {
+	Users(ctx context.Context) ([]domain.User, error)
+	Password(ctx context.Context, username string) (string, error)
+	ApplyChanges(ctx context.Context, changes []domain.UserChange) error
+	Replace(ctx context.Context, users []domain.User) error
+	Checksum(ctx context.Context) (string, error)
+}
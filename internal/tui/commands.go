@@ -1,66 +1,93 @@
 package tui
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"vpn/internal/hysteria/app/add_user"
+	"vpn/internal/hysteria/app/control_service"
+	"vpn/internal/hysteria/app/generate_config"
+	"vpn/internal/hysteria/app/get_acl"
 	"vpn/internal/hysteria/app/get_connection_url"
+	"vpn/internal/hysteria/app/get_settings"
 	"vpn/internal/hysteria/app/get_user_stats"
+	"vpn/internal/hysteria/app/list_connection_urls"
 	"vpn/internal/hysteria/app/list_users"
 	"vpn/internal/hysteria/app/remove_user"
 	"vpn/internal/hysteria/app/rotate_password"
+	"vpn/internal/hysteria/app/rotate_passwords"
+	"vpn/internal/hysteria/app/test_acl"
+	"vpn/internal/hysteria/app/update_settings"
+	"vpn/internal/hysteria/domain"
 )
 
-func loadUsersCmd(listUC *list_users.UseCase, statsUC *get_user_stats.UseCase) tea.Cmd {
+func loadUsersCmd(ctx context.Context, listUC *list_users.UseCase, statsUC *get_user_stats.UseCase) tea.Cmd {
 	return func() tea.Msg {
-		users, err := listUC.Execute(context.Background())
+		view, err := listUC.View(ctx)
 		if err != nil {
 			return usersLoadedMsg{users: nil, stats: nil, err: err}
 		}
 		stats := map[string]get_user_stats.UserStats{}
 		if statsUC != nil {
-			stats, _ = statsUC.Execute(context.Background(), users)
+			stats, _ = statsUC.Execute(ctx, view.Users)
 		}
-		return usersLoadedMsg{users: users, stats: stats, err: nil}
+		return usersLoadedMsg{users: view.Users, stats: stats, authMode: view.Auth.Mode, readOnly: view.ReadOnly, warning: view.Warning}
 	}
 }
 
-func addUserCmd(uc *add_user.UseCase, username string) tea.Cmd {
+func addUserCmd(ctx context.Context, uc *add_user.UseCase, username string) tea.Cmd {
 	return func() tea.Msg {
-		password, err := uc.Execute(context.Background(), username)
-		if err != nil {
+		user, err := uc.Execute(ctx, username)
+		if !applied(err) {
 			return operationMsg{err: err}
 		}
-		return operationMsg{title: "User created", body: fmt.Sprintf("User: %s\nPassword: %s", username, password), refresh: true}
+		return operationMsg{title: "User created", body: fmt.Sprintf("User: %s\nPassword: %s", user.Username, user.Password) + auditWarning(err), refresh: true}
 	}
 }
 
-func rotatePasswordCmd(uc *rotate_password.UseCase, username string) tea.Cmd {
+func rotatePasswordCmd(ctx context.Context, uc *rotate_password.UseCase, username string) tea.Cmd {
 	return func() tea.Msg {
-		password, err := uc.Execute(context.Background(), username)
-		if err != nil {
+		password, err := uc.Execute(ctx, username)
+		if !applied(err) {
 			return operationMsg{err: err}
 		}
-		return operationMsg{title: "Password rotated", body: fmt.Sprintf("User: %s\nNew password: %s", username, password), refresh: true}
+		return operationMsg{title: "Password rotated", body: fmt.Sprintf("User: %s\nNew password: %s", username, password) + auditWarning(err), refresh: true}
+	}
+}
+
+func rotatePasswordsCmd(ctx context.Context, uc *rotate_passwords.UseCase, usernames []string) tea.Cmd {
+	return func() tea.Msg {
+		rotated, err := uc.Execute(ctx, rotate_passwords.Selector{Usernames: usernames})
+		if !applied(err) {
+			return operationMsg{err: err}
+		}
+		lines := make([]string, 0, len(rotated))
+		for _, u := range rotated {
+			lines = append(lines, fmt.Sprintf("%s: %s", u.Username, u.Password))
+		}
+		return operationMsg{title: fmt.Sprintf("Passwords rotated (%d users, one restart)", len(rotated)), body: strings.Join(lines, "\n") + auditWarning(err), refresh: true, clearSelection: true}
 	}
 }
 
-func removeUserCmd(uc *remove_user.UseCase, username string) tea.Cmd {
+func removeUserCmd(ctx context.Context, uc *remove_user.UseCase, username string) tea.Cmd {
 	return func() tea.Msg {
-		if err := uc.Execute(context.Background(), username); err != nil {
+		if err := uc.Execute(ctx, username); err != nil {
 			return operationMsg{err: err}
 		}
 		return operationMsg{title: "User removed", body: fmt.Sprintf("User %s removed", username), refresh: true}
 	}
 }
 
-func connectionCmd(uc *get_connection_url.UseCase, username string) tea.Cmd {
+func connectionCmd(ctx context.Context, uc *get_connection_url.UseCase, username string) tea.Cmd {
 	return func() tea.Msg {
-		url, err := uc.Execute(context.Background(), username)
+		url, err := uc.Execute(ctx, username)
 		if err != nil {
 			return operationMsg{connection: true, err: err}
 		}
@@ -68,6 +95,139 @@ func connectionCmd(uc *get_connection_url.UseCase, username string) tea.Cmd {
 	}
 }
 
+func generateConfigCmd(ctx context.Context, uc *generate_config.UseCase, opts generate_config.Options, path string) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := os.Stat(path); err == nil {
+			return operationMsg{err: fmt.Errorf("%s already exists", path)}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return operationMsg{err: err}
+		}
+		result, err := uc.Execute(ctx, opts, true)
+		if err != nil {
+			return operationMsg{err: err}
+		}
+		if err := os.WriteFile(path, result.Config, 0o600); err != nil {
+			return operationMsg{err: err}
+		}
+		lines := []string{"Written to " + path}
+		if result.ObfsPassword != "" {
+			lines = append(lines, "Obfs password: "+result.ObfsPassword)
+		}
+		if result.StatsURL != "" {
+			lines = append(lines, "trafficStats "+result.StatsURL+" saved to the CLI config")
+		}
+		lines = append(lines, "", string(result.Config))
+		return operationMsg{title: "Config generated", body: strings.Join(lines, "\n")}
+	}
+}
+
+func loadSettingsCmd(ctx context.Context, uc *get_settings.UseCase) tea.Cmd {
+	return func() tea.Msg {
+		values, err := uc.Execute(ctx)
+		return settingsLoadedMsg{values: values, err: err}
+	}
+}
+
+// updateSettingsCmd applies req and, when client URLs changed, lists the
+// new URL of every user in the result.
+func updateSettingsCmd(ctx context.Context, uc *update_settings.UseCase, urlsUC *list_connection_urls.UseCase, req update_settings.Request) tea.Cmd {
+	return func() tea.Msg {
+		result, err := uc.Execute(ctx, req)
+		if !applied(err) {
+			return operationMsg{err: err}
+		}
+		if len(result.Updates) == 0 {
+			return operationMsg{title: "Settings unchanged", body: "The value is already set"}
+		}
+		lines := make([]string, 0, len(result.Updates))
+		for _, u := range result.Updates {
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", u.Key, settingLabel(u.Old), settingLabel(u.New)))
+		}
+		if result.ClientsAffected() {
+			urls, urlsErr := urlsUC.Execute(ctx)
+			if urlsErr != nil {
+				return operationMsg{err: errors.Join(fmt.Errorf("settings applied, but client urls failed: %w", urlsErr), err)}
+			}
+			lines = append(lines, "", "Every client URL changed, send the new ones:")
+			for _, c := range urls {
+				if c.Username == "" {
+					lines = append(lines, c.URL)
+					continue
+				}
+				lines = append(lines, c.Username+": "+c.URL)
+			}
+		}
+		return operationMsg{title: "Settings updated", body: strings.Join(lines, "\n") + auditWarning(err)}
+	}
+}
+
+// applied reports whether the change behind err went through: err is nil
+// or only says its audit entry was not recorded, so the result, which may
+// hold new credentials, must still be shown.
+func applied(err error) bool {
+	return err == nil || errors.Is(err, domain.ErrAuditRecord)
+}
+
+// auditWarning is appended to the result of a change whose audit entry was
+// not recorded.
+func auditWarning(err error) string {
+	if err == nil {
+		return ""
+	}
+	return "\n\nWarning: " + err.Error()
+}
+
+func loadACLCmd(ctx context.Context, uc *get_acl.UseCase) tea.Cmd {
+	return func() tea.Msg {
+		acl, err := uc.Execute(ctx)
+		return aclLoadedMsg{acl: acl, err: err}
+	}
+}
+
+// updateACLCmd runs one edit of the update_acl use case.
+func updateACLCmd(apply func() (domain.ACL, error), note string) tea.Cmd {
+	return func() tea.Msg {
+		acl, err := apply()
+		if err != nil {
+			return aclLoadedMsg{err: err}
+		}
+		return aclLoadedMsg{acl: acl, note: note}
+	}
+}
+
+func testACLCmd(ctx context.Context, uc *test_acl.UseCase, acl domain.ACL, target domain.ACLTarget) tea.Cmd {
+	return func() tea.Msg {
+		result, err := uc.Execute(ctx, target)
+		if err != nil {
+			return aclLoadedMsg{acl: acl, err: err}
+		}
+		rule := "no rule matched, default outbound"
+		if result.Rule != "" {
+			rule = fmt.Sprintf("rule #%d %s", result.ACLMatch.Rule+1, result.Rule)
+		}
+		note := fmt.Sprintf("%s %s/%d -> %s (%s)", target.Host, target.Proto, target.Port, result.Outbound.Name, rule)
+		if len(result.Skipped) > 0 {
+			note += fmt.Sprintf("; %d geoip/geosite rule(s) skipped", len(result.Skipped))
+		}
+		return aclLoadedMsg{acl: acl, note: note}
+	}
+}
+
+// serviceLogLines is the log tail the service panel shows.
+const serviceLogLines = 20
+
+func loadServiceCmd(ctx context.Context, uc *control_service.UseCase) tea.Cmd {
+	return func() tea.Msg {
+		status, err := uc.Status(ctx)
+		if err != nil {
+			return serviceLoadedMsg{err: err}
+		}
+		var logs bytes.Buffer
+		logsErr := uc.Logs(ctx, &logs, serviceLogLines, false)
+		return serviceLoadedMsg{status: status, logs: logs.String(), logsErr: logsErr}
+	}
+}
+
 func renderQRCode(content string) string {
 	path, err := exec.LookPath("qrencode")
 	if err != nil {
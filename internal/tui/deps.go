@@ -1,24 +1,77 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 
 	appconfig "vpn/internal/config"
 	"vpn/internal/hysteria/app/add_user"
+	"vpn/internal/hysteria/app/control_service"
+	"vpn/internal/hysteria/app/generate_config"
+	"vpn/internal/hysteria/app/get_acl"
 	"vpn/internal/hysteria/app/get_connection_url"
+	"vpn/internal/hysteria/app/get_settings"
 	"vpn/internal/hysteria/app/get_user_stats"
+	"vpn/internal/hysteria/app/list_connection_urls"
 	"vpn/internal/hysteria/app/list_users"
 	"vpn/internal/hysteria/app/remove_user"
+	"vpn/internal/hysteria/app/resolve_config_path"
 	"vpn/internal/hysteria/app/rotate_password"
+	"vpn/internal/hysteria/app/rotate_passwords"
+	"vpn/internal/hysteria/app/test_acl"
+	"vpn/internal/hysteria/app/update_acl"
+	"vpn/internal/hysteria/app/update_settings"
 )
 
 type Dependencies struct {
 	AddUser        *add_user.UseCase
 	RotatePassword *rotate_password.UseCase
+	RotateBatch    *rotate_passwords.UseCase
 	RemoveUser     *remove_user.UseCase
 	ListUsers      *list_users.UseCase
 	UserStats      *get_user_stats.UseCase
 	Connection     *get_connection_url.UseCase
+	GenerateConfig *generate_config.UseCase
+	Settings       *get_settings.UseCase
+	UpdateSettings *update_settings.UseCase
+	ClientURLs     *list_connection_urls.UseCase
+	ACL            *get_acl.UseCase
+	UpdateACL      *update_acl.UseCase
+	TestACL        *test_acl.UseCase
+	Service        *control_service.UseCase
+
+	// Context is the server context the use cases were built for; Contexts
+	// lists all of them for the switcher.
+	Context       string
+	Contexts      []string
+	SwitchContext func(name string) (*Dependencies, error)
+}
+
+// BuildForContext builds dependencies for one server context of base and
+// lets the TUI rebuild them for another one.
+func BuildForContext(base appconfig.Config, name string) (*Dependencies, error) {
+	cfg, err := base.ForContext(name)
+	if err != nil {
+		return nil, err
+	}
+	// In a container Hysteria reads its config from a mount; edit the host file.
+	resolveUC, err := resolve_config_path.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build config path usecase: %w", err)
+	}
+	if cfg.HysteriaConfigPath, err = resolveUC.Execute(context.Background()); err != nil {
+		return nil, err
+	}
+	deps, err := BuildDependencies(cfg)
+	if err != nil {
+		return nil, err
+	}
+	deps.Context = cfg.Context
+	deps.Contexts = base.ContextNames()
+	deps.SwitchContext = func(name string) (*Dependencies, error) {
+		return BuildForContext(base, name)
+	}
+	return deps, nil
 }
 
 func BuildDependencies(cfg appconfig.Config) (*Dependencies, error) {
@@ -32,6 +85,11 @@ func BuildDependencies(cfg appconfig.Config) (*Dependencies, error) {
 		return nil, fmt.Errorf("build rotate-password usecase: %w", err)
 	}
 
+	rotateBatchUC, err := rotate_passwords.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build rotate-passwords usecase: %w", err)
+	}
+
 	removeUC, err := remove_user.BuildUseCase(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("build remove-user usecase: %w", err)
@@ -50,13 +108,54 @@ func BuildDependencies(cfg appconfig.Config) (*Dependencies, error) {
 	if err != nil {
 		return nil, fmt.Errorf("build user-stats usecase: %w", err)
 	}
+	generateConfigUC, err := generate_config.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build config generate usecase: %w", err)
+	}
+	settingsUC, err := get_settings.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build settings get usecase: %w", err)
+	}
+	updateSettingsUC, err := update_settings.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build settings set usecase: %w", err)
+	}
+	clientURLsUC, err := list_connection_urls.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build client urls usecase: %w", err)
+	}
+	aclUC, err := get_acl.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build acl list usecase: %w", err)
+	}
+	updateACLUC, err := update_acl.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build acl usecase: %w", err)
+	}
+	testACLUC, err := test_acl.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build acl test usecase: %w", err)
+	}
+	serviceUC, err := control_service.BuildUseCase(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build service usecase: %w", err)
+	}
 
 	return &Dependencies{
 		AddUser:        addUC,
 		RotatePassword: rotateUC,
+		RotateBatch:    rotateBatchUC,
 		RemoveUser:     removeUC,
 		ListUsers:      listUC,
 		UserStats:      userStatsUC,
 		Connection:     connectionUC,
+		GenerateConfig: generateConfigUC,
+		Settings:       settingsUC,
+		UpdateSettings: updateSettingsUC,
+		ClientURLs:     clientURLsUC,
+		ACL:            aclUC,
+		UpdateACL:      updateACLUC,
+		TestACL:        testACLUC,
+		Service:        serviceUC,
 	}, nil
 }
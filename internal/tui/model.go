@@ -1,15 +1,27 @@
 package tui
 
 import (
+	"context"
+
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 
 	"vpn/internal/hysteria/app/add_user"
+	"vpn/internal/hysteria/app/control_service"
+	"vpn/internal/hysteria/app/generate_config"
+	"vpn/internal/hysteria/app/get_acl"
 	"vpn/internal/hysteria/app/get_connection_url"
+	"vpn/internal/hysteria/app/get_settings"
 	"vpn/internal/hysteria/app/get_user_stats"
+	"vpn/internal/hysteria/app/list_connection_urls"
 	"vpn/internal/hysteria/app/list_users"
 	"vpn/internal/hysteria/app/remove_user"
 	"vpn/internal/hysteria/app/rotate_password"
+	"vpn/internal/hysteria/app/rotate_passwords"
+	"vpn/internal/hysteria/app/test_acl"
+	"vpn/internal/hysteria/app/update_acl"
+	"vpn/internal/hysteria/app/update_settings"
+	"vpn/internal/hysteria/domain"
 )
 
 type appState int
@@ -22,6 +34,26 @@ const (
 	stateUserActions
 	stateResult
 	stateConnection
+	stateContexts
+	stateConfigWizard
+	stateSettings
+	stateSettingEdit
+	stateSettingConfirm
+	stateACL
+	stateACLInput
+	stateService
+)
+
+// ACL screen panes and what the input line is asking for.
+const (
+	aclPaneRules = iota
+	aclPaneOutbounds
+)
+
+const (
+	aclInputRule = iota
+	aclInputOutbound
+	aclInputTest
 )
 
 const (
@@ -32,17 +64,43 @@ const (
 )
 
 type usersLoadedMsg struct {
-	users []string
-	stats map[string]get_user_stats.UserStats
-	err   error
+	users    []string
+	stats    map[string]get_user_stats.UserStats
+	authMode string
+	readOnly bool
+	warning  string
+	err      error
+}
+
+type settingsLoadedMsg struct {
+	values []domain.SettingValue
+	err    error
+}
+
+// aclLoadedMsg carries the ACL after a load or an edit; note is shown
+// under the lists, e.g. the outcome of a test.
+type aclLoadedMsg struct {
+	acl  domain.ACL
+	note string
+	err  error
+}
+
+// serviceLoadedMsg carries the service status and a tail of its log;
+// logsErr only means the log could not be read.
+type serviceLoadedMsg struct {
+	status  domain.ServiceStatus
+	logs    string
+	logsErr error
+	err     error
 }
 
 type operationMsg struct {
-	title      string
-	body       string
-	err        error
-	refresh    bool
-	connection bool
+	title          string
+	body           string
+	err            error
+	refresh        bool
+	connection     bool
+	clearSelection bool
 }
 
 type styles struct {
@@ -65,18 +123,37 @@ type styles struct {
 
 type model struct {
 	state appState
+	ctx   context.Context
 
 	addUC        *add_user.UseCase
 	rotateUC     *rotate_password.UseCase
+	batchUC      *rotate_passwords.UseCase
 	removeUC     *remove_user.UseCase
 	listUC       *list_users.UseCase
 	statsUC      *get_user_stats.UseCase
 	connectionUC *get_connection_url.UseCase
+	generateUC   *generate_config.UseCase
+	settingsUC   *get_settings.UseCase
+	updateUC     *update_settings.UseCase
+	clientURLsUC *list_connection_urls.UseCase
+	aclUC        *get_acl.UseCase
+	updateACLUC  *update_acl.UseCase
+	testACLUC    *test_acl.UseCase
+	serviceUC    *control_service.UseCase
+
+	serverContext string
+	contexts      []string
+	switchContext func(name string) (*Dependencies, error)
+	contextCursor int
 
 	users       []string
 	usersCursor int
 	loading     bool
 	userStats   map[string]get_user_stats.UserStats
+	selected    map[string]bool
+	authMode    string
+	readOnly    bool
+	warning     string
 
 	selectedUser  string
 	actions       []string
@@ -85,6 +162,30 @@ type model struct {
 
 	input textinput.Model
 
+	// wizardStep indexes generate_config.Fields; the step after the last
+	// field asks where to save the config.
+	wizard     generate_config.Options
+	wizardStep int
+	wizardErr  string
+
+	// pendingSettings waits in stateSettingConfirm for changes that
+	// break every client URL.
+	settings        []domain.SettingValue
+	settingsCursor  int
+	settingsErr     string
+	pendingSettings update_settings.Request
+
+	acl       domain.ACL
+	aclPane   int
+	aclCursor int
+	aclInput  int
+	aclNote   string
+	aclErr    bool
+
+	service     domain.ServiceStatus
+	serviceLogs string
+	serviceNote string
+
 	resultTitle string
 	resultBody  string
 	resultErr   bool
@@ -95,23 +196,19 @@ type model struct {
 	styles styles
 }
 
-func newModel(deps *Dependencies) model {
+func newModel(ctx context.Context, deps *Dependencies) model {
 	ti := textinput.New()
 	ti.Focus()
 	ti.CharLimit = 128
 	ti.Width = 42
 	ti.Prompt = ""
 
-	return model{
-		state:        stateUsers,
-		addUC:        deps.AddUser,
-		rotateUC:     deps.RotatePassword,
-		removeUC:     deps.RemoveUser,
-		listUC:       deps.ListUsers,
-		statsUC:      deps.UserStats,
-		connectionUC: deps.Connection,
-		loading:      true,
-		userStats:    map[string]get_user_stats.UserStats{},
+	m := model{
+		state:     stateUsers,
+		ctx:       ctx,
+		loading:   true,
+		userStats: map[string]get_user_stats.UserStats{},
+		selected:  map[string]bool{},
 		actions: []string{
 			"Rotate password",
 			"Remove user",
@@ -127,4 +224,27 @@ func newModel(deps *Dependencies) model {
 		input:  ti,
 		styles: newStyles(),
 	}
+	return m.withDependencies(deps)
+}
+
+func (m model) withDependencies(deps *Dependencies) model {
+	m.addUC = deps.AddUser
+	m.rotateUC = deps.RotatePassword
+	m.batchUC = deps.RotateBatch
+	m.removeUC = deps.RemoveUser
+	m.listUC = deps.ListUsers
+	m.statsUC = deps.UserStats
+	m.connectionUC = deps.Connection
+	m.generateUC = deps.GenerateConfig
+	m.settingsUC = deps.Settings
+	m.updateUC = deps.UpdateSettings
+	m.clientURLsUC = deps.ClientURLs
+	m.aclUC = deps.ACL
+	m.updateACLUC = deps.UpdateACL
+	m.testACLUC = deps.TestACL
+	m.serviceUC = deps.Service
+	m.serverContext = deps.Context
+	m.contexts = deps.Contexts
+	m.switchContext = deps.SwitchContext
+	return m
 }
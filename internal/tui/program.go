@@ -1,9 +1,14 @@
 package tui
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/user"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"vpn/internal/hysteria/domain"
 )
 
 func Run(deps *Dependencies) error {
@@ -11,10 +16,19 @@ func Run(deps *Dependencies) error {
 		return fmt.Errorf("dependencies are required")
 	}
 
-	p := tea.NewProgram(newModel(deps), tea.WithAltScreen())
+	ctx := domain.ContextWithActor(context.Background(), sessionActor())
+	p := tea.NewProgram(newModel(ctx, deps), tea.WithAltScreen())
 	_, err := p.Run()
 	if err != nil {
 		return fmt.Errorf("run tui: %w", err)
 	}
 	return nil
 }
+
+func sessionActor() domain.Actor {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return domain.Actor{Kind: domain.ActorTUI, Name: fmt.Sprintf("%s/%d", name, os.Getpid())}
+}
@@ -1,12 +1,17 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"vpn/internal/hysteria/app/generate_config"
+	"vpn/internal/hysteria/app/update_settings"
+	"vpn/internal/hysteria/domain"
 )
 
-func (m model) Init() tea.Cmd { return loadUsersCmd(m.listUC, m.statsUC) }
+func (m model) Init() tea.Cmd { return loadUsersCmd(m.ctx, m.listUC, m.statsUC) }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -25,10 +30,68 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.users = msg.users
 		m.userStats = msg.stats
+		m.authMode = msg.authMode
+		m.readOnly = msg.readOnly
+		m.warning = msg.warning
+		present := make(map[string]bool, len(m.users))
+		for _, u := range m.users {
+			present[u] = true
+		}
+		for u := range m.selected {
+			if !present[u] {
+				delete(m.selected, u)
+			}
+		}
 		if m.usersCursor >= len(m.users) {
 			m.usersCursor = max(0, len(m.users)-1)
 		}
 		return m, nil
+	case settingsLoadedMsg:
+		if msg.err != nil {
+			m.resultTitle = "Load settings failed"
+			m.resultBody = msg.err.Error()
+			m.resultErr = true
+			m.state = stateResult
+			return m, nil
+		}
+		m.settings = msg.values
+		m.settingsCursor = min(m.settingsCursor, max(0, len(m.settings)-1))
+		m.state = stateSettings
+		return m, nil
+	case aclLoadedMsg:
+		if msg.err != nil && m.state != stateACL && m.state != stateACLInput {
+			m.resultTitle = "Load ACL failed"
+			m.resultBody = msg.err.Error()
+			m.resultErr = true
+			m.state = stateResult
+			return m, nil
+		}
+		if msg.err != nil {
+			m.aclNote, m.aclErr = msg.err.Error(), true
+			m.state = stateACL
+			return m, nil
+		}
+		m.acl = msg.acl
+		m.aclNote, m.aclErr = msg.note, false
+		m.aclCursor = min(m.aclCursor, max(0, m.aclPaneLen()-1))
+		m.state = stateACL
+		return m, nil
+	case serviceLoadedMsg:
+		if msg.err != nil {
+			m.resultTitle = "Load service status failed"
+			m.resultBody = msg.err.Error()
+			m.resultErr = true
+			m.state = stateResult
+			return m, nil
+		}
+		m.service = msg.status
+		m.serviceLogs = strings.TrimRight(msg.logs, "\n")
+		m.serviceNote = ""
+		if msg.logsErr != nil {
+			m.serviceNote = msg.logsErr.Error()
+		}
+		m.state = stateService
+		return m, nil
 	case operationMsg:
 		if msg.connection {
 			if msg.err != nil {
@@ -45,6 +108,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if msg.clearSelection && msg.err == nil {
+			m.selected = map[string]bool{}
+		}
 		if msg.err != nil {
 			m.resultTitle = "Operation failed"
 			m.resultBody = msg.err.Error()
@@ -57,7 +123,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = stateResult
 		if msg.refresh {
 			m.loading = true
-			return m, loadUsersCmd(m.listUC, m.statsUC)
+			return m, loadUsersCmd(m.ctx, m.listUC, m.statsUC)
 		}
 		return m, nil
 	case tea.KeyMsg:
@@ -68,6 +134,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateAddInput(msg)
 		case stateUserActions:
 			return m.updateUserActions(msg)
+		case stateContexts:
+			return m.updateContexts(msg)
+		case stateConfigWizard:
+			return m.updateConfigWizard(msg)
+		case stateSettings:
+			return m.updateSettings(msg)
+		case stateSettingEdit:
+			return m.updateSettingEdit(msg)
+		case stateSettingConfirm:
+			return m.updateSettingConfirm(msg)
+		case stateACL:
+			return m.updateACL(msg)
+		case stateACLInput:
+			return m.updateACLInput(msg)
+		case stateService:
+			return m.updateService(msg)
 		case stateResult, stateConnection:
 			if msg.String() == "q" || msg.String() == "ctrl+c" || msg.String() == "f10" {
 				return m, tea.Quit
@@ -80,16 +162,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) updateUsers(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.readOnly {
+		return m.updateReadOnlyUsers(msg)
+	}
 	switch msg.String() {
 	case "q", "ctrl+c", "f10":
 		return m, tea.Quit
+	case "s", "f3":
+		return m.openContexts()
+	case "g", "f4":
+		return m.openConfigWizard()
+	case "e", "f7":
+		m.settingsErr = ""
+		return m, loadSettingsCmd(m.ctx, m.settingsUC)
+	case "l", "f9":
+		m.aclPane, m.aclCursor, m.aclNote = aclPaneRules, 0, ""
+		return m, loadACLCmd(m.ctx, m.aclUC)
+	case "v":
+		return m, loadServiceCmd(m.ctx, m.serviceUC)
 	case "a", "f2":
 		m.input.SetValue("")
 		m.state = stateAddInput
 		return m, nil
 	case "r", "f5":
 		m.loading = true
-		return m, loadUsersCmd(m.listUC, m.statsUC)
+		return m, loadUsersCmd(m.ctx, m.listUC, m.statsUC)
 	case "up", "k":
 		if m.usersCursor > 0 {
 			m.usersCursor--
@@ -98,6 +195,25 @@ func (m model) updateUsers(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.usersCursor < len(m.users)-1 {
 			m.usersCursor++
 		}
+	case " ", "space":
+		if len(m.users) == 0 {
+			return m, nil
+		}
+		username := m.users[m.usersCursor]
+		if m.selected[username] {
+			delete(m.selected, username)
+		} else {
+			m.selected[username] = true
+		}
+		if m.usersCursor < len(m.users)-1 {
+			m.usersCursor++
+		}
+	case "R", "f8":
+		usernames := m.selectedUsernames()
+		if len(usernames) == 0 {
+			return m, nil
+		}
+		return m, rotatePasswordsCmd(m.ctx, m.batchUC, usernames)
 	case "enter", "f6":
 		if len(m.users) == 0 {
 			return m, nil
@@ -109,6 +225,56 @@ func (m model) updateUsers(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+func (m model) updateReadOnlyUsers(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c", "f10":
+		return m, tea.Quit
+	case "r", "f5":
+		m.loading = true
+		return m, loadUsersCmd(m.ctx, m.listUC, m.statsUC)
+	case "s", "f3":
+		return m.openContexts()
+	case "g", "f4":
+		return m.openConfigWizard()
+	case "e", "f7":
+		m.settingsErr = ""
+		return m, loadSettingsCmd(m.ctx, m.settingsUC)
+	case "l", "f9":
+		m.aclPane, m.aclCursor, m.aclNote = aclPaneRules, 0, ""
+		return m, loadACLCmd(m.ctx, m.aclUC)
+	case "v":
+		return m, loadServiceCmd(m.ctx, m.serviceUC)
+	case "up", "k":
+		if m.usersCursor > 0 {
+			m.usersCursor--
+		}
+	case "down", "j":
+		if m.usersCursor < len(m.users)-1 {
+			m.usersCursor++
+		}
+	case "enter", "f6":
+		return m, connectionCmd(m.ctx, m.connectionUC, "")
+	case "a", "f2", " ", "space", "R", "f8":
+		m.resultTitle = "Read-only auth mode"
+		m.resultBody = fmt.Sprintf("auth.type is %s: users are managed outside of auth.userpass.\nRun \"migrate-auth --to userpass\" in the CLI to manage users here.", m.authMode)
+		m.resultErr = true
+		m.state = stateResult
+	}
+	return m, nil
+}
+
+func (m model) updateService(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateUsers
+	case "q", "ctrl+c", "f10":
+		return m, tea.Quit
+	case "r", "f5":
+		return m, loadServiceCmd(m.ctx, m.serviceUC)
+	}
+	return m, nil
+}
+
 func (m model) updateAddInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "esc":
@@ -123,7 +289,7 @@ func (m model) updateAddInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.state = stateResult
 			return m, nil
 		}
-		return m, addUserCmd(m.addUC, username)
+		return m, addUserCmd(m.ctx, m.addUC, username)
 	}
 	var cmd tea.Cmd
 	m.input, cmd = m.input.Update(msg)
@@ -146,11 +312,11 @@ func (m model) updateUserActions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "enter":
 		switch userAction(m.actionsCursor) {
 		case actRotate:
-			return m, rotatePasswordCmd(m.rotateUC, m.selectedUser)
+			return m, rotatePasswordCmd(m.ctx, m.rotateUC, m.selectedUser)
 		case actRemove:
-			return m, removeUserCmd(m.removeUC, m.selectedUser)
+			return m, removeUserCmd(m.ctx, m.removeUC, m.selectedUser)
 		case actConnection:
-			return m, connectionCmd(m.connectionUC, m.selectedUser)
+			return m, connectionCmd(m.ctx, m.connectionUC, m.selectedUser)
 		case actBack:
 			m.state = stateUsers
 			return m, nil
@@ -158,3 +324,271 @@ func (m model) updateUserActions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 	return m, nil
 }
+
+func (m model) openContexts() (tea.Model, tea.Cmd) {
+	if len(m.contexts) == 0 || m.switchContext == nil {
+		m.resultTitle = "No server contexts"
+		m.resultBody = "Add \"contexts:\" to the CLI config to manage several servers."
+		m.resultErr = true
+		m.state = stateResult
+		return m, nil
+	}
+	m.contextCursor = 0
+	for i, name := range m.contexts {
+		if name == m.serverContext {
+			m.contextCursor = i
+		}
+	}
+	m.state = stateContexts
+	return m, nil
+}
+
+func (m model) updateContexts(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateUsers
+		return m, nil
+	case "up", "k":
+		if m.contextCursor > 0 {
+			m.contextCursor--
+		}
+	case "down", "j":
+		if m.contextCursor < len(m.contexts)-1 {
+			m.contextCursor++
+		}
+	case "enter":
+		deps, err := m.switchContext(m.contexts[m.contextCursor])
+		if err != nil {
+			m.resultTitle = "Switch server failed"
+			m.resultBody = err.Error()
+			m.resultErr = true
+			m.state = stateResult
+			return m, nil
+		}
+		m = m.withDependencies(deps)
+		m.users = nil
+		m.selected = map[string]bool{}
+		m.usersCursor = 0
+		m.authMode = ""
+		m.readOnly = false
+		m.loading = true
+		m.state = stateUsers
+		return m, loadUsersCmd(m.ctx, m.listUC, m.statsUC)
+	}
+	return m, nil
+}
+
+func (m model) openConfigWizard() (tea.Model, tea.Cmd) {
+	m.wizard = generate_config.DefaultOptions()
+	m.wizardStep = -1
+	m.wizardErr = ""
+	m.state = stateConfigWizard
+	return m.nextWizardStep(), nil
+}
+
+// nextWizardStep skips questions that do not apply to the answers so far.
+func (m model) nextWizardStep() model {
+	m.wizardStep++
+	for m.wizardStep < len(generate_config.Fields) && !generate_config.Fields[m.wizardStep].Applies(m.wizard) {
+		m.wizardStep++
+	}
+	if m.wizardStep < len(generate_config.Fields) {
+		m.input.SetValue(m.wizard.Get(generate_config.Fields[m.wizardStep].Key))
+	} else {
+		m.input.SetValue("hysteria-config.yaml")
+	}
+	m.input.CursorEnd()
+	return m
+}
+
+func (m model) updateConfigWizard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateUsers
+		return m, nil
+	case "enter":
+		value := strings.TrimSpace(m.input.Value())
+		if m.wizardStep >= len(generate_config.Fields) {
+			if value == "" {
+				m.wizardErr = "file path is required"
+				return m, nil
+			}
+			return m, generateConfigCmd(m.ctx, m.generateUC, m.wizard, value)
+		}
+		if err := m.wizard.Set(generate_config.Fields[m.wizardStep].Key, value); err != nil {
+			m.wizardErr = err.Error()
+			return m, nil
+		}
+		m.wizardErr = ""
+		return m.nextWizardStep(), nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateUsers
+		return m, nil
+	case "up", "k":
+		if m.settingsCursor > 0 {
+			m.settingsCursor--
+		}
+	case "down", "j":
+		if m.settingsCursor < len(m.settings)-1 {
+			m.settingsCursor++
+		}
+	case "enter":
+		if len(m.settings) == 0 {
+			return m, nil
+		}
+		m.settingsErr = ""
+		m.input.SetValue(m.settings[m.settingsCursor].Value)
+		m.input.CursorEnd()
+		m.state = stateSettingEdit
+	case "n":
+		if len(m.settings) == 0 || m.settings[m.settingsCursor].Def.Key != "obfs.password" {
+			return m, nil
+		}
+		m.pendingSettings = update_settings.Request{GenerateObfsPassword: true}
+		m.state = stateSettingConfirm
+	}
+	return m, nil
+}
+
+func (m model) updateSettingEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateSettings
+		return m, nil
+	case "enter":
+		def := m.settings[m.settingsCursor].Def
+		value, err := def.Normalize(m.input.Value())
+		if err != nil {
+			m.settingsErr = err.Error()
+			return m, nil
+		}
+		m.settingsErr = ""
+		m.pendingSettings = update_settings.Request{Changes: []domain.SettingChange{{Key: def.Key, Value: value}}}
+		if def.AffectsClients && value != m.settings[m.settingsCursor].Value {
+			m.state = stateSettingConfirm
+			return m, nil
+		}
+		return m, updateSettingsCmd(m.ctx, m.updateUC, m.clientURLsUC, m.pendingSettings)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateSettingConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		return m, updateSettingsCmd(m.ctx, m.updateUC, m.clientURLsUC, m.pendingSettings)
+	case "n", "N", "esc":
+		m.state = stateSettings
+	}
+	return m, nil
+}
+
+func (m model) aclPaneLen() int {
+	if m.aclPane == aclPaneOutbounds {
+		return len(m.acl.Outbounds)
+	}
+	return len(m.acl.Rules)
+}
+
+func (m model) updateACL(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateUsers
+		return m, nil
+	case "tab":
+		m.aclPane = (m.aclPane + 1) % 2
+		m.aclCursor = 0
+	case "up", "k":
+		if m.aclCursor > 0 {
+			m.aclCursor--
+		}
+	case "down", "j":
+		if m.aclCursor < m.aclPaneLen()-1 {
+			m.aclCursor++
+		}
+	case "a", "f2":
+		m.aclInput = aclInputRule
+		if m.aclPane == aclPaneOutbounds {
+			m.aclInput = aclInputOutbound
+		}
+		m.input.SetValue("")
+		m.state = stateACLInput
+	case "t":
+		m.aclInput = aclInputTest
+		m.input.SetValue("")
+		m.state = stateACLInput
+	case "d", "delete":
+		if m.aclPaneLen() == 0 {
+			return m, nil
+		}
+		if m.aclPane == aclPaneOutbounds {
+			name := m.acl.Outbounds[m.aclCursor].Name
+			return m, updateACLCmd(func() (domain.ACL, error) { return m.updateACLUC.RemoveOutbound(m.ctx, name) }, "Outbound "+name+" removed")
+		}
+		index := m.aclCursor
+		return m, updateACLCmd(func() (domain.ACL, error) { return m.updateACLUC.RemoveRule(m.ctx, index) }, fmt.Sprintf("Rule #%d removed", index+1))
+	case "K", "shift+up", "J", "shift+down":
+		to := m.aclCursor - 1
+		if msg.String() == "J" || msg.String() == "shift+down" {
+			to = m.aclCursor + 1
+		}
+		if to < 0 || to >= m.aclPaneLen() {
+			return m, nil
+		}
+		from := m.aclCursor
+		m.aclCursor = to
+		if m.aclPane == aclPaneOutbounds {
+			name := m.acl.Outbounds[from].Name
+			return m, updateACLCmd(func() (domain.ACL, error) { return m.updateACLUC.MoveOutbound(m.ctx, name, to) }, "")
+		}
+		return m, updateACLCmd(func() (domain.ACL, error) { return m.updateACLUC.MoveRule(m.ctx, from, to) }, "")
+	}
+	return m, nil
+}
+
+func (m model) updateACLInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.state = stateACL
+		return m, nil
+	case "enter":
+		value := strings.TrimSpace(m.input.Value())
+		if value == "" {
+			m.state = stateACL
+			return m, nil
+		}
+		switch m.aclInput {
+		case aclInputRule:
+			return m, updateACLCmd(func() (domain.ACL, error) { return m.updateACLUC.AddRule(m.ctx, value, -1) }, "Rule added")
+		case aclInputOutbound:
+			outbound, err := parseOutboundSpec(value)
+			if err != nil {
+				m.aclNote, m.aclErr = err.Error(), true
+				m.state = stateACL
+				return m, nil
+			}
+			return m, updateACLCmd(func() (domain.ACL, error) { return m.updateACLUC.AddOutbound(m.ctx, outbound) }, "Outbound "+outbound.Name+" added")
+		case aclInputTest:
+			target, err := parseACLTarget(value)
+			if err != nil {
+				m.aclNote, m.aclErr = err.Error(), true
+				m.state = stateACL
+				return m, nil
+			}
+			return m, testACLCmd(m.ctx, m.testACLUC, m.acl, target)
+		}
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
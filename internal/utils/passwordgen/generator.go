@@ -2,68 +2,102 @@ package passwordgen
 
 import (
 	"crypto/rand"
+	_ "embed"
+	"encoding/binary"
 	"fmt"
+	"strings"
+	"sync"
 )
 
-const generatedPasswordLength = 32
+//go:embed wordlist.txt
+var wordlist string
 
-var (
-	lower = "abcdefghijklmnopqrstuvwxyz"
-	upper = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	digit = "0123456789"
-	all   = lower + upper + digit
-)
+var words = sync.OnceValue(func() []string { return strings.Fields(wordlist) })
 
-type Generator struct{}
+type Generator struct {
+	policy Policy
+}
 
-func NewGenerator() *Generator {
-	return &Generator{}
+func NewGenerator(policy Policy) *Generator {
+	return &Generator{policy: policy.withDefaults()}
 }
 
-func (g *Generator) Generate() (string, error) {
-	buf := make([]byte, generatedPasswordLength)
-	for i := range buf {
-		idx, err := secureInt(len(all))
-		if err != nil {
-			return "", err
-		}
-		buf[i] = all[idx]
-	}
+func (g *Generator) Policy() Policy {
+	return g.policy
+}
+
+func (g *Generator) Check(password string) error {
+	return g.policy.Check(password)
+}
 
-	if err := forceClass(buf, 0, lower); err != nil {
+func (g *Generator) Generate() (string, error) {
+	if err := g.policy.Validate(); err != nil {
 		return "", err
 	}
-	if err := forceClass(buf, 1, upper); err != nil {
-		return "", err
+	if g.policy.Mode == ModePassphrase {
+		return g.passphrase()
 	}
-	if err := forceClass(buf, 2, digit); err != nil {
-		return "", err
+	return g.random()
+}
+
+// random draws every character uniformly from the union of the classes and
+// redraws the whole password when a class is missing. Rejection keeps all
+// valid passwords equally likely, unlike forcing a class into a fixed
+// position.
+func (g *Generator) random() (string, error) {
+	sets := g.policy.classSets()
+	alphabet := strings.Join(sets, "")
+	buf := make([]byte, g.policy.Length)
+	for {
+		for i := range buf {
+			idx, err := secureInt(len(alphabet))
+			if err != nil {
+				return "", err
+			}
+			buf[i] = alphabet[idx]
+		}
+		if hasEveryClass(buf, sets) {
+			return string(buf), nil
+		}
 	}
+}
 
-	return string(buf), nil
+func (g *Generator) passphrase() (string, error) {
+	list := words()
+	picked := make([]string, g.policy.Words)
+	for i := range picked {
+		idx, err := secureInt(len(list))
+		if err != nil {
+			return "", err
+		}
+		picked[i] = list[idx]
+	}
+	return strings.Join(picked, g.policy.Separator), nil
 }
 
-func forceClass(buf []byte, pos int, charset string) error {
-	idx, err := secureInt(len(charset))
-	if err != nil {
-		return err
+func hasEveryClass(buf []byte, sets []string) bool {
+	for _, set := range sets {
+		if !strings.ContainsAny(string(buf), set) {
+			return false
+		}
 	}
-	buf[pos] = charset[idx]
-	return nil
+	return true
 }
 
+// secureInt returns a uniform integer in [0, max) from crypto/rand,
+// rejecting values from the incomplete last range to avoid modulo bias.
 func secureInt(max int) (int, error) {
-	if max <= 0 {
+	if max <= 0 || max > 1<<31 {
 		return 0, fmt.Errorf("invalid max: %d", max)
 	}
-	var b [1]byte
-	limit := byte(256 - (256 % max))
+	limit := uint32((1 << 32) - (1<<32)%uint64(max))
+	var b [4]byte
 	for {
 		if _, err := rand.Read(b[:]); err != nil {
 			return 0, err
 		}
-		if b[0] < limit {
-			return int(b[0]) % max, nil
+		if v := binary.BigEndian.Uint32(b[:]); limit == 0 || v < limit {
+			return int(v % uint32(max)), nil
 		}
 	}
 }
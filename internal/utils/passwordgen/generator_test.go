@@ -0,0 +1,199 @@
+package passwordgen
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := []Policy{
+		{},
+		{Length: 8, Classes: []string{ClassLower, ClassUpper, ClassDigit, ClassSymbol}},
+		{Mode: ModePassphrase, Words: 4, Separator: "."},
+	}
+	for _, p := range valid {
+		if err := p.Validate(); err != nil {
+			t.Fatalf("%+v: unexpected error %v", p, err)
+		}
+	}
+	invalid := []Policy{
+		{Mode: "pin"},
+		{Length: 6},
+		{Length: 200},
+		{Classes: []string{"emoji"}},
+		{Classes: []string{ClassDigit, ClassDigit}},
+		{Mode: ModePassphrase, Words: 2},
+		{Mode: ModePassphrase, Separator: ":"},
+		{MinLength: 4},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); !errors.Is(err, ErrInvalidPolicy) {
+			t.Fatalf("%+v: expected ErrInvalidPolicy, got %v", p, err)
+		}
+	}
+	if _, err := NewGenerator(Policy{Length: 4}).Generate(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("generate must refuse an invalid policy, got %v", err)
+	}
+}
+
+func TestGenerateRandomClasses(t *testing.T) {
+	t.Parallel()
+
+	policy := Policy{Length: 8, Classes: []string{ClassLower, ClassUpper, ClassDigit, ClassSymbol}, ExcludeAmbiguous: true}
+	g := NewGenerator(policy)
+	for i := 0; i < 2000; i++ {
+		password, err := g.Generate()
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if len(password) != 8 {
+			t.Fatalf("unexpected length: %q", password)
+		}
+		for _, set := range g.policy.classSets() {
+			if !strings.ContainsAny(password, set) {
+				t.Fatalf("%q misses class %q", password, set)
+			}
+		}
+		if strings.ContainsAny(password, ambiguous) {
+			t.Fatalf("%q contains ambiguous characters", password)
+		}
+	}
+}
+
+// TestRandomPositionsUniform checks every position separately, including
+// the first ones the old generator forced into fixed classes.
+func TestRandomPositionsUniform(t *testing.T) {
+	t.Parallel()
+
+	const samples = 12000
+	g := NewGenerator(Policy{})
+	alphabet := strings.Join(g.policy.classSets(), "")
+	counts := make([]map[byte]int, g.policy.Length)
+	for i := range counts {
+		counts[i] = map[byte]int{}
+	}
+	for i := 0; i < samples; i++ {
+		password, err := g.Generate()
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		for pos := range password {
+			counts[pos][password[pos]]++
+		}
+	}
+	expected := float64(samples) / float64(len(alphabet))
+	for pos, c := range counts {
+		// df = 61; the 1e-9 critical value is about 140.
+		if chi := chiSquare(c, alphabet, expected); chi > 140 {
+			t.Fatalf("position %d is not uniform: chi-square %.1f", pos, chi)
+		}
+	}
+}
+
+func TestSecureIntUniform(t *testing.T) {
+	t.Parallel()
+
+	// 7 does not divide 2^32, so modulo bias would show without rejection.
+	const max, samples = 7, 70000
+	counts := map[byte]int{}
+	for i := 0; i < samples; i++ {
+		v, err := secureInt(max)
+		if err != nil {
+			t.Fatalf("secureInt: %v", err)
+		}
+		counts[byte('0'+v)]++
+	}
+	// df = 6; the 1e-9 critical value is about 52.
+	if chi := chiSquare(counts, "0123456", samples/max); chi > 52 {
+		t.Fatalf("secureInt is not uniform: chi-square %.1f %v", chi, counts)
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	t.Parallel()
+
+	known := map[string]bool{}
+	for _, w := range words() {
+		if known[w] || strings.Trim(w, "abcdefghijklmnopqrstuvwxyz") != "" {
+			t.Fatalf("wordlist entry %q is duplicated or not lowercase ascii", w)
+		}
+		known[w] = true
+	}
+
+	g := NewGenerator(Policy{Mode: ModePassphrase, Words: 5, Separator: "."})
+	seen := map[string]int{}
+	for i := 0; i < 500; i++ {
+		phrase, err := g.Generate()
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		parts := strings.Split(phrase, ".")
+		if len(parts) != 5 {
+			t.Fatalf("unexpected passphrase %q", phrase)
+		}
+		for _, w := range parts {
+			if !known[w] {
+				t.Fatalf("%q is not from the wordlist", w)
+			}
+			seen[w]++
+		}
+	}
+	// 2500 draws from ~1900 words: a working generator leaves few repeats.
+	if len(seen) < 1000 {
+		t.Fatalf("only %d distinct words in 2500 draws", len(seen))
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	t.Parallel()
+
+	policy := Policy{MinLength: 10}
+	for _, password := range []string{"migrated-Pass1", "p@ss/w?rd#%&+=", strings.Repeat("x", 128)} {
+		if err := policy.Check(password); err != nil {
+			t.Fatalf("%q: unexpected error %v", password, err)
+		}
+	}
+	for _, password := range []string{"short", "has:colon-inside", "has space inside", "tab\tinside-pass", "non-ascii-пароль", strings.Repeat("x", 129)} {
+		err := policy.Check(password)
+		if !errors.Is(err, ErrInvalidPassword) {
+			t.Fatalf("%q: expected ErrInvalidPassword, got %v", password, err)
+		}
+		if strings.Contains(err.Error(), password) {
+			t.Fatalf("error leaks the password: %v", err)
+		}
+	}
+	if err := DefaultPolicy().Check("elevenchars"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("default minimum must be 12, got %v", err)
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	t.Parallel()
+
+	if e := DefaultPolicy().Entropy(); e < 190 || e > 190.6 {
+		t.Fatalf("unexpected default entropy %.2f", e)
+	}
+	// 36^8 strings minus those without letters or without digits.
+	small := Policy{Length: 8, Classes: []string{ClassLower, ClassDigit}}
+	want := math.Log2(math.Pow(36, 8) - math.Pow(26, 8) - math.Pow(10, 8))
+	if e := small.Entropy(); math.Abs(e-want) > 1e-9 {
+		t.Fatalf("expected %.4f bits, got %.4f", want, e)
+	}
+	phrase := Policy{Mode: ModePassphrase, Words: 6}
+	if e, want := phrase.Entropy(), 6*math.Log2(float64(len(words()))); math.Abs(e-want) > 1e-9 {
+		t.Fatalf("expected %.4f bits, got %.4f", want, e)
+	}
+}
+
+func chiSquare(counts map[byte]int, alphabet string, expected float64) float64 {
+	var chi float64
+	for i := 0; i < len(alphabet); i++ {
+		d := float64(counts[alphabet[i]]) - expected
+		chi += d * d / expected
+	}
+	return chi
+}
@@ -0,0 +1,219 @@
+package passwordgen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	ModeRandom     = "random"
+	ModePassphrase = "passphrase"
+
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+const (
+	defaultLength    = 32
+	defaultWords     = 7
+	defaultSeparator = "-"
+	defaultMinLength = 12
+	minLength        = 8
+	maxLength        = 128
+	minWords         = 4
+	maxWords         = 24
+)
+
+// Symbols are limited to URL-unreserved characters so passwords can be put
+// into hy2:// URLs without escaping; ':' would also split userpass auth.
+var charsets = map[string]string{
+	ClassLower:  "abcdefghijklmnopqrstuvwxyz",
+	ClassUpper:  "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	ClassDigit:  "0123456789",
+	ClassSymbol: "-_.~",
+}
+
+var classOrder = []string{ClassLower, ClassUpper, ClassDigit, ClassSymbol}
+
+const ambiguous = "0Oo1lI"
+
+var (
+	ErrInvalidPolicy   = errors.New("invalid password policy")
+	ErrInvalidPassword = errors.New("password rejected by policy")
+)
+
+// Policy describes generated passwords. Zero fields take the defaults: 32
+// random characters from lower, upper and digit classes, or 7 words joined
+// with "-" in passphrase mode.
+type Policy struct {
+	Mode string
+	// Length and Classes apply to random mode; every listed class appears at
+	// least once.
+	Length           int
+	Classes          []string
+	ExcludeAmbiguous bool
+	// Words and Separator apply to passphrase mode.
+	Words     int
+	Separator string
+	// MinLength applies to passwords supplied by the operator instead of
+	// generated ones.
+	MinLength int
+}
+
+func DefaultPolicy() Policy {
+	return Policy{}.withDefaults()
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.Mode == "" {
+		p.Mode = ModeRandom
+	}
+	if p.Length == 0 {
+		p.Length = defaultLength
+	}
+	if len(p.Classes) == 0 {
+		p.Classes = []string{ClassLower, ClassUpper, ClassDigit}
+	}
+	if p.Words == 0 {
+		p.Words = defaultWords
+	}
+	if p.Separator == "" {
+		p.Separator = defaultSeparator
+	}
+	if p.MinLength == 0 {
+		p.MinLength = defaultMinLength
+	}
+	return p
+}
+
+func (p Policy) Validate() error {
+	p = p.withDefaults()
+	if p.MinLength < minLength || p.MinLength > maxLength {
+		return fmt.Errorf("%w: min_length %d is outside %d..%d", ErrInvalidPolicy, p.MinLength, minLength, maxLength)
+	}
+	switch p.Mode {
+	case ModeRandom:
+		if p.Length < minLength || p.Length > maxLength {
+			return fmt.Errorf("%w: length %d is outside %d..%d", ErrInvalidPolicy, p.Length, minLength, maxLength)
+		}
+		seen := map[string]bool{}
+		for _, class := range p.Classes {
+			if _, ok := charsets[class]; !ok {
+				return fmt.Errorf("%w: unknown class %q (allowed: %s)", ErrInvalidPolicy, class, strings.Join(classOrder, ", "))
+			}
+			if seen[class] {
+				return fmt.Errorf("%w: class %q listed twice", ErrInvalidPolicy, class)
+			}
+			seen[class] = true
+		}
+		if len(p.Classes) > p.Length {
+			return fmt.Errorf("%w: length %d cannot fit %d classes", ErrInvalidPolicy, p.Length, len(p.Classes))
+		}
+	case ModePassphrase:
+		if p.Words < minWords || p.Words > maxWords {
+			return fmt.Errorf("%w: words %d is outside %d..%d", ErrInvalidPolicy, p.Words, minWords, maxWords)
+		}
+		if len(p.Separator) != 1 || !strings.Contains(charsets[ClassSymbol], p.Separator) {
+			return fmt.Errorf("%w: separator %q must be one of %q", ErrInvalidPolicy, p.Separator, charsets[ClassSymbol])
+		}
+	default:
+		return fmt.Errorf("%w: unknown mode %q (allowed: %s, %s)", ErrInvalidPolicy, p.Mode, ModeRandom, ModePassphrase)
+	}
+	return nil
+}
+
+// Check validates a password supplied by the operator. Only the length and
+// the character set are enforced so passwords migrated from another server
+// keep working; errors name the offending position, never the password.
+//
+// Printable ASCII is accepted except space and ':', which splits userpass
+// auth on the wire. Other URL-special characters are escaped when the
+// hy2:// link is built.
+func (p Policy) Check(password string) error {
+	p = p.withDefaults()
+	if n := len(password); n < p.MinLength || n > maxLength {
+		return fmt.Errorf("%w: length %d is outside %d..%d", ErrInvalidPassword, n, p.MinLength, maxLength)
+	}
+	for i := 0; i < len(password); i++ {
+		switch c := password[i]; {
+		case c == ':':
+			return fmt.Errorf("%w: character %d is ':', which breaks userpass auth", ErrInvalidPassword, i+1)
+		case c <= ' ' || c >= 0x7f:
+			return fmt.Errorf("%w: character %d is whitespace, a control character or not ASCII", ErrInvalidPassword, i+1)
+		}
+	}
+	return nil
+}
+
+// Entropy estimates the strength of generated passwords in bits. Random
+// passwords that miss a required class are redrawn, so the count of valid
+// passwords is taken by inclusion-exclusion over the classes.
+func (p Policy) Entropy() float64 {
+	p = p.withDefaults()
+	if p.Mode == ModePassphrase {
+		return float64(p.Words) * math.Log2(float64(len(words())))
+	}
+	sets := p.classSets()
+	total := new(big.Int)
+	for mask := 0; mask < 1<<len(sets); mask++ {
+		size := 0
+		for i, set := range sets {
+			if mask&(1<<i) == 0 {
+				size += len(set)
+			}
+		}
+		term := new(big.Int).Exp(big.NewInt(int64(size)), big.NewInt(int64(p.Length)), nil)
+		if popcount(mask)%2 == 1 {
+			total.Sub(total, term)
+		} else {
+			total.Add(total, term)
+		}
+	}
+	// maxLength keeps the count well inside float64 range.
+	f, _ := new(big.Float).SetInt(total).Float64()
+	return math.Log2(f)
+}
+
+func (p Policy) String() string {
+	p = p.withDefaults()
+	if p.Mode == ModePassphrase {
+		return fmt.Sprintf("passphrase, %d words separated by %q", p.Words, p.Separator)
+	}
+	desc := fmt.Sprintf("random, %d characters from %s", p.Length, strings.Join(p.Classes, "+"))
+	if p.ExcludeAmbiguous {
+		desc += " without " + ambiguous
+	}
+	return desc
+}
+
+// classSets returns the characters of every class in the policy, with
+// ambiguous ones removed when requested.
+func (p Policy) classSets() []string {
+	sets := make([]string, 0, len(p.Classes))
+	for _, class := range p.Classes {
+		set := charsets[class]
+		if p.ExcludeAmbiguous {
+			set = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguous, r) {
+					return -1
+				}
+				return r
+			}, set)
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+func popcount(v int) int {
+	n := 0
+	for ; v > 0; v &= v - 1 {
+		n++
+	}
+	return n
+}
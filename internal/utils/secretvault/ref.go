@@ -0,0 +1,53 @@
+package secretvault
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	SourceLiteral = "literal"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceVault   = "vault"
+)
+
+// ParseRef splits a config value into its source and target. "env:NAME",
+// "file:/path" and "vault:name" point elsewhere; anything else is the
+// secret itself.
+func ParseRef(value string) (source, target string) {
+	for _, source := range []string{SourceEnv, SourceFile, SourceVault} {
+		if target, ok := strings.CutPrefix(value, source+":"); ok && target != "" {
+			return source, target
+		}
+	}
+	return SourceLiteral, value
+}
+
+// Resolve returns the secret a config value refers to. Secret files must
+// be 0600; a trailing newline is trimmed.
+func Resolve(value string, vault *Vault) (string, error) {
+	source, target := ParseRef(value)
+	switch source {
+	case SourceEnv:
+		secret, ok := os.LookupEnv(target)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", target)
+		}
+		return secret, nil
+	case SourceFile:
+		if err := CheckPrivate(target); err != nil {
+			return "", err
+		}
+		raw, err := os.ReadFile(target)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(raw), "\r\n"), nil
+	case SourceVault:
+		return vault.Get(target)
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,285 @@
+package secretvault
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	vaultHeader = "vpn-vault/v1"
+	keyPrefix   = "VPN-SECRET-KEY-"
+	// pendingKeySuffix marks a key written by an interrupted Rotate.
+	pendingKeySuffix = ".new"
+)
+
+var (
+	ErrNoKey     = errors.New("vault key file not found")
+	ErrBadKey    = errors.New("vault cannot be decrypted with this key")
+	ErrNotFound  = errors.New("secret not found")
+	ErrBadFormat = errors.New("malformed vault")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Vault keeps named secrets in a file encrypted to an X25519 key, in the
+// spirit of age: every write uses a fresh ephemeral key, the file key is
+// derived with HKDF-SHA256 and the entries are sealed with
+// XChaCha20-Poly1305. Only the key file can unlock the vault.
+type Vault struct {
+	path    string
+	keyFile string
+}
+
+func New(path, keyFile string) *Vault {
+	return &Vault{path: path, keyFile: keyFile}
+}
+
+func (v *Vault) Path() string    { return v.path }
+func (v *Vault) KeyFile() string { return v.keyFile }
+
+// Exists reports whether the vault has been set up, i.e. its key exists.
+func (v *Vault) Exists() bool {
+	_, err := os.Stat(v.keyFile)
+	return err == nil
+}
+
+func (v *Vault) Get(name string) (string, error) {
+	entries, _, err := v.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := entries[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q in %s", ErrNotFound, name, v.path)
+	}
+	return value, nil
+}
+
+func (v *Vault) Names() ([]string, error) {
+	entries, _, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Set stores a secret, creating the key file on first use. It reports
+// whether a new key was created so callers can tell the operator to back
+// it up.
+func (v *Vault) Set(name, value string) (created bool, err error) {
+	if !v.Exists() {
+		if _, err := os.Stat(v.path); err == nil {
+			return false, fmt.Errorf("%w: %s exists but %s is missing", ErrNoKey, v.path, v.keyFile)
+		}
+		if err := writeKey(v.keyFile); err != nil {
+			return false, fmt.Errorf("create vault key: %w", err)
+		}
+		created = true
+	}
+	entries, key, err := v.load()
+	if err != nil {
+		return created, err
+	}
+	entries[name] = value
+	return created, v.save(entries, key.PublicKey())
+}
+
+func (v *Vault) Delete(name string) error {
+	entries, key, err := v.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[name]; !ok {
+		return fmt.Errorf("%w: %q in %s", ErrNotFound, name, v.path)
+	}
+	delete(entries, name)
+	return v.save(entries, key.PublicKey())
+}
+
+// Rotate re-encrypts the vault to a new key and replaces the key file.
+// The new key is written next to the old one first; if the process dies
+// before it is moved into place, the next load finishes the rotation.
+func (v *Vault) Rotate() error {
+	entries, _, err := v.load()
+	if err != nil {
+		return err
+	}
+	pending := v.keyFile + pendingKeySuffix
+	if err := writeKey(pending); err != nil {
+		return fmt.Errorf("create vault key: %w", err)
+	}
+	key, err := readKey(pending)
+	if err != nil {
+		return err
+	}
+	if err := v.save(entries, key.PublicKey()); err != nil {
+		os.Remove(pending)
+		return err
+	}
+	return os.Rename(pending, v.keyFile)
+}
+
+func (v *Vault) load() (map[string]string, *ecdh.PrivateKey, error) {
+	key, err := readKey(v.keyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: %s (store a secret with \"secrets set\" first)", ErrNoKey, v.keyFile)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := os.ReadFile(v.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, key, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := decrypt(raw, key)
+	if errors.Is(err, ErrBadKey) {
+		pending := v.keyFile + pendingKeySuffix
+		if next, nextErr := readKey(pending); nextErr == nil {
+			if entries, nextErr := decrypt(raw, next); nextErr == nil {
+				if err := os.Rename(pending, v.keyFile); err != nil {
+					return nil, nil, fmt.Errorf("finish key rotation: %w", err)
+				}
+				return entries, next, nil
+			}
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", v.path, err)
+	}
+	return entries, key, nil
+}
+
+func (v *Vault) save(entries map[string]string, recipient *ecdh.PublicKey) error {
+	plain, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return err
+	}
+	aead, err := fileCipher(shared, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return err
+	}
+	header := vaultHeader + "\nx25519 " + b64.EncodeToString(ephemeral.PublicKey().Bytes())
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(header))
+	return WritePrivate(v.path, []byte(header+"\n"+b64.EncodeToString(sealed)+"\n"))
+}
+
+func decrypt(raw []byte, key *ecdh.PrivateKey) (map[string]string, error) {
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 3 || lines[0] != vaultHeader || !strings.HasPrefix(lines[1], "x25519 ") {
+		return nil, ErrBadFormat
+	}
+	ephemeralRaw, err := b64.DecodeString(strings.TrimPrefix(lines[1], "x25519 "))
+	if err != nil {
+		return nil, ErrBadFormat
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(ephemeralRaw)
+	if err != nil {
+		return nil, ErrBadFormat
+	}
+	sealed, err := b64.DecodeString(lines[2])
+	if err != nil {
+		return nil, ErrBadFormat
+	}
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return nil, ErrBadFormat
+	}
+	aead, err := fileCipher(shared, ephemeral, key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrBadFormat
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(lines[0]+"\n"+lines[1]))
+	if err != nil {
+		return nil, ErrBadKey
+	}
+	entries := map[string]string{}
+	if err := json.Unmarshal(plain, &entries); err != nil {
+		return nil, ErrBadFormat
+	}
+	return entries, nil
+}
+
+// fileCipher derives the cipher of one vault write from the X25519 shared
+// secret, bound to both public keys.
+func fileCipher(shared []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeral.Bytes()...), recipient.Bytes()...)
+	key, err := hkdf.Key(sha256.New, shared, salt, vaultHeader, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.NewX(key)
+}
+
+func writeKey(path string) error {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("# vpn secrets vault key, created %s\n# public key: %s\n%s%s\n",
+		time.Now().UTC().Format(time.RFC3339), b64.EncodeToString(key.PublicKey().Bytes()), keyPrefix, b64.EncodeToString(key.Bytes()))
+	return WritePrivate(path, []byte(content))
+}
+
+func readKey(path string) (*ecdh.PrivateKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := CheckPrivate(path); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, keyPrefix) {
+			continue
+		}
+		raw, err := b64.DecodeString(strings.TrimPrefix(line, keyPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid key: %w", path, err)
+		}
+		return ecdh.X25519().NewPrivateKey(raw)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s: no %s line", path, keyPrefix)
+}
@@ -0,0 +1,160 @@
+package secretvault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestVault(t *testing.T) *Vault {
+	t.Helper()
+	dir := t.TempDir()
+	return New(filepath.Join(dir, "secrets.vault"), filepath.Join(dir, "secrets.key"))
+}
+
+func TestVaultRoundTrip(t *testing.T) {
+	v := newTestVault(t)
+	if v.Exists() {
+		t.Fatal("fresh vault must not exist")
+	}
+	if _, err := v.Get("stats"); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+
+	created, err := v.Set("stats", "s3cret-value")
+	if err != nil || !created {
+		t.Fatalf("first set must create the key: created=%v err=%v", created, err)
+	}
+	if created, err := v.Set("other", "x"); err != nil || created {
+		t.Fatalf("second set must reuse the key: created=%v err=%v", created, err)
+	}
+	if got, err := v.Get("stats"); err != nil || got != "s3cret-value" {
+		t.Fatalf("unexpected value %q: %v", got, err)
+	}
+
+	raw, err := os.ReadFile(v.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "s3cret-value") || !strings.HasPrefix(string(raw), vaultHeader+"\n") {
+		t.Fatalf("vault is not encrypted:\n%s", raw)
+	}
+	for _, path := range []string{v.Path(), v.KeyFile()} {
+		if err := CheckPrivate(path); err != nil {
+			t.Fatalf("expected 0600: %v", err)
+		}
+	}
+
+	if err := v.Delete("other"); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := v.Names(); err != nil || len(names) != 1 || names[0] != "stats" {
+		t.Fatalf("unexpected names %v: %v", names, err)
+	}
+	if _, err := v.Get("other"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// A flipped ciphertext byte must not decrypt.
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	last := []byte(lines[2])
+	last[10] ^= 1
+	lines[2] = string(last)
+	if err := os.WriteFile(v.Path(), []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Get("stats"); err == nil {
+		t.Fatal("tampered vault must not open")
+	}
+}
+
+func TestVaultRotate(t *testing.T) {
+	v := newTestVault(t)
+	if _, err := v.Set("stats", "value"); err != nil {
+		t.Fatal(err)
+	}
+	oldKey, err := os.ReadFile(v.KeyFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Rotate(); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	newKey, _ := os.ReadFile(v.KeyFile())
+	if string(newKey) == string(oldKey) {
+		t.Fatal("rotate must replace the key")
+	}
+	if got, err := v.Get("stats"); err != nil || got != "value" {
+		t.Fatalf("entries must survive rotation: %q %v", got, err)
+	}
+
+	// The old key no longer opens the vault.
+	if err := os.WriteFile(v.KeyFile(), oldKey, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Get("stats"); !errors.Is(err, ErrBadKey) {
+		t.Fatalf("expected ErrBadKey with the old key, got %v", err)
+	}
+
+	// An interrupted rotation left the new key next to the old one.
+	if err := os.WriteFile(v.KeyFile()+pendingKeySuffix, newKey, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get("stats"); err != nil || got != "value" {
+		t.Fatalf("pending key must finish the rotation: %q %v", got, err)
+	}
+	if _, err := os.Stat(v.KeyFile() + pendingKeySuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("pending key must be moved into place: %v", err)
+	}
+}
+
+func TestVaultRejectsInsecureKey(t *testing.T) {
+	v := newTestVault(t)
+	if _, err := v.Set("stats", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(v.KeyFile(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Get("stats"); !errors.Is(err, ErrInsecureMode) {
+		t.Fatalf("expected ErrInsecureMode, got %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	v := newTestVault(t)
+	if _, err := v.Set("stats", "from-vault"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VPN_TEST_SECRET", "from-env")
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"plain":               "plain",
+		"env:VPN_TEST_SECRET": "from-env",
+		"file:" + file:        "from-file",
+		"vault:stats":         "from-vault",
+		"vault:":              "vault:",
+	}
+	for value, want := range cases {
+		got, err := Resolve(value, v)
+		if err != nil || got != want {
+			t.Fatalf("%s: expected %q, got %q (%v)", value, want, got, err)
+		}
+	}
+
+	if _, err := Resolve("env:VPN_TEST_MISSING", v); err == nil {
+		t.Fatal("missing env must fail")
+	}
+	if err := os.Chmod(file, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Resolve("file:"+file, v); !errors.Is(err, ErrInsecureMode) {
+		t.Fatalf("expected ErrInsecureMode, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Package resources embeds the provisioning assets so an installed binary
+// can run init from any directory.
+package resources
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed ansible/hysteria_init.yml ansible/vars.yaml defaults/config.yaml
+var assets embed.FS
+
+// Asset paths as laid out by Extract. The playbook finds the config at
+// {{ playbook_dir }}/../config.yaml, so the layout matches vars.yaml.
+const (
+	Playbook = "ansible/hysteria_init.yml"
+	Vars     = "ansible/vars.yaml"
+	Config   = "config.yaml"
+)
+
+var layout = map[string]string{
+	Playbook: "ansible/hysteria_init.yml",
+	Vars:     "ansible/vars.yaml",
+	Config:   "defaults/config.yaml",
+}
+
+// Extract writes every asset below dir and returns the written paths.
+// Existing files are kept unless overwrite is set; all destinations are
+// checked before the first write, so a refusal leaves dir untouched.
+func Extract(dir string, overwrite bool) ([]string, error) {
+	names := []string{Playbook, Vars, Config}
+	if !overwrite {
+		for _, name := range names {
+			dst := filepath.Join(dir, filepath.FromSlash(name))
+			if _, err := os.Stat(dst); err == nil {
+				return nil, fmt.Errorf("%s: %w (use --force to overwrite)", dst, fs.ErrExist)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+
+	var written []string
+	for _, name := range names {
+		data, err := assets.ReadFile(layout[name])
+		if err != nil {
+			return written, err
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(dst, data, 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, dst)
+	}
+	return written, nil
+}
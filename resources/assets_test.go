@@ -0,0 +1,49 @@
+package resources
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractWritesPlaybookLayout(t *testing.T) {
+	dir := t.TempDir()
+	written, err := Extract(dir, false)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if len(written) != 3 {
+		t.Fatalf("written = %v", written)
+	}
+	config, err := os.ReadFile(filepath.Join(dir, Config))
+	sample, _ := assets.ReadFile(layout[Config])
+	if err != nil || !bytes.Equal(config, sample) || len(config) == 0 {
+		t.Fatalf("config not extracted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, Playbook)); err != nil {
+		t.Fatalf("playbook not extracted: %v", err)
+	}
+
+	if _, err := Extract(dir, false); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected ErrExist on second extract, got %v", err)
+	}
+	if _, err := Extract(dir, true); err != nil {
+		t.Fatalf("extract with overwrite: %v", err)
+	}
+}
+
+func TestExtractRefusesBeforeWriting(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, Config), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Extract(dir, false); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected ErrExist, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, Playbook)); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("playbook written before the refusal: %v", err)
+	}
+}